@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// command is a CLI subcommand run instead of the HTTP server, e.g. `go run . import-products -store <id> -file products.csv`
+type command func(db *gorm.DB, args []string) error
+
+var commands = map[string]command{
+	"import-products": importProductsCommand,
+	"export-orders":   exportOrdersCommand,
+}
+
+// runCommand runs the subcommand named by args[0] and returns the process exit code
+func runCommand(db *gorm.DB, args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "unknown command %q, available commands: %v\n", args[0], names)
+		return 2
+	}
+	if err := cmd(db, args[1:]); err != nil {
+		log.Printf("%s: %v", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func importProductsCommand(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("import-products", flag.ContinueOnError)
+	store := flags.String("store", "", "ID of the store to import into")
+	file := flags.String("file", "", "CSV file to import")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	storeID, err := uuid.Parse(*store)
+	if err != nil {
+		return fmt.Errorf("a valid -store is required")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	importService := services.NewImportService(repositories.NewImportRepository(db))
+	job, err := importService.RunProductImport(storeID, f.Name(), f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Import %s %s: %d rows, %d imported, %d failed\n", job.ID, job.Status, job.TotalRows, job.ImportedRows, job.FailedRows)
+	if job.FailedRows > 0 {
+		fmt.Println(job.Errors)
+	}
+	return nil
+}
+
+func exportOrdersCommand(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("export-orders", flag.ContinueOnError)
+	format := flags.String("format", services.ExportFormatCSV, "csv or ndjson")
+	round := flags.String("round", "", "only orders of this sales round")
+	from := flags.String("from", "", "only orders on or after this date (2006-01-02) or RFC 3339 time")
+	to := flags.String("to", "", "only orders up to and including this date, or before this RFC 3339 time")
+	out := flags.String("out", "", "file to write to instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !services.ValidExportFormat(*format) {
+		return fmt.Errorf("-format must be csv or ndjson")
+	}
+
+	var filter dtos.OrderExportFilter
+	var err error
+	if *round != "" {
+		roundID, err := uuid.Parse(*round)
+		if err != nil {
+			return fmt.Errorf("-round is not a valid UUID")
+		}
+		filter.RoundID = &roundID
+	}
+	if filter.From, err = services.ParseExportTime(*from, false); err != nil {
+		return err
+	}
+	if filter.To, err = services.ParseExportTime(*to, true); err != nil {
+		return err
+	}
+
+	output := os.Stdout
+	if *out != "" {
+		if output, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer output.Close()
+	}
+
+	w := bufio.NewWriter(output)
+	exportService := services.NewExportService(repositories.NewExportRepository(db))
+	if err := exportService.ExportOrders(filter, *format, w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
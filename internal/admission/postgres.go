@@ -0,0 +1,288 @@
+package admission
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type cachedConfig struct {
+	config   RoundConfig
+	loadedAt time.Time
+}
+
+type postgresQueue struct {
+	db      *gorm.DB
+	lookup  RoundLookup
+	options Options
+
+	mu        sync.Mutex
+	configs   map[uuid.UUID]cachedConfig
+	lastPrune time.Time
+}
+
+// NewPostgresQueue creates a Queue that keeps its tickets in the admission-ticket and admission-round tables, so
+// every instance of the API shares the queues and together admits buyers at each round's rate
+func NewPostgresQueue(db *gorm.DB, lookup RoundLookup, options Options) Queue {
+	return &postgresQueue{
+		db:      db,
+		lookup:  lookup,
+		options: options.withDefaults(),
+		configs: make(map[uuid.UUID]cachedConfig),
+	}
+}
+
+func (q *postgresQueue) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			q.Tick(now)
+		}
+	}()
+}
+
+func (q *postgresQueue) Tick(now time.Time) {
+	var rounds []models.AdmissionRound
+	if err := q.db.Find(&rounds).Error; err != nil {
+		log.Printf("admission queue: loading rounds: %v", err)
+		return
+	}
+	for _, round := range rounds {
+		if err := q.admit(round.RoundID, now); err != nil {
+			log.Printf("admission queue: admitting to round %s: %v", round.RoundID, err)
+		}
+	}
+
+	q.mu.Lock()
+	prune := now.Sub(q.lastPrune) >= time.Minute
+	if prune {
+		q.lastPrune = now
+	}
+	q.mu.Unlock()
+	if prune {
+		if err := q.prune(now); err != nil {
+			log.Printf("admission queue: pruning: %v", err)
+		}
+	}
+}
+
+// admit lets through as many waiting tickets as the round has earned since it was last ticked, by any instance.
+// An instance that finds the round locked leaves it to the one ticking it.
+func (q *postgresQueue) admit(roundID uuid.UUID, now time.Time) error {
+	config, err := q.config(roundID, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) { // The round was deleted; so is its queue
+		if err := q.db.Where("round_id = ?", roundID).Delete(&models.AdmissionTicket{}).Error; err != nil {
+			return err
+		}
+		return q.db.Where("round_id = ?", roundID).Delete(&models.AdmissionRound{}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		var round models.AdmissionRound
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			First(&round, "round_id = ?", roundID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !now.After(round.LastTick) { // Another instance, whose clock may run ahead, ticked already
+			return nil
+		}
+
+		allowance := earned(config, round.Allowance, now.Sub(round.LastTick), now)
+		if n := int(allowance); n > 0 {
+			due := q.waiting(tx, roundID, now).Select("token").Order("seq").Limit(n)
+			result := tx.Model(&models.AdmissionTicket{}).Where("token IN (?)", due).Update("admitted_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			allowance -= float64(result.RowsAffected)
+		}
+		var waiting int64
+		if err := q.waiting(tx, roundID, now).Count(&waiting).Error; err != nil {
+			return err
+		}
+		return tx.Model(&round).Updates(map[string]interface{}{
+			"allowance": leftOver(allowance, int(waiting)),
+			"last_tick": now,
+		}).Error
+	})
+}
+
+// waiting selects the tickets of a round that wait to be admitted and have not been abandoned
+func (q *postgresQueue) waiting(tx *gorm.DB, roundID uuid.UUID, now time.Time) *gorm.DB {
+	return tx.Model(&models.AdmissionTicket{}).
+		Where("round_id = ? AND admitted_at IS NULL AND last_seen >= ?", roundID, now.Add(-q.options.AbandonAfter))
+}
+
+// prune drops rounds that have ended, waiting tickets that were abandoned and admitted tickets that have expired
+func (q *postgresQueue) prune(now time.Time) error {
+	ended := q.db.Model(&models.AdmissionRound{}).Select("round_id").Where("ends_at < ?", now.Add(-q.options.AdmitFor))
+	err := q.db.
+		Where("round_id IN (?)", ended).
+		Or("admitted_at IS NULL AND last_seen < ?", now.Add(-q.options.AbandonAfter)).
+		Or("admitted_at < ?", now.Add(-q.options.AdmitFor)).
+		Delete(&models.AdmissionTicket{}).Error
+	if err != nil {
+		return err
+	}
+	return q.db.Where("ends_at < ?", now.Add(-q.options.AdmitFor)).Delete(&models.AdmissionRound{}).Error
+}
+
+func (q *postgresQueue) Join(roundID uuid.UUID) (Ticket, error) {
+	now := q.options.Now()
+	config, err := q.config(roundID, now)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if !now.Before(config.EndDate) {
+		return Ticket{}, ErrRoundClosed
+	}
+
+	t := models.AdmissionTicket{Token: uuid.New().String(), RoundID: roundID, LastSeen: now}
+	err = q.db.Transaction(func(tx *gorm.DB) error {
+		if config.Rate > 0 {
+			round := models.AdmissionRound{RoundID: roundID, LastTick: now, EndsAt: config.EndDate}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "round_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"ends_at"}),
+			}).Create(&round).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			t.AdmittedAt = &now
+		}
+		return tx.Create(&t).Error
+	})
+	if err != nil {
+		return Ticket{}, err
+	}
+	return q.ticket(config, t, now)
+}
+
+func (q *postgresQueue) Status(roundID uuid.UUID, token string) (Ticket, error) {
+	now := q.options.Now()
+	t, err := q.lookupTicket(roundID, token, now)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if err := q.db.Model(&t).Update("last_seen", now).Error; err != nil {
+		return Ticket{}, err
+	}
+	config, err := q.config(roundID, now)
+	if err != nil {
+		return Ticket{}, err
+	}
+	return q.ticket(config, t, now)
+}
+
+func (q *postgresQueue) Admit(roundID uuid.UUID, token string) error {
+	now := q.options.Now()
+	config, err := q.config(roundID, now)
+	if err != nil {
+		return err
+	}
+	if config.Rate <= 0 {
+		return nil
+	}
+
+	// Claiming in one statement keeps two instances from both using an admission
+	result := q.db.Model(&models.AdmissionTicket{}).
+		Where("token = ? AND round_id = ? AND NOT claimed AND admitted_at >= ?", token, roundID, now.Add(-q.options.AdmitFor)).
+		Update("claimed", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+
+	t, err := q.lookupTicket(roundID, token, now)
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.AdmittedAt == nil:
+		if err := q.db.Model(&t).Update("last_seen", now).Error; err != nil {
+			return err
+		}
+		return ErrNotAdmitted
+	case now.Sub(*t.AdmittedAt) > q.options.AdmitFor:
+		return ErrAdmissionExpired
+	}
+	return ErrTicketUsed
+}
+
+func (q *postgresQueue) Release(roundID uuid.UUID, token string) {
+	err := q.db.Model(&models.AdmissionTicket{}).
+		Where("token = ? AND round_id = ?", token, roundID).
+		Update("claimed", false).Error
+	if err != nil {
+		log.Printf("admission queue: releasing a token of round %s: %v", roundID, err)
+	}
+}
+
+// lookupTicket returns a round's ticket, or ErrUnknownTicket once the ticket was abandoned
+func (q *postgresQueue) lookupTicket(roundID uuid.UUID, token string, now time.Time) (models.AdmissionTicket, error) {
+	var t models.AdmissionTicket
+	err := q.db.First(&t, "token = ? AND round_id = ?", token, roundID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return t, ErrUnknownTicket
+	}
+	if err != nil {
+		return t, err
+	}
+	if t.AdmittedAt == nil && now.Sub(t.LastSeen) > q.options.AbandonAfter {
+		if err := q.db.Delete(&t).Error; err != nil {
+			return t, err
+		}
+		return t, ErrUnknownTicket
+	}
+	return t, nil
+}
+
+func (q *postgresQueue) ticket(config RoundConfig, t models.AdmissionTicket, now time.Time) (Ticket, error) {
+	var ahead int64
+	if t.AdmittedAt == nil {
+		if err := q.waiting(q.db, t.RoundID, now).Where("seq < ?", t.Seq).Count(&ahead).Error; err != nil {
+			return Ticket{}, err
+		}
+	}
+	return newTicket(t.RoundID, t.Token, t.AdmittedAt, int(ahead), config, q.options, now), nil
+}
+
+// config returns a round's config, cached for ConfigTTL in this instance
+func (q *postgresQueue) config(roundID uuid.UUID, now time.Time) (RoundConfig, error) {
+	q.mu.Lock()
+	cached, ok := q.configs[roundID]
+	q.mu.Unlock()
+	if ok && now.Sub(cached.loadedAt) < q.options.ConfigTTL {
+		return cached.config, nil
+	}
+
+	config, err := q.lookup(roundID)
+	if err != nil {
+		return RoundConfig{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, cached := range q.configs {
+		if now.Sub(cached.loadedAt) >= q.options.ConfigTTL {
+			delete(q.configs, id)
+		}
+	}
+	q.configs[roundID] = cachedConfig{config: config, loadedAt: now}
+	return config, nil
+}
@@ -0,0 +1,386 @@
+package admission
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// TokenHeader carries the queue token on purchase requests
+const TokenHeader = "X-Queue-Token"
+
+var (
+	// ErrRoundClosed is returned when joining the queue of a round that has ended
+	ErrRoundClosed = errors.New("sales round has ended")
+	// ErrUnknownTicket is returned for a token the queue does not know, e.g. one that lapsed
+	ErrUnknownTicket = errors.New("queue token is not known")
+	// ErrTicketUsed is returned when an admitted token is presented again; each admission is good for one purchase
+	ErrTicketUsed = errors.New("queue token has already been used")
+	// ErrNotAdmitted is returned while a ticket is still waiting in the queue
+	ErrNotAdmitted = errors.New("queue token has not been admitted yet")
+	// ErrAdmissionExpired is returned when an admitted ticket was not used in time
+	ErrAdmissionExpired = errors.New("queue admission has expired")
+)
+
+// RoundConfig tells the queue how a sales round admits buyers
+type RoundConfig struct {
+	Rate      float64 // Buyers admitted per second; 0 means the round is not queued
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// RoundLookup loads the admission config of a sales round
+type RoundLookup func(roundID uuid.UUID) (RoundConfig, error)
+
+// SalesRoundLookup loads round configs from the sales round repository
+func SalesRoundLookup(salesRoundRepo repositories.SalesRoundRepository) RoundLookup {
+	return func(roundID uuid.UUID) (RoundConfig, error) {
+		round, err := salesRoundRepo.GetSalesRoundByID(roundID)
+		if err != nil {
+			return RoundConfig{}, err
+		}
+		return RoundConfig{Rate: float64(round.AdmissionRate), StartDate: round.StartDate, EndDate: round.EndDate}, nil
+	}
+}
+
+// Ticket is a client's place in a round's admission queue
+type Ticket struct {
+	Token      string     `json:"token"`
+	RoundID    uuid.UUID  `json:"round_id"`
+	Position   int        `json:"position"` // Tickets ahead of this one; 0 once admitted
+	Admitted   bool       `json:"admitted"`
+	AdmittedAt *time.Time `json:"admitted_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Purchases with the token are refused after this
+	RetryAfter int        `json:"retry_after"`          // Suggested seconds until the next poll
+}
+
+// Options tune a Queue
+type Options struct {
+	AdmitFor     time.Duration    // How long an admitted ticket may purchase
+	AbandonAfter time.Duration    // Waiting tickets not polled for this long lose their place
+	ConfigTTL    time.Duration    // How long a round's config is cached
+	Now          func() time.Time // Clock of the queue; defaults to time.Now
+}
+
+// Queue is a virtual waiting room in front of purchases. Every queued round admits its tickets in
+// join order at the round's rate, starting when the round opens, so the database only sees as many buyers as
+// the rate lets through.
+type Queue interface {
+	// Start admits tickets every interval in the background
+	Start(interval time.Duration)
+	// Tick admits the tickets due at now
+	Tick(now time.Time)
+	// Join puts a new ticket at the end of a round's queue
+	Join(roundID uuid.UUID) (Ticket, error)
+	// Status returns a ticket's place in the queue; polling also keeps a waiting ticket from being abandoned
+	Status(roundID uuid.UUID, token string) (Ticket, error)
+	// Admit claims an admitted token for a purchase from a round, so it cannot be used again; rounds that are not
+	// queued admit everyone
+	Admit(roundID uuid.UUID, token string) error
+	// Release gives back the claim of a token whose purchase did not go through
+	Release(roundID uuid.UUID, token string)
+}
+
+type ticket struct {
+	token      string
+	seq        int
+	lastSeen   time.Time
+	admittedAt *time.Time
+	claimed    bool // A purchase has used the admission
+}
+
+type roundQueue struct {
+	config    RoundConfig
+	loadedAt  time.Time
+	byToken   map[string]*ticket
+	waiting   []*ticket // Not admitted yet, in join order
+	nextSeq   int       // Sequence number of the next ticket to join
+	allowance float64   // Admissions earned but not used yet
+	lastTick  time.Time
+}
+
+type queue struct {
+	lookup  RoundLookup
+	options Options
+
+	mu        sync.Mutex
+	rounds    map[uuid.UUID]*roundQueue
+	lastPrune time.Time
+}
+
+// NewQueue creates a Queue that loads round configs with lookup and keeps the tickets in this process. Each
+// instance of the API would then admit buyers at the full rate and not know the others' tokens, so it is only
+// for a single instance; use NewPostgresQueue when several serve the API.
+func NewQueue(lookup RoundLookup, options Options) Queue {
+	return &queue{
+		lookup:  lookup,
+		options: options.withDefaults(),
+		rounds:  make(map[uuid.UUID]*roundQueue),
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.AdmitFor <= 0 {
+		o.AdmitFor = 10 * time.Minute
+	}
+	if o.AbandonAfter <= 0 {
+		o.AbandonAfter = 2 * time.Minute
+	}
+	if o.ConfigTTL <= 0 {
+		o.ConfigTTL = 30 * time.Second
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+func (q *queue) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			q.Tick(now)
+		}
+	}()
+}
+
+func (q *queue) Tick(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, round := range q.rounds {
+		q.admit(round, now)
+	}
+
+	if now.Sub(q.lastPrune) >= time.Minute {
+		q.prune(now)
+		q.lastPrune = now
+	}
+}
+
+// admit lets through as many waiting tickets as the round has earned since the last tick. Admissions do not
+// pile up while nobody waits, so the rate holds even when a crowd arrives at once.
+func (q *queue) admit(round *roundQueue, now time.Time) {
+	round.allowance = earned(round.config, round.allowance, now.Sub(round.lastTick), now)
+	if now.After(round.lastTick) {
+		round.lastTick = now
+	}
+	for round.allowance >= 1 && len(round.waiting) > 0 {
+		t := round.waiting[0]
+		round.waiting = round.waiting[1:]
+		if q.abandoned(t, now) {
+			delete(round.byToken, t.token)
+			continue
+		}
+		admittedAt := now
+		t.admittedAt = &admittedAt
+		round.allowance--
+	}
+	round.allowance = leftOver(round.allowance, len(round.waiting))
+}
+
+// earned returns the admissions a round has to hand out at now, given the allowance left at its last tick,
+// elapsed ago. Rounds that are not queued or not open yet earn none.
+func earned(config RoundConfig, allowance float64, elapsed time.Duration, now time.Time) float64 {
+	if config.Rate <= 0 || now.Before(config.StartDate) {
+		return 0
+	}
+	if elapsed <= 0 {
+		return allowance
+	}
+	return allowance + config.Rate*elapsed.Seconds()
+}
+
+// leftOver caps the allowance a round keeps after a tick: with nobody waiting, at most one admission is kept
+func leftOver(allowance float64, waiting int) float64 {
+	if waiting == 0 && allowance > 1 {
+		return 1
+	}
+	return allowance
+}
+
+// prune drops rounds that have ended, waiting tickets that were abandoned and admitted tickets that have expired
+func (q *queue) prune(now time.Time) {
+	for id, round := range q.rounds {
+		if now.After(round.config.EndDate.Add(q.options.AdmitFor)) {
+			delete(q.rounds, id)
+			continue
+		}
+		waiting := round.waiting[:0]
+		for _, t := range round.waiting {
+			if !q.abandoned(t, now) {
+				waiting = append(waiting, t)
+			}
+		}
+		round.waiting = waiting
+		for token, t := range round.byToken {
+			if q.abandoned(t, now) || t.admittedAt != nil && now.Sub(*t.admittedAt) > q.options.AdmitFor {
+				delete(round.byToken, token)
+			}
+		}
+	}
+}
+
+// abandoned reports whether a waiting ticket has not been polled for so long that it lost its place; it is
+// neither admitted nor counted in the positions of the tickets behind it
+func (q *queue) abandoned(t *ticket, now time.Time) bool {
+	return t.admittedAt == nil && now.Sub(t.lastSeen) > q.options.AbandonAfter
+}
+
+// lookupTicket returns a round's ticket, or ErrUnknownTicket once the ticket was abandoned
+func (q *queue) lookupTicket(round *roundQueue, token string, now time.Time) (*ticket, error) {
+	t, ok := round.byToken[token]
+	if !ok {
+		return nil, ErrUnknownTicket
+	}
+	if q.abandoned(t, now) {
+		delete(round.byToken, token) // admit and prune drop it from the waiting tickets
+		return nil, ErrUnknownTicket
+	}
+	return t, nil
+}
+
+func (q *queue) Join(roundID uuid.UUID) (Ticket, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.options.Now()
+	round, err := q.round(roundID, now)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if !now.Before(round.config.EndDate) {
+		return Ticket{}, ErrRoundClosed
+	}
+
+	t := &ticket{token: uuid.New().String(), seq: round.nextSeq, lastSeen: now}
+	round.nextSeq++
+	round.byToken[t.token] = t
+	if round.config.Rate > 0 {
+		round.waiting = append(round.waiting, t)
+	} else {
+		t.admittedAt = &now
+	}
+	return q.ticket(roundID, round, t, now), nil
+}
+
+func (q *queue) Status(roundID uuid.UUID, token string) (Ticket, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	round, ok := q.rounds[roundID]
+	if !ok {
+		return Ticket{}, ErrUnknownTicket
+	}
+	now := q.options.Now()
+	t, err := q.lookupTicket(round, token, now)
+	if err != nil {
+		return Ticket{}, err
+	}
+	t.lastSeen = now
+	return q.ticket(roundID, round, t, now), nil
+}
+
+func (q *queue) Admit(roundID uuid.UUID, token string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.options.Now()
+	round, err := q.round(roundID, now)
+	if err != nil {
+		return err
+	}
+	if round.config.Rate <= 0 {
+		return nil
+	}
+
+	t, err := q.lookupTicket(round, token, now)
+	if err != nil {
+		return err
+	}
+	if t.admittedAt == nil {
+		t.lastSeen = now
+		return ErrNotAdmitted
+	}
+	if now.Sub(*t.admittedAt) > q.options.AdmitFor {
+		return ErrAdmissionExpired
+	}
+	if t.claimed {
+		return ErrTicketUsed
+	}
+	t.claimed = true
+	return nil
+}
+
+func (q *queue) Release(roundID uuid.UUID, token string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if round, ok := q.rounds[roundID]; ok {
+		if t, ok := round.byToken[token]; ok {
+			t.claimed = false
+		}
+	}
+}
+
+// round returns the queue of a round, loading or refreshing its config when needed
+func (q *queue) round(roundID uuid.UUID, now time.Time) (*roundQueue, error) {
+	round, ok := q.rounds[roundID]
+	if ok && now.Sub(round.loadedAt) < q.options.ConfigTTL {
+		return round, nil
+	}
+
+	config, err := q.lookup(roundID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		round = &roundQueue{byToken: make(map[string]*ticket), lastTick: now}
+		q.rounds[roundID] = round
+	}
+	round.config = config
+	round.loadedAt = now
+	return round, nil
+}
+
+func (q *queue) ticket(roundID uuid.UUID, round *roundQueue, t *ticket, now time.Time) Ticket {
+	position := 0
+	if t.admittedAt == nil {
+		for _, ahead := range round.waiting {
+			if ahead.seq >= t.seq {
+				break
+			}
+			if !q.abandoned(ahead, now) {
+				position++
+			}
+		}
+	}
+	return newTicket(roundID, t.token, t.admittedAt, position, round.config, q.options, now)
+}
+
+// newTicket describes a ticket to its holder: when its admission expires, or its position and when to poll again
+func newTicket(roundID uuid.UUID, token string, admittedAt *time.Time, position int, config RoundConfig, options Options, now time.Time) Ticket {
+	result := Ticket{Token: token, RoundID: roundID, RetryAfter: 1}
+	if admittedAt != nil {
+		expiresAt := admittedAt.Add(options.AdmitFor)
+		result.Admitted = true
+		result.AdmittedAt = admittedAt
+		result.ExpiresAt = &expiresAt
+		result.RetryAfter = 0
+		return result
+	}
+
+	result.Position = position
+	wait := float64(position+1) / config.Rate
+	if untilOpen := config.StartDate.Sub(now).Seconds(); untilOpen > 0 {
+		wait += untilOpen
+	}
+	// Poll at most every second and at least every half minute, so the ticket is not abandoned
+	result.RetryAfter = int(math.Min(30, math.Max(1, math.Ceil(wait/2))))
+	return result
+}
@@ -0,0 +1,226 @@
+package admission
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeClock is the queue's clock in tests; the tests move it forward themselves
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestQueue(clock *fakeClock, config RoundConfig, options Options) Queue {
+	options.Now = clock.Now
+	return NewQueue(func(uuid.UUID) (RoundConfig, error) {
+		return config, nil
+	}, options)
+}
+
+// TestQueueLoad sends a crowd of buyers at a round as it opens. Buyers poll every tick and purchase as soon as
+// they are admitted; some try to purchase before they are admitted, without a token or with someone else's.
+func TestQueueLoad(t *testing.T) {
+	const (
+		buyers = 300
+		rate   = 25
+		tick   = 100 * time.Millisecond
+	)
+	opened := time.Date(2026, 10, 23, 20, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: opened}
+	roundID := uuid.New()
+	queue := newTestQueue(clock, RoundConfig{Rate: rate, StartDate: opened, EndDate: opened.Add(time.Hour)}, Options{})
+
+	tokens := make([]string, buyers)
+	for i := range tokens {
+		ticket, err := queue.Join(roundID)
+		if err != nil {
+			t.Fatalf("Join() error = %v", err)
+		}
+		if ticket.Admitted || ticket.Position != i {
+			t.Fatalf("buyer %d joined at position %d (admitted %v), want position %d", i, ticket.Position, ticket.Admitted, i)
+		}
+		tokens[i] = ticket.Token
+
+		// Cheaters try to purchase before they are admitted
+		if i%10 == 0 {
+			if err := queue.Admit(roundID, tokens[i]); !errors.Is(err, ErrNotAdmitted) {
+				t.Errorf("Admit() before admission error = %v, want ErrNotAdmitted", err)
+			}
+			if err := queue.Admit(roundID, ""); !errors.Is(err, ErrUnknownTicket) {
+				t.Errorf("Admit() without a token error = %v, want ErrUnknownTicket", err)
+			}
+		}
+	}
+
+	purchasedAt := make([]time.Time, buyers)
+	perSecond := make(map[int]int)
+	purchased := 0
+	for step := 0; purchased < buyers; step++ {
+		if step > 2*buyers/rate*int(time.Second/tick) {
+			t.Fatalf("only %d of %d buyers purchased after %s", purchased, buyers, clock.now.Sub(opened))
+		}
+		clock.advance(tick)
+		queue.Tick(clock.now)
+
+		for i, token := range tokens {
+			if !purchasedAt[i].IsZero() {
+				continue
+			}
+			ticket, err := queue.Status(roundID, token)
+			if err != nil {
+				t.Fatalf("Status() of buyer %d error = %v", i, err)
+			}
+			if !ticket.Admitted {
+				continue
+			}
+			if err := queue.Admit(roundID, token); err != nil {
+				t.Fatalf("Admit() of admitted buyer %d error = %v", i, err)
+			}
+			purchasedAt[i] = clock.now
+			perSecond[int(clock.now.Sub(opened)/time.Second)]++
+			purchased++
+
+			// The token is spent: neither its owner nor anyone it was shared with can purchase again
+			if err := queue.Admit(roundID, token); !errors.Is(err, ErrTicketUsed) {
+				t.Errorf("second Admit() of buyer %d error = %v, want ErrTicketUsed", i, err)
+			}
+		}
+	}
+
+	for second, count := range perSecond {
+		if count > rate {
+			t.Errorf("second %d admitted %d purchases, more than the rate of %d", second, count, rate)
+		}
+	}
+	for i := 1; i < buyers; i++ {
+		if purchasedAt[i].Before(purchasedAt[i-1]) {
+			t.Errorf("buyer %d purchased before buyer %d, who joined earlier", i, i-1)
+		}
+	}
+	if elapsed, ideal := purchasedAt[buyers-1].Sub(opened), time.Duration(buyers/rate)*time.Second; elapsed > ideal+time.Second {
+		t.Errorf("last purchase after %s, want about %s", elapsed, ideal)
+	}
+}
+
+func TestQueueAbandonedTickets(t *testing.T) {
+	start := time.Date(2026, 10, 23, 20, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start.Add(-10 * time.Second)}
+	roundID := uuid.New()
+	queue := newTestQueue(clock, RoundConfig{Rate: 1, StartDate: start, EndDate: start.Add(time.Hour)},
+		Options{AbandonAfter: 5 * time.Second, AdmitFor: 10 * time.Second})
+
+	var a, b, c string
+	for _, token := range []*string{&a, &b, &c} {
+		ticket, err := queue.Join(roundID)
+		if err != nil {
+			t.Fatalf("Join() error = %v", err)
+		}
+		*token = ticket.Token
+	}
+	poll := func(tokens ...string) {
+		for _, token := range tokens {
+			if _, err := queue.Status(roundID, token); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+		}
+	}
+
+	// b stops polling before the round opens and loses its place
+	clock.advance(3 * time.Second)
+	poll(a, b, c)
+	for i := 0; i < 2; i++ {
+		clock.advance(3 * time.Second)
+		poll(a, c)
+	}
+	if ticket, err := queue.Status(roundID, c); err != nil || ticket.Position != 1 {
+		t.Errorf("Status() of the ticket behind an abandoned one = position %d, %v; want position 1", ticket.Position, err)
+	}
+	if _, err := queue.Status(roundID, b); !errors.Is(err, ErrUnknownTicket) {
+		t.Errorf("Status() of an abandoned ticket error = %v, want ErrUnknownTicket", err)
+	}
+	if err := queue.Admit(roundID, b); !errors.Is(err, ErrUnknownTicket) {
+		t.Errorf("Admit() of an abandoned ticket error = %v, want ErrUnknownTicket", err)
+	}
+
+	// The round opens; one ticket is admitted per second and the abandoned one is skipped
+	clock.now = start
+	queue.Tick(clock.now)
+	for i := 0; i < 2; i++ {
+		clock.advance(time.Second)
+		queue.Tick(clock.now)
+		poll(a, c)
+	}
+	for _, token := range []string{a, c} {
+		if ticket, _ := queue.Status(roundID, token); !ticket.Admitted {
+			t.Errorf("ticket %s was not admitted after the abandoned one was skipped", token)
+		}
+	}
+
+	// A purchase that did not go through gives the admission back
+	if err := queue.Admit(roundID, c); err != nil {
+		t.Fatalf("Admit() error = %v", err)
+	}
+	queue.Release(roundID, c)
+	if err := queue.Admit(roundID, c); err != nil {
+		t.Errorf("Admit() after Release() error = %v", err)
+	}
+
+	// An admission that is not used in time expires
+	clock.advance(11 * time.Second)
+	if err := queue.Admit(roundID, a); !errors.Is(err, ErrAdmissionExpired) {
+		t.Errorf("Admit() of an expired admission error = %v, want ErrAdmissionExpired", err)
+	}
+}
+
+func TestQueueUnqueuedRound(t *testing.T) {
+	now := time.Date(2026, 10, 23, 20, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: now}
+	queue := newTestQueue(clock, RoundConfig{StartDate: now, EndDate: now.Add(time.Hour)}, Options{})
+
+	for _, token := range []string{"", "not-a-token"} {
+		if err := queue.Admit(uuid.New(), token); err != nil {
+			t.Errorf("Admit(%q) to a round that is not queued error = %v", token, err)
+		}
+	}
+}
+
+func TestEarnedAdmissions(t *testing.T) {
+	opened := time.Date(2026, 10, 23, 20, 0, 0, 0, time.UTC)
+	config := RoundConfig{Rate: 10, StartDate: opened, EndDate: opened.Add(time.Hour)}
+	tests := []struct {
+		name      string
+		config    RoundConfig
+		allowance float64
+		elapsed   time.Duration
+		now       time.Time
+		want      float64
+	}{
+		{"earned at the rate", config, 0.5, 250 * time.Millisecond, opened.Add(time.Second), 3},
+		{"not open yet", config, 0.5, time.Second, opened.Add(-time.Second), 0},
+		{"not queued", RoundConfig{StartDate: opened}, 0.5, time.Second, opened.Add(time.Second), 0},
+		// An instance whose clock lags another's sees time go backwards; the round keeps what it has
+		{"clock behind the last tick", config, 0.5, -time.Second, opened.Add(time.Second), 0.5},
+	}
+	for _, tt := range tests {
+		if got := earned(tt.config, tt.allowance, tt.elapsed, tt.now); got != tt.want {
+			t.Errorf("%s: earned() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := leftOver(4.5, 0); got != 1 {
+		t.Errorf("leftOver() with nobody waiting = %v, want 1", got)
+	}
+	if got := leftOver(4.5, 3); got != 4.5 {
+		t.Errorf("leftOver() with buyers waiting = %v, want 4.5", got)
+	}
+}
@@ -0,0 +1,165 @@
+// Package carriers abstracts the shipping carriers orders are sent with. A carrier quotes rates for parcels,
+// books a shipment and issues its label, and reports tracking events, which are polled to keep shipments
+// up to date.
+package carriers
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tracking statuses carriers report
+const (
+	TrackingInfoReceived   = "info_received" // The shipment is booked; the parcel has not been picked up
+	TrackingPickedUp       = "picked_up"
+	TrackingInTransit      = "in_transit"
+	TrackingOutForDelivery = "out_for_delivery"
+	TrackingFailedAttempt  = "failed_attempt" // Delivery failed, e.g. nobody home; the carrier tries again
+	TrackingDelivered      = "delivered"
+	TrackingReturned       = "returned" // Sent back to the sender
+)
+
+// Label formats
+const (
+	LabelPNG = "png"
+	LabelPDF = "pdf"
+	LabelZPL = "zpl" // For thermal label printers
+)
+
+var (
+	// ErrUnknownCarrier is returned for a carrier that is not registered
+	ErrUnknownCarrier = errors.New("unsupported carrier")
+	// ErrUnknownService is returned when a carrier does not offer the requested service
+	ErrUnknownService = errors.New("carrier does not offer this service")
+	// ErrUnknownTracking is returned when a carrier does not know a tracking number
+	ErrUnknownTracking = errors.New("unknown tracking number")
+	// ErrAlreadyPickedUp is returned when a label is cancelled after the carrier picked the parcel up
+	ErrAlreadyPickedUp = errors.New("parcel has already been picked up")
+)
+
+// postalCode finds a Thai postal code in a free-form address
+var postalCode = regexp.MustCompile(`\b\d{5}\b`)
+
+// Address is where a parcel is picked up or delivered
+type Address struct {
+	Name        string
+	Phone       string
+	Line1       string
+	Line2       string
+	SubDistrict string // Tambon / khwaeng
+	District    string // Amphoe / khet
+	Province    string
+	PostalCode  string
+	Country     string // ISO 3166-1 alpha-2, TH when empty
+}
+
+// PostalCodeFrom returns the last postal code in a free-form address, or an empty string
+func PostalCodeFrom(address string) string {
+	codes := postalCode.FindAllString(address, -1)
+	if len(codes) == 0 {
+		return ""
+	}
+	return codes[len(codes)-1]
+}
+
+// Parcel is a box to ship
+type Parcel struct {
+	WeightGrams int
+	LengthCm    float64
+	WidthCm     float64
+	HeightCm    float64
+}
+
+// RateRequest describes the parcels to quote for
+type RateRequest struct {
+	Origin         Address
+	Destination    Address
+	Parcels        []Parcel
+	DeclaredValue  float64 // Value of the goods, for insurance
+	CashOnDelivery float64 // Amount the carrier collects from the customer; 0 for prepaid orders
+}
+
+// Rate is the price of shipping with a service of a carrier
+type Rate struct {
+	Carrier       string
+	Service       string
+	Description   string
+	Amount        float64
+	Currency      string
+	EstimatedDays int
+}
+
+// LabelRequest books a shipment with a service of the carrier
+type LabelRequest struct {
+	RateRequest
+	Reference string // Our reference, the order code
+	Service   string
+}
+
+// Label is a booked shipment and the label to stick on its parcels
+type Label struct {
+	TrackingNumber string
+	Service        string
+	Amount         float64 // What the carrier charges
+	Currency       string
+	Format         string // LabelPNG, LabelPDF or LabelZPL
+	Data           []byte
+}
+
+// TrackingEvent is a scan or status update of a parcel
+type TrackingEvent struct {
+	Status      string
+	Description string
+	Location    string
+	OccurredAt  time.Time
+}
+
+// Carrier is a shipping company
+type Carrier interface {
+	// Name is what shipments use to refer to the carrier
+	Name() string
+	// QuoteRates returns the price of each service that can ship the parcels
+	QuoteRates(request RateRequest) ([]Rate, error)
+	// CreateShipment books a shipment and returns its tracking number and label
+	CreateShipment(request LabelRequest) (*Label, error)
+	// CancelShipment voids the label of a shipment that has not been picked up
+	CancelShipment(trackingNumber string) error
+	// TrackingEvents returns everything the carrier knows about a shipment, oldest first
+	TrackingEvents(trackingNumber string) ([]TrackingEvent, error)
+}
+
+// Registry holds the configured carriers
+type Registry struct {
+	carriers map[string]Carrier
+}
+
+// NewRegistry creates a registry of carriers
+func NewRegistry(carriers ...Carrier) *Registry {
+	registry := &Registry{carriers: make(map[string]Carrier, len(carriers))}
+	for _, carrier := range carriers {
+		registry.carriers[carrier.Name()] = carrier
+	}
+	return registry
+}
+
+// Get returns the carrier with a name
+func (r *Registry) Get(name string) (Carrier, error) {
+	carrier, ok := r.carriers[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownCarrier
+	}
+	return carrier, nil
+}
+
+// Names returns the names of the registered carriers, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.carriers))
+	for name := range r.carriers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,118 @@
+package carriers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestFakeCarrierQuoteRates(t *testing.T) {
+	// 1.5 kg actual and 1.2 kg volumetric weight is charged as 2 kg
+	parcel := Parcel{WeightGrams: 1500, LengthCm: 30, WidthCm: 20, HeightCm: 10}
+	bangkok := Address{PostalCode: "10110"}
+	tests := []struct {
+		name          string
+		request       RateRequest
+		standard      float64
+		express       float64
+		estimatedDays int
+	}{
+		{
+			name:          "across provinces",
+			request:       RateRequest{Origin: bangkok, Destination: Address{PostalCode: "50200"}, Parcels: []Parcel{parcel}},
+			standard:      60,
+			express:       108,
+			estimatedDays: 3,
+		},
+		{
+			name:          "within the province",
+			request:       RateRequest{Origin: bangkok, Destination: Address{PostalCode: "10400"}, Parcels: []Parcel{parcel}},
+			standard:      50,
+			express:       90,
+			estimatedDays: 2,
+		},
+		{
+			name: "cash on delivery and insured value",
+			request: RateRequest{Origin: bangkok, Destination: Address{PostalCode: "50200"}, Parcels: []Parcel{parcel},
+				CashOnDelivery: 500, DeclaredValue: 5000},
+			standard:      130, // 60, COD fee of at least 20, 1% of 5000
+			express:       178,
+			estimatedDays: 3,
+		},
+		{
+			name: "bulky parcels by volume",
+			request: RateRequest{Origin: bangkok, Destination: Address{PostalCode: "50200"},
+				Parcels: []Parcel{parcel, {WeightGrams: 200, LengthCm: 50, WidthCm: 40, HeightCm: 30}}},
+			standard:      240, // 2 kg and 12 kg volumetric
+			express:       432,
+			estimatedDays: 3,
+		},
+	}
+	carrier := NewFakeCarrier(time.Hour)
+	for _, tt := range tests {
+		rates, err := carrier.QuoteRates(tt.request)
+		if err != nil {
+			t.Fatalf("%s: QuoteRates() error = %v", tt.name, err)
+		}
+		if len(rates) != 2 || rates[0].Service != FakeServiceStandard || rates[1].Service != FakeServiceExpress {
+			t.Fatalf("%s: QuoteRates() = %+v, want standard and express", tt.name, rates)
+		}
+		if rates[0].Amount != tt.standard || rates[1].Amount != tt.express || rates[0].EstimatedDays != tt.estimatedDays {
+			t.Errorf("%s: standard %.2f in %d days, express %.2f; want %.2f in %d days, %.2f",
+				tt.name, rates[0].Amount, rates[0].EstimatedDays, rates[1].Amount, tt.standard, tt.estimatedDays, tt.express)
+		}
+	}
+
+	if _, err := carrier.QuoteRates(RateRequest{Parcels: []Parcel{{}}}); err == nil {
+		t.Error("QuoteRates() for a parcel without weight succeeded")
+	}
+}
+
+func TestFakeCarrierLabelAndTracking(t *testing.T) {
+	carrier := NewFakeCarrier(time.Hour)
+	label, err := carrier.CreateShipment(LabelRequest{
+		RateRequest: RateRequest{Origin: Address{PostalCode: "10110"}, Destination: Address{PostalCode: "50200"}, Parcels: []Parcel{{WeightGrams: 900}}},
+		Service:     FakeServiceExpress,
+	})
+	if err != nil {
+		t.Fatalf("CreateShipment() error = %v", err)
+	}
+	if label.Service != FakeServiceExpress || label.Amount != 81 || label.Format != LabelPNG {
+		t.Errorf("label is %s for %.2f as %s, want express for 81.00 as png", label.Service, label.Amount, label.Format)
+	}
+	if _, err := png.Decode(bytes.NewReader(label.Data)); err != nil {
+		t.Errorf("label is not a PNG: %v", err)
+	}
+
+	// A parcel just booked has only been announced, and can still be cancelled
+	events, err := carrier.TrackingEvents(label.TrackingNumber)
+	if err != nil || len(events) != 1 || events[0].Status != TrackingInfoReceived {
+		t.Fatalf("TrackingEvents() = %+v, %v; want info received", events, err)
+	}
+	if err := carrier.CancelShipment(label.TrackingNumber); err != nil {
+		t.Fatalf("CancelShipment() error = %v", err)
+	}
+	if _, err := carrier.TrackingEvents(label.TrackingNumber); !errors.Is(err, ErrUnknownTracking) {
+		t.Errorf("TrackingEvents() of a cancelled label error = %v, want ErrUnknownTracking", err)
+	}
+
+	// One booked two and a half hours ago was picked up and has reached the hub
+	booked := fmt.Sprintf("FK%010d%02dTH", time.Now().Add(-150*time.Minute).Unix(), 7)
+	events, err = carrier.TrackingEvents(booked)
+	if err != nil || len(events) != 3 || events[2].Status != TrackingInTransit {
+		t.Errorf("TrackingEvents() = %+v, %v; want three events up to in transit", events, err)
+	}
+	if err := carrier.CancelShipment(booked); !errors.Is(err, ErrAlreadyPickedUp) {
+		t.Errorf("CancelShipment() after pick-up error = %v, want ErrAlreadyPickedUp", err)
+	}
+
+	if _, err := carrier.CreateShipment(LabelRequest{RateRequest: RateRequest{Parcels: []Parcel{{WeightGrams: 900}}}, Service: "overnight"}); !errors.Is(err, ErrUnknownService) {
+		t.Errorf("CreateShipment() with an unknown service error = %v, want ErrUnknownService", err)
+	}
+	if _, err := carrier.TrackingEvents("EX123456789TH"); !errors.Is(err, ErrUnknownTracking) {
+		t.Errorf("TrackingEvents() of another carrier's number error = %v, want ErrUnknownTracking", err)
+	}
+}
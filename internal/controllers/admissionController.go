@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/admission"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AdmissionController interface {
+	JoinQueue(c *fiber.Ctx) error
+	GetQueueStatus(c *fiber.Ctx) error
+	// RequireAdmission guards purchase endpoints: queued rounds only accept admitted queue tokens, each for one purchase
+	RequireAdmission(c *fiber.Ctx) error
+}
+
+type admissionController struct {
+	queue admission.Queue
+}
+
+func NewAdmissionController(queue admission.Queue) AdmissionController {
+	return &admissionController{
+		queue: queue,
+	}
+}
+
+// JoinQueue godoc
+// @Summary Join the waiting room of a sales round
+// @Description Get a queue token for a sales round. Buyers are admitted in the order they joined at the round's admission_rate once it opens; send the token as X-Queue-Token when purchasing.
+// @Tags Admission Queue
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Success 201 {object} admission.Ticket
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /sales-rounds/{id}/queue [post]
+func (h *admissionController) JoinQueue(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	ticket, err := h.queue.Join(roundID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
+		case errors.Is(err, admission.ErrRoundClosed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "could not join queue", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(ticket)
+}
+
+// GetQueueStatus godoc
+// @Summary Poll a queue token
+// @Description Get the position of a queue token. Poll again after retry_after seconds; tokens that stop polling lose their place.
+// @Tags Admission Queue
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param token path string true "Queue token"
+// @Success 200 {object} admission.Ticket
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /sales-rounds/{id}/queue/{token} [get]
+func (h *admissionController) GetQueueStatus(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	ticket, err := h.queue.Status(roundID, c.Params("token"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ticket.Admitted {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ticket.RetryAfter))
+	}
+	return c.JSON(ticket)
+}
+
+func (h *admissionController) RequireAdmission(c *fiber.Ctx) error {
+	var body struct {
+		RoundID uuid.UUID `json:"round_id"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.RoundID == uuid.Nil {
+		return c.Next() // The handler reports the invalid body
+	}
+
+	token := c.Get(admission.TokenHeader)
+	err := h.queue.Admit(body.RoundID, token)
+	switch {
+	case err == nil:
+		err = c.Next()
+		if err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest {
+			h.queue.Release(body.RoundID, token) // A purchase that did not go through does not use up the admission
+		}
+		return err
+	case errors.Is(err, admission.ErrNotAdmitted):
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, admission.ErrUnknownTicket), errors.Is(err, admission.ErrAdmissionExpired), errors.Is(err, admission.ErrTicketUsed):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+			"queue": fmt.Sprintf("/sales-rounds/%s/queue", body.RoundID),
+		})
+	}
+	return c.Next() // The round could not be looked up; the handler reports it
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"errors"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/thaiaddress"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CustomerAddressController interface {
+	CreateAddress(c *fiber.Ctx) error
+	GetCustomerAddresses(c *fiber.Ctx) error
+	GetAddress(c *fiber.Ctx) error
+	UpdateAddress(c *fiber.Ctx) error
+	SetDefaultAddress(c *fiber.Ctx) error
+	DeleteAddress(c *fiber.Ctx) error
+	GetProvinces(c *fiber.Ctx) error
+}
+
+type customerAddressController struct {
+	addressRepository repositories.CustomerAddressRepository
+}
+
+func NewCustomerAddressController(addressRepository repositories.CustomerAddressRepository) CustomerAddressController {
+	return &customerAddressController{addressRepository: addressRepository}
+}
+
+// CreateAddress godoc
+// @Summary Add an address to a customer's address book
+// @Description Add a delivery address. The subdistrict, district, province and postcode are checked against the Thai administrative areas and stored with their Thai names. The customer's first address becomes their default.
+// @Tags Customer Addresses
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param address body dtos.CustomerAddressDTO true "Address"
+// @Success 201 {object} dtos.CustomerAddressResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /customers/{id}/addresses [post]
+func (h *customerAddressController) CreateAddress(c *fiber.Ctx) error {
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.CustomerAddressDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	address := models.CustomerAddress{CustomerID: customerID, IsDefault: dto.IsDefault}
+	if err := applyAddressDTO(&address, dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "address is not valid", "details": err.Error()})
+	}
+
+	if err := h.addressRepository.CreateAddress(&address); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "customer not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create address", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toCustomerAddressResponse(address))
+}
+
+// GetCustomerAddresses godoc
+// @Summary Get a customer's address book
+// @Description Get a customer's addresses, the default first
+// @Tags Customer Addresses
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Success 200 {array} dtos.CustomerAddressResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /customers/{id}/addresses [get]
+func (h *customerAddressController) GetCustomerAddresses(c *fiber.Ctx) error {
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	addresses, err := h.addressRepository.GetAddressesByCustomerID(customerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve addresses"})
+	}
+
+	responses := make([]dtos.CustomerAddressResponseDTO, 0, len(addresses))
+	for _, address := range addresses {
+		responses = append(responses, toCustomerAddressResponse(address))
+	}
+	return c.JSON(responses)
+}
+
+// GetAddress godoc
+// @Summary Get address by ID
+// @Description Get an entry of a customer's address book
+// @Tags Customer Addresses
+// @Produce json
+// @Param id path string true "Address ID"
+// @Success 200 {object} dtos.CustomerAddressResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /addresses/{id} [get]
+func (h *customerAddressController) GetAddress(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	address, err := h.addressRepository.GetAddressByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "address not found"})
+	}
+	return c.JSON(toCustomerAddressResponse(*address))
+}
+
+// UpdateAddress godoc
+// @Summary Edit an address
+// @Description Edit an entry of a customer's address book. Orders already placed keep the address they were placed with.
+// @Tags Customer Addresses
+// @Accept json
+// @Produce json
+// @Param id path string true "Address ID"
+// @Param address body dtos.CustomerAddressDTO true "Address"
+// @Success 200 {object} dtos.CustomerAddressResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /addresses/{id} [put]
+func (h *customerAddressController) UpdateAddress(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.CustomerAddressDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+
+	address, err := h.addressRepository.GetAddressByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "address not found"})
+	}
+	if err := applyAddressDTO(address, dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "address is not valid", "details": err.Error()})
+	}
+	if err := h.addressRepository.UpdateAddress(address); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update address", "details": err.Error()})
+	}
+	return c.JSON(toCustomerAddressResponse(*address))
+}
+
+// SetDefaultAddress godoc
+// @Summary Make an address the default
+// @Description Make an address its customer's default, which purchases ship to when they name no address
+// @Tags Customer Addresses
+// @Produce json
+// @Param id path string true "Address ID"
+// @Success 200 {object} dtos.CustomerAddressResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /addresses/{id}/default [post]
+func (h *customerAddressController) SetDefaultAddress(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	address, err := h.addressRepository.SetDefaultAddress(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "address not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not set default address", "details": err.Error()})
+	}
+	return c.JSON(toCustomerAddressResponse(*address))
+}
+
+// DeleteAddress godoc
+// @Summary Delete an address
+// @Description Remove an address from its customer's address book. When it was the default, the most recently added address becomes the default.
+// @Tags Customer Addresses
+// @Param id path string true "Address ID"
+// @Success 204
+// @Failure 404 {object} fiber.Map
+// @Router /addresses/{id} [delete]
+func (h *customerAddressController) DeleteAddress(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.addressRepository.DeleteAddress(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "address not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete address", "details": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetProvinces godoc
+// @Summary Get Thai administrative areas
+// @Description Get the provinces addresses are checked against, with the districts and subdistricts the dataset lists
+// @Tags Customer Addresses
+// @Produce json
+// @Success 200 {array} thaiaddress.Area
+// @Router /addresses/provinces [get]
+func (h *customerAddressController) GetProvinces(c *fiber.Ctx) error {
+	return c.JSON(thaiaddress.Provinces())
+}
+
+// applyAddressDTO validates an address and copies it, with the areas named as in the dataset, onto address
+func applyAddressDTO(address *models.CustomerAddress, dto *dtos.CustomerAddressDTO) error {
+	if err := validate.Struct(dto); err != nil {
+		return errors.New(utils.ParseValidationErrors(err))
+	}
+	phone, err := thaiaddress.NormalizePhone(dto.Phone)
+	if err != nil {
+		return err
+	}
+	areas, err := thaiaddress.Normalize(thaiaddress.Address{
+		Subdistrict: dto.Subdistrict,
+		District:    dto.District,
+		Province:    dto.Province,
+		Postcode:    dto.Postcode,
+	})
+	if err != nil {
+		return err
+	}
+
+	address.Label = dto.Label
+	address.PostalAddress = models.PostalAddress{
+		Recipient:   dto.Recipient,
+		Phone:       phone,
+		HouseNumber: dto.HouseNumber,
+		Subdistrict: areas.Subdistrict,
+		District:    areas.District,
+		Province:    areas.Province,
+		Postcode:    areas.Postcode,
+	}
+	return nil
+}
+
+func toCustomerAddressResponse(address models.CustomerAddress) dtos.CustomerAddressResponseDTO {
+	return dtos.CustomerAddressResponseDTO{
+		ID:               address.ID,
+		CustomerID:       address.CustomerID,
+		Label:            address.Label,
+		PostalAddressDTO: *services.PostalAddressResponse(address.PostalAddress),
+		IsDefault:        address.IsDefault,
+		CreatedAt:        address.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:        address.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
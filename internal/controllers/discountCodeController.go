@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/discounts"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DiscountCodeController interface {
+	CreateDiscountCode(c *fiber.Ctx) error
+	GetDiscountCodes(c *fiber.Ctx) error
+	GetDiscountCode(c *fiber.Ctx) error
+	UpdateDiscountCode(c *fiber.Ctx) error
+	DeleteDiscountCode(c *fiber.Ctx) error
+	QuoteDiscounts(c *fiber.Ctx) error
+}
+
+type discountCodeController struct {
+	discountRepository repositories.DiscountRepository
+}
+
+func NewDiscountCodeController(discountRepository repositories.DiscountRepository) DiscountCodeController {
+	return &discountCodeController{discountRepository: discountRepository}
+}
+
+// CreateDiscountCode godoc
+// @Summary Create a discount code
+// @Description Create a discount code: a percentage or fixed amount off, free shipping, or buy X get Y, limited to the whole order, a store, a category, a variant or a sales round. Codes are case-insensitive, can have a validity window, a minimum, a cap, and limits on how many orders and how many orders per customer use them.
+// @Tags Discount Codes
+// @Accept json
+// @Produce json
+// @Param discountCode body dtos.DiscountCodeDTO true "Discount code"
+// @Success 201 {object} dtos.DiscountCodeResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /discount-codes [post]
+func (h *discountCodeController) CreateDiscountCode(c *fiber.Ctx) error {
+	dto := new(dtos.DiscountCodeDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	code := new(models.DiscountCode)
+	if err := applyDiscountCodeDTO(code, dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": err.Error()})
+	}
+	if err := h.discountRepository.CreateDiscountCode(code); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not create discount code", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toDiscountCodeResponse(*code))
+}
+
+// GetDiscountCodes godoc
+// @Summary Get all discount codes
+// @Description Get every discount code, newest first, with how many orders used it
+// @Tags Discount Codes
+// @Produce json
+// @Success 200 {array} dtos.DiscountCodeResponseDTO
+// @Failure 500 {object} fiber.Map
+// @Router /discount-codes [get]
+func (h *discountCodeController) GetDiscountCodes(c *fiber.Ctx) error {
+	codes, err := h.discountRepository.GetAllDiscountCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve discount codes"})
+	}
+
+	responses := make([]dtos.DiscountCodeResponseDTO, 0, len(codes))
+	for _, code := range codes {
+		responses = append(responses, toDiscountCodeResponse(code))
+	}
+	return c.JSON(responses)
+}
+
+// GetDiscountCode godoc
+// @Summary Get discount code by ID
+// @Description Get a discount code with how many orders used it
+// @Tags Discount Codes
+// @Produce json
+// @Param id path string true "Discount code ID"
+// @Success 200 {object} dtos.DiscountCodeResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /discount-codes/{id} [get]
+func (h *discountCodeController) GetDiscountCode(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	code, err := h.discountRepository.GetDiscountCodeByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "discount code not found"})
+	}
+	return c.JSON(toDiscountCodeResponse(*code))
+}
+
+// UpdateDiscountCode godoc
+// @Summary Update a discount code
+// @Description Replace the terms of a discount code. Orders already placed keep their discounts, and uses so far still count against the new limits.
+// @Tags Discount Codes
+// @Accept json
+// @Produce json
+// @Param id path string true "Discount code ID"
+// @Param discountCode body dtos.DiscountCodeDTO true "Discount code"
+// @Success 200 {object} dtos.DiscountCodeResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /discount-codes/{id} [put]
+func (h *discountCodeController) UpdateDiscountCode(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.DiscountCodeDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	code, err := h.discountRepository.GetDiscountCodeByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "discount code not found"})
+	}
+	if err := applyDiscountCodeDTO(code, dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": err.Error()})
+	}
+	if err := h.discountRepository.UpdateDiscountCode(code); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not update discount code", "details": err.Error()})
+	}
+	return c.JSON(toDiscountCodeResponse(*code))
+}
+
+// DeleteDiscountCode godoc
+// @Summary Delete a discount code
+// @Description Delete a discount code so it can no longer be used; orders placed with it keep their discounts
+// @Tags Discount Codes
+// @Param id path string true "Discount code ID"
+// @Success 204
+// @Failure 404 {object} fiber.Map
+// @Router /discount-codes/{id} [delete]
+func (h *discountCodeController) DeleteDiscountCode(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.discountRepository.DeleteDiscountCode(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "discount code not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete discount code"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// QuoteDiscounts godoc
+// @Summary Price an order with discount codes
+// @Description Price the items of an order at their prices in the sales round with discount codes and VAT, as placing the order would, without placing it or using the codes. Use it to check codes at checkout.
+// @Tags Discount Codes
+// @Accept json
+// @Produce json
+// @Param quote body dtos.DiscountQuoteRequestDTO true "Items and discount codes"
+// @Success 200 {object} dtos.DiscountQuoteResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /discount-codes/quote [post]
+func (h *discountCodeController) QuoteDiscounts(c *fiber.Ctx) error {
+	dto := new(dtos.DiscountQuoteRequestDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	order := models.Order{CustomerID: dto.CustomerID, RoundID: dto.RoundID}
+	for _, item := range dto.Items {
+		if item.Quantity <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "quantity must be positive", "details": item.VariantID.String()})
+		}
+		order.OrderDetail = append(order.OrderDetail, models.OrderDetail{VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	if err := h.discountRepository.QuoteOrder(&order, dto.DiscountCodes); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "product variant not found in the sales round"})
+		case errors.Is(err, repositories.ErrDiscountCodeUsedUp), errors.Is(err, repositories.ErrDiscountCodeCustomerLimit):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case isDiscountError(err):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not price order", "details": err.Error()})
+	}
+
+	response := dtos.DiscountQuoteResponseDTO{
+		DiscountAmount: tax.Round(order.DiscountAmount),
+		NetAmount:      order.NetAmount,
+		TaxAmount:      order.TaxAmount,
+		TotalPrice:     order.TotalPrice,
+		FreeShipping:   order.FreeShipping,
+		Lines:          make([]dtos.DiscountQuoteLineDTO, 0, len(order.OrderDetail)),
+	}
+	for _, line := range order.OrderDetail {
+		response.Subtotal += line.Price * float64(line.Quantity)
+		quoteLine := dtos.DiscountQuoteLineDTO{
+			VariantID:      line.VariantID,
+			Quantity:       line.Quantity,
+			RegularPrice:   line.RegularPrice,
+			Price:          line.Price,
+			DiscountAmount: line.DiscountAmount,
+			TotalPrice:     line.TotalPrice,
+			TaxAmount:      line.TaxAmount,
+		}
+		// Lines of a quote are not saved, so their discounts carry no order line ID
+		for _, discount := range line.Discounts {
+			quoteLine.Discounts = append(quoteLine.Discounts, dtos.OrderDiscountDTO{
+				VariantID: line.VariantID,
+				Code:      discount.Code,
+				Amount:    discount.Amount,
+			})
+		}
+		response.Lines = append(response.Lines, quoteLine)
+	}
+	response.Subtotal = tax.Round(response.Subtotal)
+	return c.JSON(response)
+}
+
+// isDiscountError reports whether err is a discount code that cannot be applied to an order as it is
+func isDiscountError(err error) bool {
+	for _, target := range []error{
+		repositories.ErrUnknownDiscountCode,
+		repositories.ErrDiscountCodeNotValid,
+		discounts.ErrNotStackable,
+		discounts.ErrNotApplicable,
+		discounts.ErrBelowMinimum,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDiscountCodeDTO sets the terms of a discount code from a request, checking they make sense together
+func applyDiscountCodeDTO(code *models.DiscountCode, dto *dtos.DiscountCodeDTO) error {
+	code.Code = dto.Code
+	code.Description = dto.Description
+	code.Kind = dto.Kind
+	code.Value = dto.Value
+	code.BuyQuantity = dto.BuyQuantity
+	code.GetQuantity = dto.GetQuantity
+	code.Scope = dto.Scope
+	if code.Scope == "" {
+		code.Scope = discounts.ScopeOrder
+	}
+	code.ScopeID = dto.ScopeID
+	if code.Scope == discounts.ScopeOrder {
+		code.ScopeID = nil
+	}
+	code.MinSubtotal = dto.MinSubtotal
+	code.MaxDiscount = dto.MaxDiscount
+	code.StartsAt = dto.StartsAt
+	code.EndsAt = dto.EndsAt
+	code.UsageLimit = dto.UsageLimit
+	code.PerCustomerLimit = dto.PerCustomerLimit
+	code.Stackable = dto.Stackable
+	code.Active = dto.Active == nil || *dto.Active
+
+	if code.StartsAt != nil && code.EndsAt != nil && !code.EndsAt.After(*code.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	var scopeID uuid.UUID
+	if code.ScopeID != nil {
+		scopeID = *code.ScopeID
+	}
+	return discounts.Code{
+		Code:        code.Code,
+		Kind:        code.Kind,
+		Value:       code.Value,
+		BuyQuantity: code.BuyQuantity,
+		GetQuantity: code.GetQuantity,
+		Scope:       code.Scope,
+		ScopeID:     scopeID,
+		MinSubtotal: code.MinSubtotal,
+		MaxDiscount: code.MaxDiscount,
+	}.Validate()
+}
+
+func toDiscountCodeResponse(code models.DiscountCode) dtos.DiscountCodeResponseDTO {
+	return dtos.DiscountCodeResponseDTO{
+		ID:               code.ID,
+		Code:             code.Code,
+		Description:      code.Description,
+		Kind:             code.Kind,
+		Value:            code.Value,
+		BuyQuantity:      code.BuyQuantity,
+		GetQuantity:      code.GetQuantity,
+		Scope:            code.Scope,
+		ScopeID:          code.ScopeID,
+		MinSubtotal:      code.MinSubtotal,
+		MaxDiscount:      code.MaxDiscount,
+		StartsAt:         code.StartsAt,
+		EndsAt:           code.EndsAt,
+		UsageLimit:       code.UsageLimit,
+		PerCustomerLimit: code.PerCustomerLimit,
+		UsedCount:        code.UsedCount,
+		Stackable:        code.Stackable,
+		Active:           code.Active,
+		CreatedAt:        code.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:        code.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
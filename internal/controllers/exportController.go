@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"time"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ExportController interface {
+	ExportOrders(c *fiber.Ctx) error
+}
+
+type exportController struct {
+	exportService services.ExportService
+}
+
+func NewExportController(exportService services.ExportService) ExportController {
+	return &exportController{exportService: exportService}
+}
+
+// ExportOrders godoc
+// @Summary Export order lines
+// @Description Stream every order line joined with its order, variant SKU and customer email as CSV or NDJSON
+// @Tags Exports
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param round_id query string false "Sales Round ID"
+// @Param from query string false "Orders on or after this date (2006-01-02) or RFC 3339 time"
+// @Param to query string false "Orders up to and including this date, or before this RFC 3339 time"
+// @Param format query string false "csv (default) or ndjson"
+// @Success 200 {string} string
+// @Failure 400 {object} fiber.Map
+// @Router /exports/orders [get]
+func (h *exportController) ExportOrders(c *fiber.Ctx) error {
+	query := new(dtos.OrderExportQueryDTO)
+	if err := c.QueryParser(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "query is not valid"})
+	}
+	if err := validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+	format := query.Format
+	if format == "" {
+		format = services.ExportFormatCSV
+	}
+
+	var filter dtos.OrderExportFilter
+	var err error
+	if query.RoundID != "" {
+		roundID := uuid.MustParse(query.RoundID)
+		filter.RoundID = &roundID
+	}
+	if filter.From, err = services.ParseExportTime(query.From, false); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid from", "details": err.Error()})
+	}
+	if filter.To, err = services.ParseExportTime(query.To, true); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid to", "details": err.Error()})
+	}
+
+	fileName := fmt.Sprintf("orders-%s.%s", time.Now().Format("20060102-150405"), format)
+	if format == services.ExportFormatCSV {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName))
+
+	// The body is written while rows are read; once streaming started an error can only end the response early
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.exportService.ExportOrders(filter, format, w); err != nil {
+			log.Printf("Order export failed: %v", err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("Order export was not fully sent: %v", err)
+		}
+	})
+	return nil
+}
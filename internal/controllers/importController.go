@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ImportController interface {
+	ImportProducts(c *fiber.Ctx) error
+	GetAllImportJobs(c *fiber.Ctx) error
+	GetImportJobByID(c *fiber.Ctx) error
+}
+
+type importController struct {
+	importService services.ImportService
+}
+
+func NewImportController(importService services.ImportService) ImportController {
+	return &importController{importService: importService}
+}
+
+// ImportProducts godoc
+// @Summary Import products from CSV
+// @Description Upsert categories, products and variants (matched by sku_code) from a CSV upload (form field "file") or a text/csv body. The import runs as a background job; poll it for progress and the per-row error report.
+// @Tags Imports
+// @Accept mpfd
+// @Accept plain
+// @Produce json
+// @Param store_id query string true "Store ID"
+// @Param file formData file false "CSV file"
+// @Success 202 {object} dtos.ImportJobResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /imports/products [post]
+func (h *importController) ImportProducts(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Query("store_id", c.FormValue("store_id")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "a valid store_id is required"})
+	}
+
+	var body io.Reader = bytes.NewReader(c.Body())
+	fileName := ""
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not read uploaded file", "details": err.Error()})
+		}
+		defer f.Close()
+		body = f
+		fileName = file.Filename
+	}
+
+	job, err := h.importService.StartProductImport(storeID, fileName, body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not start import", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(toImportJobResponse(*job))
+}
+
+// GetAllImportJobs godoc
+// @Summary Get import jobs
+// @Description Get import jobs, optionally filtered by store
+// @Tags Imports
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} dtos.ImportJobResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /imports [get]
+func (h *importController) GetAllImportJobs(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	jobs, err := h.importService.GetAllImportJobs(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve import jobs"})
+	}
+
+	responses := make([]dtos.ImportJobResponseDTO, 0, len(jobs))
+	for _, job := range jobs {
+		responses = append(responses, toImportJobResponse(job))
+	}
+	return c.JSON(responses)
+}
+
+// GetImportJobByID godoc
+// @Summary Get import job by ID
+// @Description Get the progress and per-row error report of an import job
+// @Tags Imports
+// @Produce json
+// @Param id path string true "Import Job ID"
+// @Success 200 {object} dtos.ImportJobResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /imports/{id} [get]
+func (h *importController) GetImportJobByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	job, err := h.importService.GetImportJob(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "import job not found"})
+	}
+	return c.JSON(toImportJobResponse(*job))
+}
+
+func toImportJobResponse(job models.ImportJob) dtos.ImportJobResponseDTO {
+	rowErrors := []dtos.ImportRowErrorDTO{}
+	if job.Errors != "" {
+		_ = json.Unmarshal([]byte(job.Errors), &rowErrors)
+	}
+	progress := 100.0
+	if job.TotalRows > 0 {
+		progress = float64(job.ProcessedRows) * 100 / float64(job.TotalRows)
+	}
+
+	return dtos.ImportJobResponseDTO{
+		ID:            job.ID,
+		StoreID:       job.StoreID,
+		Type:          job.Type,
+		Status:        job.Status,
+		FileName:      job.FileName,
+		TotalRows:     job.TotalRows,
+		ProcessedRows: job.ProcessedRows,
+		ImportedRows:  job.ImportedRows,
+		FailedRows:    job.FailedRows,
+		Progress:      progress,
+		Errors:        rowErrors,
+		StartedAt:     job.StartedAt,
+		FinishedAt:    job.FinishedAt,
+		CreatedAt:     job.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,479 @@
+package controllers
+
+import (
+	"errors"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InventoryController interface {
+	CreateLocation(c *fiber.Ctx) error
+	GetLocationsByStore(c *fiber.Ctx) error
+	UpdateLocation(c *fiber.Ctx) error
+	DeleteLocation(c *fiber.Ctx) error
+	GetLocationStock(c *fiber.Ctx) error
+	SetLocationStock(c *fiber.Ctx) error
+	GetVariantStock(c *fiber.Ctx) error
+	CreateTransfer(c *fiber.Ctx) error
+	GetAllTransfers(c *fiber.Ctx) error
+	ReceiveTransfer(c *fiber.Ctx) error
+	CancelTransfer(c *fiber.Ctx) error
+	GetStockMovements(c *fiber.Ctx) error
+	GetLowStock(c *fiber.Ctx) error
+}
+
+type inventoryController struct {
+	inventoryRepository      repositories.InventoryRepository
+	stockMovementRepository  repositories.StockMovementRepository
+	productVariantRepository repositories.ProductVariantRepository
+}
+
+func NewInventoryController(inventoryRepository repositories.InventoryRepository, stockMovementRepository repositories.StockMovementRepository, productVariantRepository repositories.ProductVariantRepository) InventoryController {
+	return &inventoryController{
+		inventoryRepository:      inventoryRepository,
+		stockMovementRepository:  stockMovementRepository,
+		productVariantRepository: productVariantRepository,
+	}
+}
+
+// CreateLocation godoc
+// @Summary Create an inventory location
+// @Description Create a shop or warehouse location under a store
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param id path string true "Store ID"
+// @Param location body dtos.InventoryLocationCreateDTO true "Location"
+// @Success 201 {object} dtos.InventoryLocationResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stores/{id}/locations [post]
+func (h *inventoryController) CreateLocation(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.InventoryLocationCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	location := models.InventoryLocation{StoreID: storeID, Name: dto.Name, Type: locationType(dto.Type)}
+	if err := h.inventoryRepository.CreateLocation(&location); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create location"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toLocationResponse(location))
+}
+
+// GetLocationsByStore godoc
+// @Summary Get the inventory locations of a store
+// @Description Get the inventory locations of a store
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Store ID"
+// @Success 200 {array} dtos.InventoryLocationResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stores/{id}/locations [get]
+func (h *inventoryController) GetLocationsByStore(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	locations, err := h.inventoryRepository.GetLocationsByStoreID(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve locations"})
+	}
+
+	responses := make([]dtos.InventoryLocationResponseDTO, 0, len(locations))
+	for _, location := range locations {
+		responses = append(responses, toLocationResponse(location))
+	}
+	return c.JSON(responses)
+}
+
+// UpdateLocation godoc
+// @Summary Update an inventory location
+// @Description Update an inventory location
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param location body dtos.InventoryLocationUpdateDTO true "Location"
+// @Success 200 {object} dtos.InventoryLocationResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /locations/{id} [put]
+func (h *inventoryController) UpdateLocation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.InventoryLocationUpdateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	location, err := h.inventoryRepository.GetLocationByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "location not found"})
+	}
+
+	if dto.Name != "" {
+		location.Name = dto.Name
+	}
+	location.Type = locationType(dto.Type)
+
+	if err := h.inventoryRepository.UpdateLocation(location); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update location"})
+	}
+	return c.JSON(toLocationResponse(*location))
+}
+
+// DeleteLocation godoc
+// @Summary Delete an inventory location
+// @Description Delete an inventory location that no longer holds stock
+// @Tags Inventory
+// @Param id path string true "Location ID"
+// @Success 204
+// @Failure 400 {object} fiber.Map
+// @Router /locations/{id} [delete]
+func (h *inventoryController) DeleteLocation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.inventoryRepository.DeleteLocation(id); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not delete location", "details": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetLocationStock godoc
+// @Summary Get stock levels at a location
+// @Description Get the per-variant stock levels held at a location
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Location ID"
+// @Success 200 {array} dtos.StockLevelResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /locations/{id}/stock [get]
+func (h *inventoryController) GetLocationStock(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	levels, err := h.inventoryRepository.GetStockLevelsByLocationID(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve stock levels"})
+	}
+	return c.JSON(toStockLevelResponses(levels))
+}
+
+// SetLocationStock godoc
+// @Summary Set the stock level of a variant at a location
+// @Description Set the on-hand quantity of a variant at a location. The difference is added to or taken from the product's stock, so it keeps counting what the locations hold.
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param id path string true "Location ID"
+// @Param level body dtos.StockLevelSetDTO true "Stock level"
+// @Success 200 {object} dtos.StockLevelResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /locations/{id}/stock [put]
+func (h *inventoryController) SetLocationStock(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.StockLevelSetDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	if _, err := h.inventoryRepository.GetLocationByID(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "location not found"})
+	}
+
+	level, err := h.inventoryRepository.SetStockLevel(id, dto.VariantID, dto.Quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "product variant not found"})
+		case errors.Is(err, repositories.ErrLocationNotInStore), errors.Is(err, repositories.ErrInsufficientProductStock):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not set stock level"})
+	}
+	return c.JSON(toStockLevelResponses([]models.VariantStockLevel{*level})[0])
+}
+
+// GetVariantStock godoc
+// @Summary Get stock levels of a variant
+// @Description Get the stock levels of a variant across all locations
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Variant ID"
+// @Success 200 {array} dtos.StockLevelResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /product-variants/{id}/stock [get]
+func (h *inventoryController) GetVariantStock(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	levels, err := h.inventoryRepository.GetStockLevelsByVariantID(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve stock levels"})
+	}
+	return c.JSON(toStockLevelResponses(levels))
+}
+
+// CreateTransfer godoc
+// @Summary Transfer stock between locations
+// @Description Send stock from one location to another of the variant's store; it stays in transit until received
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param transfer body dtos.StockTransferCreateDTO true "Transfer"
+// @Success 201 {object} dtos.StockTransferResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stock-transfers [post]
+func (h *inventoryController) CreateTransfer(c *fiber.Ctx) error {
+	dto := new(dtos.StockTransferCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	transfer := models.StockTransfer{
+		VariantID:      dto.VariantID,
+		FromLocationID: dto.FromLocationID,
+		ToLocationID:   dto.ToLocationID,
+		Quantity:       dto.Quantity,
+		Note:           dto.Note,
+	}
+
+	if err := h.inventoryRepository.CreateTransfer(&transfer); err != nil {
+		if errors.Is(err, repositories.ErrInsufficientLocationStock) || errors.Is(err, repositories.ErrLocationNotInStore) || errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not create transfer", "details": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create transfer"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toTransferResponse(transfer))
+}
+
+// GetAllTransfers godoc
+// @Summary Get stock transfers
+// @Description Get stock transfers, optionally filtered by status
+// @Tags Inventory
+// @Produce json
+// @Param status query string false "in_transit, received or cancelled"
+// @Success 200 {array} dtos.StockTransferResponseDTO
+// @Failure 500 {object} fiber.Map
+// @Router /stock-transfers [get]
+func (h *inventoryController) GetAllTransfers(c *fiber.Ctx) error {
+	transfers, err := h.inventoryRepository.GetAllTransfers(c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve transfers"})
+	}
+
+	responses := make([]dtos.StockTransferResponseDTO, 0, len(transfers))
+	for _, transfer := range transfers {
+		responses = append(responses, toTransferResponse(transfer))
+	}
+	return c.JSON(responses)
+}
+
+// ReceiveTransfer godoc
+// @Summary Receive a stock transfer
+// @Description Book an in-transit transfer into its destination location
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} dtos.StockTransferResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /stock-transfers/{id}/receive [put]
+func (h *inventoryController) ReceiveTransfer(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	transfer, err := h.inventoryRepository.ReceiveTransfer(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not receive transfer", "details": err.Error()})
+	}
+	return c.JSON(toTransferResponse(*transfer))
+}
+
+// CancelTransfer godoc
+// @Summary Cancel a stock transfer
+// @Description Cancel an in-transit transfer and return the stock to its source location
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Transfer ID"
+// @Success 200 {object} dtos.StockTransferResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /stock-transfers/{id}/cancel [put]
+func (h *inventoryController) CancelTransfer(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	transfer, err := h.inventoryRepository.CancelTransfer(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not cancel transfer", "details": err.Error()})
+	}
+	return c.JSON(toTransferResponse(*transfer))
+}
+
+// GetStockMovements godoc
+// @Summary Get stock movements
+// @Description Get the stock movement ledger, optionally for one variant and/or location
+// @Tags Inventory
+// @Produce json
+// @Param variant_id query string false "Variant ID"
+// @Param location_id query string false "Location ID"
+// @Success 200 {array} dtos.StockMovementResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stock-movements [get]
+func (h *inventoryController) GetStockMovements(c *fiber.Ctx) error {
+	variantID, err := optionalUUIDQuery(c, "variant_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid variant_id"})
+	}
+	locationID, err := optionalUUIDQuery(c, "location_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid location_id"})
+	}
+
+	movements, err := h.stockMovementRepository.GetStockMovements(variantID, locationID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve stock movements"})
+	}
+
+	responses := make([]dtos.StockMovementResponseDTO, 0, len(movements))
+	for _, movement := range movements {
+		responses = append(responses, dtos.StockMovementResponseDTO{
+			ID:            movement.ID,
+			VariantID:     movement.VariantID,
+			ProductID:     movement.ProductID,
+			LocationID:    movement.LocationID,
+			Quantity:      movement.Quantity,
+			Reason:        movement.Reason,
+			ReasonCode:    movement.ReasonCode,
+			ReferenceType: movement.ReferenceType,
+			ReferenceID:   movement.ReferenceID,
+			UnitCost:      movement.UnitCost,
+			CreatedAt:     movement.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return c.JSON(responses)
+}
+
+// GetLowStock godoc
+// @Summary Get low-stock variants
+// @Description Get the variants whose on-hand stock is at or below their reorder point
+// @Tags Inventory
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} dtos.LowStockVariantDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /inventory/low-stock [get]
+func (h *inventoryController) GetLowStock(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	variants, err := h.productVariantRepository.GetLowStockVariants(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve low-stock variants"})
+	}
+	if variants == nil {
+		variants = []dtos.LowStockVariantDTO{}
+	}
+	return c.JSON(variants)
+}
+
+// locationType returns the type of a location, shop unless given
+func locationType(locationType string) string {
+	if locationType == "" {
+		return "shop"
+	}
+	return locationType
+}
+
+func toLocationResponse(location models.InventoryLocation) dtos.InventoryLocationResponseDTO {
+	return dtos.InventoryLocationResponseDTO{
+		ID:        location.ID,
+		StoreID:   location.StoreID,
+		Name:      location.Name,
+		Type:      location.Type,
+		CreatedAt: location.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: location.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func toStockLevelResponses(levels []models.VariantStockLevel) []dtos.StockLevelResponseDTO {
+	responses := make([]dtos.StockLevelResponseDTO, 0, len(levels))
+	for _, level := range levels {
+		responses = append(responses, dtos.StockLevelResponseDTO{
+			VariantID:  level.VariantID,
+			LocationID: level.LocationID,
+			Quantity:   level.Quantity,
+			UpdatedAt:  level.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return responses
+}
+
+func toTransferResponse(transfer models.StockTransfer) dtos.StockTransferResponseDTO {
+	return dtos.StockTransferResponseDTO{
+		ID:             transfer.ID,
+		VariantID:      transfer.VariantID,
+		FromLocationID: transfer.FromLocationID,
+		ToLocationID:   transfer.ToLocationID,
+		FromStoreID:    transfer.FromStoreID,
+		ToStoreID:      transfer.ToStoreID,
+		Quantity:       transfer.Quantity,
+		Status:         transfer.Status,
+		Note:           transfer.Note,
+		ShippedAt:      transfer.ShippedAt,
+		ReceivedAt:     transfer.ReceivedAt,
+	}
+}
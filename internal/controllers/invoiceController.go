@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/invoicing"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InvoiceController interface {
+	IssueInvoice(c *fiber.Ctx) error
+	GetOrderInvoices(c *fiber.Ctx) error
+	GetInvoice(c *fiber.Ctx) error
+	GetInvoiceXML(c *fiber.Ctx) error
+	GetInvoiceHTML(c *fiber.Ctx) error
+	CreateCreditNote(c *fiber.Ctx) error
+}
+
+type invoiceController struct {
+	invoiceRepository repositories.InvoiceRepository
+}
+
+func NewInvoiceController(invoiceRepository repositories.InvoiceRepository) InvoiceController {
+	return &invoiceController{invoiceRepository: invoiceRepository}
+}
+
+// IssueInvoice godoc
+// @Summary Issue the tax invoice of an order
+// @Description Issue the receipt/tax invoice of a paid order, numbered next in its store's sequence for the year. The order's lines must be of one store, which must have a tax ID. Buyer fields left empty are taken from the order and its customer.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param buyer body dtos.InvoiceIssueDTO false "Buyer"
+// @Success 201 {object} dtos.InvoiceResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /orders/{id}/invoices [post]
+func (h *invoiceController) IssueInvoice(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.InvoiceIssueDTO)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(dto); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+		}
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+	if dto.BuyerTaxID != "" && !tax.ValidTaxID(dto.BuyerTaxID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "buyer_tax_id is not a valid tax ID"})
+	}
+
+	invoice, err := h.invoiceRepository.IssueInvoice(orderID, models.InvoiceParty{
+		Name:       dto.BuyerName,
+		TaxID:      dto.BuyerTaxID,
+		BranchCode: dto.BuyerBranchCode,
+		Address:    dto.BuyerAddress,
+		Postcode:   dto.BuyerPostcode,
+		Email:      dto.BuyerEmail,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "order not found"})
+		case errors.Is(err, repositories.ErrOrderNotInvoiceable), errors.Is(err, repositories.ErrOrderInvoiced):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, repositories.ErrOrderSpansStores), errors.Is(err, repositories.ErrStoreNotTaxRegistered):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not issue invoice", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toInvoiceResponse(*invoice))
+}
+
+// GetOrderInvoices godoc
+// @Summary Get the invoices of an order
+// @Description Get the tax invoice and credit notes of an order, in the order they were issued
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {array} dtos.InvoiceResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /orders/{id}/invoices [get]
+func (h *invoiceController) GetOrderInvoices(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	invoices, err := h.invoiceRepository.GetInvoicesByOrderID(orderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve invoices"})
+	}
+
+	responses := make([]dtos.InvoiceResponseDTO, 0, len(invoices))
+	for _, invoice := range invoices {
+		responses = append(responses, toInvoiceResponse(invoice))
+	}
+	return c.JSON(responses)
+}
+
+// GetInvoice godoc
+// @Summary Get invoice by ID
+// @Description Get a tax invoice or credit note with its lines
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Success 200 {object} dtos.InvoiceResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /invoices/{id} [get]
+func (h *invoiceController) GetInvoice(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	invoice, err := h.invoiceRepository.GetInvoiceByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "invoice not found"})
+	}
+	return c.JSON(toInvoiceResponse(*invoice))
+}
+
+// GetInvoiceXML godoc
+// @Summary Get the e-Tax invoice XML of an invoice
+// @Description Download a tax invoice or credit note as the XML of the ETDA e-Tax invoice standard, to sign and submit to the Revenue Department
+// @Tags Invoices
+// @Produce xml
+// @Param id path string true "Invoice ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} fiber.Map
+// @Router /invoices/{id}/xml [get]
+func (h *invoiceController) GetInvoiceXML(c *fiber.Ctx) error {
+	invoice, original, err := h.loadInvoice(c)
+	if invoice == nil {
+		return err
+	}
+
+	body, err := invoicing.XML(*invoice, original)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not render invoice", "details": err.Error()})
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+invoice.Number+`.xml"`)
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	return c.Send(body)
+}
+
+// GetInvoiceHTML godoc
+// @Summary Get the printable page of an invoice
+// @Description Get a tax invoice or credit note as an HTML page laid out for A4; print it to PDF to send to the buyer
+// @Tags Invoices
+// @Produce html
+// @Param id path string true "Invoice ID"
+// @Success 200 {string} string
+// @Failure 404 {object} fiber.Map
+// @Router /invoices/{id}/html [get]
+func (h *invoiceController) GetInvoiceHTML(c *fiber.Ctx) error {
+	invoice, original, err := h.loadInvoice(c)
+	if invoice == nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := invoicing.HTML(&body, *invoice, original); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not render invoice", "details": err.Error()})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(body.Bytes())
+}
+
+// CreateCreditNote godoc
+// @Summary Issue a credit note against a tax invoice
+// @Description Issue a credit note reducing a tax invoice, for a payment refund of its order or for an amount including VAT. The credit is spread over the invoice's lines in proportion to their amounts, and cannot exceed what is left of the invoice after earlier credit notes. A refund can only be credited once.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param creditNote body dtos.CreditNoteCreateDTO true "Refund or amount to credit"
+// @Success 201 {object} dtos.InvoiceResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /invoices/{id}/credit-notes [post]
+func (h *invoiceController) CreateCreditNote(c *fiber.Ctx) error {
+	invoiceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.CreditNoteCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	note, err := h.invoiceRepository.IssueCreditNote(invoiceID, dto.RefundID, dto.Amount, dto.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "invoice or refund not found"})
+		case errors.Is(err, repositories.ErrRefundCredited), errors.Is(err, repositories.ErrCreditExceedsInvoice):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, repositories.ErrNotTaxInvoice), errors.Is(err, repositories.ErrRefundNotOfOrder):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not issue credit note", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toInvoiceResponse(*note))
+}
+
+// loadInvoice loads the invoice named by the id parameter and, for a credit note, the tax invoice it reduces.
+// When they cannot be loaded it writes the error response and returns a nil invoice.
+func (h *invoiceController) loadInvoice(c *fiber.Ctx) (*models.Invoice, *models.Invoice, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	invoice, err := h.invoiceRepository.GetInvoiceByID(id)
+	if err != nil {
+		return nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "invoice not found"})
+	}
+	if invoice.InvoiceID == nil {
+		return invoice, nil, nil
+	}
+	original, err := h.invoiceRepository.GetInvoiceByID(*invoice.InvoiceID)
+	if err != nil {
+		return nil, nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not load the credited invoice"})
+	}
+	return invoice, original, nil
+}
+
+func toInvoiceResponse(invoice models.Invoice) dtos.InvoiceResponseDTO {
+	response := dtos.InvoiceResponseDTO{
+		ID:          invoice.ID,
+		StoreID:     invoice.StoreID,
+		OrderID:     invoice.OrderID,
+		Type:        invoice.Type,
+		Name:        invoicing.DocumentName(invoice),
+		Number:      invoice.Number,
+		IssuedAt:    invoice.IssuedAt,
+		Seller:      toInvoicePartyDTO(invoice.Seller),
+		Buyer:       toInvoicePartyDTO(invoice.Buyer),
+		Currency:    invoice.Currency,
+		NetAmount:   invoice.NetAmount,
+		TaxAmount:   invoice.TaxAmount,
+		GrossAmount: invoice.GrossAmount,
+		InvoiceID:   invoice.InvoiceID,
+		RefundID:    invoice.RefundID,
+		Reason:      invoice.Reason,
+		Lines:       make([]dtos.InvoiceLineResponseDTO, 0, len(invoice.Lines)),
+	}
+	for _, line := range invoice.Lines {
+		response.Lines = append(response.Lines, dtos.InvoiceLineResponseDTO{
+			LineNumber:    line.LineNumber,
+			OrderDetailID: line.OrderDetailID,
+			Description:   line.Description,
+			Quantity:      line.Quantity,
+			UnitPrice:     line.UnitPrice,
+			TaxRate:       line.TaxRate,
+			NetAmount:     line.NetAmount,
+			TaxAmount:     line.TaxAmount,
+			GrossAmount:   line.GrossAmount,
+		})
+	}
+	return response
+}
+
+func toInvoicePartyDTO(party models.InvoiceParty) dtos.InvoicePartyDTO {
+	return dtos.InvoicePartyDTO{
+		Name:       party.Name,
+		TaxID:      party.TaxID,
+		BranchCode: party.BranchCode,
+		Address:    party.Address,
+		Postcode:   party.Postcode,
+		Email:      party.Email,
+	}
+}
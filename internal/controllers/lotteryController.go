@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"errors"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LotteryController interface {
+	EnterLottery(c *fiber.Ctx) error
+	GetLottery(c *fiber.Ctx) error
+	DrawLottery(c *fiber.Ctx) error
+}
+
+type lotteryController struct {
+	lotteryService       services.LotteryService
+	salesRoundRepository repositories.SalesRoundRepository
+}
+
+func NewLotteryController(lotteryService services.LotteryService, salesRoundRepository repositories.SalesRoundRepository) LotteryController {
+	return &lotteryController{
+		lotteryService:       lotteryService,
+		salesRoundRepository: salesRoundRepository,
+	}
+}
+
+// EnterLottery godoc
+// @Summary Enter a lottery sales round
+// @Description Register a customer's entry for a variant while the lottery round is open. Winners are drawn when the round ends and get a pending order to pay before the round's payment window runs out.
+// @Tags Lottery
+// @Accept json
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param entry body dtos.LotteryEntryCreateDTO true "Lottery entry"
+// @Success 201 {object} dtos.LotteryEntryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-rounds/{id}/lottery/entries [post]
+func (h *lotteryController) EnterLottery(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.LotteryEntryCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "lottery entry is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	entry := &models.LotteryEntry{
+		RoundID:         roundID,
+		VariantID:       dto.VariantID,
+		CustomerID:      dto.CustomerID,
+		Quantity:        dto.Quantity,
+		DeliveryAddress: dto.DeliveryAddress,
+		PaymentSource:   dto.PaymentSource,
+	}
+	if err := h.lotteryService.EnterLottery(entry); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round or variant not found"})
+		case errors.Is(err, repositories.ErrAlreadyEntered), errors.Is(err, repositories.ErrLotteryClosed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not enter lottery", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toLotteryEntryResponse(*entry))
+}
+
+// GetLottery godoc
+// @Summary Get a lottery sales round
+// @Description Get the draw commitment, the revealed seed once drawn, and the entries in draw order, so the draw can be verified
+// @Tags Lottery
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param customer_id query string false "Only this customer's entries"
+// @Success 200 {object} dtos.LotteryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-rounds/{id}/lottery [get]
+func (h *lotteryController) GetLottery(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	customerID, err := optionalUUIDQuery(c, "customer_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid customer_id"})
+	}
+
+	round, err := h.salesRoundRepository.GetSalesRoundByID(roundID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
+	}
+	if round.Mode != models.SalesRoundModeLottery {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": repositories.ErrNotLotteryRound.Error()})
+	}
+
+	entries, err := h.lotteryService.GetLotteryEntries(roundID, customerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve lottery entries"})
+	}
+
+	response := dtos.LotteryResponseDTO{
+		RoundID:        round.ID,
+		StartDate:      round.StartDate,
+		EndDate:        round.EndDate,
+		DrawCommitment: round.DrawCommitment,
+		DrawnAt:        round.DrawnAt,
+		Entries:        make([]dtos.LotteryEntryResponseDTO, 0, len(entries)),
+	}
+	if round.DrawnAt != nil {
+		response.DrawSeed = round.DrawSeed
+	}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, toLotteryEntryResponse(entry))
+	}
+	return c.JSON(response)
+}
+
+// DrawLottery godoc
+// @Summary Draw a lottery sales round
+// @Description Draw an ended lottery round now instead of waiting for the scheduler
+// @Tags Lottery
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Success 200 {array} dtos.LotteryEntryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /sales-rounds/{id}/lottery/draw [post]
+func (h *lotteryController) DrawLottery(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	winners, err := h.lotteryService.DrawLottery(roundID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
+		case errors.Is(err, repositories.ErrLotteryDrawn), errors.Is(err, repositories.ErrLotteryStillOpen):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not draw lottery", "details": err.Error()})
+	}
+
+	responses := make([]dtos.LotteryEntryResponseDTO, 0, len(winners))
+	for _, entry := range winners {
+		responses = append(responses, toLotteryEntryResponse(entry))
+	}
+	return c.JSON(responses)
+}
+
+func toLotteryEntryResponse(entry models.LotteryEntry) dtos.LotteryEntryResponseDTO {
+	return dtos.LotteryEntryResponseDTO{
+		ID:          entry.ID,
+		RoundID:     entry.RoundID,
+		VariantID:   entry.VariantID,
+		CustomerID:  entry.CustomerID,
+		Quantity:    entry.Quantity,
+		Status:      entry.Status,
+		Ticket:      entry.Ticket,
+		Rank:        entry.Rank,
+		WonQuantity: entry.WonQuantity,
+		OrderID:     entry.OrderID,
+		CreatedAt:   entry.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
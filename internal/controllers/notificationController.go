@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationController interface {
+	GetNotifications(c *fiber.Ctx) error
+	MarkNotificationRead(c *fiber.Ctx) error
+}
+
+type notificationController struct {
+	notificationRepository repositories.NotificationRepository
+}
+
+func NewNotificationController(notificationRepository repositories.NotificationRepository) NotificationController {
+	return &notificationController{notificationRepository: notificationRepository}
+}
+
+// GetNotifications godoc
+// @Summary Get notifications
+// @Description Get notifications, optionally filtered by type and unread state
+// @Tags Notifications
+// @Produce json
+// @Param type query string false "Notification type, e.g. low_stock"
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {array} models.Notification
+// @Failure 500 {object} fiber.Map
+// @Router /notifications [get]
+func (h *notificationController) GetNotifications(c *fiber.Ctx) error {
+	notifications, err := h.notificationRepository.GetNotifications(c.Query("type"), c.QueryBool("unread"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve notifications"})
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+	return c.JSON(notifications)
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification as read
+// @Description Mark a notification as read
+// @Tags Notifications
+// @Param id path string true "Notification ID"
+// @Success 204
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /notifications/{id}/read [put]
+func (h *notificationController) MarkNotificationRead(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.notificationRepository.MarkNotificationRead(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unread notification not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update notification"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -1,14 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"runtime"
 	"sync"
 
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type OrderController interface {
@@ -16,6 +19,8 @@ type OrderController interface {
 	GetAllOrders(c *fiber.Ctx) error
 	UpdateOrder(c *fiber.Ctx) error
 	DeleteOrder(c *fiber.Ctx) error
+	CancelOrder(c *fiber.Ctx) error
+	ConfirmOrder(c *fiber.Ctx) error
 }
 
 type orderController struct {
@@ -62,10 +67,7 @@ func (h *orderController) CreateOrder(c *fiber.Ctx) error {
 		Items:           items,
 	})
 	if err != nil {
-		if err.Error() == "not enough stock" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "not enough stock"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return purchaseErrorResponse(c, dto.RoundID, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(response)
@@ -226,6 +228,102 @@ func (h *orderController) DeleteOrder(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// CancelOrder godoc
+// @Summary Cancel an order
+// @Description Cancel an order; its quantities go back to the sales round and are offered to waitlisted customers
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param cancel body dtos.OrderCancelDTO false "Cancellation"
+// @Success 200 {object} dtos.OrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /orders/{id}/cancel [post]
+func (h *orderController) CancelOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.OrderCancelDTO)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(dto); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+		}
+	}
+
+	order, err := h.purchaseService.CancelOrder(id, dto.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "order not found"})
+		case errors.Is(err, repositories.ErrOrderShipped):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not cancel order", "details": err.Error()})
+	}
+
+	return c.JSON(toOrderResponse(*order))
+}
+
+// ConfirmOrder godoc
+// @Summary Confirm payment of a pending order
+// @Description Mark a pending order, such as a lottery win, as paid. Orders past their payment deadline cannot be confirmed.
+// @Tags Orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} dtos.OrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /orders/{id}/confirm [post]
+func (h *orderController) ConfirmOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	order, err := h.purchaseService.ConfirmOrder(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "order not found"})
+		case errors.Is(err, repositories.ErrPaymentOverdue):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not confirm order", "details": err.Error()})
+	}
+	return c.JSON(toOrderResponse(*order))
+}
+
+func toOrderResponse(order models.Order) dtos.OrderResponseDTO {
+	items := make([]dtos.OrderItemDTO, 0, len(order.OrderDetail))
+	for _, line := range order.OrderDetail {
+		items = append(items, dtos.OrderItemDTO{VariantID: line.VariantID, Quantity: line.Quantity})
+	}
+	return dtos.OrderResponseDTO{
+		ID:              order.ID,
+		CustomerID:      order.CustomerID,
+		RoundID:         order.RoundID,
+		OrderDate:       order.OrderDate,
+		Status:          order.Status,
+		Code:            order.Code,
+		TotalPrice:      order.TotalPrice,
+		NetAmount:       order.NetAmount,
+		TaxAmount:       order.TaxAmount,
+		DeliveryAddress: order.DeliveryAddress,
+		AddressID:       order.AddressID,
+		ShippingAddress: services.PostalAddressResponse(order.ShippingAddress),
+		PaymentSource:   order.PaymentSource,
+		PaymentDueAt:    order.PaymentDueAt,
+		CreatedAt:       order.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:       order.UpdatedAt.Format("2006-01-02 15:04:05"),
+		Items:           items,
+	}
+}
+
 func init() {
 	// Use all available cores
 	runtime.GOMAXPROCS(runtime.NumCPU())
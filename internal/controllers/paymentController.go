@@ -0,0 +1,343 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/payments"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/B6137151/InventoryMarketplaceSystem/pkg/qrcode"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentController interface {
+	StartPayment(c *fiber.Ctx) error
+	GetOrderPayments(c *fiber.Ctx) error
+	GetPayment(c *fiber.Ctx) error
+	CapturePayment(c *fiber.Ctx) error
+	RefundPayment(c *fiber.Ctx) error
+	HandleWebhook(c *fiber.Ctx) error
+	SimulatePayment(c *fiber.Ctx) error
+	GetPaymentQR(c *fiber.Ctx) error
+}
+
+type paymentController struct {
+	paymentService services.PaymentService
+	mockProvider   *payments.MockProvider // nil unless the mock provider is enabled
+}
+
+func NewPaymentController(paymentService services.PaymentService, mockProvider *payments.MockProvider) PaymentController {
+	return &paymentController{
+		paymentService: paymentService,
+		mockProvider:   mockProvider,
+	}
+}
+
+// StartPayment godoc
+// @Summary Start paying an order
+// @Description Create a payment intent for a pending order with the provider named in its payment source. If the order already has a payment the customer can complete, that one is returned.
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 201 {object} dtos.PaymentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Failure 502 {object} fiber.Map
+// @Router /orders/{id}/payments [post]
+func (h *paymentController) StartPayment(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	payment, err := h.paymentService.StartPayment(orderID)
+	if err != nil {
+		return paymentErrorResponse(c, err, "could not start payment")
+	}
+	return c.Status(fiber.StatusCreated).JSON(services.PaymentResponse(*payment))
+}
+
+// GetOrderPayments godoc
+// @Summary Get the payments of an order
+// @Description Get every payment attempt of an order with its refunds, oldest first
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {array} dtos.PaymentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /orders/{id}/payments [get]
+func (h *paymentController) GetOrderPayments(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	orderPayments, err := h.paymentService.GetOrderPayments(orderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve payments"})
+	}
+
+	responses := make([]dtos.PaymentResponseDTO, 0, len(orderPayments))
+	for _, payment := range orderPayments {
+		responses = append(responses, services.PaymentResponse(payment))
+	}
+	return c.JSON(responses)
+}
+
+// GetPayment godoc
+// @Summary Get a payment
+// @Description Get a payment by ID with its refunds
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Success 200 {object} dtos.PaymentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /payments/{id} [get]
+func (h *paymentController) GetPayment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	payment, err := h.paymentService.GetPayment(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "payment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve payment"})
+	}
+	return c.JSON(services.PaymentResponse(*payment))
+}
+
+// CapturePayment godoc
+// @Summary Capture a payment
+// @Description Capture an authorized payment and mark its order paid. Providers' webhooks normally do this; the endpoint is for payments whose webhook was missed. Payments that are not authorized are refused with 409; PromptPay payments are captured by matching their transfer in a bank statement.
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Success 200 {object} dtos.PaymentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Failure 502 {object} fiber.Map
+// @Router /payments/{id}/capture [post]
+func (h *paymentController) CapturePayment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	payment, err := h.paymentService.CapturePayment(id)
+	if err != nil {
+		return paymentErrorResponse(c, err, "could not capture payment")
+	}
+	return c.JSON(services.PaymentResponse(*payment))
+}
+
+// RefundPayment godoc
+// @Summary Refund a payment
+// @Description Refund part or all of a captured payment. An amount of 0 refunds everything that is left.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param refund body dtos.PaymentRefundDTO true "Refund"
+// @Success 200 {object} dtos.PaymentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Failure 502 {object} fiber.Map
+// @Router /payments/{id}/refunds [post]
+func (h *paymentController) RefundPayment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.PaymentRefundDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refund is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	payment, err := h.paymentService.RefundPayment(id, dto.Amount, dto.Reason)
+	if err != nil {
+		return paymentErrorResponse(c, err, "could not refund payment")
+	}
+	return c.JSON(services.PaymentResponse(*payment))
+}
+
+// HandleWebhook godoc
+// @Summary Receive a payment provider webhook
+// @Description Endpoint payment providers report payments to. The request must carry the provider's signature.
+// @Tags Payments
+// @Accept json
+// @Param provider path string true "Provider name, e.g. mock"
+// @Success 204
+// @Failure 400 {object} fiber.Map
+// @Failure 401 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /payment-webhooks/{provider} [post]
+func (h *paymentController) HandleWebhook(c *fiber.Ctx) error {
+	header := http.Header{}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	if err := h.paymentService.HandleWebhook(c.Params("provider"), header, c.Body()); err != nil {
+		switch {
+		case errors.Is(err, payments.ErrUnknownProvider):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, payments.ErrNotSupported):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "provider does not send webhooks"})
+		case errors.Is(err, payments.ErrInvalidSignature):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "payment not found"})
+		}
+		// Anything else is worth a retry by the provider
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not process webhook", "details": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SimulatePayment godoc
+// @Summary Simulate the customer of a mock payment
+// @Description Pay or decline a payment made with the mock provider. The mock provider's signed webhook is processed as if it had been received, so the order is paid on success. Only available when the mock provider is enabled.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param simulation body dtos.PaymentSimulateDTO true "Outcome"
+// @Success 200 {object} dtos.PaymentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /payments/{id}/simulate [post]
+func (h *paymentController) SimulatePayment(c *fiber.Ctx) error {
+	if h.mockProvider == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "the mock payment provider is not enabled"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.PaymentSimulateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "simulation is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	payment, err := h.paymentService.GetPayment(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "payment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve payment"})
+	}
+	if payment.Provider != h.mockProvider.Name() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment was not made with the mock provider"})
+	}
+
+	header, body, err := h.mockProvider.Simulate(payment.ProviderIntentID, dto.Outcome)
+	if err != nil {
+		return paymentErrorResponse(c, err, "could not simulate payment")
+	}
+	if err := h.paymentService.HandleWebhook(h.mockProvider.Name(), header, body); err != nil {
+		return paymentErrorResponse(c, err, "could not process mock webhook")
+	}
+
+	if payment, err = h.paymentService.GetPayment(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve payment"})
+	}
+	return c.JSON(services.PaymentResponse(*payment))
+}
+
+// GetPaymentQR godoc
+// @Summary Get the PromptPay QR code of an order
+// @Description Get the PromptPay QR code a pending order is paid with, for its total and its store's PromptPay ID. The payment is started if the order has none the customer can complete. Returns JSON with the EMVCo payload and the PNG as a data URL, or the PNG itself with ?format=png or Accept: image/png.
+// @Tags Payments
+// @Produce json
+// @Produce png
+// @Param id path string true "Order ID"
+// @Param format query string false "png to get the image only"
+// @Success 200 {object} dtos.PaymentQRResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Failure 502 {object} fiber.Map
+// @Router /orders/{id}/payment-qr [get]
+func (h *paymentController) GetPaymentQR(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	payment, err := h.paymentService.StartPayment(orderID)
+	if err != nil {
+		return paymentErrorResponse(c, err, "could not start payment")
+	}
+	var nextAction payments.NextAction
+	if payment.NextAction != "" {
+		if err := json.Unmarshal([]byte(payment.NextAction), &nextAction); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not read payment", "details": err.Error()})
+		}
+	}
+	payload := nextAction.Data["payload"]
+	if nextAction.Type != "promptpay_qr" || payload == "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "order is not paid by PromptPay", "details": "payment provider is " + payment.Provider})
+	}
+
+	code, err := qrcode.Encode(payload, qrcode.Medium)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not encode QR code", "details": err.Error()})
+	}
+	image, err := code.PNG(8)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not render QR code", "details": err.Error()})
+	}
+
+	if c.Query("format") == "png" || c.Accepts(fiber.MIMEApplicationJSON, "image/png") == "image/png" {
+		c.Set(fiber.HeaderContentType, "image/png")
+		return c.Send(image)
+	}
+	return c.JSON(dtos.PaymentQRResponseDTO{
+		PaymentID:   payment.ID,
+		OrderID:     payment.OrderID,
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		PromptPayID: payment.PayeeID,
+		Payload:     payload,
+		Image:       "data:image/png;base64," + base64.StdEncoding.EncodeToString(image),
+	})
+}
+
+// paymentErrorResponse maps payment errors to responses; provider failures are reported as 502
+func paymentErrorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, payments.ErrUnknownIntent):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": message, "details": err.Error()})
+	case errors.Is(err, payments.ErrUnknownProvider):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": message, "details": err.Error()})
+	case errors.Is(err, services.ErrOrderNotPayable), errors.Is(err, payments.ErrInvalidState),
+		errors.Is(err, repositories.ErrRefundExceedsCaptured), errors.Is(err, payments.ErrNotSupported):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": message, "details": err.Error()})
+	}
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": message, "details": err.Error()})
+}
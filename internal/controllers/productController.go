@@ -7,6 +7,7 @@ import (
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -43,6 +44,9 @@ func (h *productController) CreateProduct(c *fiber.Ctx) error {
 	if err := c.BodyParser(dto); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
 	}
+	if dto.TaxCategory != "" && !tax.ValidCategory(dto.TaxCategory) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": tax.ErrUnknownCategory.Error(), "details": tax.Categories()})
+	}
 
 	product := models.Product{
 		StoreID:     dto.StoreID,
@@ -54,6 +58,7 @@ func (h *productController) CreateProduct(c *fiber.Ctx) error {
 		Stock:       dto.Stock,
 		Price:       dto.Price,
 		ImageURL:    dto.ImageURL,
+		TaxCategory: dto.TaxCategory,
 	}
 
 	var wg sync.WaitGroup
@@ -83,6 +88,7 @@ func (h *productController) CreateProduct(c *fiber.Ctx) error {
 		Stock:       product.Stock,
 		Price:       product.Price,
 		ImageURL:    product.ImageURL,
+		TaxCategory: product.TaxCategory,
 		CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:   product.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
@@ -132,6 +138,7 @@ func (h *productController) GetAllProducts(c *fiber.Ctx) error {
 			Stock:       product.Stock,
 			Price:       product.Price,
 			ImageURL:    product.ImageURL,
+			TaxCategory: product.TaxCategory,
 			CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
 			UpdatedAt:   product.UpdatedAt.Format("2006-01-02 15:04:05"),
 		})
@@ -164,6 +171,9 @@ func (h *productController) UpdateProduct(c *fiber.Ctx) error {
 	if err := c.BodyParser(dto); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
 	}
+	if dto.TaxCategory != "" && !tax.ValidCategory(dto.TaxCategory) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": tax.ErrUnknownCategory.Error(), "details": tax.Categories()})
+	}
 
 	var wg sync.WaitGroup
 	errChan := make(chan error, 1)
@@ -199,6 +209,9 @@ func (h *productController) UpdateProduct(c *fiber.Ctx) error {
 	product.Stock = dto.Stock
 	product.Price = dto.Price
 	product.ImageURL = dto.ImageURL
+	if dto.TaxCategory != "" {
+		product.TaxCategory = dto.TaxCategory
+	}
 
 	// Reset the wait group for the update operation
 	wg.Add(1)
@@ -230,6 +243,7 @@ func (h *productController) UpdateProduct(c *fiber.Ctx) error {
 		Stock:       product.Stock,
 		Price:       product.Price,
 		ImageURL:    product.ImageURL,
+		TaxCategory: product.TaxCategory,
 		CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:   product.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
@@ -4,6 +4,7 @@ import (
 	"runtime"
 	"sync"
 
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
@@ -42,12 +43,17 @@ func (h *productVariantController) CreateProductVariant(c *fiber.Ctx) error {
 	if err := c.BodyParser(dto); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
 	}
+	if err := validate.StructPartial(dto, "ReorderPoint", "ReorderQuantity"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
 
 	productVariant := models.ProductVariant{
-		ProductID: dto.ProductID,
-		SKUCode:   dto.SKUCode,
-		Price:     dto.Price,
-		ImageURL:  dto.ImageURL,
+		ProductID:       dto.ProductID,
+		SKUCode:         dto.SKUCode,
+		Price:           dto.Price,
+		ImageURL:        dto.ImageURL,
+		ReorderPoint:    dto.ReorderPoint,
+		ReorderQuantity: dto.ReorderQuantity,
 	}
 
 	var wg sync.WaitGroup
@@ -67,13 +73,15 @@ func (h *productVariantController) CreateProductVariant(c *fiber.Ctx) error {
 	}
 
 	response := dtos.ProductVariantResponseDTO{
-		ID:        productVariant.VariantID,
-		ProductID: productVariant.ProductID,
-		SKUCode:   productVariant.SKUCode,
-		Price:     productVariant.Price,
-		ImageURL:  productVariant.ImageURL,
-		CreatedAt: productVariant.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt: productVariant.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ID:              productVariant.VariantID,
+		ProductID:       productVariant.ProductID,
+		SKUCode:         productVariant.SKUCode,
+		Price:           productVariant.Price,
+		ImageURL:        productVariant.ImageURL,
+		CreatedAt:       productVariant.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:       productVariant.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ReorderPoint:    productVariant.ReorderPoint,
+		ReorderQuantity: productVariant.ReorderQuantity,
 	}
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
@@ -111,13 +119,15 @@ func (h *productVariantController) GetAllProductVariants(c *fiber.Ctx) error {
 	var productVariantResponses []dtos.ProductVariantResponseDTO
 	for _, productVariant := range productVariants {
 		productVariantResponses = append(productVariantResponses, dtos.ProductVariantResponseDTO{
-			ID:        productVariant.VariantID,
-			ProductID: productVariant.ProductID,
-			SKUCode:   productVariant.SKUCode,
-			Price:     productVariant.Price,
-			ImageURL:  productVariant.ImageURL,
-			CreatedAt: productVariant.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: productVariant.UpdatedAt.Format("2006-01-02 15:04:05"),
+			ID:              productVariant.VariantID,
+			ProductID:       productVariant.ProductID,
+			SKUCode:         productVariant.SKUCode,
+			Price:           productVariant.Price,
+			ImageURL:        productVariant.ImageURL,
+			CreatedAt:       productVariant.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt:       productVariant.UpdatedAt.Format("2006-01-02 15:04:05"),
+			ReorderPoint:    productVariant.ReorderPoint,
+			ReorderQuantity: productVariant.ReorderQuantity,
 		})
 	}
 
@@ -126,7 +136,7 @@ func (h *productVariantController) GetAllProductVariants(c *fiber.Ctx) error {
 
 // UpdateProductVariant godoc
 // @Summary Update a product variant
-// @Description Update a product variant
+// @Description Update a product variant. The reorder point and quantity are kept when left out. A variant whose stock is not kept per location is compared with its product's stock, which its sibling variants share.
 // @Tags Product Variants
 // @Accept json
 // @Produce json
@@ -148,6 +158,9 @@ func (h *productVariantController) UpdateProductVariant(c *fiber.Ctx) error {
 	if err := c.BodyParser(dto); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
 	}
+	if err := validate.StructPartial(dto, "ReorderPoint", "ReorderQuantity"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
 
 	// Initialize the wait group and error channel for thread-safe operations
 	var wg sync.WaitGroup
@@ -160,9 +173,7 @@ func (h *productVariantController) UpdateProductVariant(c *fiber.Ctx) error {
 		defer wg.Done()
 		var err error
 		productVariant, err = h.productVariantRepository.GetProductVariantByID(uuidID)
-		if err != nil {
-			errChan <- err
-		}
+		errChan <- err
 	}()
 
 	// Wait for the fetch operation to complete
@@ -179,6 +190,12 @@ func (h *productVariantController) UpdateProductVariant(c *fiber.Ctx) error {
 	productVariant.SKUCode = dto.SKUCode
 	productVariant.Price = dto.Price
 	productVariant.ImageURL = dto.ImageURL
+	if dto.ReorderPoint != nil {
+		productVariant.ReorderPoint = *dto.ReorderPoint
+	}
+	if dto.ReorderQuantity != nil {
+		productVariant.ReorderQuantity = *dto.ReorderQuantity
+	}
 
 	// Reset the wait group and reuse the channel for the update operation
 	wg.Add(1)
@@ -200,13 +217,15 @@ func (h *productVariantController) UpdateProductVariant(c *fiber.Ctx) error {
 
 	// Prepare the response object
 	response := dtos.ProductVariantResponseDTO{
-		ID:        productVariant.VariantID,
-		ProductID: productVariant.ProductID,
-		SKUCode:   productVariant.SKUCode,
-		Price:     productVariant.Price,
-		ImageURL:  productVariant.ImageURL,
-		CreatedAt: productVariant.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt: productVariant.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ID:              productVariant.VariantID,
+		ProductID:       productVariant.ProductID,
+		SKUCode:         productVariant.SKUCode,
+		Price:           productVariant.Price,
+		ImageURL:        productVariant.ImageURL,
+		CreatedAt:       productVariant.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:       productVariant.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ReorderPoint:    productVariant.ReorderPoint,
+		ReorderQuantity: productVariant.ReorderQuantity,
 	}
 	return c.JSON(response)
 }
@@ -1,9 +1,15 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/payments"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type PurchaseController interface {
@@ -20,13 +26,14 @@ func NewPurchaseController(purchaseService services.PurchaseService) PurchaseCon
 
 // MakePurchase godoc
 // @Summary Make a new purchase
-// @Description Make a new purchase
+// @Description Place a pending order and start its payment with the provider named in payment_source (e.g. mock). Items are charged at their sale price in the round. The order is paid once the payment is captured. The order ships to the address book entry named by address_id, to delivery_address, or else to the customer's default address. The discount_codes are applied to the lines before VAT; several codes can only be combined if every one of them is stackable.
 // @Tags Purchases
 // @Accept json
 // @Produce json
 // @Param purchase body dtos.PurchaseCreateDTO true "Purchase"
 // @Success 201 {object} dtos.OrderResponseDTO
 // @Failure 400 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
 // @Failure 500 {object} fiber.Map
 // @Router /purchases [post]
 func (h *purchaseController) MakePurchase(c *fiber.Ctx) error {
@@ -37,11 +44,42 @@ func (h *purchaseController) MakePurchase(c *fiber.Ctx) error {
 
 	response, err := h.PurchaseService.MakePurchase(*dto)
 	if err != nil {
-		if err.Error() == "not enough stock" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "not enough stock"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return purchaseErrorResponse(c, dto.RoundID, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
+
+// purchaseErrorResponse maps a MakePurchase error to a response; a sold-out round points to its waitlist and
+// a lottery round to its entries
+func purchaseErrorResponse(c *fiber.Ctx, roundID uuid.UUID, err error) error {
+	if errors.Is(err, repositories.ErrRoundSoldOut) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":    "sold out",
+			"details":  err.Error(),
+			"waitlist": fmt.Sprintf("/sales-rounds/%s/waitlist", roundID),
+		})
+	}
+	if errors.Is(err, repositories.ErrLotteryRound) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   err.Error(),
+			"lottery": fmt.Sprintf("/sales-rounds/%s/lottery/entries", roundID),
+		})
+	}
+	if errors.Is(err, repositories.ErrUnknownAddress) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, repositories.ErrDiscountCodeUsedUp) || errors.Is(err, repositories.ErrDiscountCodeCustomerLimit) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if isDiscountError(err) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if errors.Is(err, payments.ErrUnknownProvider) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "details": "payment_source must name a payment provider"})
+	}
+	if err.Error() == "not enough stock" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "not enough stock"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PurchaseOrderController interface {
+	CreatePurchaseOrder(c *fiber.Ctx) error
+	GetAllPurchaseOrders(c *fiber.Ctx) error
+	GetPurchaseOrderByID(c *fiber.Ctx) error
+	SendPurchaseOrder(c *fiber.Ctx) error
+	ReceivePurchaseOrder(c *fiber.Ctx) error
+	ClosePurchaseOrder(c *fiber.Ctx) error
+}
+
+type purchaseOrderController struct {
+	purchaseOrderRepository repositories.PurchaseOrderRepository
+	supplierRepository      repositories.SupplierRepository
+	inventoryRepository     repositories.InventoryRepository
+}
+
+func NewPurchaseOrderController(purchaseOrderRepository repositories.PurchaseOrderRepository, supplierRepository repositories.SupplierRepository, inventoryRepository repositories.InventoryRepository) PurchaseOrderController {
+	return &purchaseOrderController{
+		purchaseOrderRepository: purchaseOrderRepository,
+		supplierRepository:      supplierRepository,
+		inventoryRepository:     inventoryRepository,
+	}
+}
+
+// CreatePurchaseOrder godoc
+// @Summary Draft a new purchase order
+// @Description Draft a new purchase order with one line per variant
+// @Tags Purchase Orders
+// @Accept json
+// @Produce json
+// @Param purchaseOrder body dtos.PurchaseOrderCreateDTO true "Purchase Order"
+// @Success 201 {object} dtos.PurchaseOrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /purchase-orders [post]
+func (h *purchaseOrderController) CreatePurchaseOrder(c *fiber.Ctx) error {
+	dto := new(dtos.PurchaseOrderCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	supplier, err := h.supplierRepository.GetSupplierByID(dto.SupplierID)
+	if err != nil || supplier.StoreID != dto.StoreID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "supplier not found for this store"})
+	}
+	if dto.LocationID != nil {
+		location, err := h.inventoryRepository.GetLocationByID(*dto.LocationID)
+		if err != nil || location.StoreID != dto.StoreID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "location not found for this store"})
+		}
+	}
+
+	lines := make([]models.PurchaseOrderLine, 0, len(dto.Lines))
+	for _, line := range dto.Lines {
+		lines = append(lines, models.PurchaseOrderLine{
+			VariantID:       line.VariantID,
+			QuantityOrdered: line.Quantity,
+			UnitCost:        line.UnitCost,
+		})
+	}
+
+	purchaseOrder := models.PurchaseOrder{
+		StoreID:    dto.StoreID,
+		SupplierID: dto.SupplierID,
+		LocationID: dto.LocationID,
+		Code:       fmt.Sprintf("PO-%s", uuid.New().String()),
+		Status:     models.PurchaseOrderDraft,
+		ExpectedAt: dto.ExpectedAt,
+		Note:       dto.Note,
+		Lines:      lines,
+	}
+	if err := h.purchaseOrderRepository.CreatePurchaseOrder(&purchaseOrder); err != nil {
+		if errors.Is(err, repositories.ErrVariantNotInStore) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not create purchase order", "details": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create purchase order"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toPurchaseOrderResponse(purchaseOrder))
+}
+
+// GetAllPurchaseOrders godoc
+// @Summary Get purchase orders
+// @Description Get purchase orders, optionally filtered by store and status
+// @Tags Purchase Orders
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param status query string false "draft, sent, partially_received or closed"
+// @Success 200 {array} dtos.PurchaseOrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /purchase-orders [get]
+func (h *purchaseOrderController) GetAllPurchaseOrders(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	purchaseOrders, err := h.purchaseOrderRepository.GetAllPurchaseOrders(storeID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve purchase orders"})
+	}
+
+	responses := make([]dtos.PurchaseOrderResponseDTO, 0, len(purchaseOrders))
+	for _, purchaseOrder := range purchaseOrders {
+		responses = append(responses, toPurchaseOrderResponse(purchaseOrder))
+	}
+	return c.JSON(responses)
+}
+
+// GetPurchaseOrderByID godoc
+// @Summary Get purchase order by ID
+// @Description Get purchase order by ID
+// @Tags Purchase Orders
+// @Produce json
+// @Param id path string true "Purchase Order ID"
+// @Success 200 {object} dtos.PurchaseOrderResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /purchase-orders/{id} [get]
+func (h *purchaseOrderController) GetPurchaseOrderByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	purchaseOrder, err := h.purchaseOrderRepository.GetPurchaseOrderByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "purchase order not found"})
+	}
+	return c.JSON(toPurchaseOrderResponse(*purchaseOrder))
+}
+
+// SendPurchaseOrder godoc
+// @Summary Mark a purchase order as sent
+// @Description Mark a draft purchase order as sent to the supplier
+// @Tags Purchase Orders
+// @Produce json
+// @Param id path string true "Purchase Order ID"
+// @Success 200 {object} dtos.PurchaseOrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /purchase-orders/{id}/send [put]
+func (h *purchaseOrderController) SendPurchaseOrder(c *fiber.Ctx) error {
+	return h.transition(c, []string{models.PurchaseOrderDraft}, models.PurchaseOrderSent)
+}
+
+// ClosePurchaseOrder godoc
+// @Summary Close a purchase order
+// @Description Close a sent or partially received purchase order; nothing more will be received against it
+// @Tags Purchase Orders
+// @Produce json
+// @Param id path string true "Purchase Order ID"
+// @Success 200 {object} dtos.PurchaseOrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /purchase-orders/{id}/close [put]
+func (h *purchaseOrderController) ClosePurchaseOrder(c *fiber.Ctx) error {
+	return h.transition(c, []string{models.PurchaseOrderSent, models.PurchaseOrderPartiallyReceived}, models.PurchaseOrderClosed)
+}
+
+// ReceivePurchaseOrder godoc
+// @Summary Receive goods against a purchase order
+// @Description Receive all or part of the ordered quantities; stock is increased through stock movement records
+// @Tags Purchase Orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Purchase Order ID"
+// @Param receipt body dtos.PurchaseOrderReceiveDTO true "Received lines"
+// @Success 200 {object} dtos.PurchaseOrderResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /purchase-orders/{id}/receive [post]
+func (h *purchaseOrderController) ReceivePurchaseOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.PurchaseOrderReceiveDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	purchaseOrder, err := h.purchaseOrderRepository.ReceivePurchaseOrder(id, dto.Lines)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "purchase order not found"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not receive purchase order", "details": err.Error()})
+	}
+	return c.JSON(toPurchaseOrderResponse(*purchaseOrder))
+}
+
+func (h *purchaseOrderController) transition(c *fiber.Ctx, from []string, to string) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	purchaseOrder, err := h.purchaseOrderRepository.UpdatePurchaseOrderStatus(id, from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "purchase order not found"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not update purchase order", "details": err.Error()})
+	}
+	return c.JSON(toPurchaseOrderResponse(*purchaseOrder))
+}
+
+func toPurchaseOrderResponse(purchaseOrder models.PurchaseOrder) dtos.PurchaseOrderResponseDTO {
+	lines := make([]dtos.PurchaseOrderLineResponseDTO, 0, len(purchaseOrder.Lines))
+	totalCost := 0.0
+	for _, line := range purchaseOrder.Lines {
+		lines = append(lines, dtos.PurchaseOrderLineResponseDTO{
+			ID:               line.ID,
+			VariantID:        line.VariantID,
+			QuantityOrdered:  line.QuantityOrdered,
+			QuantityReceived: line.QuantityReceived,
+			UnitCost:         line.UnitCost,
+		})
+		totalCost += line.UnitCost * float64(line.QuantityOrdered)
+	}
+
+	return dtos.PurchaseOrderResponseDTO{
+		ID:         purchaseOrder.ID,
+		StoreID:    purchaseOrder.StoreID,
+		SupplierID: purchaseOrder.SupplierID,
+		LocationID: purchaseOrder.LocationID,
+		Code:       purchaseOrder.Code,
+		Status:     purchaseOrder.Status,
+		ExpectedAt: purchaseOrder.ExpectedAt,
+		SentAt:     purchaseOrder.SentAt,
+		ClosedAt:   purchaseOrder.ClosedAt,
+		Note:       purchaseOrder.Note,
+		TotalCost:  totalCost,
+		Lines:      lines,
+		CreatedAt:  purchaseOrder.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:  purchaseOrder.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
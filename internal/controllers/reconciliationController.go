@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReconciliationController interface {
+	ImportStatement(c *fiber.Ctx) error
+	GetStatements(c *fiber.Ctx) error
+	GetStatement(c *fiber.Ctx) error
+	GetReviewQueue(c *fiber.Ctx) error
+	MatchLine(c *fiber.Ctx) error
+	IgnoreLine(c *fiber.Ctx) error
+}
+
+type reconciliationController struct {
+	reconciliationService services.ReconciliationService
+}
+
+func NewReconciliationController(reconciliationService services.ReconciliationService) ReconciliationController {
+	return &reconciliationController{reconciliationService: reconciliationService}
+}
+
+// ImportStatement godoc
+// @Summary Upload a bank statement
+// @Description Reconcile the credits of a bank statement CSV (form field "file" or a text/csv body) with pending orders. The header must name a date column (date, transaction_date or value_date) and an amount column, or credit and debit columns; time, reference and description are optional. A credit whose reference names one pending order of the same amount, or that is the only pending order of its amount placed shortly before the transfer, marks that order paid; the rest are queued for review.
+// @Tags Reconciliation
+// @Accept mpfd
+// @Accept plain
+// @Produce json
+// @Param store_id query string false "Only match orders of this store"
+// @Param file formData file false "Bank statement CSV"
+// @Success 201 {object} dtos.BankStatementResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Router /bank-statements [post]
+func (h *reconciliationController) ImportStatement(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	var body io.Reader = bytes.NewReader(c.Body())
+	fileName := ""
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not read uploaded file", "details": err.Error()})
+		}
+		defer f.Close()
+		body = f
+		fileName = file.Filename
+	}
+
+	statement, err := h.reconciliationService.ImportStatement(storeID, fileName, body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not import bank statement", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toBankStatementResponse(*statement))
+}
+
+// GetStatements godoc
+// @Summary Get bank statements
+// @Description Get uploaded bank statements with their reconciliation summary, newest first
+// @Tags Reconciliation
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} dtos.BankStatementResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /bank-statements [get]
+func (h *reconciliationController) GetStatements(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	statements, err := h.reconciliationService.GetStatements(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve bank statements"})
+	}
+
+	responses := make([]dtos.BankStatementResponseDTO, 0, len(statements))
+	for _, statement := range statements {
+		responses = append(responses, toBankStatementResponse(statement))
+	}
+	return c.JSON(responses)
+}
+
+// GetStatement godoc
+// @Summary Get bank statement by ID
+// @Description Get a bank statement with each of its lines and how it was reconciled
+// @Tags Reconciliation
+// @Produce json
+// @Param id path string true "Bank Statement ID"
+// @Success 200 {object} dtos.BankStatementResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /bank-statements/{id} [get]
+func (h *reconciliationController) GetStatement(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	statement, err := h.reconciliationService.GetStatement(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "bank statement not found"})
+	}
+	return c.JSON(toBankStatementResponse(*statement))
+}
+
+// GetReviewQueue godoc
+// @Summary Get statement lines awaiting review
+// @Description Get the bank statement lines that could pay several orders, or none, oldest transfer first
+// @Tags Reconciliation
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} dtos.StatementLineResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /bank-statement-lines/review [get]
+func (h *reconciliationController) GetReviewQueue(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	lines, err := h.reconciliationService.GetReviewQueue(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve the review queue"})
+	}
+
+	responses := make([]dtos.StatementLineResponseDTO, 0, len(lines))
+	for _, line := range lines {
+		responses = append(responses, toStatementLineResponse(line))
+	}
+	return c.JSON(responses)
+}
+
+// MatchLine godoc
+// @Summary Match a statement line to an order
+// @Description Record that a bank statement line paid a pending order and mark the order paid
+// @Tags Reconciliation
+// @Accept json
+// @Produce json
+// @Param id path string true "Statement Line ID"
+// @Param match body dtos.StatementLineMatchDTO true "Order paid by the line"
+// @Success 200 {object} dtos.StatementLineResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /bank-statement-lines/{id}/match [post]
+func (h *reconciliationController) MatchLine(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.StatementLineMatchDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	line, err := h.reconciliationService.MatchLine(id, dto.OrderID, dto.Note)
+	if err != nil {
+		return statementLineErrorResponse(c, err, "could not match statement line")
+	}
+	return c.JSON(toStatementLineResponse(*line))
+}
+
+// IgnoreLine godoc
+// @Summary Dismiss a statement line
+// @Description Record that a bank statement line did not pay an order, e.g. a transfer between the store's own accounts
+// @Tags Reconciliation
+// @Accept json
+// @Produce json
+// @Param id path string true "Statement Line ID"
+// @Param ignore body dtos.StatementLineIgnoreDTO true "Why the line is dismissed"
+// @Success 200 {object} dtos.StatementLineResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /bank-statement-lines/{id}/ignore [post]
+func (h *reconciliationController) IgnoreLine(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.StatementLineIgnoreDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	line, err := h.reconciliationService.IgnoreLine(id, dto.Note)
+	if err != nil {
+		return statementLineErrorResponse(c, err, "could not ignore statement line")
+	}
+	return c.JSON(toStatementLineResponse(*line))
+}
+
+func statementLineErrorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "statement line or order not found"})
+	case errors.Is(err, repositories.ErrStatementLineClosed), errors.Is(err, repositories.ErrOrderNotPending):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": message, "details": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message, "details": err.Error()})
+	}
+}
+
+func toBankStatementResponse(statement models.BankStatement) dtos.BankStatementResponseDTO {
+	response := dtos.BankStatementResponseDTO{
+		ID:             statement.ID,
+		StoreID:        statement.StoreID,
+		FileName:       statement.FileName,
+		TotalLines:     statement.TotalLines,
+		MatchedLines:   statement.MatchedLines,
+		ReviewLines:    statement.ReviewLines,
+		UnmatchedLines: statement.UnmatchedLines,
+		IgnoredLines:   statement.IgnoredLines,
+		CreatedAt:      statement.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	for _, line := range statement.Lines {
+		response.Lines = append(response.Lines, toStatementLineResponse(line))
+	}
+	return response
+}
+
+func toStatementLineResponse(line models.BankStatementLine) dtos.StatementLineResponseDTO {
+	candidates := []uuid.UUID{}
+	if line.Candidates != "" {
+		_ = json.Unmarshal([]byte(line.Candidates), &candidates)
+	}
+	return dtos.StatementLineResponseDTO{
+		ID:           line.ID,
+		StatementID:  line.StatementID,
+		LineNumber:   line.LineNumber,
+		TransactedAt: line.TransactedAt,
+		Amount:       line.Amount,
+		Reference:    line.Reference,
+		Description:  line.Description,
+		Status:       line.Status,
+		OrderID:      line.OrderID,
+		Candidates:   candidates,
+		Note:         line.Note,
+		ResolvedAt:   line.ResolvedAt,
+	}
+}
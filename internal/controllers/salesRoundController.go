@@ -1,15 +1,19 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"runtime"
 	"sync"
+	"time"
 
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type SalesRoundController interface {
@@ -19,6 +23,9 @@ type SalesRoundController interface {
 	UpdateSalesRound(c *fiber.Ctx) error
 	DeleteSalesRound(c *fiber.Ctx) error
 	GetCombinedSalesRoundProductData(c *fiber.Ctx) error // New method
+	AllocateSalesRound(c *fiber.Ctx) error
+	CloneSalesRound(c *fiber.Ctx) error
+	GetSalesRoundAnalytics(c *fiber.Ctx) error
 }
 
 type salesRoundController struct {
@@ -37,7 +44,7 @@ func NewSalesRoundController(salesRoundRepository repositories.SalesRoundReposit
 
 // CreateSalesRound godoc
 // @Summary Create a new sales round
-// @Description Create a new sales round
+// @Description Create a new sales round. Lottery rounds publish the SHA-256 commitment of their draw seed.
 // @Tags Sales Rounds
 // @Accept json
 // @Produce json
@@ -52,10 +59,23 @@ func (c *salesRoundController) CreateSalesRound(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
 	}
 
+	if !validSalesRoundMode(dto.Mode) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mode must be fcfs or lottery"})
+	}
+	if dto.AdmissionRate < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "admission_rate cannot be negative"})
+	}
+	if dto.PaymentWindowMinutes < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_window_minutes cannot be negative"})
+	}
+
 	salesRound := models.SalesRound{
-		Name:      dto.Name,
-		StartDate: dto.StartDate,
-		EndDate:   dto.EndDate,
+		Name:                 dto.Name,
+		StartDate:            dto.StartDate,
+		EndDate:              dto.EndDate,
+		Mode:                 dto.Mode,
+		PaymentWindowMinutes: dto.PaymentWindowMinutes,
+		AdmissionRate:        dto.AdmissionRate,
 	}
 
 	var wg sync.WaitGroup
@@ -74,15 +94,7 @@ func (c *salesRoundController) CreateSalesRound(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create sales round"})
 	}
 
-	response := dtos.SalesRoundResponseDTO{
-		ID:        salesRound.ID,
-		Name:      salesRound.Name,
-		StartDate: salesRound.StartDate,
-		EndDate:   salesRound.EndDate,
-		CreatedAt: salesRound.CreatedAt,
-		UpdatedAt: salesRound.UpdatedAt,
-	}
-	return ctx.Status(fiber.StatusCreated).JSON(response)
+	return ctx.Status(fiber.StatusCreated).JSON(toSalesRoundResponse(salesRound))
 }
 
 // GetAllSalesRounds godoc
@@ -102,14 +114,7 @@ func (c *salesRoundController) GetAllSalesRounds(ctx *fiber.Ctx) error {
 
 	var responses []dtos.SalesRoundResponseDTO
 	for _, round := range salesRounds {
-		responses = append(responses, dtos.SalesRoundResponseDTO{
-			ID:        round.ID,
-			Name:      round.Name,
-			StartDate: round.StartDate,
-			EndDate:   round.EndDate,
-			CreatedAt: round.CreatedAt,
-			UpdatedAt: round.UpdatedAt,
-		})
+		responses = append(responses, toSalesRoundResponse(round))
 	}
 	return ctx.JSON(responses)
 }
@@ -121,7 +126,7 @@ func (c *salesRoundController) GetAllSalesRounds(ctx *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Sales Round ID"
-// @Success 200 {object} models.SalesRoundDetail
+// @Success 200 {array} dtos.CombinedSalesRoundDetailResponse
 // @Failure 400 {object} fiber.Map
 // @Failure 500 {object} fiber.Map
 // @Router /sales-rounds/{id}/details [get]
@@ -188,9 +193,27 @@ func (c *salesRoundController) UpdateSalesRound(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
 	}
 
+	if !validSalesRoundMode(dto.Mode) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mode must be fcfs or lottery"})
+	}
+	if dto.AdmissionRate < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "admission_rate cannot be negative"})
+	}
+	if dto.PaymentWindowMinutes < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_window_minutes cannot be negative"})
+	}
+	if dto.Mode != "" && dto.Mode != salesRound.Mode {
+		if !time.Now().Before(salesRound.StartDate) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mode cannot change once the round has started"})
+		}
+		salesRound.Mode = dto.Mode
+	}
+
 	salesRound.Name = dto.Name
 	salesRound.StartDate = dto.StartDate
 	salesRound.EndDate = dto.EndDate
+	salesRound.PaymentWindowMinutes = dto.PaymentWindowMinutes
+	salesRound.AdmissionRate = dto.AdmissionRate
 
 	wg.Add(1)
 	go func() {
@@ -205,15 +228,7 @@ func (c *salesRoundController) UpdateSalesRound(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update sales round"})
 	}
 
-	response := dtos.SalesRoundResponseDTO{
-		ID:        salesRound.ID,
-		Name:      salesRound.Name,
-		StartDate: salesRound.StartDate,
-		EndDate:   salesRound.EndDate,
-		CreatedAt: salesRound.CreatedAt,
-		UpdatedAt: salesRound.UpdatedAt,
-	}
-	return ctx.JSON(response)
+	return ctx.JSON(toSalesRoundResponse(*salesRound))
 }
 
 // DeleteSalesRound godoc
@@ -258,6 +273,143 @@ func (h *salesRoundController) GetCombinedSalesRoundProductData(c *fiber.Ctx) er
 	return c.JSON(data)
 }
 
+// AllocateSalesRound godoc
+// @Summary Allocate many variants to a sales round
+// @Description Allocate explicit variant quantities and/or a percentage of the stock of every variant in a category, all or nothing. With dry_run the resulting allocation table is returned without saving anything.
+// @Tags Sales Rounds
+// @Accept json
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param allocation body dtos.SalesRoundAllocationRequestDTO true "Allocations"
+// @Success 200 {object} dtos.SalesRoundAllocationResultDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /sales-rounds/{id}/allocations [post]
+func (c *salesRoundController) AllocateSalesRound(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid round ID"})
+	}
+
+	dto := new(dtos.SalesRoundAllocationRequestDTO)
+	if err := ctx.BodyParser(dto); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if len(dto.Allocations) == 0 && len(dto.Categories) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nothing to allocate"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "allocations are not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	result, err := c.salesRoundDetailRepository.AllocateToSalesRound(id, *dto)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not allocate to sales round", "details": err.Error()})
+	}
+	return ctx.JSON(result)
+}
+
+// CloneSalesRound godoc
+// @Summary Clone a sales round
+// @Description Create a new round with new dates and the same variant line-up, optionally scaling every allocated quantity. Nothing is created unless every variant can be allocated.
+// @Tags Sales Rounds
+// @Accept json
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param clone body dtos.SalesRoundCloneDTO true "Clone"
+// @Success 201 {object} dtos.SalesRoundResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /sales-rounds/{id}/clone [post]
+func (c *salesRoundController) CloneSalesRound(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid round ID"})
+	}
+
+	dto := new(dtos.SalesRoundCloneDTO)
+	if err := ctx.BodyParser(dto); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	source, err := c.salesRoundRepository.GetSalesRoundByID(id)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
+	}
+	if dto.Name == "" {
+		dto.Name = source.Name
+	}
+	if dto.QuantityScale == 0 {
+		dto.QuantityScale = 1
+	}
+
+	salesRound := models.SalesRound{
+		Name:      dto.Name,
+		StartDate: dto.StartDate,
+		EndDate:   dto.EndDate,
+	}
+	if err := c.salesRoundRepository.CloneSalesRound(id, &salesRound, dto.QuantityScale); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not clone sales round", "details": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(toSalesRoundResponse(salesRound))
+}
+
+// GetSalesRoundAnalytics godoc
+// @Summary Get sales round analytics
+// @Description Report how a sales round sold, per variant and in total: units sold, sales at the regular price, the markdown given by the round's sale prices, what discount codes took off and the revenue. Only orders that were paid and not cancelled count.
+// @Tags Sales Rounds
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Success 200 {object} dtos.SalesRoundAnalyticsDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-rounds/{id}/analytics [get]
+func (c *salesRoundController) GetSalesRoundAnalytics(ctx *fiber.Ctx) error {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid round ID"})
+	}
+
+	analytics, err := c.salesRoundRepository.GetSalesRoundAnalytics(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sales round not found"})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve sales round analytics"})
+	}
+	return ctx.JSON(analytics)
+}
+
+func toSalesRoundResponse(round models.SalesRound) dtos.SalesRoundResponseDTO {
+	return dtos.SalesRoundResponseDTO{
+		ID:                   round.ID,
+		Name:                 round.Name,
+		StartDate:            round.StartDate,
+		EndDate:              round.EndDate,
+		TemplateID:           round.TemplateID,
+		Mode:                 round.Mode,
+		AdmissionRate:        round.AdmissionRate,
+		PaymentWindowMinutes: round.PaymentWindowMinutes,
+		DrawCommitment:       round.DrawCommitment,
+		DrawnAt:              round.DrawnAt,
+		CreatedAt:            round.CreatedAt,
+		UpdatedAt:            round.UpdatedAt,
+	}
+}
+
+// validSalesRoundMode accepts the sales round modes; empty leaves the default
+func validSalesRoundMode(mode string) bool {
+	return mode == "" || mode == models.SalesRoundModeFCFS || mode == models.SalesRoundModeLottery
+}
+
 func init() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 }
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
+
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type SalesRoundDetailController interface {
@@ -47,12 +50,18 @@ func (h *salesRoundDetailController) CreateSalesRoundDetail(c *fiber.Ctx) error
 
 	// Create the sales round detail
 	salesRoundDetail := &models.SalesRoundDetail{
-		RoundID:       dto.RoundID,
-		VariantID:     dto.VariantID,
-		Quantity:      dto.Quantity,
-		QuantityLimit: dto.QuantityLimit,
-		Remaining:     product.Stock, // Set the remaining stock
-		ProductStock:  product.Stock,
+		RoundID:         dto.RoundID,
+		VariantID:       dto.VariantID,
+		Quantity:        dto.Quantity,
+		QuantityLimit:   dto.QuantityLimit,
+		Remaining:       product.Stock, // Set the remaining stock
+		ProductStock:    product.Stock,
+		LocationID:      dto.LocationID,
+		RoundPrice:      dto.RoundPrice,
+		DiscountPercent: dto.DiscountPercent,
+	}
+	if err := salesRoundDetail.ValidateSalePrice(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	// Update the product stock
@@ -61,6 +70,14 @@ func (h *salesRoundDetailController) CreateSalesRoundDetail(c *fiber.Ctx) error
 	}
 
 	if err := h.salesRoundDetailRepository.CreateSalesRoundDetail(salesRoundDetail); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrInsufficientLocationStock),
+			errors.Is(err, repositories.ErrLocationNotInStore),
+			errors.Is(err, repositories.ErrAllocationLocationMismatch):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create sales round detail"})
 	}
 
@@ -98,6 +115,18 @@ func (h *salesRoundDetailController) UpdateSalesRoundDetail(c *fiber.Ctx) error
 	salesRoundDetail.QuantityLimit = dto.QuantityLimit
 	salesRoundDetail.Remaining = dto.Remaining
 	salesRoundDetail.ProductStock = dto.ProductStock
+	switch {
+	case dto.ClearSalePrice && (dto.RoundPrice != nil || dto.DiscountPercent != nil):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "clear_sale_price cannot be combined with round_price or discount_percent"})
+	case dto.ClearSalePrice:
+		salesRoundDetail.RoundPrice, salesRoundDetail.DiscountPercent = nil, nil
+	case dto.RoundPrice != nil || dto.DiscountPercent != nil:
+		// A round price replaces a discount percentage and the other way round
+		salesRoundDetail.RoundPrice, salesRoundDetail.DiscountPercent = dto.RoundPrice, dto.DiscountPercent
+	}
+	if err := salesRoundDetail.ValidateSalePrice(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	if err := h.salesRoundDetailRepository.UpdateSalesRoundDetail(salesRoundDetail); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update sales round detail"})
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SalesRoundTemplateController interface {
+	CreateTemplate(c *fiber.Ctx) error
+	GetAllTemplates(c *fiber.Ctx) error
+	GetTemplateByID(c *fiber.Ctx) error
+	UpdateTemplate(c *fiber.Ctx) error
+	DeleteTemplate(c *fiber.Ctx) error
+	MaterializeTemplates(c *fiber.Ctx) error
+}
+
+type salesRoundTemplateController struct {
+	templateRepository repositories.SalesRoundTemplateRepository
+	roundScheduler     services.RoundScheduler
+}
+
+func NewSalesRoundTemplateController(templateRepository repositories.SalesRoundTemplateRepository, roundScheduler services.RoundScheduler) SalesRoundTemplateController {
+	return &salesRoundTemplateController{
+		templateRepository: templateRepository,
+		roundScheduler:     roundScheduler,
+	}
+}
+
+// CreateTemplate godoc
+// @Summary Create a sales round template
+// @Description Create a recurring round, e.g. every Friday at 20:00 for 120 minutes, with a line-up given as lines or copied from source_round_id. Rounds are materialized lead_days ahead.
+// @Tags Sales Round Templates
+// @Accept json
+// @Produce json
+// @Param template body dtos.SalesRoundTemplateCreateDTO true "Template"
+// @Success 201 {object} dtos.SalesRoundTemplateResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-round-templates [post]
+func (h *salesRoundTemplateController) CreateTemplate(c *fiber.Ctx) error {
+	template := new(models.SalesRoundTemplate)
+	if err := h.bindTemplate(c, template); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "template is not valid", "details": err.Error()})
+	}
+
+	if err := h.templateRepository.CreateTemplate(template); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create template"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toSalesRoundTemplateResponse(*template))
+}
+
+// GetAllTemplates godoc
+// @Summary Get all sales round templates
+// @Description Get all sales round templates with their next occurrences
+// @Tags Sales Round Templates
+// @Produce json
+// @Success 200 {array} dtos.SalesRoundTemplateResponseDTO
+// @Failure 500 {object} fiber.Map
+// @Router /sales-round-templates [get]
+func (h *salesRoundTemplateController) GetAllTemplates(c *fiber.Ctx) error {
+	templates, err := h.templateRepository.GetAllTemplates()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve templates"})
+	}
+
+	responses := make([]dtos.SalesRoundTemplateResponseDTO, 0, len(templates))
+	for _, template := range templates {
+		responses = append(responses, toSalesRoundTemplateResponse(template))
+	}
+	return c.JSON(responses)
+}
+
+// GetTemplateByID godoc
+// @Summary Get sales round template by ID
+// @Description Get sales round template by ID
+// @Tags Sales Round Templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} dtos.SalesRoundTemplateResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /sales-round-templates/{id} [get]
+func (h *salesRoundTemplateController) GetTemplateByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	template, err := h.templateRepository.GetTemplateByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "template not found"})
+	}
+	return c.JSON(toSalesRoundTemplateResponse(*template))
+}
+
+// UpdateTemplate godoc
+// @Summary Update a sales round template
+// @Description Replace the schedule and line-up of a template; rounds already materialized are not changed
+// @Tags Sales Round Templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param template body dtos.SalesRoundTemplateCreateDTO true "Template"
+// @Success 200 {object} dtos.SalesRoundTemplateResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-round-templates/{id} [put]
+func (h *salesRoundTemplateController) UpdateTemplate(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	template, err := h.templateRepository.GetTemplateByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "template not found"})
+	}
+	if err := h.bindTemplate(c, template); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "template is not valid", "details": err.Error()})
+	}
+
+	if err := h.templateRepository.UpdateTemplate(template); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update template"})
+	}
+	return c.JSON(toSalesRoundTemplateResponse(*template))
+}
+
+// DeleteTemplate godoc
+// @Summary Delete a sales round template
+// @Description Delete a template; rounds already materialized are kept
+// @Tags Sales Round Templates
+// @Param id path string true "Template ID"
+// @Success 204
+// @Failure 500 {object} fiber.Map
+// @Router /sales-round-templates/{id} [delete]
+func (h *salesRoundTemplateController) DeleteTemplate(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.templateRepository.DeleteTemplate(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete template"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// MaterializeTemplates godoc
+// @Summary Materialize due sales rounds now
+// @Description Run the round scheduler now instead of waiting for its next run
+// @Tags Sales Round Templates
+// @Success 204
+// @Router /sales-round-templates/materialize [post]
+func (h *salesRoundTemplateController) MaterializeTemplates(c *fiber.Ctx) error {
+	h.roundScheduler.MaterializeDue()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// bindTemplate validates the request body and copies it onto template
+func (h *salesRoundTemplateController) bindTemplate(c *fiber.Ctx, template *models.SalesRoundTemplate) error {
+	dto := new(dtos.SalesRoundTemplateCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return fmt.Errorf("request body is not valid")
+	}
+	if err := validate.Struct(dto); err != nil {
+		return fmt.Errorf("%s", utils.ParseValidationErrors(err))
+	}
+
+	weekday, err := parseWeekday(dto.Weekday)
+	if err != nil {
+		return err
+	}
+	if _, err := time.Parse("15:04", dto.StartTime); err != nil {
+		return fmt.Errorf("start_time must be HH:MM")
+	}
+	if dto.Timezone == "" {
+		dto.Timezone = "Asia/Bangkok"
+	}
+	if _, err := time.LoadLocation(dto.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", dto.Timezone)
+	}
+	if dto.LeadDays == 0 {
+		dto.LeadDays = 7
+	}
+
+	lines := make([]models.SalesRoundTemplateLine, 0, len(dto.Lines))
+	if dto.SourceRoundID != nil {
+		if lines, err = h.templateRepository.GetTemplateLinesFromRound(*dto.SourceRoundID); err != nil {
+			return fmt.Errorf("could not copy the line-up of round %s", *dto.SourceRoundID)
+		}
+	}
+	for _, line := range dto.Lines {
+		lines = append(lines, models.SalesRoundTemplateLine{
+			VariantID:       line.VariantID,
+			Quantity:        line.Quantity,
+			QuantityLimit:   line.QuantityLimit,
+			LocationID:      line.LocationID,
+			RoundPrice:      line.RoundPrice,
+			DiscountPercent: line.DiscountPercent,
+		})
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("a template needs lines or a source_round_id with a line-up")
+	}
+	for _, line := range lines {
+		sale := models.SalesRoundDetail{RoundPrice: line.RoundPrice, DiscountPercent: line.DiscountPercent}
+		if err := sale.ValidateSalePrice(); err != nil {
+			return fmt.Errorf("variant %s: %w", line.VariantID, err)
+		}
+	}
+
+	template.Name = dto.Name
+	template.Weekday = weekday
+	template.StartTime = dto.StartTime
+	template.DurationMinutes = dto.DurationMinutes
+	template.Timezone = dto.Timezone
+	template.LeadDays = dto.LeadDays
+	template.Active = dto.Active == nil || *dto.Active
+	template.Lines = lines
+	return nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if v := strings.ToLower(value); v == name || v == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", value)
+}
+
+func toSalesRoundTemplateResponse(template models.SalesRoundTemplate) dtos.SalesRoundTemplateResponseDTO {
+	lines := make([]dtos.SalesRoundTemplateLineDTO, 0, len(template.Lines))
+	for _, line := range template.Lines {
+		lines = append(lines, dtos.SalesRoundTemplateLineDTO{
+			VariantID:       line.VariantID,
+			Quantity:        line.Quantity,
+			QuantityLimit:   line.QuantityLimit,
+			LocationID:      line.LocationID,
+			RoundPrice:      line.RoundPrice,
+			DiscountPercent: line.DiscountPercent,
+		})
+	}
+
+	from := time.Now()
+	if template.LastMaterializedAt != nil && template.LastMaterializedAt.After(from) {
+		from = *template.LastMaterializedAt
+	}
+	next, _ := template.NextOccurrences(from, from.AddDate(0, 0, 7*4))
+
+	return dtos.SalesRoundTemplateResponseDTO{
+		ID:                 template.ID,
+		Name:               template.Name,
+		Weekday:            strings.ToLower(template.Weekday.String()),
+		StartTime:          template.StartTime,
+		DurationMinutes:    template.DurationMinutes,
+		Timezone:           template.Timezone,
+		LeadDays:           template.LeadDays,
+		Active:             template.Active,
+		LastMaterializedAt: template.LastMaterializedAt,
+		NextOccurrences:    next,
+		Lines:              lines,
+		CreatedAt:          template.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:          template.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
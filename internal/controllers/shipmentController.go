@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/carriers"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ShipmentController interface {
+	CreateShipment(c *fiber.Ctx) error
+	GetOrderShipments(c *fiber.Ctx) error
+	GetShipment(c *fiber.Ctx) error
+	GetCarriers(c *fiber.Ctx) error
+	QuoteRates(c *fiber.Ctx) error
+	GetLabel(c *fiber.Ctx) error
+	GetTracking(c *fiber.Ctx) error
+	TrackShipment(c *fiber.Ctx) error
+}
+
+type shipmentController struct {
+	shipmentRepository repositories.ShipmentRepository
+	shippingService    services.ShippingService
+}
+
+func NewShipmentController(shipmentRepository repositories.ShipmentRepository, shippingService services.ShippingService) ShipmentController {
+	return &shipmentController{
+		shipmentRepository: shipmentRepository,
+		shippingService:    shippingService,
+	}
+}
+
+// CreateShipment godoc
+// @Summary Ship an order
+// @Description Record a shipment of some or all of the lines of a paid order. Without items, everything not shipped yet is shipped. The order moves to partially_shipped, or to shipped once every line is shipped in full. With create_label, the shipment of the parcels is booked with the carrier, which issues its tracking number and label; its tracking is then polled.
+// @Tags Shipments
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param shipment body dtos.ShipmentCreateDTO true "Shipment"
+// @Success 201 {object} dtos.ShipmentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Router /orders/{id}/shipments [post]
+func (h *shipmentController) CreateShipment(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.ShipmentCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	shipment := models.Shipment{
+		OrderID:        orderID,
+		Carrier:        dto.Carrier,
+		TrackingNumber: dto.TrackingNumber,
+		Note:           dto.Note,
+	}
+	if dto.ShippedAt != nil {
+		if dto.ShippedAt.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "shipped_at cannot be in the future"})
+		}
+		shipment.ShippedAt = *dto.ShippedAt
+	}
+	for _, item := range dto.Items {
+		shipment.Items = append(shipment.Items, models.ShipmentItem{OrderDetailID: item.OrderDetailID, Quantity: item.Quantity})
+	}
+
+	if dto.CreateLabel {
+		err = h.shippingService.ShipWithCarrier(&shipment, dto.Service, toParcels(dto.Parcels))
+	} else {
+		err = h.shipmentRepository.CreateShipment(&shipment)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "order not found"})
+		case errors.Is(err, repositories.ErrOrderNotShippable), errors.Is(err, repositories.ErrNothingToShip):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, carriers.ErrUnknownCarrier), errors.Is(err, carriers.ErrUnknownService):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not create shipment", "details": err.Error()})
+	}
+	shipment.Label = nil
+	return c.Status(fiber.StatusCreated).JSON(toShipmentResponse(shipment))
+}
+
+// GetOrderShipments godoc
+// @Summary Get the shipments of an order
+// @Description Get every shipment of an order with its items, in the order they were shipped
+// @Tags Shipments
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {array} dtos.ShipmentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /orders/{id}/shipments [get]
+func (h *shipmentController) GetOrderShipments(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	shipments, err := h.shipmentRepository.GetShipmentsByOrderID(orderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve shipments"})
+	}
+
+	responses := make([]dtos.ShipmentResponseDTO, 0, len(shipments))
+	for _, shipment := range shipments {
+		responses = append(responses, toShipmentResponse(shipment))
+	}
+	return c.JSON(responses)
+}
+
+// GetShipment godoc
+// @Summary Get shipment by ID
+// @Description Get a shipment with its items and tracking events
+// @Tags Shipments
+// @Produce json
+// @Param id path string true "Shipment ID"
+// @Success 200 {object} dtos.ShipmentResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /shipments/{id} [get]
+func (h *shipmentController) GetShipment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	shipment, err := h.shipmentRepository.GetShipmentByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shipment not found"})
+	}
+	return c.JSON(toShipmentResponse(*shipment))
+}
+
+// GetCarriers godoc
+// @Summary Get carriers
+// @Description Get the names of the carriers shipments can be booked with
+// @Tags Shipments
+// @Produce json
+// @Success 200 {array} string
+// @Router /carriers [get]
+func (h *shipmentController) GetCarriers(c *fiber.Ctx) error {
+	return c.JSON(h.shippingService.Carriers())
+}
+
+// QuoteRates godoc
+// @Summary Quote shipping rates for an order
+// @Description Get what each service of a carrier charges to ship parcels from the order's store to its delivery address
+// @Tags Shipments
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body dtos.ShippingRateRequestDTO true "Carrier and parcels"
+// @Success 200 {array} dtos.ShippingRateResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 502 {object} fiber.Map
+// @Router /orders/{id}/shipping-rates [post]
+func (h *shipmentController) QuoteRates(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.ShippingRateRequestDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	rates, err := h.shippingService.QuoteRates(orderID, dto.Carrier, toParcels(dto.Parcels))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "order not found"})
+		case errors.Is(err, carriers.ErrUnknownCarrier), errors.Is(err, repositories.ErrOrderSpansStores):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "could not quote shipping rates", "details": err.Error()})
+	}
+
+	responses := make([]dtos.ShippingRateResponseDTO, 0, len(rates))
+	for _, rate := range rates {
+		responses = append(responses, dtos.ShippingRateResponseDTO{
+			Carrier:       rate.Carrier,
+			Service:       rate.Service,
+			Description:   rate.Description,
+			Amount:        rate.Amount,
+			Currency:      rate.Currency,
+			EstimatedDays: rate.EstimatedDays,
+		})
+	}
+	return c.JSON(responses)
+}
+
+// GetLabel godoc
+// @Summary Get the label of a shipment
+// @Description Download the label the carrier issued for a shipment, as PNG, PDF or ZPL
+// @Tags Shipments
+// @Produce png
+// @Produce application/pdf
+// @Produce plain
+// @Param id path string true "Shipment ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} fiber.Map
+// @Router /shipments/{id}/label [get]
+func (h *shipmentController) GetLabel(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	shipment, err := h.shipmentRepository.GetShipmentLabel(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shipment not found"})
+	}
+	if len(shipment.Label) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shipment has no label"})
+	}
+
+	switch shipment.LabelFormat {
+	case carriers.LabelPNG:
+		c.Set(fiber.HeaderContentType, "image/png")
+	case carriers.LabelPDF:
+		c.Set(fiber.HeaderContentType, "application/pdf")
+	default:
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s.%s"`, shipment.TrackingNumber, shipment.LabelFormat))
+	return c.Send(shipment.Label)
+}
+
+// GetTracking godoc
+// @Summary Get the tracking of a shipment
+// @Description Get the tracking events the carrier reported for a shipment, oldest first
+// @Tags Shipments
+// @Produce json
+// @Param id path string true "Shipment ID"
+// @Success 200 {array} dtos.ShipmentTrackingEventResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /shipments/{id}/tracking [get]
+func (h *shipmentController) GetTracking(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	shipment, err := h.shipmentRepository.GetShipmentByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shipment not found"})
+	}
+	return c.JSON(toTrackingEventResponses(shipment.TrackingEvents))
+}
+
+// TrackShipment godoc
+// @Summary Poll the tracking of a shipment
+// @Description Fetch the latest tracking events from the carrier now instead of waiting for the next poll, and update the shipment and its order
+// @Tags Shipments
+// @Produce json
+// @Param id path string true "Shipment ID"
+// @Success 200 {object} dtos.ShipmentResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 502 {object} fiber.Map
+// @Router /shipments/{id}/track [post]
+func (h *shipmentController) TrackShipment(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if _, err := h.shippingService.TrackShipment(id); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "shipment not found"})
+		case errors.Is(err, carriers.ErrUnknownCarrier), errors.Is(err, carriers.ErrUnknownTracking):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "could not track shipment", "details": err.Error()})
+	}
+
+	shipment, err := h.shipmentRepository.GetShipmentByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve shipment"})
+	}
+	return c.JSON(toShipmentResponse(*shipment))
+}
+
+func toParcels(parcels []dtos.ParcelDTO) []carriers.Parcel {
+	result := make([]carriers.Parcel, 0, len(parcels))
+	for _, parcel := range parcels {
+		result = append(result, carriers.Parcel{
+			WeightGrams: parcel.WeightGrams,
+			LengthCm:    parcel.LengthCm,
+			WidthCm:     parcel.WidthCm,
+			HeightCm:    parcel.HeightCm,
+		})
+	}
+	return result
+}
+
+func toTrackingEventResponses(events []models.ShipmentTrackingEvent) []dtos.ShipmentTrackingEventResponseDTO {
+	responses := make([]dtos.ShipmentTrackingEventResponseDTO, 0, len(events))
+	for _, event := range events {
+		responses = append(responses, dtos.ShipmentTrackingEventResponseDTO{
+			Status:      event.Status,
+			Description: event.Description,
+			Location:    event.Location,
+			OccurredAt:  event.OccurredAt,
+		})
+	}
+	return responses
+}
+
+func toShipmentResponse(shipment models.Shipment) dtos.ShipmentResponseDTO {
+	items := make([]dtos.ShipmentItemResponseDTO, 0, len(shipment.Items))
+	for _, item := range shipment.Items {
+		items = append(items, dtos.ShipmentItemResponseDTO{
+			OrderDetailID: item.OrderDetailID,
+			VariantID:     item.VariantID,
+			Quantity:      item.Quantity,
+		})
+	}
+	return dtos.ShipmentResponseDTO{
+		ID:             shipment.ID,
+		OrderID:        shipment.OrderID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		Service:        shipment.Service,
+		ShippingCost:   shipment.ShippingCost,
+		Status:         shipment.Status,
+		Note:           shipment.Note,
+		LabelFormat:    shipment.LabelFormat,
+		ShippedAt:      shipment.ShippedAt,
+		DeliveredAt:    shipment.DeliveredAt,
+		LastTrackedAt:  shipment.LastTrackedAt,
+		Items:          items,
+		TrackingEvents: toTrackingEventResponses(shipment.TrackingEvents),
+		CreatedAt:      shipment.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:      shipment.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
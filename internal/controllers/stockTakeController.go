@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StockTakeController interface {
+	CreateStockTake(c *fiber.Ctx) error
+	GetAllStockTakes(c *fiber.Ctx) error
+	GetStockTakeByID(c *fiber.Ctx) error
+	SubmitCounts(c *fiber.Ctx) error
+	SubmitCountsCSV(c *fiber.Ctx) error
+	GetVarianceReport(c *fiber.Ctx) error
+	ApproveStockTake(c *fiber.Ctx) error
+	CancelStockTake(c *fiber.Ctx) error
+}
+
+type stockTakeController struct {
+	stockTakeRepository repositories.StockTakeRepository
+	inventoryRepository repositories.InventoryRepository
+}
+
+func NewStockTakeController(stockTakeRepository repositories.StockTakeRepository, inventoryRepository repositories.InventoryRepository) StockTakeController {
+	return &stockTakeController{
+		stockTakeRepository: stockTakeRepository,
+		inventoryRepository: inventoryRepository,
+	}
+}
+
+// CreateStockTake godoc
+// @Summary Open a stock-take session
+// @Description Open a physical count of a store, or of one of its locations when location_id is given
+// @Tags Stock Takes
+// @Accept json
+// @Produce json
+// @Param stockTake body dtos.StockTakeSessionCreateDTO true "Stock Take"
+// @Success 201 {object} dtos.StockTakeSessionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stock-takes [post]
+func (h *stockTakeController) CreateStockTake(c *fiber.Ctx) error {
+	dto := new(dtos.StockTakeSessionCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+	if dto.LocationID != nil {
+		location, err := h.inventoryRepository.GetLocationByID(*dto.LocationID)
+		if err != nil || location.StoreID != dto.StoreID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "location not found for this store"})
+		}
+	}
+
+	session := models.StockTakeSession{
+		StoreID:    dto.StoreID,
+		LocationID: dto.LocationID,
+		Status:     models.StockTakeOpen,
+		Note:       dto.Note,
+	}
+	if err := h.stockTakeRepository.CreateSession(&session); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create stock take"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toStockTakeResponse(session))
+}
+
+// GetAllStockTakes godoc
+// @Summary Get stock-take sessions
+// @Description Get stock-take sessions, optionally filtered by store and status
+// @Tags Stock Takes
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Param status query string false "open, approved or cancelled"
+// @Success 200 {array} dtos.StockTakeSessionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stock-takes [get]
+func (h *stockTakeController) GetAllStockTakes(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	sessions, err := h.stockTakeRepository.GetAllSessions(storeID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve stock takes"})
+	}
+
+	responses := make([]dtos.StockTakeSessionResponseDTO, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, toStockTakeResponse(session))
+	}
+	return c.JSON(responses)
+}
+
+// GetStockTakeByID godoc
+// @Summary Get stock-take session by ID
+// @Description Get a stock-take session with its counts
+// @Tags Stock Takes
+// @Produce json
+// @Param id path string true "Stock Take ID"
+// @Success 200 {object} dtos.StockTakeSessionResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /stock-takes/{id} [get]
+func (h *stockTakeController) GetStockTakeByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	session, err := h.stockTakeRepository.GetSessionByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "stock take not found"})
+	}
+	return c.JSON(toStockTakeResponse(*session))
+}
+
+// SubmitCounts godoc
+// @Summary Submit counted quantities
+// @Description Submit counted quantities per variant (by variant_id or sku_code); a variant counted again replaces its earlier count
+// @Tags Stock Takes
+// @Accept json
+// @Produce json
+// @Param id path string true "Stock Take ID"
+// @Param counts body dtos.StockTakeCountsDTO true "Counts"
+// @Success 200 {object} dtos.StockTakeSessionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /stock-takes/{id}/counts [post]
+func (h *stockTakeController) SubmitCounts(c *fiber.Ctx) error {
+	dto := new(dtos.StockTakeCountsDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	return h.saveCounts(c, dto.Counts)
+}
+
+// SubmitCountsCSV godoc
+// @Summary Submit counted quantities as CSV
+// @Description Submit counts as a CSV upload (form field "file") or a text/csv body with the header sku_code,counted_quantity[,reason_code]
+// @Tags Stock Takes
+// @Accept mpfd
+// @Accept plain
+// @Produce json
+// @Param id path string true "Stock Take ID"
+// @Param file formData file false "CSV file"
+// @Success 200 {object} dtos.StockTakeSessionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /stock-takes/{id}/counts/csv [post]
+func (h *stockTakeController) SubmitCountsCSV(c *fiber.Ctx) error {
+	var body io.Reader = bytes.NewReader(c.Body())
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not read uploaded file", "details": err.Error()})
+		}
+		defer f.Close()
+		body = f
+	}
+
+	counts, err := parseStockTakeCSV(body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "CSV is not valid", "details": err.Error()})
+	}
+	return h.saveCounts(c, counts)
+}
+
+// GetVarianceReport godoc
+// @Summary Get the variance report of a stock take
+// @Description Compare counted quantities with system stock; open sessions use current stock, approved sessions the stock at approval
+// @Tags Stock Takes
+// @Produce json
+// @Param id path string true "Stock Take ID"
+// @Success 200 {object} dtos.StockTakeVarianceReportDTO
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stock-takes/{id}/variance [get]
+func (h *stockTakeController) GetVarianceReport(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	report, err := h.stockTakeRepository.GetVarianceReport(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "stock take not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not build variance report", "details": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// ApproveStockTake godoc
+// @Summary Approve a stock take
+// @Description Post every variance as a stock adjustment with its reason code and close the session; a store-wide session must count every variant of a product it counts
+// @Tags Stock Takes
+// @Accept json
+// @Produce json
+// @Param id path string true "Stock Take ID"
+// @Param approval body dtos.StockTakeApproveDTO false "Approval"
+// @Success 200 {object} dtos.StockTakeSessionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /stock-takes/{id}/approve [put]
+func (h *stockTakeController) ApproveStockTake(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.StockTakeApproveDTO)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(dto); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+		}
+	}
+
+	session, err := h.stockTakeRepository.ApproveSession(id, dto.DefaultReasonCode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "stock take not found"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not approve stock take", "details": err.Error()})
+	}
+	return c.JSON(toStockTakeResponse(*session))
+}
+
+// CancelStockTake godoc
+// @Summary Cancel a stock take
+// @Description Cancel an open stock take without changing any stock
+// @Tags Stock Takes
+// @Produce json
+// @Param id path string true "Stock Take ID"
+// @Success 200 {object} dtos.StockTakeSessionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /stock-takes/{id}/cancel [put]
+func (h *stockTakeController) CancelStockTake(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	session, err := h.stockTakeRepository.CancelSession(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "stock take not found"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not cancel stock take", "details": err.Error()})
+	}
+	return c.JSON(toStockTakeResponse(*session))
+}
+
+func (h *stockTakeController) saveCounts(c *fiber.Ctx, counts []dtos.StockTakeCountDTO) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	if len(counts) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no counts submitted"})
+	}
+	if err := validate.Struct(dtos.StockTakeCountsDTO{Counts: counts}); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	session, err := h.stockTakeRepository.SaveCounts(id, counts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "stock take not found"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not save counts", "details": err.Error()})
+	}
+	return c.JSON(toStockTakeResponse(*session))
+}
+
+// parseStockTakeCSV reads counts from CSV with a header row naming the sku_code (or variant_id),
+// counted_quantity and optional reason_code columns
+func parseStockTakeCSV(r io.Reader) ([]dtos.StockTakeCountDTO, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	quantityColumn, ok := columns["counted_quantity"]
+	if !ok {
+		return nil, fmt.Errorf("missing counted_quantity column")
+	}
+	skuColumn, hasSKU := columns["sku_code"]
+	variantColumn, hasVariant := columns["variant_id"]
+	if !hasSKU && !hasVariant {
+		return nil, fmt.Errorf("missing sku_code or variant_id column")
+	}
+	reasonColumn, hasReason := columns["reason_code"]
+
+	field := func(record []string, column int) string {
+		if column < len(record) {
+			return strings.TrimSpace(record[column])
+		}
+		return ""
+	}
+
+	var counts []dtos.StockTakeCountDTO
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		quantity, err := strconv.Atoi(field(record, quantityColumn))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: counted_quantity is not a number", line)
+		}
+		count := dtos.StockTakeCountDTO{CountedQuantity: quantity}
+		if hasSKU {
+			count.SKUCode = field(record, skuColumn)
+		}
+		if hasVariant && field(record, variantColumn) != "" {
+			if count.VariantID, err = uuid.Parse(field(record, variantColumn)); err != nil {
+				return nil, fmt.Errorf("line %d: variant_id is not a valid UUID", line)
+			}
+		}
+		if hasReason {
+			count.ReasonCode = field(record, reasonColumn)
+		}
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+func toStockTakeResponse(session models.StockTakeSession) dtos.StockTakeSessionResponseDTO {
+	counts := make([]dtos.StockTakeCountResponseDTO, 0, len(session.Counts))
+	for _, count := range session.Counts {
+		counts = append(counts, dtos.StockTakeCountResponseDTO{
+			ID:              count.ID,
+			VariantID:       count.VariantID,
+			CountedQuantity: count.CountedQuantity,
+			SystemQuantity:  count.SystemQuantity,
+			ReasonCode:      count.ReasonCode,
+		})
+	}
+
+	return dtos.StockTakeSessionResponseDTO{
+		ID:         session.ID,
+		StoreID:    session.StoreID,
+		LocationID: session.LocationID,
+		Status:     session.Status,
+		Note:       session.Note,
+		ApprovedAt: session.ApprovedAt,
+		Counts:     counts,
+		CreatedAt:  session.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:  session.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/payments"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -44,7 +45,12 @@ func (h *storeController) CreateStore(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
 	}
 
-	store := models.Store{StoreName: dto.StoreName, Location: dto.Location}
+	promptPayID, err := optionalPromptPayID(dto.PromptPayID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	store := models.Store{StoreName: dto.StoreName, Location: dto.Location, PromptPayID: promptPayID}
 
 	var wg sync.WaitGroup
 	errChan := make(chan error, 1)
@@ -70,10 +76,11 @@ func (h *storeController) CreateStore(c *fiber.Ctx) error {
 	}
 
 	response := dtos.StoreResponseDTO{
-		ID:        store.ID,
-		StoreName: store.StoreName,
-		Location:  store.Location,
-		CreatedAt: store.CreatedAt.Format("2006-01-02 15:04:05"),
+		ID:          store.ID,
+		StoreName:   store.StoreName,
+		Location:    store.Location,
+		PromptPayID: store.PromptPayID,
+		CreatedAt:   store.CreatedAt.Format("2006-01-02 15:04:05"),
 	}
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
@@ -211,8 +218,13 @@ func (h *storeController) UpdateStore(c *fiber.Ctx) error {
 	}
 
 	// Proceed to update the store details if found
+	promptPayID, err := optionalPromptPayID(dto.PromptPayID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 	store.StoreName = dto.StoreName
 	store.Location = dto.Location
+	store.PromptPayID = promptPayID
 
 	wg.Add(1)
 	go func() {
@@ -235,10 +247,11 @@ func (h *storeController) UpdateStore(c *fiber.Ctx) error {
 	}
 
 	response := dtos.StoreResponseDTO{
-		ID:        store.ID,
-		StoreName: store.StoreName,
-		Location:  store.Location,
-		CreatedAt: store.CreatedAt.Format("2006-01-02 15:04:05"),
+		ID:          store.ID,
+		StoreName:   store.StoreName,
+		Location:    store.Location,
+		PromptPayID: store.PromptPayID,
+		CreatedAt:   store.CreatedAt.Format("2006-01-02 15:04:05"),
 	}
 	return c.JSON(response)
 }
@@ -289,3 +302,11 @@ func init() {
 	// Use all available cores
 	runtime.GOMAXPROCS(runtime.NumCPU())
 }
+
+// optionalPromptPayID normalizes a store's PromptPay ID; an empty one is allowed
+func optionalPromptPayID(id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	return payments.NormalizePromptPayID(id)
+}
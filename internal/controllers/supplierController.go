@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SupplierController interface {
+	CreateSupplier(c *fiber.Ctx) error
+	GetAllSuppliers(c *fiber.Ctx) error
+	GetSupplierByID(c *fiber.Ctx) error
+	UpdateSupplier(c *fiber.Ctx) error
+	DeleteSupplier(c *fiber.Ctx) error
+}
+
+type supplierController struct {
+	supplierRepository repositories.SupplierRepository
+}
+
+func NewSupplierController(supplierRepository repositories.SupplierRepository) SupplierController {
+	return &supplierController{supplierRepository: supplierRepository}
+}
+
+// CreateSupplier godoc
+// @Summary Create a new supplier
+// @Description Create a new supplier
+// @Tags Suppliers
+// @Accept json
+// @Produce json
+// @Param supplier body dtos.SupplierCreateDTO true "Supplier"
+// @Success 201 {object} dtos.SupplierResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /suppliers [post]
+func (h *supplierController) CreateSupplier(c *fiber.Ctx) error {
+	dto := new(dtos.SupplierCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	supplier := models.Supplier{
+		StoreID:     dto.StoreID,
+		Name:        dto.Name,
+		ContactName: dto.ContactName,
+		Email:       dto.Email,
+		Phone:       dto.Phone,
+	}
+	if err := h.supplierRepository.CreateSupplier(&supplier); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create supplier"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toSupplierResponse(supplier))
+}
+
+// GetAllSuppliers godoc
+// @Summary Get all suppliers
+// @Description Get all suppliers, optionally for a single store
+// @Tags Suppliers
+// @Produce json
+// @Param store_id query string false "Store ID"
+// @Success 200 {array} dtos.SupplierResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /suppliers [get]
+func (h *supplierController) GetAllSuppliers(c *fiber.Ctx) error {
+	storeID, err := optionalUUIDQuery(c, "store_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid store_id"})
+	}
+
+	suppliers, err := h.supplierRepository.GetAllSuppliers(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve suppliers"})
+	}
+
+	responses := make([]dtos.SupplierResponseDTO, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		responses = append(responses, toSupplierResponse(supplier))
+	}
+	return c.JSON(responses)
+}
+
+// GetSupplierByID godoc
+// @Summary Get supplier by ID
+// @Description Get supplier by ID
+// @Tags Suppliers
+// @Produce json
+// @Param id path string true "Supplier ID"
+// @Success 200 {object} dtos.SupplierResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /suppliers/{id} [get]
+func (h *supplierController) GetSupplierByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	supplier, err := h.supplierRepository.GetSupplierByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "supplier not found"})
+	}
+	return c.JSON(toSupplierResponse(*supplier))
+}
+
+// UpdateSupplier godoc
+// @Summary Update a supplier
+// @Description Update a supplier
+// @Tags Suppliers
+// @Accept json
+// @Produce json
+// @Param id path string true "Supplier ID"
+// @Param supplier body dtos.SupplierUpdateDTO true "Supplier"
+// @Success 200 {object} dtos.SupplierResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /suppliers/{id} [put]
+func (h *supplierController) UpdateSupplier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.SupplierUpdateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+
+	supplier, err := h.supplierRepository.GetSupplierByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "supplier not found"})
+	}
+
+	if dto.Name != "" {
+		supplier.Name = dto.Name
+	}
+	supplier.ContactName = dto.ContactName
+	supplier.Email = dto.Email
+	supplier.Phone = dto.Phone
+
+	if err := h.supplierRepository.UpdateSupplier(supplier); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update supplier"})
+	}
+	return c.JSON(toSupplierResponse(*supplier))
+}
+
+// DeleteSupplier godoc
+// @Summary Delete a supplier
+// @Description Delete a supplier
+// @Tags Suppliers
+// @Param id path string true "Supplier ID"
+// @Success 204
+// @Failure 500 {object} fiber.Map
+// @Router /suppliers/{id} [delete]
+func (h *supplierController) DeleteSupplier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.supplierRepository.DeleteSupplier(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete supplier"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func toSupplierResponse(supplier models.Supplier) dtos.SupplierResponseDTO {
+	return dtos.SupplierResponseDTO{
+		ID:          supplier.ID,
+		StoreID:     supplier.StoreID,
+		Name:        supplier.Name,
+		ContactName: supplier.ContactName,
+		Email:       supplier.Email,
+		Phone:       supplier.Phone,
+		CreatedAt:   supplier.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   supplier.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// optionalUUIDQuery parses an optional UUID query parameter; it returns nil when the parameter is absent
+func optionalUUIDQuery(c *fiber.Ctx, key string) (*uuid.UUID, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TaxController interface {
+	GetTaxSettings(c *fiber.Ctx) error
+	UpdateTaxSettings(c *fiber.Ctx) error
+	GetVATSummary(c *fiber.Ctx) error
+}
+
+type taxController struct {
+	taxRepository repositories.TaxRepository
+}
+
+func NewTaxController(taxRepository repositories.TaxRepository) TaxController {
+	return &taxController{taxRepository: taxRepository}
+}
+
+// GetTaxSettings godoc
+// @Summary Get a store's tax settings
+// @Description Get a store's VAT registration, whether its prices include VAT and the rate of each tax category
+// @Tags Tax
+// @Produce json
+// @Param id path string true "Store ID"
+// @Success 200 {object} dtos.StoreTaxSettingsResponseDTO
+// @Failure 404 {object} fiber.Map
+// @Router /stores/{id}/tax-settings [get]
+func (h *taxController) GetTaxSettings(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	store, err := h.taxRepository.GetStoreTaxSettings(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "store not found"})
+	}
+	return c.JSON(toStoreTaxSettingsResponse(*store))
+}
+
+// UpdateTaxSettings godoc
+// @Summary Set a store's tax settings
+// @Description Set the VAT registration printed on a store's tax invoices, whether its prices include VAT, and the VAT rate of tax categories where it differs from the default (standard 7%, zero_rated and exempt 0%). Orders already placed keep the tax they were placed with.
+// @Tags Tax
+// @Accept json
+// @Produce json
+// @Param id path string true "Store ID"
+// @Param settings body dtos.StoreTaxSettingsDTO true "Tax settings"
+// @Success 200 {object} dtos.StoreTaxSettingsResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /stores/{id}/tax-settings [put]
+func (h *taxController) UpdateTaxSettings(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	dto := new(dtos.StoreTaxSettingsDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "validation failed", "details": utils.ParseValidationErrors(err)})
+	}
+	if dto.TaxID != "" && !tax.ValidTaxID(dto.TaxID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tax_id is not a valid tax ID"})
+	}
+	if dto.TaxID != "" && dto.BranchCode == "" {
+		dto.BranchCode = "00000"
+	}
+	for category, rate := range dto.Rates {
+		if !tax.ValidCategory(category) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": tax.ErrUnknownCategory.Error(), "details": category})
+		}
+		if rate < 0 || rate > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rate must be between 0 and 100", "details": category})
+		}
+		if category == tax.CategoryExempt && rate != 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "exempt sales cannot be taxed"})
+		}
+	}
+
+	store, err := h.taxRepository.UpdateStoreTaxSettings(&models.Store{
+		ID:               storeID,
+		LegalName:        dto.LegalName,
+		TaxID:            dto.TaxID,
+		BranchCode:       dto.BranchCode,
+		PricesExcludeTax: dto.PricesExcludeTax,
+	}, dto.Rates)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "store not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update tax settings", "details": err.Error()})
+	}
+	return c.JSON(toStoreTaxSettingsResponse(*store))
+}
+
+// GetVATSummary godoc
+// @Summary Get a store's monthly VAT summary
+// @Description Sum the net sales, VAT and gross sales of a store's paid orders placed in a month, by tax category and rate, less the credit notes issued in the month, for the VAT return
+// @Tags Tax
+// @Produce json
+// @Param id path string true "Store ID"
+// @Param month query string false "Month as YYYY-MM; defaults to the current month"
+// @Success 200 {object} dtos.VATSummaryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stores/{id}/vat-summary [get]
+func (h *taxController) GetVATSummary(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	from := time.Now()
+	if month := c.Query("month"); month != "" {
+		from, err = time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "month must be formatted as YYYY-MM"})
+		}
+	}
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+
+	summary, err := h.taxRepository.GetVATSummary(storeID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not compute VAT summary", "details": err.Error()})
+	}
+
+	response := dtos.VATSummaryResponseDTO{
+		StoreID:     storeID,
+		Month:       fmt.Sprintf("%04d-%02d", from.Year(), from.Month()),
+		From:        from,
+		To:          to,
+		Orders:      summary.Orders,
+		CreditNotes: summary.CreditNotes,
+	}
+	response.Lines = addVATSummaryRows(&response, summary.Rows, 1)
+	response.Credits = addVATSummaryRows(&response, summary.Credits, -1)
+	response.TaxableSales = tax.Round(response.TaxableSales)
+	response.ZeroRatedSales = tax.Round(response.ZeroRatedSales)
+	response.ExemptSales = tax.Round(response.ExemptSales)
+	response.OutputTax = tax.Round(response.OutputTax)
+	response.GrossAmount = tax.Round(response.GrossAmount)
+	return c.JSON(response)
+}
+
+// addVATSummaryRows adds rows to the totals of a VAT summary, or with sign -1 takes credited rows off them, and
+// returns the rows as lines
+func addVATSummaryRows(response *dtos.VATSummaryResponseDTO, rows []repositories.VATSummaryRow, sign float64) []dtos.VATSummaryLineDTO {
+	lines := make([]dtos.VATSummaryLineDTO, 0, len(rows))
+	for _, row := range rows {
+		switch {
+		case row.Category == tax.CategoryExempt:
+			response.ExemptSales += sign * row.NetAmount
+		case row.Rate > 0:
+			response.TaxableSales += sign * row.NetAmount
+		default:
+			response.ZeroRatedSales += sign * row.NetAmount
+		}
+		response.OutputTax += sign * row.TaxAmount
+		response.GrossAmount += sign * row.GrossAmount
+		lines = append(lines, dtos.VATSummaryLineDTO{
+			Category:    row.Category,
+			Rate:        row.Rate,
+			Lines:       row.Lines,
+			NetAmount:   tax.Round(row.NetAmount),
+			TaxAmount:   tax.Round(row.TaxAmount),
+			GrossAmount: tax.Round(row.GrossAmount),
+		})
+	}
+	return lines
+}
+
+func toStoreTaxSettingsResponse(store models.Store) dtos.StoreTaxSettingsResponseDTO {
+	response := dtos.StoreTaxSettingsResponseDTO{
+		StoreID:          store.ID,
+		LegalName:        store.LegalName,
+		TaxID:            store.TaxID,
+		BranchCode:       store.BranchCode,
+		PricesExcludeTax: store.PricesExcludeTax,
+		Rates:            make(map[string]float64),
+		Overrides:        make(map[string]float64),
+	}
+	for _, rate := range store.TaxRates {
+		response.Overrides[rate.Category] = rate.Rate
+	}
+	config := tax.Config{Rates: response.Overrides}
+	for _, category := range tax.Categories() {
+		response.Rates[category] = config.Rate(category)
+	}
+	return response
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+)
+
+func TestVATSummaryIsNetOfCreditNotes(t *testing.T) {
+	var response dtos.VATSummaryResponseDTO
+	response.Lines = addVATSummaryRows(&response, []repositories.VATSummaryRow{
+		{Category: tax.CategoryExempt, Lines: 1, NetAmount: 50, GrossAmount: 50},
+		{Category: tax.CategoryStandard, Rate: 7, Lines: 3, NetAmount: 300, TaxAmount: 21, GrossAmount: 321},
+		{Category: tax.CategoryZeroRated, Lines: 1, NetAmount: 80, GrossAmount: 80},
+	}, 1)
+	// A refund of 107 on a standard-rated order, credited this month
+	response.Credits = addVATSummaryRows(&response, []repositories.VATSummaryRow{
+		{Category: tax.CategoryStandard, Rate: 7, Lines: 1, NetAmount: 100, TaxAmount: 7, GrossAmount: 107},
+	}, -1)
+
+	want := dtos.VATSummaryResponseDTO{TaxableSales: 200, ZeroRatedSales: 80, ExemptSales: 50, OutputTax: 14, GrossAmount: 344}
+	if response.TaxableSales != want.TaxableSales || response.ZeroRatedSales != want.ZeroRatedSales ||
+		response.ExemptSales != want.ExemptSales || response.OutputTax != want.OutputTax || response.GrossAmount != want.GrossAmount {
+		t.Errorf("totals = taxable %v, zero rated %v, exempt %v, tax %v, gross %v; want %v, %v, %v, %v, %v",
+			response.TaxableSales, response.ZeroRatedSales, response.ExemptSales, response.OutputTax, response.GrossAmount,
+			want.TaxableSales, want.ZeroRatedSales, want.ExemptSales, want.OutputTax, want.GrossAmount)
+	}
+	if len(response.Lines) != 3 || len(response.Credits) != 1 || response.Credits[0].TaxAmount != 7 {
+		t.Errorf("lines = %+v, credits = %+v; want 3 sales lines and the credit as a positive line", response.Lines, response.Credits)
+	}
+}
@@ -0,0 +1,6 @@
+package controllers
+
+import "github.com/go-playground/validator/v10"
+
+// validate checks request DTOs against their validate tags
+var validate = validator.New()
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WaitlistController interface {
+	JoinWaitlist(c *fiber.Ctx) error
+	GetWaitlist(c *fiber.Ctx) error
+	LeaveWaitlist(c *fiber.Ctx) error
+}
+
+type waitlistController struct {
+	waitlistService services.WaitlistService
+}
+
+func NewWaitlistController(waitlistService services.WaitlistService) WaitlistController {
+	return &waitlistController{
+		waitlistService: waitlistService,
+	}
+}
+
+// JoinWaitlist godoc
+// @Summary Join the waitlist of a round variant
+// @Description Add a customer to the waitlist of a sold-out variant. Released quantity is offered in the order customers joined and held for a limited time.
+// @Tags Waitlist
+// @Accept json
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param entry body dtos.WaitlistJoinDTO true "Waitlist entry"
+// @Success 201 {object} dtos.WaitlistEntryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 409 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-rounds/{id}/waitlist [post]
+func (h *waitlistController) JoinWaitlist(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.WaitlistJoinDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "waitlist entry is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	entry := &models.WaitlistEntry{
+		RoundID:    roundID,
+		VariantID:  dto.VariantID,
+		CustomerID: dto.CustomerID,
+		Quantity:   dto.Quantity,
+	}
+	if err := h.waitlistService.JoinWaitlist(entry); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "variant is not part of the sales round"})
+		}
+		if errors.Is(err, repositories.ErrAlreadyWaitlisted) || errors.Is(err, repositories.ErrLotteryRound) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not join waitlist", "details": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toWaitlistEntryResponse(*entry))
+}
+
+// GetWaitlist godoc
+// @Summary Get the waitlist of a sales round
+// @Description Get the waitlist entries of a sales round in queue order, optionally for one variant or status
+// @Tags Waitlist
+// @Produce json
+// @Param id path string true "Sales Round ID"
+// @Param variant_id query string false "Variant ID"
+// @Param status query string false "Status (waiting, offered, fulfilled, expired, cancelled)"
+// @Success 200 {array} dtos.WaitlistEntryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /sales-rounds/{id}/waitlist [get]
+func (h *waitlistController) GetWaitlist(c *fiber.Ctx) error {
+	roundID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+	variantID, err := optionalUUIDQuery(c, "variant_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid variant_id"})
+	}
+
+	entries, err := h.waitlistService.GetWaitlist(roundID, variantID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve waitlist"})
+	}
+
+	responses := make([]dtos.WaitlistEntryResponseDTO, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, toWaitlistEntryResponse(entry))
+	}
+	return c.JSON(responses)
+}
+
+// LeaveWaitlist godoc
+// @Summary Leave a waitlist
+// @Description Remove a customer from a waitlist; quantity held for them is offered to the next customer
+// @Tags Waitlist
+// @Produce json
+// @Param id path string true "Waitlist Entry ID"
+// @Success 200 {object} dtos.WaitlistEntryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /waitlist/{id} [delete]
+func (h *waitlistController) LeaveWaitlist(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	entry, err := h.waitlistService.LeaveWaitlist(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "waitlist entry not found"})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "could not leave waitlist", "details": err.Error()})
+	}
+	return c.JSON(toWaitlistEntryResponse(*entry))
+}
+
+func toWaitlistEntryResponse(entry models.WaitlistEntry) dtos.WaitlistEntryResponseDTO {
+	response := dtos.WaitlistEntryResponseDTO{
+		ID:              entry.ID,
+		RoundID:         entry.RoundID,
+		VariantID:       entry.VariantID,
+		CustomerID:      entry.CustomerID,
+		Quantity:        entry.Quantity,
+		Status:          entry.Status,
+		OfferedQuantity: entry.OfferedQuantity,
+		CreatedAt:       entry.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if entry.OfferedAt != nil {
+		response.OfferedAt = entry.OfferedAt.Format("2006-01-02 15:04:05")
+	}
+	if entry.OfferExpiresAt != nil {
+		response.OfferExpiresAt = entry.OfferExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
@@ -0,0 +1,390 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/webhooks"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookController interface {
+	CreateSubscription(c *fiber.Ctx) error
+	GetSubscriptions(c *fiber.Ctx) error
+	GetSubscription(c *fiber.Ctx) error
+	UpdateSubscription(c *fiber.Ctx) error
+	DeleteSubscription(c *fiber.Ctx) error
+	GetDeliveries(c *fiber.Ctx) error
+	SendTest(c *fiber.Ctx) error
+	ReplayDelivery(c *fiber.Ctx) error
+}
+
+type webhookController struct {
+	webhookService  services.WebhookService
+	storeRepository repositories.StoreRepository
+}
+
+func NewWebhookController(webhookService services.WebhookService, storeRepository repositories.StoreRepository) WebhookController {
+	return &webhookController{
+		webhookService:  webhookService,
+		storeRepository: storeRepository,
+	}
+}
+
+// CreateSubscription godoc
+// @Summary Subscribe to the events of a store
+// @Description Send the given event types of a store to a URL. Deliveries are signed with HMAC-SHA256 in the X-Webhook-Signature header; the secret is generated when none is given and is only returned in this response.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Store ID"
+// @Param subscription body dtos.WebhookSubscriptionCreateDTO true "Webhook subscription"
+// @Success 201 {object} dtos.WebhookSubscriptionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stores/{id}/webhooks [post]
+func (h *webhookController) CreateSubscription(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.WebhookSubscriptionCreateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "webhook subscription is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+	if err := webhooks.ValidateURL(dto.URL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	eventTypes, err := validWebhookEventTypes(dto.EventTypes)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if _, err := h.storeRepository.GetStoreByID(storeID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "store not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve store"})
+	}
+
+	subscription := &models.WebhookSubscription{
+		StoreID:     storeID,
+		URL:         dto.URL,
+		Secret:      dto.Secret,
+		EventTypes:  eventTypes,
+		Description: dto.Description,
+		Active:      true,
+	}
+	if err := h.webhookService.CreateSubscription(subscription); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not create webhook subscription", "details": err.Error()})
+	}
+
+	response := toWebhookSubscriptionResponse(*subscription)
+	response.Secret = subscription.Secret
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// GetSubscriptions godoc
+// @Summary Get the webhook subscriptions of a store
+// @Description Get the webhook subscriptions of a store
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Store ID"
+// @Success 200 {array} dtos.WebhookSubscriptionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /stores/{id}/webhooks [get]
+func (h *webhookController) GetSubscriptions(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	subscriptions, err := h.webhookService.GetSubscriptions(storeID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve webhook subscriptions"})
+	}
+
+	responses := make([]dtos.WebhookSubscriptionResponseDTO, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		responses = append(responses, toWebhookSubscriptionResponse(subscription))
+	}
+	return c.JSON(responses)
+}
+
+// GetSubscription godoc
+// @Summary Get a webhook subscription
+// @Description Get a webhook subscription by ID
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook Subscription ID"
+// @Success 200 {object} dtos.WebhookSubscriptionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Router /webhooks/{id} [get]
+func (h *webhookController) GetSubscription(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	subscription, err := h.webhookService.GetSubscriptionByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook subscription not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve webhook subscription"})
+	}
+	return c.JSON(toWebhookSubscriptionResponse(*subscription))
+}
+
+// UpdateSubscription godoc
+// @Summary Update a webhook subscription
+// @Description Change the URL, event types, description or active flag of a subscription, or rotate its secret. A rotated secret is only returned in this response.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook Subscription ID"
+// @Param subscription body dtos.WebhookSubscriptionUpdateDTO true "Changes"
+// @Success 200 {object} dtos.WebhookSubscriptionResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /webhooks/{id} [put]
+func (h *webhookController) UpdateSubscription(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	dto := new(dtos.WebhookSubscriptionUpdateDTO)
+	if err := c.BodyParser(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "request body is not valid"})
+	}
+	if err := validate.Struct(dto); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "webhook subscription is not valid", "details": utils.ParseValidationErrors(err)})
+	}
+
+	subscription, err := h.webhookService.GetSubscriptionByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook subscription not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve webhook subscription"})
+	}
+
+	if dto.URL != nil {
+		if err := webhooks.ValidateURL(*dto.URL); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		subscription.URL = *dto.URL
+	}
+	if dto.EventTypes != nil {
+		eventTypes, err := validWebhookEventTypes(dto.EventTypes)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		subscription.EventTypes = eventTypes
+	}
+	if dto.Description != nil {
+		subscription.Description = *dto.Description
+	}
+	if dto.Active != nil {
+		subscription.Active = *dto.Active
+	}
+	if dto.RotateSecret {
+		if subscription.Secret, err = webhooks.NewSecret(); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not generate secret"})
+		}
+	}
+
+	if err := h.webhookService.UpdateSubscription(subscription); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not update webhook subscription", "details": err.Error()})
+	}
+
+	response := toWebhookSubscriptionResponse(*subscription)
+	if dto.RotateSecret {
+		response.Secret = subscription.Secret
+	}
+	return c.JSON(response)
+}
+
+// DeleteSubscription godoc
+// @Summary Delete a webhook subscription
+// @Description Delete a webhook subscription; its pending deliveries are marked failed
+// @Tags Webhooks
+// @Param id path string true "Webhook Subscription ID"
+// @Success 204
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /webhooks/{id} [delete]
+func (h *webhookController) DeleteSubscription(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	if err := h.webhookService.DeleteSubscription(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook subscription not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not delete webhook subscription"})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary Get the delivery log of a webhook subscription
+// @Description Get the deliveries of a subscription, newest first, with the response code and body of their last attempt
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook Subscription ID"
+// @Param status query string false "Status (pending, succeeded, failed)"
+// @Success 200 {array} dtos.WebhookDeliveryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /webhooks/{id}/deliveries [get]
+func (h *webhookController) GetDeliveries(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(id, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve webhook deliveries"})
+	}
+
+	responses := make([]dtos.WebhookDeliveryResponseDTO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		responses = append(responses, toWebhookDeliveryResponse(delivery))
+	}
+	return c.JSON(responses)
+}
+
+// SendTest godoc
+// @Summary Send a test event
+// @Description Send a signed webhook.test event to the subscription URL right away and return the delivery. A failed test is retried like any other delivery.
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook Subscription ID"
+// @Success 200 {object} dtos.WebhookDeliveryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /webhooks/{id}/test [post]
+func (h *webhookController) SendTest(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	delivery, err := h.webhookService.SendTest(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook subscription not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not send test event", "details": err.Error()})
+	}
+	return c.JSON(toWebhookDeliveryResponse(*delivery))
+}
+
+// ReplayDelivery godoc
+// @Summary Replay a webhook delivery
+// @Description Send the payload of a delivery again, right away, as a new delivery. The event ID stays the same so receivers can deduplicate.
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook Delivery ID"
+// @Success 200 {object} dtos.WebhookDeliveryResponseDTO
+// @Failure 400 {object} fiber.Map
+// @Failure 404 {object} fiber.Map
+// @Failure 500 {object} fiber.Map
+// @Router /webhook-deliveries/{id}/replay [post]
+func (h *webhookController) ReplayDelivery(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid UUID format"})
+	}
+
+	delivery, err := h.webhookService.Replay(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "webhook delivery or its subscription not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not replay webhook delivery", "details": err.Error()})
+	}
+	return c.JSON(toWebhookDeliveryResponse(*delivery))
+}
+
+// validWebhookEventTypes checks the requested event types and joins them for storage
+func validWebhookEventTypes(eventTypes []string) (string, error) {
+	for _, eventType := range eventTypes {
+		if eventType == "*" {
+			return "*", nil
+		}
+		known := false
+		for _, t := range models.WebhookEventTypes {
+			if t == eventType {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", errors.New("unknown event type " + eventType + "; expected one of " + strings.Join(models.WebhookEventTypes, ", ") + " or *")
+		}
+	}
+	return strings.Join(eventTypes, ","), nil
+}
+
+func toWebhookSubscriptionResponse(subscription models.WebhookSubscription) dtos.WebhookSubscriptionResponseDTO {
+	return dtos.WebhookSubscriptionResponseDTO{
+		ID:          subscription.ID,
+		StoreID:     subscription.StoreID,
+		URL:         subscription.URL,
+		EventTypes:  subscription.EventTypeList(),
+		Description: subscription.Description,
+		Active:      subscription.Active,
+		CreatedAt:   subscription.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   subscription.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func toWebhookDeliveryResponse(delivery models.WebhookDelivery) dtos.WebhookDeliveryResponseDTO {
+	response := dtos.WebhookDeliveryResponseDTO{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseCode:   delivery.ResponseCode,
+		ResponseBody:   delivery.ResponseBody,
+		LastError:      delivery.LastError,
+		ReplayOfID:     delivery.ReplayOfID,
+		Payload:        delivery.Payload,
+		CreatedAt:      delivery.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		response.NextAttemptAt = delivery.NextAttemptAt.Format("2006-01-02 15:04:05")
+	}
+	if delivery.LastAttemptAt != nil {
+		response.LastAttemptAt = delivery.LastAttemptAt.Format("2006-01-02 15:04:05")
+	}
+	if delivery.DeliveredAt != nil {
+		response.DeliveredAt = delivery.DeliveredAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
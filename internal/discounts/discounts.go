@@ -0,0 +1,305 @@
+// Package discounts prices discount codes against the lines of an order. A code takes a percentage or a fixed
+// amount off the lines in its scope, makes shipping free, or gives away units of what is bought (buy X get Y).
+// Codes are applied in a fixed order, each to what the codes before it left of the lines, and a line is never
+// discounted below zero. Amounts are rounded to the satang and spread over the lines they were earned on, so
+// every order line knows which codes reduced it and by how much.
+package discounts
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/google/uuid"
+)
+
+// Kinds of discount
+const (
+	KindPercentage   = "percentage"    // Value percent off the lines in scope
+	KindFixedAmount  = "fixed_amount"  // Value off the lines in scope, spread over them by amount
+	KindFreeShipping = "free_shipping" // The order ships for free
+	KindBuyXGetY     = "buy_x_get_y"   // Of every BuyQuantity + GetQuantity units in scope, the GetQuantity cheapest are Value percent off (free when 0)
+)
+
+// Scopes a discount is limited to
+const (
+	ScopeOrder    = "order" // Every line
+	ScopeStore    = "store"
+	ScopeCategory = "category"
+	ScopeVariant  = "variant"
+	ScopeRound    = "round" // Every line of orders placed in the sales round
+)
+
+var (
+	// ErrUnknownKind is returned for a discount kind that does not exist
+	ErrUnknownKind = errors.New("unknown discount kind")
+	// ErrUnknownScope is returned for a discount scope that does not exist
+	ErrUnknownScope = errors.New("unknown discount scope")
+	// ErrNotStackable is returned when a code that cannot be combined is used with another code
+	ErrNotStackable = errors.New("discount code cannot be combined with other codes")
+	// ErrNotApplicable is returned when a code applies to none of the lines
+	ErrNotApplicable = errors.New("discount code does not apply to this order")
+	// ErrBelowMinimum is returned when the lines a code applies to do not add up to its minimum
+	ErrBelowMinimum = errors.New("order does not reach the discount code's minimum")
+)
+
+// Code is a discount code to price
+type Code struct {
+	ID          uuid.UUID
+	Code        string
+	Kind        string
+	Value       float64 // Percent for percentage and buy_x_get_y, amount for fixed_amount
+	BuyQuantity int     // buy_x_get_y only
+	GetQuantity int     // buy_x_get_y only
+	Scope       string
+	ScopeID     uuid.UUID // Store, category, variant or sales round of the scope
+	MinSubtotal float64   // Lines in scope must add up to this before any discount
+	MaxDiscount float64   // Most the code takes off; 0 for no cap
+	Stackable   bool      // Can be combined with other stackable codes
+}
+
+// Validate reports whether a code is well formed
+func (c Code) Validate() error {
+	switch c.Scope {
+	case ScopeOrder:
+	case ScopeStore, ScopeCategory, ScopeVariant, ScopeRound:
+		if c.ScopeID == uuid.Nil {
+			return fmt.Errorf("a %s scope needs the ID of the %s", c.Scope, c.Scope)
+		}
+	default:
+		return ErrUnknownScope
+	}
+	switch c.Kind {
+	case KindPercentage:
+		if c.Value <= 0 || c.Value > 100 {
+			return fmt.Errorf("a percentage must be above 0 and at most 100")
+		}
+	case KindFixedAmount:
+		if c.Value <= 0 {
+			return fmt.Errorf("a fixed amount must be positive")
+		}
+	case KindFreeShipping:
+	case KindBuyXGetY:
+		if c.BuyQuantity <= 0 || c.GetQuantity <= 0 {
+			return fmt.Errorf("buy and get quantities must be positive")
+		}
+		if c.Value < 0 || c.Value > 100 {
+			return fmt.Errorf("a percentage must be between 0 and 100")
+		}
+	default:
+		return ErrUnknownKind
+	}
+	if c.MinSubtotal < 0 || c.MaxDiscount < 0 {
+		return fmt.Errorf("minimum and maximum cannot be negative")
+	}
+	return nil
+}
+
+// Line is a line of an order to discount
+type Line struct {
+	VariantID  uuid.UUID
+	StoreID    uuid.UUID
+	CategoryID uuid.UUID
+	Price      float64 // Per unit
+	Quantity   int
+}
+
+// Cart is an order to discount
+type Cart struct {
+	RoundID     uuid.UUID
+	Lines       []Line
+	ShippingFee float64
+}
+
+// Allocation is what a code took off a line
+type Allocation struct {
+	Line   int // Index in Cart.Lines
+	CodeID uuid.UUID
+	Code   string
+	Amount float64
+}
+
+// Result is what a set of codes takes off an order
+type Result struct {
+	Allocations      []Allocation
+	LineDiscounts    []float64             // Total taken off each line, by index in Cart.Lines
+	CodeDiscounts    map[uuid.UUID]float64 // Total taken off by each code, shipping included
+	ShippingDiscount float64
+	FreeShipping     bool
+	Total            float64 // Everything taken off, shipping included
+}
+
+// kindOrder is the order codes are applied in: units given away first, then percentages, then fixed amounts, so
+// a percentage never discounts what a fixed amount already took off
+var kindOrder = map[string]int{KindBuyXGetY: 0, KindPercentage: 1, KindFixedAmount: 2, KindFreeShipping: 3}
+
+// Apply prices codes against a cart. It fails when a code is not stackable but others are given, or when a code
+// does not apply to the cart.
+func Apply(cart Cart, codes []Code) (Result, error) {
+	result := Result{
+		LineDiscounts: make([]float64, len(cart.Lines)),
+		CodeDiscounts: make(map[uuid.UUID]float64, len(codes)),
+	}
+	if len(codes) > 1 {
+		for _, code := range codes {
+			if !code.Stackable {
+				return Result{}, fmt.Errorf("%w: %s", ErrNotStackable, code.Code)
+			}
+		}
+	}
+
+	ordered := append([]Code(nil), codes...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return kindOrder[ordered[i].Kind] < kindOrder[ordered[j].Kind]
+	})
+
+	remaining := make([]float64, len(cart.Lines))
+	for i, line := range cart.Lines {
+		remaining[i] = tax.Round(line.Price * float64(line.Quantity))
+	}
+
+	for _, code := range ordered {
+		if err := code.Validate(); err != nil {
+			return Result{}, fmt.Errorf("%s: %w", code.Code, err)
+		}
+		inScope := make([]int, 0, len(cart.Lines))
+		subtotal := 0.0
+		for i, line := range cart.Lines {
+			if code.covers(cart, line) {
+				inScope = append(inScope, i)
+				subtotal += tax.Round(line.Price * float64(line.Quantity))
+			}
+		}
+		if len(inScope) == 0 {
+			return Result{}, fmt.Errorf("%w: %s", ErrNotApplicable, code.Code)
+		}
+		if subtotal < code.MinSubtotal {
+			return Result{}, fmt.Errorf("%w: %s needs %.2f", ErrBelowMinimum, code.Code, code.MinSubtotal)
+		}
+
+		if code.Kind == KindFreeShipping {
+			shipping := tax.Round(cart.ShippingFee - result.ShippingDiscount)
+			if code.MaxDiscount > 0 && shipping > code.MaxDiscount {
+				shipping = code.MaxDiscount
+			}
+			result.FreeShipping = true
+			result.ShippingDiscount = tax.Round(result.ShippingDiscount + shipping)
+			result.CodeDiscounts[code.ID] += shipping
+			result.Total += shipping
+			continue
+		}
+
+		amounts := code.lineAmounts(cart, inScope, remaining)
+		total := 0.0
+		for _, amount := range amounts {
+			total += amount
+		}
+		if code.MaxDiscount > 0 && total > code.MaxDiscount {
+			amounts = spread(code.MaxDiscount, inScope, amounts)
+			total = code.MaxDiscount
+		}
+		for _, i := range inScope {
+			amount := amounts[i]
+			if amount <= 0 {
+				continue
+			}
+			remaining[i] = tax.Round(remaining[i] - amount)
+			result.LineDiscounts[i] = tax.Round(result.LineDiscounts[i] + amount)
+			result.Allocations = append(result.Allocations, Allocation{Line: i, CodeID: code.ID, Code: code.Code, Amount: amount})
+		}
+		result.CodeDiscounts[code.ID] = tax.Round(result.CodeDiscounts[code.ID] + total)
+		result.Total += total
+	}
+	result.Total = tax.Round(result.Total)
+	return result, nil
+}
+
+// covers reports whether a code applies to a line of the cart
+func (c Code) covers(cart Cart, line Line) bool {
+	switch c.Scope {
+	case ScopeStore:
+		return line.StoreID == c.ScopeID
+	case ScopeCategory:
+		return line.CategoryID == c.ScopeID
+	case ScopeVariant:
+		return line.VariantID == c.ScopeID
+	case ScopeRound:
+		return cart.RoundID == c.ScopeID
+	}
+	return true
+}
+
+// lineAmounts returns what a code takes off each line, by index in the cart, given what is left of the lines
+func (c Code) lineAmounts(cart Cart, inScope []int, remaining []float64) map[int]float64 {
+	amounts := make(map[int]float64, len(inScope))
+	switch c.Kind {
+	case KindPercentage:
+		for _, i := range inScope {
+			amounts[i] = tax.Round(remaining[i] * c.Value / 100)
+		}
+	case KindFixedAmount:
+		weights := make(map[int]float64, len(inScope))
+		left := 0.0
+		for _, i := range inScope {
+			weights[i] = remaining[i]
+			left += remaining[i]
+		}
+		amounts = spread(min(c.Value, tax.Round(left)), inScope, weights)
+	case KindBuyXGetY:
+		// Every unit in scope, most expensive first; the cheapest units of each complete group are given away
+		type unit struct {
+			line  int
+			price float64
+		}
+		var units []unit
+		for _, i := range inScope {
+			for q := 0; q < cart.Lines[i].Quantity; q++ {
+				units = append(units, unit{line: i, price: cart.Lines[i].Price})
+			}
+		}
+		sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+		free := len(units) / (c.BuyQuantity + c.GetQuantity) * c.GetQuantity
+		percent := c.Value
+		if percent == 0 {
+			percent = 100
+		}
+		for _, u := range units[len(units)-free:] {
+			amounts[u.line] += u.price * percent / 100
+		}
+		for i, amount := range amounts {
+			amounts[i] = min(tax.Round(amount), remaining[i])
+		}
+	}
+	return amounts
+}
+
+// spread divides amount over lines in proportion to their weights. The last line with a weight takes what
+// rounding leaves over.
+func spread(amount float64, lines []int, weights map[int]float64) map[int]float64 {
+	total := 0.0
+	last := -1
+	for _, i := range lines {
+		if weights[i] > 0 {
+			total += weights[i]
+			last = i
+		}
+	}
+	amounts := make(map[int]float64, len(lines))
+	if total <= 0 {
+		return amounts
+	}
+	left := tax.Round(amount)
+	for _, i := range lines {
+		if weights[i] <= 0 {
+			continue
+		}
+		share := left
+		if i != last {
+			share = tax.Round(amount * weights[i] / total)
+		}
+		amounts[i] = share
+		left = tax.Round(left - share)
+	}
+	return amounts
+}
@@ -0,0 +1,273 @@
+package discounts
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/google/uuid"
+)
+
+func TestApply(t *testing.T) {
+	store, shirts, mugs := uuid.New(), uuid.New(), uuid.New()
+	shirt, mug := uuid.New(), uuid.New()
+	cart := Cart{
+		RoundID: uuid.New(),
+		Lines: []Line{
+			{VariantID: shirt, StoreID: store, CategoryID: shirts, Price: 100, Quantity: 2},
+			{VariantID: mug, StoreID: store, CategoryID: mugs, Price: 50, Quantity: 1},
+		},
+		ShippingFee: 40,
+	}
+	code := func(kind string, value float64, stackable bool) Code {
+		return Code{ID: uuid.New(), Code: kind, Kind: kind, Value: value, Scope: ScopeOrder, Stackable: stackable}
+	}
+	scoped := func(c Code, scope string, scopeID uuid.UUID) Code {
+		c.Scope, c.ScopeID = scope, scopeID
+		return c
+	}
+	buyXGetY := func(buy, get int, percent float64) Code {
+		c := code(KindBuyXGetY, percent, false)
+		c.BuyQuantity, c.GetQuantity = buy, get
+		return c
+	}
+
+	tests := []struct {
+		name          string
+		codes         []Code
+		wantLines     []float64
+		wantShipping  float64
+		wantTotal     float64
+		wantErr       error
+		wantAnyErr    bool
+		wantPerCode   []float64 // Discount of each code, in the order given
+		wantAllocated int       // Allocations made
+	}{
+		{
+			name:          "percentage off every line",
+			codes:         []Code{code(KindPercentage, 10, false)},
+			wantLines:     []float64{20, 5},
+			wantTotal:     25,
+			wantPerCode:   []float64{25},
+			wantAllocated: 2,
+		},
+		{
+			name:          "fixed amount spread by line amount",
+			codes:         []Code{code(KindFixedAmount, 30, false)},
+			wantLines:     []float64{24, 6},
+			wantTotal:     30,
+			wantPerCode:   []float64{30},
+			wantAllocated: 2,
+		},
+		{
+			name:          "fixed amount above the subtotal stops at zero",
+			codes:         []Code{code(KindFixedAmount, 500, false)},
+			wantLines:     []float64{200, 50},
+			wantTotal:     250,
+			wantPerCode:   []float64{250},
+			wantAllocated: 2,
+		},
+		{
+			name:          "stacked codes apply percentages before fixed amounts whatever order they are given in",
+			codes:         []Code{code(KindFixedAmount, 30, true), code(KindPercentage, 10, true)},
+			wantLines:     []float64{44, 11},
+			wantTotal:     55,
+			wantPerCode:   []float64{30, 25},
+			wantAllocated: 4,
+		},
+		{
+			name:          "stacked codes never take a line below zero",
+			codes:         []Code{code(KindPercentage, 100, true), code(KindFixedAmount, 10, true)},
+			wantLines:     []float64{200, 50},
+			wantTotal:     250,
+			wantPerCode:   []float64{250, 0},
+			wantAllocated: 2,
+		},
+		{
+			name:    "code that cannot be combined",
+			codes:   []Code{code(KindPercentage, 10, false), code(KindFixedAmount, 30, true)},
+			wantErr: ErrNotStackable,
+		},
+		{
+			name:          "category scope",
+			codes:         []Code{scoped(code(KindPercentage, 10, false), ScopeCategory, mugs)},
+			wantLines:     []float64{0, 5},
+			wantTotal:     5,
+			wantPerCode:   []float64{5},
+			wantAllocated: 1,
+		},
+		{
+			name:          "round scope",
+			codes:         []Code{scoped(code(KindPercentage, 10, false), ScopeRound, cart.RoundID)},
+			wantLines:     []float64{20, 5},
+			wantTotal:     25,
+			wantPerCode:   []float64{25},
+			wantAllocated: 2,
+		},
+		{
+			name:    "scope with no lines",
+			codes:   []Code{scoped(code(KindPercentage, 10, false), ScopeVariant, uuid.New())},
+			wantErr: ErrNotApplicable,
+		},
+		{
+			name: "minimum counts only the lines in scope",
+			codes: []Code{func() Code {
+				c := scoped(code(KindPercentage, 10, false), ScopeCategory, mugs)
+				c.MinSubtotal = 100
+				return c
+			}()},
+			wantErr: ErrBelowMinimum,
+		},
+		{
+			name: "maximum caps the code and is spread over its lines",
+			codes: []Code{func() Code {
+				c := code(KindPercentage, 50, false)
+				c.MaxDiscount = 60
+				return c
+			}()},
+			wantLines:     []float64{48, 12},
+			wantTotal:     60,
+			wantPerCode:   []float64{60},
+			wantAllocated: 2,
+		},
+		{
+			name:          "buy one get one gives away the cheapest unit",
+			codes:         []Code{buyXGetY(1, 1, 0)},
+			wantLines:     []float64{0, 50},
+			wantTotal:     50,
+			wantPerCode:   []float64{50},
+			wantAllocated: 1,
+		},
+		{
+			name:          "buy one get one half off within a category",
+			codes:         []Code{scoped(buyXGetY(1, 1, 50), ScopeCategory, shirts)},
+			wantLines:     []float64{50, 0},
+			wantTotal:     50,
+			wantPerCode:   []float64{50},
+			wantAllocated: 1,
+		},
+		{
+			name:          "buy two get one needs a complete group",
+			codes:         []Code{scoped(buyXGetY(2, 1, 0), ScopeCategory, shirts)},
+			wantLines:     []float64{0, 0},
+			wantTotal:     0,
+			wantPerCode:   []float64{0},
+			wantAllocated: 0,
+		},
+		{
+			name:          "free shipping",
+			codes:         []Code{code(KindFreeShipping, 0, false)},
+			wantLines:     []float64{0, 0},
+			wantShipping:  40,
+			wantTotal:     40,
+			wantPerCode:   []float64{40},
+			wantAllocated: 0,
+		},
+		{
+			name: "free shipping up to a maximum, stacked with a percentage",
+			codes: []Code{func() Code {
+				c := code(KindFreeShipping, 0, true)
+				c.MaxDiscount = 25
+				return c
+			}(), code(KindPercentage, 10, true)},
+			wantLines:     []float64{20, 5},
+			wantShipping:  25,
+			wantTotal:     50,
+			wantPerCode:   []float64{25, 25},
+			wantAllocated: 2,
+		},
+		{
+			name:       "malformed code",
+			codes:      []Code{code(KindPercentage, 150, false)},
+			wantAnyErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply(cart, tt.codes)
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(result.LineDiscounts, tt.wantLines) {
+				t.Errorf("line discounts = %v, want %v", result.LineDiscounts, tt.wantLines)
+			}
+			if result.ShippingDiscount != tt.wantShipping || result.FreeShipping != (tt.wantShipping > 0) {
+				t.Errorf("shipping discount = %v (free %v), want %v", result.ShippingDiscount, result.FreeShipping, tt.wantShipping)
+			}
+			if result.Total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", result.Total, tt.wantTotal)
+			}
+			for i, c := range tt.codes {
+				if got := result.CodeDiscounts[c.ID]; got != tt.wantPerCode[i] {
+					t.Errorf("discount of code %d (%s) = %v, want %v", i, c.Kind, got, tt.wantPerCode[i])
+				}
+			}
+			if len(result.Allocations) != tt.wantAllocated {
+				t.Errorf("got %d allocations, want %d", len(result.Allocations), tt.wantAllocated)
+			}
+
+			// Every line discount is made of its allocations, and no line is discounted below zero
+			allocated := make([]float64, len(cart.Lines))
+			for _, allocation := range result.Allocations {
+				allocated[allocation.Line] = tax.Round(allocated[allocation.Line] + allocation.Amount)
+			}
+			for i, line := range cart.Lines {
+				if allocated[i] != result.LineDiscounts[i] {
+					t.Errorf("line %d: allocations add up to %v, line discount is %v", i, allocated[i], result.LineDiscounts[i])
+				}
+				if result.LineDiscounts[i] > line.Price*float64(line.Quantity) {
+					t.Errorf("line %d discounted %v, more than its amount", i, result.LineDiscounts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		lines   []int
+		weights map[int]float64
+		want    map[int]float64
+	}{
+		{
+			name:    "in proportion",
+			amount:  30,
+			lines:   []int{0, 1},
+			weights: map[int]float64{0: 200, 1: 100},
+			want:    map[int]float64{0: 20, 1: 10},
+		},
+		{
+			name:    "last weighted line takes the rounding",
+			amount:  10,
+			lines:   []int{0, 1, 2, 3},
+			weights: map[int]float64{0: 1, 1: 1, 2: 1, 3: 0},
+			want:    map[int]float64{0: 3.33, 1: 3.33, 2: 3.34},
+		},
+		{
+			name:    "nothing to spread over",
+			amount:  10,
+			lines:   []int{0},
+			weights: map[int]float64{0: 0},
+			want:    map[int]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spread(tt.amount, tt.lines, tt.weights); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spread() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
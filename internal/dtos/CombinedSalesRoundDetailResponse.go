@@ -10,7 +10,8 @@ type CombinedSalesRoundDetailResponse struct {
 	SalesRound
 	SalesRoundDetailResponseDTO
 	SKUCode         string  `json:"sku_code"`
-	VariantPrice    float64 `json:"variant_price"`
+	VariantPrice    float64 `json:"variant_price"` // Regular price
+	SalePrice       float64 `json:"sale_price"`    // Price in the round: the round price, or the regular price less the discount percentage
 	VariantImageURL string  `json:"variant_image_url"`
 	ProductName     string  `json:"product_name"`
 	Brand           string  `json:"brand"`
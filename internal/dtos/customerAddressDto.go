@@ -0,0 +1,39 @@
+package dtos
+
+import "github.com/google/uuid"
+
+// CustomerAddressDTO is used for adding or editing an entry of a customer's address book. Subdistrict, district
+// and province can be given in Thai or English; they are stored as named in the Thai address dataset.
+type CustomerAddressDTO struct {
+	Label       string `json:"label" validate:"max=100"`
+	Recipient   string `json:"recipient" validate:"required,max=255"`
+	Phone       string `json:"phone" validate:"required,max=20"`
+	HouseNumber string `json:"house_number" validate:"required,max=255"` // House number, building, village, soi and road
+	Subdistrict string `json:"subdistrict" validate:"required,max=100"`
+	District    string `json:"district" validate:"required,max=100"`
+	Province    string `json:"province" validate:"required,max=100"`
+	Postcode    string `json:"postcode" validate:"required,len=5,numeric"`
+	IsDefault   bool   `json:"is_default"` // Ignored when editing; use the default endpoint
+}
+
+// PostalAddressDTO is used when returning a structured address
+type PostalAddressDTO struct {
+	Recipient   string `json:"recipient"`
+	Phone       string `json:"phone"`
+	HouseNumber string `json:"house_number"`
+	Subdistrict string `json:"subdistrict"`
+	District    string `json:"district"`
+	Province    string `json:"province"`
+	Postcode    string `json:"postcode"`
+}
+
+// CustomerAddressResponseDTO is used when returning an entry of a customer's address book
+type CustomerAddressResponseDTO struct {
+	ID         uuid.UUID `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	Label      string    `json:"label"`
+	PostalAddressDTO
+	IsDefault bool   `json:"is_default"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
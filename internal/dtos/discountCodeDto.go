@@ -0,0 +1,82 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiscountCodeDTO is used when creating or updating a discount code
+type DiscountCodeDTO struct {
+	Code             string     `json:"code" validate:"required,max=50"`
+	Description      string     `json:"description"`
+	Kind             string     `json:"kind" validate:"required,oneof=percentage fixed_amount free_shipping buy_x_get_y"`
+	Value            float64    `json:"value" validate:"gte=0"`                                              // Percent off, or amount off for fixed_amount
+	BuyQuantity      int        `json:"buy_quantity" validate:"gte=0"`                                       // buy_x_get_y only
+	GetQuantity      int        `json:"get_quantity" validate:"gte=0"`                                       // buy_x_get_y only
+	Scope            string     `json:"scope" validate:"omitempty,oneof=order store category variant round"` // Defaults to order
+	ScopeID          *uuid.UUID `json:"scope_id"`                                                            // Store, category, variant or sales round of the scope
+	MinSubtotal      float64    `json:"min_subtotal" validate:"gte=0"`
+	MaxDiscount      float64    `json:"max_discount" validate:"gte=0"` // 0 for no cap
+	StartsAt         *time.Time `json:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+	UsageLimit       int        `json:"usage_limit" validate:"gte=0"`        // 0 for no limit
+	PerCustomerLimit int        `json:"per_customer_limit" validate:"gte=0"` // 0 for no limit
+	Stackable        bool       `json:"stackable"`
+	Active           *bool      `json:"active"` // Defaults to true
+}
+
+// DiscountCodeResponseDTO is used when returning a discount code
+type DiscountCodeResponseDTO struct {
+	ID               uuid.UUID  `json:"id"`
+	Code             string     `json:"code"`
+	Description      string     `json:"description"`
+	Kind             string     `json:"kind"`
+	Value            float64    `json:"value"`
+	BuyQuantity      int        `json:"buy_quantity,omitempty"`
+	GetQuantity      int        `json:"get_quantity,omitempty"`
+	Scope            string     `json:"scope"`
+	ScopeID          *uuid.UUID `json:"scope_id,omitempty"`
+	MinSubtotal      float64    `json:"min_subtotal"`
+	MaxDiscount      float64    `json:"max_discount"`
+	StartsAt         *time.Time `json:"starts_at,omitempty"`
+	EndsAt           *time.Time `json:"ends_at,omitempty"`
+	UsageLimit       int        `json:"usage_limit"`
+	PerCustomerLimit int        `json:"per_customer_limit"`
+	UsedCount        int        `json:"used_count"`
+	Stackable        bool       `json:"stackable"`
+	Active           bool       `json:"active"`
+	CreatedAt        string     `json:"created_at"`
+	UpdatedAt        string     `json:"updated_at"`
+}
+
+// DiscountQuoteRequestDTO is used for pricing a would-be order with discount codes before placing it
+type DiscountQuoteRequestDTO struct {
+	CustomerID    uuid.UUID         `json:"customer_id" validate:"required"`
+	RoundID       uuid.UUID         `json:"round_id" validate:"required"`
+	Items         []PurchaseItemDTO `json:"items" validate:"required,min=1"`
+	DiscountCodes []string          `json:"discount_codes"`
+}
+
+// DiscountQuoteLineDTO is used when returning the price of a line of a quoted order
+type DiscountQuoteLineDTO struct {
+	VariantID      uuid.UUID          `json:"variant_id"`
+	Quantity       int                `json:"quantity"`
+	RegularPrice   float64            `json:"regular_price"` // Variant's own price
+	Price          float64            `json:"price"`         // Price in the sales round
+	DiscountAmount float64            `json:"discount_amount"`
+	TotalPrice     float64            `json:"total_price"` // After discounts, VAT included
+	TaxAmount      float64            `json:"tax_amount"`
+	Discounts      []OrderDiscountDTO `json:"discounts,omitempty"`
+}
+
+// DiscountQuoteResponseDTO is used when returning the price of a quoted order
+type DiscountQuoteResponseDTO struct {
+	Subtotal       float64                `json:"subtotal"` // Price x quantity of every line, before discounts
+	DiscountAmount float64                `json:"discount_amount"`
+	NetAmount      float64                `json:"net_amount"`
+	TaxAmount      float64                `json:"tax_amount"`
+	TotalPrice     float64                `json:"total_price"`
+	FreeShipping   bool                   `json:"free_shipping"`
+	Lines          []DiscountQuoteLineDTO `json:"lines"`
+}
@@ -0,0 +1,42 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderExportQueryDTO is the query string of an order export; from and to are dates (2006-01-02) or RFC 3339 times
+type OrderExportQueryDTO struct {
+	RoundID string `query:"round_id" validate:"omitempty,uuid"`
+	From    string `query:"from"`
+	To      string `query:"to"`
+	Format  string `query:"format" validate:"omitempty,oneof=csv ndjson"`
+}
+
+// OrderExportFilter selects the orders of an export; nil fields are not filtered on
+type OrderExportFilter struct {
+	RoundID *uuid.UUID
+	From    *time.Time // Orders on or after this time
+	To      *time.Time // Orders before this time
+}
+
+// OrderExportRowDTO is one order line of an order export, joined with its order, variant SKU and customer email
+type OrderExportRowDTO struct {
+	OrderID         uuid.UUID `json:"order_id"`
+	OrderCode       string    `json:"order_code"`
+	OrderDate       time.Time `json:"order_date"`
+	Status          string    `json:"status"`
+	RoundID         uuid.UUID `json:"round_id"`
+	CustomerID      uuid.UUID `json:"customer_id"`
+	CustomerEmail   string    `json:"customer_email"`
+	OrderTotal      float64   `json:"order_total"`
+	DeliveryAddress string    `json:"delivery_address"`
+	PaymentSource   string    `json:"payment_source"`
+	LineID          uuid.UUID `json:"line_id"`
+	VariantID       uuid.UUID `json:"variant_id"`
+	SKUCode         string    `json:"sku_code"`
+	Quantity        int       `json:"quantity"`
+	UnitPrice       float64   `json:"unit_price"`
+	LineTotal       float64   `json:"line_total"`
+}
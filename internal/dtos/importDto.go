@@ -0,0 +1,42 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductImportRowDTO is one parsed row of a product import; the category, product and variant
+// are validated with the same tags as their create DTOs, except that product stock and image_url are optional
+type ProductImportRowDTO struct {
+	Row      int
+	Columns  map[string]bool // Columns of the file's header; fields of optional columns it leaves out are kept
+	Category CategoryCreateDTO
+	Product  ProductCreateDTO
+	Variant  ProductVariantCreateDTO
+}
+
+// ImportRowErrorDTO describes why a row of an import was not imported
+type ImportRowErrorDTO struct {
+	Row     int    `json:"row"`
+	SKUCode string `json:"sku_code"`
+	Error   string `json:"error"`
+}
+
+// ImportJobResponseDTO is used when returning an import job with its progress and error report
+type ImportJobResponseDTO struct {
+	ID            uuid.UUID           `json:"id"`
+	StoreID       uuid.UUID           `json:"store_id"`
+	Type          string              `json:"type"`
+	Status        string              `json:"status"`
+	FileName      string              `json:"file_name"`
+	TotalRows     int                 `json:"total_rows"`
+	ProcessedRows int                 `json:"processed_rows"`
+	ImportedRows  int                 `json:"imported_rows"`
+	FailedRows    int                 `json:"failed_rows"`
+	Progress      float64             `json:"progress"` // Percentage of rows processed
+	Errors        []ImportRowErrorDTO `json:"errors"`
+	StartedAt     *time.Time          `json:"started_at"`
+	FinishedAt    *time.Time          `json:"finished_at"`
+	CreatedAt     string              `json:"created_at"`
+}
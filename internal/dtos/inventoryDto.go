@@ -0,0 +1,79 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InventoryLocationCreateDTO is used when creating a new inventory location under a store
+type InventoryLocationCreateDTO struct {
+	Name string `json:"name" validate:"required"`
+	Type string `json:"type" validate:"omitempty,oneof=shop warehouse"`
+}
+
+// InventoryLocationUpdateDTO is used when updating an existing inventory location
+type InventoryLocationUpdateDTO struct {
+	Name string `json:"name"` // Leave empty to keep the current name
+	Type string `json:"type" validate:"omitempty,oneof=shop warehouse"`
+}
+
+// InventoryLocationResponseDTO is used when returning an inventory location response
+type InventoryLocationResponseDTO struct {
+	ID        uuid.UUID `json:"id"`
+	StoreID   uuid.UUID `json:"store_id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+}
+
+// StockLevelSetDTO is used when setting the on-hand quantity of a variant at a location
+type StockLevelSetDTO struct {
+	VariantID uuid.UUID `json:"variant_id" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"min=0"`
+}
+
+// StockLevelResponseDTO is used when returning the stock level of a variant at a location
+type StockLevelResponseDTO struct {
+	VariantID  uuid.UUID `json:"variant_id"`
+	LocationID uuid.UUID `json:"location_id"`
+	Quantity   int       `json:"quantity"`
+	UpdatedAt  string    `json:"updated_at"`
+}
+
+// StockTransferCreateDTO is used when sending stock from one location to another
+type StockTransferCreateDTO struct {
+	VariantID      uuid.UUID `json:"variant_id" validate:"required"`
+	FromLocationID uuid.UUID `json:"from_location_id" validate:"required"`
+	ToLocationID   uuid.UUID `json:"to_location_id" validate:"required,nefield=FromLocationID"`
+	Quantity       int       `json:"quantity" validate:"required,gt=0"`
+	Note           string    `json:"note"`
+}
+
+// StockTransferResponseDTO is used when returning a stock transfer response
+type StockTransferResponseDTO struct {
+	ID             uuid.UUID  `json:"id"`
+	VariantID      uuid.UUID  `json:"variant_id"`
+	FromLocationID uuid.UUID  `json:"from_location_id"`
+	ToLocationID   uuid.UUID  `json:"to_location_id"`
+	FromStoreID    uuid.UUID  `json:"from_store_id"`
+	ToStoreID      uuid.UUID  `json:"to_store_id"`
+	Quantity       int        `json:"quantity"`
+	Status         string     `json:"status"`
+	Note           string     `json:"note"`
+	ShippedAt      time.Time  `json:"shipped_at"`
+	ReceivedAt     *time.Time `json:"received_at"`
+}
+
+// LowStockVariantDTO is a variant whose on-hand stock is at or below its reorder point
+type LowStockVariantDTO struct {
+	VariantID       uuid.UUID `json:"variant_id"`
+	ProductID       uuid.UUID `json:"product_id"`
+	StoreID         uuid.UUID `json:"store_id"`
+	SKUCode         string    `json:"sku_code"`
+	ProductName     string    `json:"product_name"`
+	OnHand          int       `json:"on_hand"`
+	ReorderPoint    int       `json:"reorder_point"`
+	ReorderQuantity int       `json:"reorder_quantity"`
+}
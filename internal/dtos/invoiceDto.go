@@ -0,0 +1,69 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceIssueDTO is used for issuing the tax invoice of a paid order. Fields left empty are taken from the order
+// and its customer; a business buyer gives its tax ID so it can claim the VAT.
+type InvoiceIssueDTO struct {
+	BuyerName       string `json:"buyer_name" validate:"max=255"`
+	BuyerTaxID      string `json:"buyer_tax_id" validate:"omitempty,len=13,numeric"`
+	BuyerBranchCode string `json:"buyer_branch_code" validate:"omitempty,len=5,numeric"` // Defaults to 00000, the head office, with a tax ID
+	BuyerAddress    string `json:"buyer_address" validate:"max=500"`
+	BuyerPostcode   string `json:"buyer_postcode" validate:"omitempty,len=5,numeric"`
+	BuyerEmail      string `json:"buyer_email" validate:"omitempty,email,max=255"`
+}
+
+// CreditNoteCreateDTO is used for issuing a credit note against a tax invoice, for a refund or an amount
+type CreditNoteCreateDTO struct {
+	RefundID *uuid.UUID `json:"refund_id"`                                         // Payment refund to credit; its amount is credited
+	Amount   float64    `json:"amount" validate:"required_without=RefundID,gte=0"` // Amount including VAT, when not crediting a refund
+	Reason   string     `json:"reason" validate:"required_without=RefundID"`       // The refund's reason when empty
+}
+
+// InvoicePartyDTO is the seller or buyer named on an invoice
+type InvoicePartyDTO struct {
+	Name       string `json:"name"`
+	TaxID      string `json:"tax_id,omitempty"`
+	BranchCode string `json:"branch_code,omitempty"`
+	Address    string `json:"address"`
+	Postcode   string `json:"postcode,omitempty"`
+	Email      string `json:"email,omitempty"`
+}
+
+// InvoiceLineResponseDTO is used when returning a line of an invoice
+type InvoiceLineResponseDTO struct {
+	LineNumber    int        `json:"line_number"`
+	OrderDetailID *uuid.UUID `json:"order_detail_id,omitempty"`
+	Description   string     `json:"description"`
+	Quantity      int        `json:"quantity"`
+	UnitPrice     float64    `json:"unit_price"` // Before VAT
+	TaxRate       float64    `json:"tax_rate"`
+	NetAmount     float64    `json:"net_amount"`
+	TaxAmount     float64    `json:"tax_amount"`
+	GrossAmount   float64    `json:"gross_amount"`
+}
+
+// InvoiceResponseDTO is used when returning a tax invoice or credit note
+type InvoiceResponseDTO struct {
+	ID          uuid.UUID                `json:"id"`
+	StoreID     uuid.UUID                `json:"store_id"`
+	OrderID     uuid.UUID                `json:"order_id"`
+	Type        string                   `json:"type"` // T03 for a receipt/tax invoice, 81 for a credit note
+	Name        string                   `json:"name"`
+	Number      string                   `json:"number"`
+	IssuedAt    time.Time                `json:"issued_at"`
+	Seller      InvoicePartyDTO          `json:"seller"`
+	Buyer       InvoicePartyDTO          `json:"buyer"`
+	Currency    string                   `json:"currency"`
+	NetAmount   float64                  `json:"net_amount"`
+	TaxAmount   float64                  `json:"tax_amount"`
+	GrossAmount float64                  `json:"gross_amount"`
+	InvoiceID   *uuid.UUID               `json:"invoice_id,omitempty"` // Credit notes: the tax invoice they reduce
+	RefundID    *uuid.UUID               `json:"refund_id,omitempty"`
+	Reason      string                   `json:"reason,omitempty"`
+	Lines       []InvoiceLineResponseDTO `json:"lines"`
+}
@@ -0,0 +1,44 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LotteryEntryCreateDTO is used when a customer enters a lottery sales round
+type LotteryEntryCreateDTO struct {
+	CustomerID      uuid.UUID `json:"customer_id" validate:"required"`
+	VariantID       uuid.UUID `json:"variant_id" validate:"required"`
+	Quantity        int       `json:"quantity" validate:"required,gt=0"`
+	DeliveryAddress string    `json:"delivery_address" validate:"required"`
+	PaymentSource   string    `json:"payment_source" validate:"required"`
+}
+
+// LotteryEntryResponseDTO is used when returning a lottery entry
+type LotteryEntryResponseDTO struct {
+	ID          uuid.UUID  `json:"id"`
+	RoundID     uuid.UUID  `json:"round_id"`
+	VariantID   uuid.UUID  `json:"variant_id"`
+	CustomerID  uuid.UUID  `json:"customer_id"`
+	Quantity    int        `json:"quantity"`
+	Status      string     `json:"status"`
+	Ticket      string     `json:"ticket,omitempty"`
+	Rank        int        `json:"rank,omitempty"`
+	WonQuantity int        `json:"won_quantity"`
+	OrderID     *uuid.UUID `json:"order_id,omitempty"`
+	CreatedAt   string     `json:"created_at"`
+}
+
+// LotteryResponseDTO describes a lottery round's draw so it can be verified: every ticket is the hex SHA-256
+// of "<draw_seed>:<entry id>", entries are ranked by ascending ticket, and the SHA-256 of draw_seed must
+// equal the draw_commitment published before entries opened
+type LotteryResponseDTO struct {
+	RoundID        uuid.UUID                 `json:"round_id"`
+	StartDate      time.Time                 `json:"start_date"`
+	EndDate        time.Time                 `json:"end_date"`
+	DrawCommitment string                    `json:"draw_commitment"`
+	DrawSeed       string                    `json:"draw_seed,omitempty"` // Revealed once the round is drawn
+	DrawnAt        *time.Time                `json:"drawn_at,omitempty"`
+	Entries        []LotteryEntryResponseDTO `json:"entries"`
+}
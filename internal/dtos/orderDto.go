@@ -33,18 +33,40 @@ type OrderUpdateDTO struct {
 	PaymentSource   string  `json:"payment_source" validate:"required"`
 }
 
+// OrderCancelDTO is used when cancelling an order
+type OrderCancelDTO struct {
+	Reason string `json:"reason"`
+}
+
+// OrderDiscountDTO is used when returning what a discount code took off an order line
+type OrderDiscountDTO struct {
+	OrderDetailID uuid.UUID `json:"order_detail_id"`
+	VariantID     uuid.UUID `json:"variant_id"`
+	Code          string    `json:"code"`
+	Amount        float64   `json:"amount"`
+}
+
 // OrderResponseDTO is used when returning an order response
 type OrderResponseDTO struct {
-	ID              uuid.UUID      `json:"id"`
-	CustomerID      uuid.UUID      `json:"customer_id"`
-	RoundID         uuid.UUID      `json:"round_id"`
-	OrderDate       time.Time      `json:"order_date"`
-	Status          string         `json:"status"`
-	Code            string         `json:"code"`
-	TotalPrice      float64        `json:"total_price"`
-	DeliveryAddress string         `json:"delivery_address"`
-	PaymentSource   string         `json:"payment_source"`
-	CreatedAt       string         `json:"created_at"`
-	UpdatedAt       string         `json:"updated_at"`
-	Items           []OrderItemDTO `json:"items"` // Add items to response DTO
+	ID              uuid.UUID           `json:"id"`
+	CustomerID      uuid.UUID           `json:"customer_id"`
+	RoundID         uuid.UUID           `json:"round_id"`
+	OrderDate       time.Time           `json:"order_date"`
+	Status          string              `json:"status"`
+	Code            string              `json:"code"`
+	TotalPrice      float64             `json:"total_price"`
+	NetAmount       float64             `json:"net_amount"` // TotalPrice before VAT
+	TaxAmount       float64             `json:"tax_amount"` // VAT included in TotalPrice
+	DiscountAmount  float64             `json:"discount_amount"`
+	FreeShipping    bool                `json:"free_shipping"`
+	Discounts       []OrderDiscountDTO  `json:"discounts,omitempty"` // What each discount code took off each line
+	DeliveryAddress string              `json:"delivery_address"`
+	AddressID       *uuid.UUID          `json:"address_id,omitempty"`
+	ShippingAddress *PostalAddressDTO   `json:"shipping_address,omitempty"` // The address book entry as it was when the order was placed
+	PaymentSource   string              `json:"payment_source"`
+	PaymentDueAt    *time.Time          `json:"payment_due_at,omitempty"` // Pending orders are cancelled when this passes
+	CreatedAt       string              `json:"created_at"`
+	UpdatedAt       string              `json:"updated_at"`
+	Items           []OrderItemDTO      `json:"items"`             // Add items to response DTO
+	Payment         *PaymentResponseDTO `json:"payment,omitempty"` // Payment the customer has to complete, when the order was just placed
 }
@@ -1,10 +1,14 @@
 package dtos
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // OrderHistoryCreateDTO เป็นโครงสร้างข้อมูลที่ใช้สำหรับการสร้าง OrderHistory ใหม่
 type OrderHistoryCreateDTO struct {
-	OrderID     uint      `json:"order_id" validate:"required"`
+	OrderID     uuid.UUID `json:"order_id" validate:"required"`
 	Status      string    `json:"status" validate:"required"`
 	ChangedAt   time.Time `json:"changed_at" validate:"required"`
 	Description string    `json:"description" validate:"required"`
@@ -20,7 +24,7 @@ type OrderHistoryUpdateDTO struct {
 // OrderHistoryResponseDTO เป็นโครงสร้างข้อมูลที่ใช้สำหรับการตอบกลับข้อมูล OrderHistory
 type OrderHistoryResponseDTO struct {
 	ID          uint      `json:"id"`
-	OrderID     uint      `json:"order_id"`
+	OrderID     uuid.UUID `json:"order_id"`
 	Status      string    `json:"status"`
 	ChangedAt   time.Time `json:"changed_at"`
 	Description string    `json:"description"`
@@ -0,0 +1,57 @@
+package dtos
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// PaymentRefundDTO is used when refunding a captured payment
+type PaymentRefundDTO struct {
+	Amount float64 `json:"amount" validate:"gte=0"` // 0 refunds everything that is left
+	Reason string  `json:"reason" validate:"max=500"`
+}
+
+// PaymentSimulateDTO is used to play the customer of a mock payment
+type PaymentSimulateDTO struct {
+	Outcome string `json:"outcome" validate:"required,oneof=succeed fail"`
+}
+
+// PaymentRefundResponseDTO is used when returning a refund of a payment
+type PaymentRefundResponseDTO struct {
+	ID               uuid.UUID `json:"id"`
+	ProviderRefundID string    `json:"provider_refund_id"`
+	Amount           float64   `json:"amount"`
+	Reason           string    `json:"reason,omitempty"`
+	CreatedAt        string    `json:"created_at"`
+}
+
+// PaymentResponseDTO is used when returning a payment
+type PaymentResponseDTO struct {
+	ID               uuid.UUID                  `json:"id"`
+	OrderID          uuid.UUID                  `json:"order_id"`
+	Provider         string                     `json:"provider"`
+	ProviderIntentID string                     `json:"provider_intent_id"`
+	Amount           float64                    `json:"amount"`
+	Currency         string                     `json:"currency"`
+	Status           string                     `json:"status"`
+	CapturedAmount   float64                    `json:"captured_amount"`
+	RefundedAmount   float64                    `json:"refunded_amount"`
+	NextAction       json.RawMessage            `json:"next_action,omitempty"` // What the customer has to do to pay
+	FailureReason    string                     `json:"failure_reason,omitempty"`
+	CapturedAt       string                     `json:"captured_at,omitempty"`
+	Refunds          []PaymentRefundResponseDTO `json:"refunds"`
+	CreatedAt        string                     `json:"created_at"`
+	UpdatedAt        string                     `json:"updated_at"`
+}
+
+// PaymentQRResponseDTO is used when returning the PromptPay QR code of an order
+type PaymentQRResponseDTO struct {
+	PaymentID   uuid.UUID `json:"payment_id"`
+	OrderID     uuid.UUID `json:"order_id"`
+	Amount      float64   `json:"amount"` // Amount the transfer is expected to be
+	Currency    string    `json:"currency"`
+	PromptPayID string    `json:"promptpay_id"`
+	Payload     string    `json:"payload"` // EMVCo payload encoded in the QR code
+	Image       string    `json:"image"`   // PNG of the QR code as a data URL
+}
@@ -13,6 +13,7 @@ type ProductCreateDTO struct {
 	Stock       int       `json:"stock" validate:"required"`
 	Price       float64   `json:"price" validate:"required"`     // Add Price field
 	ImageURL    string    `json:"image_url" validate:"required"` // Add ImageURL field
+	TaxCategory string    `json:"tax_category"`                  // standard (default), zero_rated or exempt
 }
 
 // ProductUpdateDTO เป็นโครงสร้างข้อมูลที่ใช้สำหรับการอัปเดต Product
@@ -26,6 +27,7 @@ type ProductUpdateDTO struct {
 	Stock       int       `json:"stock" validate:"required"`
 	Price       float64   `json:"price" validate:"required"`     // Add Price field
 	ImageURL    string    `json:"image_url" validate:"required"` // Add ImageURL field
+	TaxCategory string    `json:"tax_category"`                  // standard (default), zero_rated or exempt
 }
 
 // ProductResponseDTO เป็นโครงสร้างข้อมูลที่ใช้สำหรับการตอบกลับข้อมูล Product
@@ -40,6 +42,7 @@ type ProductResponseDTO struct {
 	Stock       int       `json:"stock"`
 	Price       float64   `json:"price"`     // Add Price field
 	ImageURL    string    `json:"image_url"` // Add ImageURL field
+	TaxCategory string    `json:"tax_category"`
 	CreatedAt   string    `json:"created_at"`
 	UpdatedAt   string    `json:"updated_at"`
 }
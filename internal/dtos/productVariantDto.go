@@ -3,25 +3,31 @@ package dtos
 import "github.com/google/uuid"
 
 type ProductVariantCreateDTO struct {
-	ProductID uuid.UUID `json:"product_id" validate:"required"`
-	SKUCode   string    `json:"sku_code" validate:"required"`
-	Price     float64   `json:"price" validate:"required"`
-	ImageURL  string    `json:"image_url"`
+	ProductID       uuid.UUID `json:"product_id" validate:"required"`
+	SKUCode         string    `json:"sku_code" validate:"required"`
+	Price           float64   `json:"price" validate:"required"`
+	ImageURL        string    `json:"image_url"`
+	ReorderPoint    int       `json:"reorder_point" validate:"min=0"`
+	ReorderQuantity int       `json:"reorder_quantity" validate:"min=0"`
 }
 
 type ProductVariantUpdateDTO struct {
-	ProductID uuid.UUID `json:"product_id" validate:"required"`
-	SKUCode   string    `json:"sku_code" validate:"required"`
-	Price     float64   `json:"price" validate:"required"`
-	ImageURL  string    `json:"image_url"`
+	ProductID       uuid.UUID `json:"product_id" validate:"required"`
+	SKUCode         string    `json:"sku_code" validate:"required"`
+	Price           float64   `json:"price" validate:"required"`
+	ImageURL        string    `json:"image_url"`
+	ReorderPoint    *int      `json:"reorder_point" validate:"omitempty,min=0"`    // Leave out to keep the current reorder point
+	ReorderQuantity *int      `json:"reorder_quantity" validate:"omitempty,min=0"` // Leave out to keep the current reorder quantity
 }
 
 type ProductVariantResponseDTO struct {
-	ID        uuid.UUID `json:"id"`
-	ProductID uuid.UUID `json:"product_id"`
-	SKUCode   string    `json:"sku_code"`
-	Price     float64   `json:"price"`
-	ImageURL  string    `json:"image_url"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
+	ID              uuid.UUID `json:"id"`
+	ProductID       uuid.UUID `json:"product_id"`
+	SKUCode         string    `json:"sku_code"`
+	Price           float64   `json:"price"`
+	ImageURL        string    `json:"image_url"`
+	CreatedAt       string    `json:"created_at"`
+	UpdatedAt       string    `json:"updated_at"`
+	ReorderPoint    int       `json:"reorder_point"`
+	ReorderQuantity int       `json:"reorder_quantity"`
 }
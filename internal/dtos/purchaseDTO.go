@@ -13,8 +13,10 @@ type PurchaseCreateDTO struct {
 	Code            string            `json:"code"`
 	TotalPrice      float64           `json:"total_price"`
 	DeliveryAddress string            `json:"delivery_address"`
+	AddressID       *uuid.UUID        `json:"address_id"` // Address book entry to ship to; the customer's default address when neither this nor delivery_address is given
 	PaymentSource   string            `json:"payment_source"`
 	Items           []PurchaseItemDTO `json:"items"`
+	DiscountCodes   []string          `json:"discount_codes"` // Codes to apply; several only if every one of them is stackable
 }
 
 type PurchaseItemDTO struct {
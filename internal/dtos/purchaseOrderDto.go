@@ -0,0 +1,106 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SupplierCreateDTO is used when creating a new supplier
+type SupplierCreateDTO struct {
+	StoreID     uuid.UUID `json:"store_id" validate:"required"`
+	Name        string    `json:"name" validate:"required"`
+	ContactName string    `json:"contact_name"`
+	Email       string    `json:"email" validate:"omitempty,email"`
+	Phone       string    `json:"phone"`
+}
+
+// SupplierUpdateDTO is used when updating an existing supplier
+type SupplierUpdateDTO struct {
+	Name        string `json:"name"` // Leave empty to keep the current name
+	ContactName string `json:"contact_name"`
+	Email       string `json:"email" validate:"omitempty,email"`
+	Phone       string `json:"phone"`
+}
+
+// SupplierResponseDTO is used when returning a supplier response
+type SupplierResponseDTO struct {
+	ID          uuid.UUID `json:"id"`
+	StoreID     uuid.UUID `json:"store_id"`
+	Name        string    `json:"name"`
+	ContactName string    `json:"contact_name"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone"`
+	CreatedAt   string    `json:"created_at"`
+	UpdatedAt   string    `json:"updated_at"`
+}
+
+// PurchaseOrderLineDTO is a single variant line on a new purchase order
+type PurchaseOrderLineDTO struct {
+	VariantID uuid.UUID `json:"variant_id" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"required,gt=0"`
+	UnitCost  float64   `json:"unit_cost" validate:"gte=0"`
+}
+
+// PurchaseOrderCreateDTO is used when drafting a new purchase order
+type PurchaseOrderCreateDTO struct {
+	StoreID    uuid.UUID              `json:"store_id" validate:"required"`
+	SupplierID uuid.UUID              `json:"supplier_id" validate:"required"`
+	LocationID *uuid.UUID             `json:"location_id"`
+	ExpectedAt *time.Time             `json:"expected_at"`
+	Note       string                 `json:"note"`
+	Lines      []PurchaseOrderLineDTO `json:"lines" validate:"required,min=1,dive"`
+}
+
+// PurchaseOrderReceiveLineDTO is the quantity of a purchase order line that arrived
+type PurchaseOrderReceiveLineDTO struct {
+	LineID   uuid.UUID `json:"line_id" validate:"required"`
+	Quantity int       `json:"quantity" validate:"required,gt=0"`
+}
+
+// PurchaseOrderReceiveDTO is used when receiving goods against a purchase order
+type PurchaseOrderReceiveDTO struct {
+	Lines []PurchaseOrderReceiveLineDTO `json:"lines" validate:"required,min=1,dive"`
+}
+
+// PurchaseOrderLineResponseDTO is used when returning a purchase order line
+type PurchaseOrderLineResponseDTO struct {
+	ID               uuid.UUID `json:"id"`
+	VariantID        uuid.UUID `json:"variant_id"`
+	QuantityOrdered  int       `json:"quantity_ordered"`
+	QuantityReceived int       `json:"quantity_received"`
+	UnitCost         float64   `json:"unit_cost"`
+}
+
+// PurchaseOrderResponseDTO is used when returning a purchase order response
+type PurchaseOrderResponseDTO struct {
+	ID         uuid.UUID                      `json:"id"`
+	StoreID    uuid.UUID                      `json:"store_id"`
+	SupplierID uuid.UUID                      `json:"supplier_id"`
+	LocationID *uuid.UUID                     `json:"location_id"`
+	Code       string                         `json:"code"`
+	Status     string                         `json:"status"`
+	ExpectedAt *time.Time                     `json:"expected_at"`
+	SentAt     *time.Time                     `json:"sent_at"`
+	ClosedAt   *time.Time                     `json:"closed_at"`
+	Note       string                         `json:"note"`
+	TotalCost  float64                        `json:"total_cost"`
+	Lines      []PurchaseOrderLineResponseDTO `json:"lines"`
+	CreatedAt  string                         `json:"created_at"`
+	UpdatedAt  string                         `json:"updated_at"`
+}
+
+// StockMovementResponseDTO is used when returning a stock movement record
+type StockMovementResponseDTO struct {
+	ID            uuid.UUID  `json:"id"`
+	VariantID     uuid.UUID  `json:"variant_id"`
+	ProductID     uuid.UUID  `json:"product_id"`
+	LocationID    *uuid.UUID `json:"location_id"`
+	Quantity      int        `json:"quantity"`
+	Reason        string     `json:"reason"`
+	ReasonCode    string     `json:"reason_code"`
+	ReferenceType string     `json:"reference_type"`
+	ReferenceID   *uuid.UUID `json:"reference_id"`
+	UnitCost      float64    `json:"unit_cost"`
+	CreatedAt     string     `json:"created_at"`
+}
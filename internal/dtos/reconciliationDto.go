@@ -0,0 +1,48 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatementLineMatchDTO is used when staff match a statement line to the order it paid
+type StatementLineMatchDTO struct {
+	OrderID uuid.UUID `json:"order_id" validate:"required"`
+	Note    string    `json:"note" validate:"max=1000"`
+}
+
+// StatementLineIgnoreDTO is used when staff dismiss a statement line that did not pay an order
+type StatementLineIgnoreDTO struct {
+	Note string `json:"note" validate:"required,max=1000"`
+}
+
+// StatementLineResponseDTO is used when returning a bank statement line and how it was reconciled
+type StatementLineResponseDTO struct {
+	ID           uuid.UUID   `json:"id"`
+	StatementID  uuid.UUID   `json:"statement_id"`
+	LineNumber   int         `json:"line_number"`
+	TransactedAt time.Time   `json:"transacted_at"`
+	Amount       float64     `json:"amount"`
+	Reference    string      `json:"reference"`
+	Description  string      `json:"description"`
+	Status       string      `json:"status"`
+	OrderID      *uuid.UUID  `json:"order_id"`
+	Candidates   []uuid.UUID `json:"candidates"` // Orders the line could pay, for review
+	Note         string      `json:"note"`
+	ResolvedAt   *time.Time  `json:"resolved_at"`
+}
+
+// BankStatementResponseDTO is used when returning an uploaded bank statement with its reconciliation summary
+type BankStatementResponseDTO struct {
+	ID             uuid.UUID                  `json:"id"`
+	StoreID        *uuid.UUID                 `json:"store_id"`
+	FileName       string                     `json:"file_name"`
+	TotalLines     int                        `json:"total_lines"`
+	MatchedLines   int                        `json:"matched_lines"`
+	ReviewLines    int                        `json:"review_lines"`
+	UnmatchedLines int                        `json:"unmatched_lines"`
+	IgnoredLines   int                        `json:"ignored_lines"`
+	Lines          []StatementLineResponseDTO `json:"lines,omitempty"`
+	CreatedAt      string                     `json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package dtos
+
+import "github.com/google/uuid"
+
+// SalesRoundAllocationDTO allocates a quantity of one variant to a sales round
+type SalesRoundAllocationDTO struct {
+	VariantID       uuid.UUID  `json:"variant_id" validate:"required"`
+	Quantity        int        `json:"quantity" validate:"required,gt=0"`
+	QuantityLimit   int        `json:"quantity_limit" validate:"required,gt=0"`
+	LocationID      *uuid.UUID `json:"location_id"`      // Inventory location the allocation is drawn from
+	RoundPrice      *float64   `json:"round_price"`      // Sale price in the round; with discount_percent, replaces the detail's sale price
+	DiscountPercent *float64   `json:"discount_percent"` // Percent off the variant's price in the round, instead of round_price
+}
+
+// SalesRoundCategoryAllocationDTO allocates a percentage of the available stock of every variant in a category
+type SalesRoundCategoryAllocationDTO struct {
+	CategoryID    uuid.UUID  `json:"category_id" validate:"required"`
+	Percent       float64    `json:"percent" validate:"required,gt=0,lte=100"`
+	QuantityLimit int        `json:"quantity_limit" validate:"required,gt=0"`
+	LocationID    *uuid.UUID `json:"location_id"` // Take the percentage of the stock at this location instead of the product stock, which is split across the product's variants
+}
+
+// SalesRoundAllocationRequestDTO is used when allocating many variants to a sales round at once
+type SalesRoundAllocationRequestDTO struct {
+	Allocations []SalesRoundAllocationDTO         `json:"allocations" validate:"dive"`
+	Categories  []SalesRoundCategoryAllocationDTO `json:"categories" validate:"dive"`
+	DryRun      bool                              `json:"dry_run"` // Return the resulting allocation table without saving it
+}
+
+// SalesRoundAllocationLineDTO is one row of the resulting allocation table
+type SalesRoundAllocationLineDTO struct {
+	VariantID          uuid.UUID  `json:"variant_id"`
+	SKUCode            string     `json:"sku_code"`
+	ProductName        string     `json:"product_name"`
+	LocationID         *uuid.UUID `json:"location_id"`
+	QuantityAllocated  int        `json:"quantity_allocated"` // Allocated by this request
+	TotalQuantity      int        `json:"total_quantity"`     // Allocated to the round in total
+	QuantityLimit      int        `json:"quantity_limit"`
+	ProductStockBefore int        `json:"product_stock_before"`
+	ProductStockAfter  int        `json:"product_stock_after"`
+}
+
+// SalesRoundAllocationResultDTO is the allocation table returned by a bulk allocation
+type SalesRoundAllocationResultDTO struct {
+	RoundID uuid.UUID                     `json:"round_id"`
+	DryRun  bool                          `json:"dry_run"`
+	Lines   []SalesRoundAllocationLineDTO `json:"lines"`
+}
@@ -4,32 +4,41 @@ import "github.com/google/uuid"
 
 // SalesRoundDetailCreateDTO is the structure used for creating a new SalesRoundDetail
 type SalesRoundDetailCreateDTO struct {
-	RoundID       uuid.UUID `json:"round_id" validate:"required"`
-	VariantID     uuid.UUID `json:"variant_id" validate:"required"`
-	Quantity      int       `json:"quantity" validate:"required"`
-	QuantityLimit int       `json:"quantity_limit" validate:"required"`
-	ProductStock  int       `json:"product_stock" validate:"required"` // New field for product stock
+	RoundID         uuid.UUID  `json:"round_id" validate:"required"`
+	VariantID       uuid.UUID  `json:"variant_id" validate:"required"`
+	Quantity        int        `json:"quantity" validate:"required"`
+	QuantityLimit   int        `json:"quantity_limit" validate:"required"`
+	ProductStock    int        `json:"product_stock" validate:"required"` // New field for product stock
+	LocationID      *uuid.UUID `json:"location_id"`                       // Inventory location the allocation is drawn from
+	RoundPrice      *float64   `json:"round_price"`                       // Price in this round instead of the variant's price
+	DiscountPercent *float64   `json:"discount_percent"`                  // Percent off the variant's price in this round, instead of round_price
 }
 
 // SalesRoundDetailUpdateDTO is the structure used for updating an existing SalesRoundDetail
 type SalesRoundDetailUpdateDTO struct {
-	RoundID       uuid.UUID `json:"round_id" validate:"required"`
-	VariantID     uuid.UUID `json:"variant_id" validate:"required"`
-	Quantity      int       `json:"quantity" validate:"required"`
-	QuantityLimit int       `json:"quantity_limit" validate:"required"`
-	Remaining     int       `json:"remaining" validate:"required"`
-	ProductStock  int       `json:"product_stock" validate:"required"` // New field for product stock
+	RoundID         uuid.UUID `json:"round_id" validate:"required"`
+	VariantID       uuid.UUID `json:"variant_id" validate:"required"`
+	Quantity        int       `json:"quantity" validate:"required"`
+	QuantityLimit   int       `json:"quantity_limit" validate:"required"`
+	Remaining       int       `json:"remaining" validate:"required"`
+	ProductStock    int       `json:"product_stock" validate:"required"` // New field for product stock
+	RoundPrice      *float64  `json:"round_price"`                       // Replaces the sale price; leave both out to keep it
+	DiscountPercent *float64  `json:"discount_percent"`                  // Replaces the sale price, instead of round_price
+	ClearSalePrice  bool      `json:"clear_sale_price"`                  // Sell at the variant's price again
 }
 
 // SalesRoundDetailResponseDTO is the structure used for responding with SalesRoundDetail data
 type SalesRoundDetailResponseDTO struct {
-	ID            uuid.UUID `json:"id"`
-	RoundID       uuid.UUID `json:"round_id"`
-	VariantID     uuid.UUID `json:"variant_id"`
-	Quantity      int       `json:"quantity"`
-	Remaining     int       `json:"remaining"`
-	QuantityLimit int       `json:"quantity_limit"`
-	ProductStock  int       `json:"product_stock"` // New field for product stock
-	CreatedAt     string    `json:"created_at"`
-	UpdatedAt     string    `json:"updated_at"`
+	ID              uuid.UUID  `json:"id"`
+	RoundID         uuid.UUID  `json:"round_id"`
+	VariantID       uuid.UUID  `json:"variant_id"`
+	Quantity        int        `json:"quantity"`
+	Remaining       int        `json:"remaining"`
+	QuantityLimit   int        `json:"quantity_limit"`
+	ProductStock    int        `json:"product_stock"` // New field for product stock
+	LocationID      *uuid.UUID `json:"location_id"`
+	RoundPrice      *float64   `json:"round_price"`
+	DiscountPercent *float64   `json:"discount_percent"`
+	CreatedAt       string     `json:"created_at"`
+	UpdatedAt       string     `json:"updated_at"`
 }
@@ -7,24 +7,75 @@ import (
 
 // SalesRoundCreateDTO is used for creating a new sales round
 type SalesRoundCreateDTO struct {
-	Name      string    `json:"name" validate:"required"`
-	StartDate time.Time `json:"start_date" validate:"required"`
-	EndDate   time.Time `json:"end_date" validate:"required"`
+	Name                 string    `json:"name" validate:"required"`
+	StartDate            time.Time `json:"start_date" validate:"required"`
+	EndDate              time.Time `json:"end_date" validate:"required"`
+	Mode                 string    `json:"mode"`                   // fcfs (default) or lottery
+	PaymentWindowMinutes int       `json:"payment_window_minutes"` // How long customers have to pay an order; 0 means 24 hours
+	AdmissionRate        int       `json:"admission_rate"`         // Buyers admitted per second through the waiting room; 0 disables it
 }
 
 // SalesRoundUpdateDTO is used for updating an existing sales round
 type SalesRoundUpdateDTO struct {
-	Name      string    `json:"name" validate:"required"`
-	StartDate time.Time `json:"start_date" validate:"required"`
-	EndDate   time.Time `json:"end_date" validate:"required"`
+	Name                 string    `json:"name" validate:"required"`
+	StartDate            time.Time `json:"start_date" validate:"required"`
+	EndDate              time.Time `json:"end_date" validate:"required"`
+	Mode                 string    `json:"mode"` // Leave empty to keep the current mode; cannot change once the round has started
+	PaymentWindowMinutes int       `json:"payment_window_minutes"`
+	AdmissionRate        int       `json:"admission_rate"`
+}
+
+// SalesRoundCloneDTO is used for cloning a sales round with its variant line-up
+type SalesRoundCloneDTO struct {
+	Name          string    `json:"name"` // Defaults to the name of the source round
+	StartDate     time.Time `json:"start_date" validate:"required"`
+	EndDate       time.Time `json:"end_date" validate:"required,gtfield=StartDate"`
+	QuantityScale float64   `json:"quantity_scale" validate:"gte=0"` // Multiplies every allocated quantity; defaults to 1
 }
 
 // SalesRoundResponseDTO is used for returning a sales round response
 type SalesRoundResponseDTO struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	StartDate time.Time `json:"start_date"`
-	EndDate   time.Time `json:"end_date"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	StartDate     time.Time  `json:"start_date"`
+	EndDate       time.Time  `json:"end_date"`
+	TemplateID    *uuid.UUID `json:"template_id"`
+	Mode          string     `json:"mode"`
+	AdmissionRate int        `json:"admission_rate"`
+	// Lottery rounds only
+	PaymentWindowMinutes int        `json:"payment_window_minutes,omitempty"`
+	DrawCommitment       string     `json:"draw_commitment,omitempty"`
+	DrawnAt              *time.Time `json:"drawn_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// SalesRoundVariantAnalyticsDTO is how one variant of a sales round sold
+type SalesRoundVariantAnalyticsDTO struct {
+	VariantID     uuid.UUID `json:"variant_id"`
+	SKUCode       string    `json:"sku_code"`
+	ProductName   string    `json:"product_name"`
+	RegularPrice  float64   `json:"regular_price"` // Variant's price now
+	SalePrice     float64   `json:"sale_price"`    // Price in the round now
+	Allocated     int       `json:"allocated"`
+	Left          int       `json:"left"` // Round quantity not sold yet
+	UnitsSold     int       `json:"units_sold"`
+	RegularSales  float64   `json:"regular_sales"`  // Units sold, at the regular price they had when ordered
+	Markdown      float64   `json:"markdown"`       // Taken off the regular price by the round's sale price
+	CodeDiscounts float64   `json:"code_discounts"` // Taken off by discount codes
+	Revenue       float64   `json:"revenue"`        // What customers paid, VAT included
+}
+
+// SalesRoundAnalyticsDTO is how a sales round sold, counting orders that were paid and not cancelled
+type SalesRoundAnalyticsDTO struct {
+	RoundID         uuid.UUID                       `json:"round_id"`
+	Name            string                          `json:"name"`
+	Orders          int                             `json:"orders"`
+	UnitsSold       int                             `json:"units_sold"`
+	RegularSales    float64                         `json:"regular_sales"`
+	Markdown        float64                         `json:"markdown"`
+	MarkdownPercent float64                         `json:"markdown_percent"` // Markdown as a percentage of regular sales
+	CodeDiscounts   float64                         `json:"code_discounts"`
+	Revenue         float64                         `json:"revenue"`
+	Variants        []SalesRoundVariantAnalyticsDTO `json:"variants"`
 }
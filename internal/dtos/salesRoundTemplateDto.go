@@ -0,0 +1,48 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SalesRoundTemplateLineDTO is a variant allocated to every round of a template
+type SalesRoundTemplateLineDTO struct {
+	VariantID       uuid.UUID  `json:"variant_id" validate:"required"`
+	Quantity        int        `json:"quantity" validate:"required,gt=0"`
+	QuantityLimit   int        `json:"quantity_limit" validate:"required,gt=0"`
+	LocationID      *uuid.UUID `json:"location_id"`
+	RoundPrice      *float64   `json:"round_price"`      // Price in every round instead of the variant's price
+	DiscountPercent *float64   `json:"discount_percent"` // Percent off the variant's price in every round, instead of round_price
+}
+
+// SalesRoundTemplateCreateDTO is used for creating or replacing a sales round template. The line-up is
+// given as lines or copied from an existing round with source_round_id.
+type SalesRoundTemplateCreateDTO struct {
+	Name            string                      `json:"name" validate:"required"`
+	Weekday         string                      `json:"weekday" validate:"required"`               // e.g. friday
+	StartTime       string                      `json:"start_time" validate:"required"`            // HH:MM, e.g. 20:00
+	DurationMinutes int                         `json:"duration_minutes" validate:"required,gt=0"` // e.g. 120
+	Timezone        string                      `json:"timezone"`                                  // Defaults to Asia/Bangkok
+	LeadDays        int                         `json:"lead_days" validate:"gte=0"`                // Defaults to 7
+	Active          *bool                       `json:"active"`                                    // Defaults to true
+	SourceRoundID   *uuid.UUID                  `json:"source_round_id"`                           // Copy the line-up of this round
+	Lines           []SalesRoundTemplateLineDTO `json:"lines" validate:"dive"`
+}
+
+// SalesRoundTemplateResponseDTO is used for returning a sales round template
+type SalesRoundTemplateResponseDTO struct {
+	ID                 uuid.UUID                   `json:"id"`
+	Name               string                      `json:"name"`
+	Weekday            string                      `json:"weekday"`
+	StartTime          string                      `json:"start_time"`
+	DurationMinutes    int                         `json:"duration_minutes"`
+	Timezone           string                      `json:"timezone"`
+	LeadDays           int                         `json:"lead_days"`
+	Active             bool                        `json:"active"`
+	LastMaterializedAt *time.Time                  `json:"last_materialized_at"`
+	NextOccurrences    []time.Time                 `json:"next_occurrences"` // Rounds that will be materialized next
+	Lines              []SalesRoundTemplateLineDTO `json:"lines"`
+	CreatedAt          string                      `json:"created_at"`
+	UpdatedAt          string                      `json:"updated_at"`
+}
@@ -0,0 +1,84 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShipmentItemDTO is the quantity of an order line in a shipment
+type ShipmentItemDTO struct {
+	OrderDetailID uuid.UUID `json:"order_detail_id" validate:"required"`
+	Quantity      int       `json:"quantity" validate:"required,gt=0"`
+}
+
+// ParcelDTO is a box to ship
+type ParcelDTO struct {
+	WeightGrams int     `json:"weight_grams" validate:"required,gt=0"`
+	LengthCm    float64 `json:"length_cm" validate:"gte=0"`
+	WidthCm     float64 `json:"width_cm" validate:"gte=0"`
+	HeightCm    float64 `json:"height_cm" validate:"gte=0"`
+}
+
+// ShipmentCreateDTO is used for shipping some or all of the lines of a paid order
+type ShipmentCreateDTO struct {
+	Carrier        string            `json:"carrier" validate:"required,max=100"`
+	TrackingNumber string            `json:"tracking_number" validate:"max=100"`
+	Note           string            `json:"note"`
+	ShippedAt      *time.Time        `json:"shipped_at"`                      // Defaults to now
+	Items          []ShipmentItemDTO `json:"items" validate:"omitempty,dive"` // Leave empty to ship everything not shipped yet
+	CreateLabel    bool              `json:"create_label"`                    // Book the shipment with the carrier, which issues the tracking number and label
+	Service        string            `json:"service" validate:"max=100"`      // Carrier service to book, e.g. express; the carrier's default when empty
+	Parcels        []ParcelDTO       `json:"parcels" validate:"required_if=CreateLabel true,omitempty,dive"`
+}
+
+// ShippingRateRequestDTO is used for quoting the rates of a carrier for the parcels of an order
+type ShippingRateRequestDTO struct {
+	Carrier string      `json:"carrier" validate:"required,max=100"`
+	Parcels []ParcelDTO `json:"parcels" validate:"required,min=1,dive"`
+}
+
+// ShippingRateResponseDTO is used when returning what a carrier service charges
+type ShippingRateResponseDTO struct {
+	Carrier       string  `json:"carrier"`
+	Service       string  `json:"service"`
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	EstimatedDays int     `json:"estimated_days"`
+}
+
+// ShipmentTrackingEventResponseDTO is used when returning a tracking event of a shipment
+type ShipmentTrackingEventResponseDTO struct {
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// ShipmentItemResponseDTO is used when returning an item of a shipment
+type ShipmentItemResponseDTO struct {
+	OrderDetailID uuid.UUID `json:"order_detail_id"`
+	VariantID     uuid.UUID `json:"variant_id"`
+	Quantity      int       `json:"quantity"`
+}
+
+// ShipmentResponseDTO is used when returning a shipment with its items
+type ShipmentResponseDTO struct {
+	ID             uuid.UUID                          `json:"id"`
+	OrderID        uuid.UUID                          `json:"order_id"`
+	Carrier        string                             `json:"carrier"`
+	TrackingNumber string                             `json:"tracking_number"`
+	Service        string                             `json:"service"`
+	ShippingCost   float64                            `json:"shipping_cost"`
+	Status         string                             `json:"status"`
+	Note           string                             `json:"note"`
+	LabelFormat    string                             `json:"label_format"` // Empty when no label was created through the carrier
+	ShippedAt      time.Time                          `json:"shipped_at"`
+	DeliveredAt    *time.Time                         `json:"delivered_at"`
+	LastTrackedAt  *time.Time                         `json:"last_tracked_at"`
+	Items          []ShipmentItemResponseDTO          `json:"items"`
+	TrackingEvents []ShipmentTrackingEventResponseDTO `json:"tracking_events,omitempty"`
+	CreatedAt      string                             `json:"created_at"`
+	UpdatedAt      string                             `json:"updated_at"`
+}
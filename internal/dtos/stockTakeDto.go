@@ -0,0 +1,78 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockTakeSessionCreateDTO is used when opening a new stock-take session
+type StockTakeSessionCreateDTO struct {
+	StoreID    uuid.UUID  `json:"store_id" validate:"required"`
+	LocationID *uuid.UUID `json:"location_id"`
+	Note       string     `json:"note"`
+}
+
+// StockTakeCountDTO is one counted variant, identified either by variant_id or by sku_code
+type StockTakeCountDTO struct {
+	VariantID       uuid.UUID `json:"variant_id"`
+	SKUCode         string    `json:"sku_code"`
+	CountedQuantity int       `json:"counted_quantity" validate:"gte=0"`
+	ReasonCode      string    `json:"reason_code"`
+}
+
+// StockTakeCountsDTO is used when submitting counts to an open session
+type StockTakeCountsDTO struct {
+	Counts []StockTakeCountDTO `json:"counts" validate:"required,dive"`
+}
+
+// StockTakeApproveDTO is used when approving a session; the default reason code applies to
+// variances whose count has no reason code of its own
+type StockTakeApproveDTO struct {
+	DefaultReasonCode string `json:"default_reason_code"`
+}
+
+// StockTakeCountResponseDTO is used when returning a counted variant
+type StockTakeCountResponseDTO struct {
+	ID              uuid.UUID `json:"id"`
+	VariantID       uuid.UUID `json:"variant_id"`
+	CountedQuantity int       `json:"counted_quantity"`
+	SystemQuantity  int       `json:"system_quantity"`
+	ReasonCode      string    `json:"reason_code"`
+}
+
+// StockTakeSessionResponseDTO is used when returning a stock-take session
+type StockTakeSessionResponseDTO struct {
+	ID         uuid.UUID                   `json:"id"`
+	StoreID    uuid.UUID                   `json:"store_id"`
+	LocationID *uuid.UUID                  `json:"location_id"`
+	Status     string                      `json:"status"`
+	Note       string                      `json:"note"`
+	ApprovedAt *time.Time                  `json:"approved_at"`
+	Counts     []StockTakeCountResponseDTO `json:"counts"`
+	CreatedAt  string                      `json:"created_at"`
+	UpdatedAt  string                      `json:"updated_at"`
+}
+
+// StockTakeVarianceLineDTO compares the counted quantity of a variant with the system stock. Store-wide sessions
+// compare whole products, whose stock their variants share.
+type StockTakeVarianceLineDTO struct {
+	ProductID       uuid.UUID `json:"product_id"`
+	VariantID       uuid.UUID `json:"variant_id"` // Nil for a product line of a store-wide session
+	SKUCode         string    `json:"sku_code"`   // Every counted SKU of a product line
+	ProductName     string    `json:"product_name"`
+	CountedQuantity int       `json:"counted_quantity"`
+	SystemQuantity  int       `json:"system_quantity"`
+	Variance        int       `json:"variance"`
+	VarianceValue   float64   `json:"variance_value"`
+	ReasonCode      string    `json:"reason_code"`
+}
+
+// StockTakeVarianceReportDTO is the variance report of a stock-take session
+type StockTakeVarianceReportDTO struct {
+	SessionID          uuid.UUID                  `json:"session_id"`
+	Status             string                     `json:"status"`
+	Lines              []StockTakeVarianceLineDTO `json:"lines"`
+	TotalVarianceUnits int                        `json:"total_variance_units"`
+	TotalVarianceValue float64                    `json:"total_variance_value"`
+}
@@ -3,18 +3,21 @@ package dtos
 import "github.com/google/uuid"
 
 type StoreCreateDTO struct {
-	StoreName string `json:"store_name" validate:"required"`
-	Location  string `json:"location"`
+	StoreName   string `json:"store_name" validate:"required"`
+	Location    string `json:"location"`
+	PromptPayID string `json:"promptpay_id"` // Mobile number, national or tax ID, or e-wallet ID
 }
 
 type StoreUpdateDTO struct {
-	StoreName string `json:"store_name"`
-	Location  string `json:"location"`
+	StoreName   string `json:"store_name"`
+	Location    string `json:"location"`
+	PromptPayID string `json:"promptpay_id"`
 }
 
 type StoreResponseDTO struct {
-	ID        uuid.UUID `json:"id"`
-	StoreName string    `json:"store_name"`
-	Location  string    `json:"location"`
-	CreatedAt string    `json:"created_at"`
+	ID          uuid.UUID `json:"id"`
+	StoreName   string    `json:"store_name"`
+	Location    string    `json:"location"`
+	PromptPayID string    `json:"promptpay_id"`
+	CreatedAt   string    `json:"created_at"`
 }
@@ -0,0 +1,55 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StoreTaxSettingsDTO is used when setting how a store taxes its sales
+type StoreTaxSettingsDTO struct {
+	LegalName        string             `json:"legal_name" validate:"max=255"`                  // Registered name printed on tax invoices; the store name when empty
+	TaxID            string             `json:"tax_id" validate:"omitempty,len=13,numeric"`     // 13-digit VAT registration number, needed to issue tax invoices
+	BranchCode       string             `json:"branch_code" validate:"omitempty,len=5,numeric"` // Defaults to 00000, the head office, with a tax ID
+	PricesExcludeTax bool               `json:"prices_exclude_tax"`                             // VAT is added to prices at checkout; by default prices include it
+	Rates            map[string]float64 `json:"rates"`                                          // Rate in percent by tax category, replacing the store's overrides; categories left out use the default
+}
+
+// StoreTaxSettingsResponseDTO is used when returning how a store taxes its sales
+type StoreTaxSettingsResponseDTO struct {
+	StoreID          uuid.UUID          `json:"store_id"`
+	LegalName        string             `json:"legal_name"`
+	TaxID            string             `json:"tax_id"`
+	BranchCode       string             `json:"branch_code"`
+	PricesExcludeTax bool               `json:"prices_exclude_tax"`
+	Rates            map[string]float64 `json:"rates"`     // Rate in effect for every tax category
+	Overrides        map[string]float64 `json:"overrides"` // Rates the store set instead of the defaults
+}
+
+// VATSummaryLineDTO is used when returning the sales of a store at one tax category and rate
+type VATSummaryLineDTO struct {
+	Category    string  `json:"category"`
+	Rate        float64 `json:"rate"`
+	Lines       int     `json:"lines"`
+	NetAmount   float64 `json:"net_amount"`
+	TaxAmount   float64 `json:"tax_amount"`
+	GrossAmount float64 `json:"gross_amount"`
+}
+
+// VATSummaryResponseDTO is used when returning a store's monthly VAT summary, with the sales split the way the
+// VAT return (P.P.30) asks for them. The totals are net of the credit notes issued in the month.
+type VATSummaryResponseDTO struct {
+	StoreID        uuid.UUID           `json:"store_id"`
+	Month          string              `json:"month"` // YYYY-MM
+	From           time.Time           `json:"from"`
+	To             time.Time           `json:"to"` // Exclusive
+	Orders         int                 `json:"orders"`
+	CreditNotes    int                 `json:"credit_notes"`
+	TaxableSales   float64             `json:"taxable_sales"`    // Net sales taxed at a positive rate
+	ZeroRatedSales float64             `json:"zero_rated_sales"` // Net sales taxed at 0%
+	ExemptSales    float64             `json:"exempt_sales"`
+	OutputTax      float64             `json:"output_tax"` // VAT charged on the sales, less VAT credited
+	GrossAmount    float64             `json:"gross_amount"`
+	Lines          []VATSummaryLineDTO `json:"lines"`
+	Credits        []VATSummaryLineDTO `json:"credits"` // Credit notes by category and rate, as positive amounts
+}
@@ -0,0 +1,26 @@
+package dtos
+
+import (
+	"github.com/google/uuid"
+)
+
+// WaitlistJoinDTO is used when a customer joins the waitlist of a sold-out round variant
+type WaitlistJoinDTO struct {
+	CustomerID uuid.UUID `json:"customer_id" validate:"required"`
+	VariantID  uuid.UUID `json:"variant_id" validate:"required"`
+	Quantity   int       `json:"quantity" validate:"required,gt=0"`
+}
+
+// WaitlistEntryResponseDTO is used when returning a waitlist entry
+type WaitlistEntryResponseDTO struct {
+	ID              uuid.UUID `json:"id"`
+	RoundID         uuid.UUID `json:"round_id"`
+	VariantID       uuid.UUID `json:"variant_id"`
+	CustomerID      uuid.UUID `json:"customer_id"`
+	Quantity        int       `json:"quantity"`
+	Status          string    `json:"status"`
+	OfferedQuantity int       `json:"offered_quantity"`
+	OfferedAt       string    `json:"offered_at,omitempty"`
+	OfferExpiresAt  string    `json:"offer_expires_at,omitempty"`
+	CreatedAt       string    `json:"created_at"`
+}
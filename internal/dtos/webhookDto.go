@@ -0,0 +1,55 @@
+package dtos
+
+import (
+	"github.com/google/uuid"
+)
+
+// WebhookSubscriptionCreateDTO is used when subscribing a URL to the events of a store
+type WebhookSubscriptionCreateDTO struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`       // https on a public address
+	Secret      string   `json:"secret" validate:"omitempty,min=16,max=255"` // Generated when empty
+	EventTypes  []string `json:"event_types" validate:"required,min=1,dive,required"`
+	Description string   `json:"description" validate:"max=255"`
+}
+
+// WebhookSubscriptionUpdateDTO is used when changing a webhook subscription; omitted fields are left alone
+type WebhookSubscriptionUpdateDTO struct {
+	URL          *string  `json:"url" validate:"omitempty,url,max=2048"` // https on a public address
+	EventTypes   []string `json:"event_types" validate:"omitempty,min=1,dive,required"`
+	Description  *string  `json:"description" validate:"omitempty,max=255"`
+	Active       *bool    `json:"active"`
+	RotateSecret bool     `json:"rotate_secret"` // Replace the secret with a new generated one
+}
+
+// WebhookSubscriptionResponseDTO is used when returning a webhook subscription. The secret is only returned
+// when it is created or rotated.
+type WebhookSubscriptionResponseDTO struct {
+	ID          uuid.UUID `json:"id"`
+	StoreID     uuid.UUID `json:"store_id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   string    `json:"created_at"`
+	UpdatedAt   string    `json:"updated_at"`
+}
+
+// WebhookDeliveryResponseDTO is used when returning an entry of the webhook delivery log
+type WebhookDeliveryResponseDTO struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	EventID        uuid.UUID  `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseCode   int        `json:"response_code"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  string     `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  string     `json:"last_attempt_at,omitempty"`
+	DeliveredAt    string     `json:"delivered_at,omitempty"`
+	ReplayOfID     *uuid.UUID `json:"replay_of_id,omitempty"`
+	Payload        string     `json:"payload"`
+	CreatedAt      string     `json:"created_at"`
+}
@@ -0,0 +1,116 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+// Envelope is how a domain event is published. Delivery is at least once, so consumers should skip IDs they
+// have already seen.
+type Envelope struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// NewEnvelope wraps an outbox event for publishing
+func NewEnvelope(event models.OutboxEvent) Envelope {
+	return Envelope{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.CreatedAt,
+		Payload:       json.RawMessage(event.Payload),
+	}
+}
+
+// Sink publishes domain events somewhere outside the database. An error makes the relay retry the event.
+type Sink interface {
+	Publish(envelope Envelope) error
+}
+
+// LogSink writes events to the application log
+type LogSink struct{}
+
+func (LogSink) Publish(envelope Envelope) error {
+	log.Printf("[event] %s %s/%s: %s", envelope.Type, envelope.AggregateType, envelope.AggregateID, envelope.Payload)
+	return nil
+}
+
+// FileSink appends events to a file, one JSON object per line
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Publish(envelope Envelope) error {
+	line, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	// The event only counts as published once it is on disk
+	return s.file.Sync()
+}
+
+// HTTPSink posts every event as JSON to a URL; any status other than 2xx is a failure
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSink creates an HTTPSink with a 10 second timeout
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *HTTPSink) Publish(envelope Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", envelope.ID.String())
+	req.Header.Set("X-Event-Type", envelope.Type)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", s.URL, resp.Status)
+	}
+	return nil
+}
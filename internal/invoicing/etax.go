@@ -0,0 +1,271 @@
+// Package invoicing renders issued tax invoices and credit notes: as the XML of the ETDA e-Tax invoice standard
+// (ขมธอ. 3-2560) to submit to the Revenue Department, and as a printable HTML page for the buyer.
+package invoicing
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+)
+
+const (
+	etdaGuideline = "ER3-2560" // Version of the ETDA guideline the XML follows
+	isoDateTime   = "2006-01-02T15:04:05"
+	unitCode      = "EA" // UN/ECE unit of the quantities, each
+)
+
+// Names of the documents, as printed on them
+var documentNames = map[string]string{
+	models.InvoiceTaxInvoiceReceipt: "ใบเสร็จรับเงิน/ใบกำกับภาษี",
+	models.InvoiceCreditNote:        "ใบลดหนี้",
+}
+
+// DocumentName returns the name of an invoice's document type, as printed on it
+func DocumentName(invoice models.Invoice) string {
+	return documentNames[invoice.Type]
+}
+
+// amount is a monetary amount, written with two decimals
+type amount float64
+
+func (a amount) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatFloat(float64(a), 'f', 2, 64)), nil
+}
+
+type schemedID struct {
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type guidelineID struct {
+	SchemeAgencyID  string `xml:"schemeAgencyID,attr"`
+	SchemeVersionID string `xml:"schemeVersionID,attr"`
+	Value           string `xml:",chardata"`
+}
+
+type documentContext struct {
+	GuidelineID guidelineID `xml:"ram:GuidelineSpecifiedDocumentContextParameter>ram:ID"`
+}
+
+type exchangedDocument struct {
+	ID               string `xml:"ram:ID"`
+	Name             string `xml:"ram:Name"`
+	TypeCode         string `xml:"ram:TypeCode"`
+	IssueDateTime    string `xml:"ram:IssueDateTime"`
+	Purpose          string `xml:"ram:Purpose,omitempty"`
+	PurposeCode      string `xml:"ram:PurposeCode,omitempty"`
+	CreationDateTime string `xml:"ram:CreationDateTime"`
+}
+
+type postalAddress struct {
+	PostcodeCode string    `xml:"ram:PostcodeCode,omitempty"`
+	LineOne      string    `xml:"ram:LineOne"`
+	CountryID    schemedID `xml:"ram:CountryID"`
+}
+
+type tradeContact struct {
+	Email string `xml:"ram:EmailURIUniversalCommunication>ram:URIID"`
+}
+
+type tradeParty struct {
+	Name            string        `xml:"ram:Name"`
+	TaxRegistration schemedID     `xml:"ram:SpecifiedTaxRegistration>ram:ID"`
+	Contact         *tradeContact `xml:"ram:DefinedTradeContact,omitempty"`
+	Address         postalAddress `xml:"ram:PostalTradeAddress"`
+}
+
+type referencedDocument struct {
+	IssuerAssignedID  string `xml:"ram:IssuerAssignedID"`
+	IssueDateTime     string `xml:"ram:IssueDateTime"`
+	ReferenceTypeCode string `xml:"ram:ReferenceTypeCode"`
+}
+
+type tradeAgreement struct {
+	Seller     tradeParty          `xml:"ram:SellerTradeParty"`
+	Buyer      tradeParty          `xml:"ram:BuyerTradeParty"`
+	Referenced *referencedDocument `xml:"ram:AdditionalReferencedDocument,omitempty"`
+}
+
+type tradeTax struct {
+	TypeCode         string `xml:"ram:TypeCode"`
+	CalculatedRate   amount `xml:"ram:CalculatedRate"`
+	BasisAmount      amount `xml:"ram:BasisAmount"`
+	CalculatedAmount amount `xml:"ram:CalculatedAmount"`
+}
+
+type monetarySummation struct {
+	OriginalInformationAmount   *amount `xml:"ram:OriginalInformationAmount,omitempty"`
+	LineTotalAmount             amount  `xml:"ram:LineTotalAmount"`
+	DifferenceInformationAmount *amount `xml:"ram:DifferenceInformationAmount,omitempty"`
+	TaxBasisTotalAmount         amount  `xml:"ram:TaxBasisTotalAmount"`
+	TaxTotalAmount              amount  `xml:"ram:TaxTotalAmount"`
+	GrandTotalAmount            amount  `xml:"ram:GrandTotalAmount"`
+}
+
+type tradeSettlement struct {
+	CurrencyCode schemedID         `xml:"ram:InvoiceCurrencyCode"`
+	Taxes        []tradeTax        `xml:"ram:ApplicableTradeTax"`
+	Summation    monetarySummation `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"`
+}
+
+type quantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    int    `xml:",chardata"`
+}
+
+type lineSummation struct {
+	TaxTotalAmount                   amount `xml:"ram:TaxTotalAmount"`
+	NetLineTotalAmount               amount `xml:"ram:NetLineTotalAmount"`
+	NetIncludingTaxesLineTotalAmount amount `xml:"ram:NetIncludingTaxesLineTotalAmount"`
+}
+
+type lineSettlement struct {
+	Tax       tradeTax      `xml:"ram:ApplicableTradeTax"`
+	Summation lineSummation `xml:"ram:SpecifiedTradeSettlementLineMonetarySummation"`
+}
+
+type lineItem struct {
+	LineID      int            `xml:"ram:AssociatedDocumentLineDocument>ram:LineID"`
+	ProductName string         `xml:"ram:SpecifiedTradeProduct>ram:Name"`
+	UnitPrice   amount         `xml:"ram:SpecifiedLineTradeAgreement>ram:GrossPriceProductTradePrice>ram:ChargeAmount"`
+	Quantity    quantity       `xml:"ram:SpecifiedLineTradeDelivery>ram:BilledQuantity"`
+	Settlement  lineSettlement `xml:"ram:SpecifiedLineTradeSettlement"`
+}
+
+type tradeTransaction struct {
+	Agreement  tradeAgreement  `xml:"ram:ApplicableHeaderTradeAgreement"`
+	Delivery   struct{}        `xml:"ram:ApplicableHeaderTradeDelivery"`
+	Settlement tradeSettlement `xml:"ram:ApplicableHeaderTradeSettlement"`
+	Lines      []lineItem      `xml:"ram:IncludedSupplyChainTradeLineItem"`
+}
+
+type crossIndustryInvoice struct {
+	XMLName     xml.Name
+	RSM         string            `xml:"xmlns:rsm,attr"`
+	RAM         string            `xml:"xmlns:ram,attr"`
+	Context     documentContext   `xml:"rsm:ExchangedDocumentContext"`
+	Document    exchangedDocument `xml:"rsm:ExchangedDocument"`
+	Transaction tradeTransaction  `xml:"rsm:SupplyChainTradeTransaction"`
+}
+
+// XML renders an invoice as an ETDA e-Tax invoice document. A credit note is rendered with original, the tax
+// invoice it reduces.
+func XML(invoice models.Invoice, original *models.Invoice) ([]byte, error) {
+	document := "TaxInvoice_CrossIndustryInvoice"
+	if invoice.Type == models.InvoiceCreditNote {
+		document = "CreditNote_CrossIndustryInvoice"
+	}
+	root := crossIndustryInvoice{
+		XMLName: xml.Name{Local: "rsm:" + document},
+		RSM:     "urn:etda:uncefact:data:standard:" + document + ":2",
+		RAM:     "urn:etda:uncefact:data:standard:" + document + "_ReusableAggregateBusinessInformationEntity:2",
+		Context: documentContext{GuidelineID: guidelineID{SchemeAgencyID: "ETDA", SchemeVersionID: "v2.0", Value: etdaGuideline}},
+		Document: exchangedDocument{
+			ID:               invoice.Number,
+			Name:             DocumentName(invoice),
+			TypeCode:         invoice.Type,
+			IssueDateTime:    invoice.IssuedAt.Format(isoDateTime),
+			CreationDateTime: invoice.CreatedAt.Format(isoDateTime),
+		},
+	}
+	if root.Document.CreationDateTime == "0001-01-01T00:00:00" {
+		root.Document.CreationDateTime = root.Document.IssueDateTime
+	}
+
+	transaction := &root.Transaction
+	transaction.Agreement.Seller = party(invoice.Seller)
+	transaction.Agreement.Buyer = party(invoice.Buyer)
+	transaction.Settlement.CurrencyCode = schemedID{SchemeID: "ISO 4217 3A", Value: invoice.Currency}
+	transaction.Settlement.Summation = monetarySummation{
+		LineTotalAmount:     amount(invoice.NetAmount),
+		TaxBasisTotalAmount: amount(invoice.NetAmount),
+		TaxTotalAmount:      amount(invoice.TaxAmount),
+		GrandTotalAmount:    amount(invoice.GrossAmount),
+	}
+	if invoice.Type == models.InvoiceCreditNote {
+		// Credit notes state why they were issued, the value of the original invoice, its corrected value and
+		// the difference, which is what they are taxed on
+		root.Document.Purpose = invoice.Reason
+		root.Document.PurposeCode = "CDNG99"
+		if original != nil {
+			transaction.Agreement.Referenced = &referencedDocument{
+				IssuerAssignedID:  original.Number,
+				IssueDateTime:     original.IssuedAt.Format(isoDateTime),
+				ReferenceTypeCode: original.Type,
+			}
+			originalNet := amount(original.NetAmount)
+			difference := amount(invoice.NetAmount)
+			transaction.Settlement.Summation.OriginalInformationAmount = &originalNet
+			transaction.Settlement.Summation.LineTotalAmount = amount(original.NetAmount - invoice.NetAmount)
+			transaction.Settlement.Summation.DifferenceInformationAmount = &difference
+		}
+	}
+
+	taxes := make(map[float64]*tradeTax)
+	for _, line := range invoice.Lines {
+		lineTax := tradeTax{
+			TypeCode:         "VAT",
+			CalculatedRate:   amount(line.TaxRate),
+			BasisAmount:      amount(line.NetAmount),
+			CalculatedAmount: amount(line.TaxAmount),
+		}
+		transaction.Lines = append(transaction.Lines, lineItem{
+			LineID:      line.LineNumber,
+			ProductName: line.Description,
+			UnitPrice:   amount(line.UnitPrice),
+			Quantity:    quantity{UnitCode: unitCode, Value: line.Quantity},
+			Settlement: lineSettlement{
+				Tax: lineTax,
+				Summation: lineSummation{
+					TaxTotalAmount:                   amount(line.TaxAmount),
+					NetLineTotalAmount:               amount(line.NetAmount),
+					NetIncludingTaxesLineTotalAmount: amount(line.GrossAmount),
+				},
+			},
+		})
+		if total, ok := taxes[line.TaxRate]; ok {
+			total.BasisAmount += lineTax.BasisAmount
+			total.CalculatedAmount += lineTax.CalculatedAmount
+		} else {
+			taxes[line.TaxRate] = &lineTax
+		}
+	}
+	rates := make([]float64, 0, len(taxes))
+	for rate := range taxes {
+		rates = append(rates, rate)
+	}
+	sort.Float64s(rates)
+	for _, rate := range rates {
+		transaction.Settlement.Taxes = append(transaction.Settlement.Taxes, *taxes[rate])
+	}
+
+	body, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// party describes the seller or buyer of an invoice. Parties are identified by their tax ID followed by their
+// branch; a buyer without a tax ID is identified as not applicable.
+func party(p models.InvoiceParty) tradeParty {
+	registration := schemedID{SchemeID: "TXID", Value: p.TaxID + p.BranchCode}
+	if p.TaxID == "" {
+		registration = schemedID{SchemeID: "OTHR", Value: "N/A"}
+	}
+	result := tradeParty{
+		Name:            p.Name,
+		TaxRegistration: registration,
+		Address: postalAddress{
+			PostcodeCode: p.Postcode,
+			LineOne:      p.Address,
+			CountryID:    schemedID{SchemeID: "3166-1 alpha-2", Value: "TH"},
+		},
+	}
+	if p.Email != "" {
+		result.Contact = &tradeContact{Email: p.Email}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package invoicing
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+)
+
+// The page prints on A4; a PDF is produced by printing it, e.g. with a browser or a headless Chrome, which also
+// supplies the Thai fonts
+var page = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) },
+	"rate":  func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) },
+	"date":  func(invoice models.Invoice) string { return invoice.IssuedAt.Format("02/01/2006") },
+}).Parse(`<!DOCTYPE html>
+<html lang="th">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} {{.Invoice.Number}}</title>
+<style>
+  @page { size: A4; margin: 15mm; }
+  body { font-family: "Sarabun", "Noto Sans Thai", sans-serif; font-size: 11pt; color: #000; }
+  h1 { font-size: 16pt; text-align: center; margin: 0 0 4mm; }
+  .parties { display: flex; justify-content: space-between; gap: 8mm; margin-bottom: 6mm; }
+  .parties div { flex: 1; }
+  table { width: 100%; border-collapse: collapse; }
+  th, td { border: 1px solid #000; padding: 1.5mm 2mm; vertical-align: top; }
+  td.number { text-align: right; white-space: nowrap; }
+  tfoot td { font-weight: bold; }
+  .meta { text-align: right; margin-bottom: 4mm; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<div class="meta">
+  เลขที่ / No. {{.Invoice.Number}}<br>
+  วันที่ / Date {{date .Invoice}}
+  {{- with .Original}}<br>อ้างอิง{{$.OriginalName}} / Reference {{.Number}} ({{date .}}){{end}}
+</div>
+<div class="parties">
+  <div>
+    <strong>ผู้ขาย / Seller</strong><br>
+    {{.Invoice.Seller.Name}}<br>
+    {{.Invoice.Seller.Address}}<br>
+    เลขประจำตัวผู้เสียภาษี / Tax ID {{.Invoice.Seller.TaxID}}<br>
+    {{if eq .Invoice.Seller.BranchCode "00000"}}สำนักงานใหญ่ / Head office{{else}}สาขา / Branch {{.Invoice.Seller.BranchCode}}{{end}}
+  </div>
+  <div>
+    <strong>ผู้ซื้อ / Buyer</strong><br>
+    {{.Invoice.Buyer.Name}}<br>
+    {{.Invoice.Buyer.Address}}
+    {{- with .Invoice.Buyer.TaxID}}<br>เลขประจำตัวผู้เสียภาษี / Tax ID {{.}}<br>
+    {{if eq $.Invoice.Buyer.BranchCode "00000"}}สำนักงานใหญ่ / Head office{{else}}สาขา / Branch {{$.Invoice.Buyer.BranchCode}}{{end}}{{end}}
+  </div>
+</div>
+<table>
+  <thead>
+    <tr><th>#</th><th>รายการ / Description</th><th>จำนวน / Qty</th><th>ราคาต่อหน่วย / Unit price</th><th>VAT %</th><th>จำนวนเงิน / Amount</th></tr>
+  </thead>
+  <tbody>
+  {{- range .Invoice.Lines}}
+    <tr><td>{{.LineNumber}}</td><td>{{.Description}}</td><td class="number">{{.Quantity}}</td><td class="number">{{money .UnitPrice}}</td><td class="number">{{rate .TaxRate}}</td><td class="number">{{money .NetAmount}}</td></tr>
+  {{- end}}
+  </tbody>
+  <tfoot>
+    <tr><td colspan="5">มูลค่าสินค้า / Net amount</td><td class="number">{{money .Invoice.NetAmount}}</td></tr>
+    <tr><td colspan="5">ภาษีมูลค่าเพิ่ม / VAT</td><td class="number">{{money .Invoice.TaxAmount}}</td></tr>
+    <tr><td colspan="5">รวมทั้งสิ้น / Total ({{.Invoice.Currency}})</td><td class="number">{{money .Invoice.GrossAmount}}</td></tr>
+  </tfoot>
+</table>
+{{- with .Invoice.Reason}}
+<p>เหตุผล / Reason: {{.}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML renders an invoice as a printable page. A credit note is rendered with original, the tax invoice it
+// reduces.
+func HTML(w io.Writer, invoice models.Invoice, original *models.Invoice) error {
+	data := struct {
+		Name         string
+		OriginalName string
+		Invoice      models.Invoice
+		Original     *models.Invoice
+	}{
+		Name:     DocumentName(invoice),
+		Invoice:  invoice,
+		Original: original,
+	}
+	if original != nil {
+		data.OriginalName = DocumentName(*original)
+	}
+	return page.Execute(w, data)
+}
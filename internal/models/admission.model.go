@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdmissionRound is the admission state of a queued sales round, shared by every instance of the API. Its row is
+// locked while tickets are admitted, so the round's rate holds however many instances tick.
+type AdmissionRound struct {
+	RoundID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Allowance float64   `gorm:"not null"`                                     // Admissions earned but not used at LastTick
+	LastTick  time.Time `gorm:"type:timestamp with time zone;not null"`       // When tickets were last admitted
+	EndsAt    time.Time `gorm:"type:timestamp with time zone;not null;index"` // End of the round, after which it is pruned
+}
+
+func (AdmissionRound) TableName() string {
+	return "admission-round"
+}
+
+// AdmissionTicket is a client's place in the admission queue of a sales round
+type AdmissionTicket struct {
+	Token      string     `gorm:"size:36;primaryKey"`
+	RoundID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Seq        int64      `gorm:"autoIncrement;not null;index"` // Join order
+	LastSeen   time.Time  `gorm:"type:timestamp with time zone;not null"`
+	AdmittedAt *time.Time `gorm:"type:timestamp with time zone"`
+	Claimed    bool       `gorm:"not null;default:false"` // A purchase has used the admission
+}
+
+func (AdmissionTicket) TableName() string {
+	return "admission-ticket"
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Bank statement line statuses
+const (
+	StatementLineMatched   = "matched"   // Paid a pending order automatically
+	StatementLineReview    = "review"    // Several orders could match, or the match is doubtful; staff must decide
+	StatementLineUnmatched = "unmatched" // No pending order matches
+	StatementLineResolved  = "resolved"  // Matched to an order by staff
+	StatementLineIgnored   = "ignored"   // Not a customer payment, e.g. a debit or a line already imported, or dismissed by staff
+)
+
+// BankStatement is an uploaded bank statement whose credits are reconciled against pending orders
+type BankStatement struct {
+	ID             uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time           `gorm:"type:timestamp with time zone;index"`
+	UpdatedAt      time.Time           `gorm:"type:timestamp with time zone"`
+	StoreID        *uuid.UUID          `gorm:"type:uuid;index"` // Only orders of this store are matched when set
+	FileName       string              `gorm:"size:255"`
+	TotalLines     int                 `gorm:"not null;default:0"`
+	MatchedLines   int                 `gorm:"not null;default:0"` // Matched automatically or by staff
+	ReviewLines    int                 `gorm:"not null;default:0"`
+	UnmatchedLines int                 `gorm:"not null;default:0"`
+	IgnoredLines   int                 `gorm:"not null;default:0"`
+	Lines          []BankStatementLine `gorm:"foreignKey:StatementID"`
+}
+
+func (BankStatement) TableName() string {
+	return "bank-statement"
+}
+
+// BankStatementLine is one transaction of a bank statement and the order it paid, if any
+type BankStatementLine struct {
+	ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt    time.Time  `gorm:"type:timestamp with time zone"`
+	UpdatedAt    time.Time  `gorm:"type:timestamp with time zone"`
+	StatementID  uuid.UUID  `gorm:"type:uuid;not null;index"` // Foreign key for the BankStatement
+	LineNumber   int        `gorm:"not null"`                 // Row of the CSV
+	TransactedAt time.Time  `gorm:"type:timestamp with time zone;not null"`
+	Amount       float64    `gorm:"not null"` // Negative for debits
+	Reference    string     `gorm:"size:255"`
+	Description  string     `gorm:"type:text"`
+	Fingerprint  string     `gorm:"size:64;not null;index"` // Identifies the same transaction in overlapping statements
+	Status       string     `gorm:"size:50;not null;index"`
+	OrderID      *uuid.UUID `gorm:"type:uuid;index"` // The order the line paid
+	Candidates   string     `gorm:"type:text"`       // JSON encoded IDs of the orders the line could pay
+	Note         string     `gorm:"type:text"`       // Why the line got its status
+	ResolvedAt   *time.Time `gorm:"type:timestamp with time zone"`
+}
+
+func (BankStatementLine) TableName() string {
+	return "bank-statement-line"
+}
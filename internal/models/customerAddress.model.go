@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// PostalAddress is a structured Thai delivery address. Orders keep a copy of the address they are shipped to,
+// so editing or deleting an address book entry does not change orders already placed.
+type PostalAddress struct {
+	Recipient   string `gorm:"size:255"`
+	Phone       string `gorm:"size:20"`
+	HouseNumber string `gorm:"size:255"` // House number, building, village, soi and road
+	Subdistrict string `gorm:"size:100"` // Tambon / khwaeng
+	District    string `gorm:"size:100"` // Amphoe / khet
+	Province    string `gorm:"size:100"`
+	Postcode    string `gorm:"size:5"`
+}
+
+// String formats the address on one line, the way it is written on a parcel
+func (a PostalAddress) String() string {
+	parts := []string{a.Recipient, a.HouseNumber, a.Subdistrict, a.District, a.Province, a.Postcode}
+	if a.Phone != "" {
+		parts = append(parts, fmt.Sprintf("Tel. %s", a.Phone))
+	}
+	nonEmpty := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
+
+// IsZero reports whether no part of the address is set
+func (a PostalAddress) IsZero() bool {
+	return a == PostalAddress{}
+}
+
+// CustomerAddress is an entry of a customer's address book. One of a customer's addresses is their default,
+// which purchases ship to when they do not name one.
+type CustomerAddress struct {
+	ID            uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt     time.Time      `gorm:"type:timestamp with time zone"`
+	UpdatedAt     time.Time      `gorm:"type:timestamp with time zone"`
+	DeletedAt     gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	CustomerID    uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the Customer
+	Label         string         `gorm:"size:100"`                 // e.g. Home or Office
+	PostalAddress `gorm:"embedded"`
+	IsDefault     bool `gorm:"not null;default:false"`
+}
+
+func (CustomerAddress) TableName() string {
+	return "customer-address"
+}
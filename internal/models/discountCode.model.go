@@ -0,0 +1,67 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// DiscountCode is a code customers enter at checkout to get a discount; see the discounts package for how codes
+// are priced
+type DiscountCode struct {
+	ID               uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt        time.Time      `gorm:"type:timestamp with time zone"`
+	UpdatedAt        time.Time      `gorm:"type:timestamp with time zone"`
+	DeletedAt        gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	Code             string         `gorm:"size:50;not null;uniqueIndex:idx_discount_code,where:deleted_at IS NULL"` // Upper case, as customers enter it
+	Description      string         `gorm:"type:text"`
+	Kind             string         `gorm:"size:20;not null"`                 // percentage, fixed_amount, free_shipping or buy_x_get_y
+	Value            float64        `gorm:"not null;default:0"`               // Percent off, or amount off for fixed_amount
+	BuyQuantity      int            `gorm:"not null;default:0"`               // buy_x_get_y: units to buy
+	GetQuantity      int            `gorm:"not null;default:0"`               // buy_x_get_y: units given away with them
+	Scope            string         `gorm:"size:20;not null;default:'order'"` // order, store, category, variant or round
+	ScopeID          *uuid.UUID     `gorm:"type:uuid;index"`                  // Store, category, variant or sales round the code is limited to
+	MinSubtotal      float64        `gorm:"not null;default:0"`               // Lines in scope must add up to this
+	MaxDiscount      float64        `gorm:"not null;default:0"`               // Most the code takes off an order; 0 for no cap
+	StartsAt         *time.Time     `gorm:"type:timestamp with time zone"`    // Valid from; immediately when empty
+	EndsAt           *time.Time     `gorm:"type:timestamp with time zone"`    // Valid until; indefinitely when empty
+	UsageLimit       int            `gorm:"not null;default:0"`               // Orders the code can be used on; 0 for no limit
+	PerCustomerLimit int            `gorm:"not null;default:0"`               // Orders a customer can use it on; 0 for no limit
+	UsedCount        int            `gorm:"not null;default:0"`               // Orders it is used on, not counting cancelled ones
+	Stackable        bool           `gorm:"not null"`                         // Can be combined with other stackable codes
+	Active           bool           `gorm:"not null"`
+}
+
+func (DiscountCode) TableName() string {
+	return "discount-code"
+}
+
+// DiscountRedemption is the use of a discount code on an order. It is removed when the order is cancelled, so
+// the use counts against the code's limits again.
+type DiscountRedemption struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time `gorm:"type:timestamp with time zone"`
+	DiscountCodeID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_discount_redemption"` // Foreign key for the DiscountCode
+	OrderID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_discount_redemption"` // Foreign key for the Order
+	CustomerID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Amount         float64   `gorm:"not null"` // Taken off the order, shipping included
+}
+
+func (DiscountRedemption) TableName() string {
+	return "discount-redemption"
+}
+
+// OrderLineDiscount is what a discount code took off an order line
+type OrderLineDiscount struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time `gorm:"type:timestamp with time zone"`
+	OrderID        uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the Order
+	OrderDetailID  uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the OrderDetail
+	DiscountCodeID uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the DiscountCode
+	Code           string    `gorm:"size:50;not null"`         // The code as it was entered
+	Amount         float64   `gorm:"not null"`                 // Taken off the line before VAT was computed
+}
+
+func (OrderLineDiscount) TableName() string {
+	return "order-line-discount"
+}
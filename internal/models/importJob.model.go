@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Import job statuses
+const (
+	ImportJobPending   = "pending"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// Import job types
+const (
+	ImportJobProducts = "products"
+)
+
+// ImportJob tracks a bulk import and its per-row error report
+type ImportJob struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt     time.Time  `gorm:"type:timestamp with time zone;index"`
+	UpdatedAt     time.Time  `gorm:"type:timestamp with time zone"`
+	StoreID       uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Type          string     `gorm:"size:50;not null"`
+	Status        string     `gorm:"size:50;not null"`
+	FileName      string     `gorm:"size:255"`
+	TotalRows     int        `gorm:"not null;default:0"`
+	ProcessedRows int        `gorm:"not null;default:0"`
+	ImportedRows  int        `gorm:"not null;default:0"`
+	FailedRows    int        `gorm:"not null;default:0"`
+	Errors        string     `gorm:"type:text"` // JSON encoded per-row error report
+	StartedAt     *time.Time `gorm:"type:timestamp with time zone"`
+	FinishedAt    *time.Time `gorm:"type:timestamp with time zone"`
+}
+
+func (ImportJob) TableName() string {
+	return "import-job"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// InventoryLocation represents a place inside a store where goods are held (e.g. shop floor, back warehouse)
+type InventoryLocation struct {
+	ID          uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt   time.Time           `gorm:"type:timestamp with time zone"`
+	UpdatedAt   time.Time           `gorm:"type:timestamp with time zone"`
+	DeletedAt   gorm.DeletedAt      `gorm:"type:timestamp with time zone;index"`
+	StoreID     uuid.UUID           `gorm:"type:uuid;not null;index"` // Store that owns this location
+	Name        string              `gorm:"size:255;not null"`
+	Type        string              `gorm:"size:50;not null;default:'shop'"` // shop or warehouse
+	StockLevels []VariantStockLevel `gorm:"foreignKey:LocationID"`           // One-to-many relationship with stock levels
+}
+
+func (InventoryLocation) TableName() string {
+	return "inventory-location"
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Invoice document types, as coded by the ETDA e-Tax invoice standard
+const (
+	InvoiceTaxInvoiceReceipt = "T03" // Receipt/tax invoice, issued for a paid order
+	InvoiceCreditNote        = "81"  // Credit note, reducing a tax invoice after a refund
+)
+
+// InvoiceParty is the seller or buyer named on an invoice, copied when the invoice is issued
+type InvoiceParty struct {
+	Name       string `gorm:"size:255"`
+	TaxID      string `gorm:"size:13"` // 13-digit tax ID; empty for a buyer who is not a business
+	BranchCode string `gorm:"size:5"`  // 00000 for the head office
+	Address    string `gorm:"size:500"`
+	Postcode   string `gorm:"size:5"`
+	Email      string `gorm:"size:255"`
+}
+
+// Invoice is a tax invoice or credit note a store issued. Numbers are gapless per store, document type and year,
+// and an invoice is never changed after it is issued; a credit note corrects it instead.
+type Invoice struct {
+	ID          uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt   time.Time     `gorm:"type:timestamp with time zone"`
+	StoreID     uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex:idx_invoice_number"` // Foreign key for the Store
+	OrderID     uuid.UUID     `gorm:"type:uuid;not null;index"`                          // Foreign key for the Order
+	Type        string        `gorm:"size:10;not null"`                                  // InvoiceTaxInvoiceReceipt or InvoiceCreditNote
+	Number      string        `gorm:"size:50;not null;uniqueIndex:idx_invoice_number"`
+	IssuedAt    time.Time     `gorm:"type:timestamp with time zone;not null"`
+	Seller      InvoiceParty  `gorm:"embedded;embeddedPrefix:seller_"`
+	Buyer       InvoiceParty  `gorm:"embedded;embeddedPrefix:buyer_"`
+	Currency    string        `gorm:"size:3;not null"`
+	NetAmount   float64       `gorm:"not null"`
+	TaxAmount   float64       `gorm:"not null"`
+	GrossAmount float64       `gorm:"not null"`
+	InvoiceID   *uuid.UUID    `gorm:"type:uuid;index"` // Credit notes: the tax invoice they reduce
+	RefundID    *uuid.UUID    `gorm:"type:uuid;index"` // Credit notes: the payment refund they record
+	Reason      string        `gorm:"type:text"`       // Credit notes: why the invoice is reduced
+	Lines       []InvoiceLine `gorm:"foreignKey:InvoiceID"`
+}
+
+func (Invoice) TableName() string {
+	return "invoice"
+}
+
+// InvoiceLine is a line of an invoice
+type InvoiceLine struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	InvoiceID     uuid.UUID  `gorm:"type:uuid;not null;index"` // Foreign key for the Invoice
+	LineNumber    int        `gorm:"not null"`
+	OrderDetailID *uuid.UUID `gorm:"type:uuid"` // The order line invoiced, if any
+	Description   string     `gorm:"size:500;not null"`
+	Quantity      int        `gorm:"not null"`
+	UnitPrice     float64    `gorm:"not null"` // Before VAT
+	TaxRate       float64    `gorm:"not null"` // Percent
+	NetAmount     float64    `gorm:"not null"`
+	TaxAmount     float64    `gorm:"not null"`
+	GrossAmount   float64    `gorm:"not null"`
+}
+
+func (InvoiceLine) TableName() string {
+	return "invoice-line"
+}
+
+// InvoiceSequence hands out the invoice numbers of a store, document type and year. Its row is locked while an
+// invoice is issued, so numbers are consecutive and a rolled back invoice does not use one up.
+type InvoiceSequence struct {
+	StoreID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Type    string    `gorm:"size:10;primaryKey"`
+	Year    int       `gorm:"primaryKey;autoIncrement:false"`
+	Last    int       `gorm:"not null;default:0"` // Last number issued
+}
+
+func (InvoiceSequence) TableName() string {
+	return "invoice-sequence"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Lottery entry statuses
+const (
+	LotteryEntered   = "entered"   // Registered, waiting for the draw
+	LotteryWon       = "won"       // Drawn; a pending order holds the won quantity until it is paid
+	LotteryLost      = "lost"      // Drawn without quantity; still first in line for forfeited units
+	LotteryClaimed   = "claimed"   // The winning order was paid
+	LotteryForfeited = "forfeited" // The winning order was not paid in time or was cancelled
+)
+
+// LotteryEntry is a customer's entry for a variant of a lottery sales round. When the round closes,
+// entries are ranked by their ticket and quantity is awarded in rank order.
+type LotteryEntry struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt       time.Time  `gorm:"type:timestamp with time zone"`
+	UpdatedAt       time.Time  `gorm:"type:timestamp with time zone"`
+	RoundID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_lottery_entry"` // Foreign key for the SalesRound
+	VariantID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_lottery_entry"` // Foreign key for the ProductVariant
+	CustomerID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_lottery_entry"` // Foreign key for the Customer
+	Quantity        int        `gorm:"not null"`                                         // Quantity the customer wants
+	DeliveryAddress string     `gorm:"type:varchar(255);not null"`                       // Used for the order if the entry wins
+	PaymentSource   string     `gorm:"type:varchar(100);not null"`
+	Status          string     `gorm:"size:50;not null;index"`
+	Ticket          string     `gorm:"size:64"`  // LotteryTicket(seed, ID), set by the draw
+	Rank            int        `gorm:"not null"` // Position in the draw, 1 first; 0 before the draw
+	WonQuantity     int        `gorm:"not null;default:0"`
+	OrderID         *uuid.UUID `gorm:"type:uuid;index"` // Pending order created for the win
+}
+
+func (LotteryEntry) TableName() string {
+	return "lottery-entry"
+}
+
+// LotteryTicket is the hex SHA-256 of "<seed>:<entry id>". Entries are ranked by ascending ticket, so anyone
+// holding the revealed seed, whose SHA-256 is the round's published commitment, can reproduce the draw.
+func LotteryTicket(seed string, entryID uuid.UUID) string {
+	sum := sha256.Sum256([]byte(seed + ":" + entryID.String()))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Notification types
+const (
+	NotificationLowStock               = "low_stock"
+	NotificationRoundMaterializeFailed = "round_materialize_failed"
+	NotificationWaitlistOffer          = "waitlist_offer"
+	NotificationLotteryWon             = "lottery_won"
+	NotificationLotteryForfeited       = "lottery_forfeited"
+)
+
+// Notification is an alert raised by the system for staff or customers
+type Notification struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt     time.Time  `gorm:"type:timestamp with time zone;index"`
+	Type          string     `gorm:"size:100;not null;index"`
+	StoreID       *uuid.UUID `gorm:"type:uuid;index"` // Store the notification is about, if any
+	Title         string     `gorm:"size:255;not null"`
+	Message       string     `gorm:"type:text;not null"`
+	ReferenceType string     `gorm:"size:100"` // What the notification refers to, e.g. product-variant
+	ReferenceID   *uuid.UUID `gorm:"type:uuid;index"`
+	Payload       string     `gorm:"type:text"` // JSON encoded details for sinks and dashboards
+	ReadAt        *time.Time `gorm:"type:timestamp with time zone"`
+}
+
+func (Notification) TableName() string {
+	return "notification"
+}
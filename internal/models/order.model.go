@@ -6,26 +6,51 @@ import (
 	"time"
 )
 
+// Order statuses
+const (
+	OrderStatusPending          = "pending"           // Awaiting payment, until PaymentDueAt if set
+	OrderStatusPaid             = "paid"              // A payment was captured, or staff confirmed the order
+	OrderStatusPartiallyShipped = "partially_shipped" // Some of the lines were shipped
+	OrderStatusShipped          = "shipped"           // Every line was shipped
+	OrderStatusDelivered        = "delivered"         // Every line was shipped and every shipment delivered
+	OrderStatusPurchased        = "ซื้อ สำเร็จ"       // Orders placed before payments were taken through a provider
+	OrderStatusCancelled        = "cancelled"
+)
+
 // Order represents an order placed by a customer
 type Order struct {
-	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	CreatedAt       time.Time      `gorm:"type:timestamp with time zone"`
-	UpdatedAt       time.Time      `gorm:"type:timestamp with time zone"`
-	DeletedAt       gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
-	OrderID         uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid()"`
-	CustomerID      uuid.UUID      `gorm:"type:uuid;not null"`
-	RoundID         uuid.UUID      `gorm:"type:uuid;not null"`
-	OrderDate       time.Time      `gorm:"not null"`
-	Status          string         `gorm:"type:varchar(100);not null"`
-	Code            string         `gorm:"type:varchar(100);not null"`
-	TotalPrice      float64        `gorm:"not null"`
-	DeliveryAddress string         `gorm:"type:varchar(255);not null"`
-	PaymentSource   string         `gorm:"type:varchar(100);not null"`
+	ID               uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt        time.Time      `gorm:"type:timestamp with time zone"`
+	UpdatedAt        time.Time      `gorm:"type:timestamp with time zone"`
+	DeletedAt        gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	OrderID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid()"`
+	CustomerID       uuid.UUID      `gorm:"type:uuid;not null"`
+	RoundID          uuid.UUID      `gorm:"type:uuid;not null"`
+	OrderDate        time.Time      `gorm:"not null"`
+	Status           string         `gorm:"type:varchar(100);not null"`
+	Code             string         `gorm:"type:varchar(100);not null"`
+	TotalPrice       float64        `gorm:"not null"`
+	NetAmount        float64        `gorm:"not null;default:0"`     // TotalPrice before VAT
+	TaxAmount        float64        `gorm:"not null;default:0"`     // VAT included in TotalPrice
+	PricesIncludeTax bool           `gorm:"not null;default:false"` // The prices of the lines included VAT; otherwise VAT was added to them
+	DiscountAmount   float64        `gorm:"not null;default:0"`     // Taken off the lines by discount codes
+	FreeShipping     bool           `gorm:"not null;default:false"` // A discount code made shipping free; the store bears its cost
+	DeliveryAddress  string         `gorm:"type:varchar(255);not null"`
+	AddressID        *uuid.UUID     `gorm:"type:uuid;index"`                   // Address book entry the order was placed with, if any
+	ShippingAddress  PostalAddress  `gorm:"embedded;embeddedPrefix:shipping_"` // Copy of that entry as it was when the order was placed
+	PaymentSource    string         `gorm:"type:varchar(100);not null"`
+	PaymentDueAt     *time.Time     `gorm:"type:timestamp with time zone;index"` // Pending orders are cancelled when this passes
+	CancelledAt      *time.Time     `gorm:"type:timestamp with time zone"`
+	CancelReason     string         `gorm:"type:text"`
 
 	Customer     Customer       `gorm:"foreignKey:CustomerID;references:ID"`
 	SalesRound   SalesRound     `gorm:"foreignKey:RoundID;references:ID"`
 	OrderDetail  []OrderDetail  `gorm:"foreignKey:OrderID"`
 	OrderHistory []OrderHistory `gorm:"foreignKey:OrderID"`
+	Payments     []Payment      `gorm:"foreignKey:OrderID"`
+	Shipments    []Shipment     `gorm:"foreignKey:OrderID"`
+
+	DiscountRedemptions []DiscountRedemption `gorm:"foreignKey:OrderID"` // Discount codes the order was placed with
 }
 
 func (Order) TableName() string {
@@ -7,19 +7,26 @@ import (
 )
 
 type OrderDetail struct {
-	ID         uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	PurchaseID uuid.UUID      `gorm:"type:uuid;not null;index"` // Added this line
-	CreatedAt  time.Time      `gorm:"type:timestamp with time zone"`
-	UpdatedAt  time.Time      `gorm:"type:timestamp with time zone"`
-	DeletedAt  gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
-	OrderID    uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the Order
-	VariantID  uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the ProductVariant
-	Quantity   int            `gorm:"not null"`                 // Quantity of the product variant ordered
-	Price      float64        `gorm:"not null"`                 // Price per unit of the product variant at the time of order
-	TotalPrice float64        `gorm:"not null"`                 // Total price for the quantity ordered
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PurchaseID     uuid.UUID      `gorm:"type:uuid;not null;index"` // Added this line
+	CreatedAt      time.Time      `gorm:"type:timestamp with time zone"`
+	UpdatedAt      time.Time      `gorm:"type:timestamp with time zone"`
+	DeletedAt      gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	OrderID        uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the Order
+	VariantID      uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the ProductVariant
+	Quantity       int            `gorm:"not null"`                 // Quantity of the product variant ordered
+	Price          float64        `gorm:"not null"`                 // Price per unit of the product variant at the time of order
+	RegularPrice   float64        `gorm:"not null;default:0"`       // Variant's own price at the time of order, before the round's sale price
+	TotalPrice     float64        `gorm:"not null"`                 // Total price for the quantity ordered, after discounts
+	DiscountAmount float64        `gorm:"not null;default:0"`       // Taken off Price x Quantity by discount codes
+	TaxCategory    string         `gorm:"size:50"`                  // Tax category of the product at the time of order
+	TaxRate        float64        `gorm:"not null;default:0"`       // VAT rate in percent
+	NetAmount      float64        `gorm:"not null;default:0"`       // TotalPrice before VAT
+	TaxAmount      float64        `gorm:"not null;default:0"`       // VAT included in TotalPrice
 
-	Order          Order          `gorm:"foreignKey:OrderID;references:ID"`
-	ProductVariant ProductVariant `gorm:"foreignKey:VariantID;references:ID"`
+	Order          Order               `gorm:"foreignKey:OrderID;references:ID"`
+	ProductVariant ProductVariant      `gorm:"foreignKey:VariantID;references:ID"`
+	Discounts      []OrderLineDiscount `gorm:"foreignKey:OrderDetailID"`
 }
 
 func (OrderDetail) TableName() string {
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Domain event types
+const (
+	EventOrderPlaced        = "order.placed"
+	EventOrderStatusChanged = "order.status_changed"
+	EventOrderCancelled     = "order.cancelled"
+	EventOrderShipped       = "order.shipped"    // A shipment left for the customer
+	EventShipmentUpdated    = "shipment.updated" // The carrier reported a new shipment status
+	EventStockAdjusted      = "stock.adjusted"
+	EventSalesRoundOpened   = "sales_round.opened"
+	EventSalesRoundClosed   = "sales_round.closed"
+	EventPaymentCaptured    = "payment.captured"
+	EventPaymentRefunded    = "payment.refunded"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes. The outbox relay
+// publishes it afterwards, at least once, so an event is never lost and never published for a rolled back change.
+type OutboxEvent struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt     time.Time  `gorm:"type:timestamp with time zone;index"`
+	Type          string     `gorm:"size:100;not null;index"`
+	AggregateType string     `gorm:"size:100;not null"` // What the event is about, e.g. order
+	AggregateID   uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Payload       string     `gorm:"type:text;not null"` // JSON encoded event data
+	PublishedAt   *time.Time `gorm:"type:timestamp with time zone;index"`
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `gorm:"type:timestamp with time zone;not null;index"` // Failed events are retried from here
+	LastError     string     `gorm:"type:text"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox-event"
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Payment statuses
+const (
+	PaymentPending           = "pending"    // Intent created; waiting for the customer to pay
+	PaymentAuthorized        = "authorized" // Paid but not captured yet
+	PaymentCaptured          = "captured"
+	PaymentFailed            = "failed"
+	PaymentRefunded          = "refunded"
+	PaymentPartiallyRefunded = "partially_refunded"
+)
+
+// Payment is an attempt to pay an order through a payment provider. An order can have several, e.g. after a
+// declined card; the order is paid once one of them is captured.
+type Payment struct {
+	ID               uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt        time.Time       `gorm:"type:timestamp with time zone;index"`
+	UpdatedAt        time.Time       `gorm:"type:timestamp with time zone"`
+	OrderID          uuid.UUID       `gorm:"type:uuid;not null;index"` // Foreign key for the Order
+	Provider         string          `gorm:"size:50;not null;uniqueIndex:idx_payment_provider_intent"`
+	ProviderIntentID string          `gorm:"size:255;not null;uniqueIndex:idx_payment_provider_intent"` // The provider's ID of the payment intent
+	Amount           float64         `gorm:"not null"`                                                  // Amount expected from the customer
+	Currency         string          `gorm:"size:3;not null"`
+	PayeeID          string          `gorm:"size:50;index"` // Account the customer pays into, e.g. the store's PromptPay ID; used to match transfers
+	Status           string          `gorm:"size:50;not null;index"`
+	CapturedAmount   float64         `gorm:"not null;default:0"`
+	RefundedAmount   float64         `gorm:"not null;default:0"`
+	NextAction       string          `gorm:"type:text"` // JSON encoded payments.NextAction telling the customer how to pay
+	FailureReason    string          `gorm:"type:text"`
+	CapturedAt       *time.Time      `gorm:"type:timestamp with time zone"`
+	Refunds          []PaymentRefund `gorm:"foreignKey:PaymentID"`
+}
+
+func (Payment) TableName() string {
+	return "payment"
+}
+
+// PaymentRefund is money returned to the customer for a captured payment
+type PaymentRefund struct {
+	ID               uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt        time.Time `gorm:"type:timestamp with time zone"`
+	PaymentID        uuid.UUID `gorm:"type:uuid;not null;index"`      // Foreign key for the Payment
+	ProviderRefundID string    `gorm:"size:255;not null;uniqueIndex"` // The provider's ID of the refund
+	Amount           float64   `gorm:"not null"`
+	Reason           string    `gorm:"type:text"`
+}
+
+func (PaymentRefund) TableName() string {
+	return "payment-refund"
+}
@@ -19,6 +19,7 @@ type Product struct {
 	Currency       string           `gorm:"size:3;not null"`
 	Stock          int              `gorm:"default:0;check:stock >= 0"`
 	Price          float64          `gorm:"not null;default:0"`
+	TaxCategory    string           `gorm:"size:50;not null;default:'standard'"` // standard, zero_rated or exempt; see the tax package
 	ImageURL       string           `gorm:"size:255"`
 	ProductVariant []ProductVariant `gorm:"foreignKey:ProductID"`
 	Store          Store            `gorm:"foreignKey:StoreID" json:"-"`
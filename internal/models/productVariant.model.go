@@ -14,6 +14,9 @@ type ProductVariant struct {
 	SKUCode           string             `gorm:"size:100;not null;unique"`                       // Stock Keeping Unit code, unique
 	Price             float64            `gorm:"not null"`                                       // Price of the product variant
 	ImageURL          string             `gorm:"size:255"`                                       // URL to the image of the product variant
+	ReorderPoint      int                `gorm:"not null;default:0"`                             // Alert when on-hand stock falls to or below this level (0 disables alerts); without location stock, the product's stock counts
+	ReorderQuantity   int                `gorm:"not null;default:0"`                             // Suggested quantity to reorder when the reorder point is reached
+	LowStockAlerted   bool               `gorm:"not null;default:false" json:"-"`                // Set once an alert was emitted, cleared when stock recovers
 	SalesRoundDetails []SalesRoundDetail `gorm:"foreignKey:VariantID"`                           // One-to-many relationship with SalesRoundDetail
 	OrderDetails      []OrderDetail      `gorm:"foreignKey:VariantID"`                           // One-to-many relationship with OrderDetail
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Purchase order statuses
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderSent              = "sent"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderClosed            = "closed"
+)
+
+// PurchaseOrder represents an order for stock placed with a supplier
+type PurchaseOrder struct {
+	ID         uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt  time.Time           `gorm:"type:timestamp with time zone"`
+	UpdatedAt  time.Time           `gorm:"type:timestamp with time zone"`
+	DeletedAt  gorm.DeletedAt      `gorm:"type:timestamp with time zone;index"`
+	StoreID    uuid.UUID           `gorm:"type:uuid;not null;index"`
+	SupplierID uuid.UUID           `gorm:"type:uuid;not null;index"`
+	LocationID *uuid.UUID          `gorm:"type:uuid"` // Inventory location goods are received into (optional)
+	Code       string              `gorm:"size:100;not null;unique"`
+	Status     string              `gorm:"size:50;not null"`
+	ExpectedAt *time.Time          `gorm:"type:timestamp with time zone"`
+	SentAt     *time.Time          `gorm:"type:timestamp with time zone"`
+	ClosedAt   *time.Time          `gorm:"type:timestamp with time zone"`
+	Note       string              `gorm:"type:text"`
+	Supplier   Supplier            `gorm:"foreignKey:SupplierID" json:"-"`
+	Lines      []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID"` // One-to-many relationship with order lines
+}
+
+func (PurchaseOrder) TableName() string {
+	return "purchase-order"
+}
+
+// PurchaseOrderLine is a single variant ordered on a purchase order
+type PurchaseOrderLine struct {
+	ID               uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt        time.Time `gorm:"type:timestamp with time zone"`
+	UpdatedAt        time.Time `gorm:"type:timestamp with time zone"`
+	PurchaseOrderID  uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the PurchaseOrder
+	VariantID        uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the ProductVariant
+	QuantityOrdered  int       `gorm:"not null;check:quantity_ordered > 0"`
+	QuantityReceived int       `gorm:"not null;default:0"`
+	UnitCost         float64   `gorm:"not null"` // Cost per unit agreed with the supplier
+}
+
+func (PurchaseOrderLine) TableName() string {
+	return "purchase-order-line"
+}
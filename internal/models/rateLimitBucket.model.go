@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket is the token bucket of one rate-limit key, shared by every instance of the API
+type RateLimitBucket struct {
+	Key        string    `gorm:"size:255;primaryKey"` // Policy name and client key, e.g. purchases:customer:<id>
+	Tokens     float64   `gorm:"not null"`            // Tokens left at RefilledAt
+	RefilledAt time.Time `gorm:"type:timestamp with time zone;not null;index"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate-limit-bucket"
+}
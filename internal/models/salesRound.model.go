@@ -6,17 +6,45 @@ import (
 	"time"
 )
 
+// Sales round modes
+const (
+	SalesRoundModeFCFS    = "fcfs"    // First come, first served through POST /purchases
+	SalesRoundModeLottery = "lottery" // Customers enter during the round and winners are drawn when it closes
+)
+
+// DefaultPaymentWindow is how long customers have to pay an order when its round does not set a window
+const DefaultPaymentWindow = 24 * time.Hour
+
 type SalesRound struct {
 	gorm.Model
-	ID        uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	CreatedAt time.Time          `gorm:"type:timestamp with time zone;autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time          `gorm:"type:timestamp with time zone;autoUpdateTime" json:"updated_at"`
-	DeletedAt gorm.DeletedAt     `gorm:"type:timestamp with time zone;index"`
-	Name      string             `gorm:"size:100;not null" json:"name"`
-	StartDate time.Time          `gorm:"type:timestamp with time zone;not null" json:"start_date"`
-	EndDate   time.Time          `gorm:"type:timestamp with time zone;not null" json:"end_date"`
-	Details   []SalesRoundDetail `gorm:"foreignKey:RoundID"` // One-to-many relationship with SalesRoundDetail
-	Orders    []Order            `gorm:"foreignKey:RoundID"`
+	ID            uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt     time.Time      `gorm:"type:timestamp with time zone;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"type:timestamp with time zone;autoUpdateTime" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	Name          string         `gorm:"size:100;not null" json:"name"`
+	StartDate     time.Time      `gorm:"type:timestamp with time zone;not null" json:"start_date"`
+	EndDate       time.Time      `gorm:"type:timestamp with time zone;not null" json:"end_date"`
+	TemplateID    *uuid.UUID     `gorm:"type:uuid;index" json:"template_id"` // Template the round was materialized from, if any
+	Mode          string         `gorm:"size:20;not null;default:fcfs" json:"mode"`
+	AdmissionRate int            `gorm:"not null;default:0" json:"admission_rate"`       // Buyers admitted per second through the waiting room; 0 disables it
+	OpenedAt      *time.Time     `gorm:"type:timestamp with time zone" json:"opened_at"` // When SalesRoundOpened was recorded
+	ClosedAt      *time.Time     `gorm:"type:timestamp with time zone" json:"closed_at"` // When SalesRoundClosed was recorded
+	// How long customers, or lottery winners, have to pay an order before it is cancelled; 0 means DefaultPaymentWindow
+	PaymentWindowMinutes int `gorm:"not null;default:0" json:"payment_window_minutes"`
+	// Lottery rounds only
+	DrawCommitment string             `gorm:"size:64" json:"draw_commitment"` // SHA-256 of the draw seed, published before entries open
+	DrawSeed       string             `gorm:"size:64" json:"-"`               // Revealed once the round is drawn
+	DrawnAt        *time.Time         `gorm:"type:timestamp with time zone" json:"drawn_at"`
+	Details        []SalesRoundDetail `gorm:"foreignKey:RoundID"` // One-to-many relationship with SalesRoundDetail
+	Orders         []Order            `gorm:"foreignKey:RoundID"`
+}
+
+// PaymentWindow returns how long customers have to pay an order placed in the round
+func (r SalesRound) PaymentWindow() time.Duration {
+	if r.PaymentWindowMinutes > 0 {
+		return time.Duration(r.PaymentWindowMinutes) * time.Minute
+	}
+	return DefaultPaymentWindow
 }
 
 // TableName sets the table name explicitly for the SalesRound model
@@ -1,27 +1,59 @@
 package models
 
 import (
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	"time"
 )
 
 type SalesRoundDetail struct {
-	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	CreatedAt      time.Time      `gorm:"type:timestamp with time zone;autoCreateTime"`
-	UpdatedAt      time.Time      `gorm:"type:timestamp with time zone;autoUpdateTime"`
-	DeletedAt      gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
-	RoundID        uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the SalesRound
-	VariantID      uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the ProductVariant
-	Quantity       int            `gorm:"not null"`                 // Quantity of product variants allocated to this sales round
-	Remaining      int            `gorm:"not null"`                 // Remaining quantity of product variants available in the sales round
-	ProductStock   int            `gorm:"not null"`                 // Product stock available for this sales round detail
-	QuantityLimit  int            `gorm:"not null"`                 // Quantity limit for this sales round detail
-	SalesRound     SalesRound     `gorm:"foreignKey:RoundID"`       // Many-to-One relationship with SalesRound
-	ProductVariant ProductVariant `gorm:"foreignKey:VariantID"`     // Many-to-One relationship with ProductVariant
+	ID                uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt         time.Time      `gorm:"type:timestamp with time zone;autoCreateTime"`
+	UpdatedAt         time.Time      `gorm:"type:timestamp with time zone;autoUpdateTime"`
+	DeletedAt         gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	RoundID           uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the SalesRound
+	VariantID         uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the ProductVariant
+	Quantity          int            `gorm:"not null"`                 // Quantity of product variants allocated to this sales round
+	AllocatedQuantity int            `gorm:"not null;default:0"`       // Total quantity allocated, not reduced by sales; used when cloning the round
+	Remaining         int            `gorm:"not null"`                 // Remaining quantity of product variants available in the sales round
+	ProductStock      int            `gorm:"not null"`                 // Product stock available for this sales round detail
+	QuantityLimit     int            `gorm:"not null"`                 // Quantity limit for this sales round detail
+	LocationID        *uuid.UUID     `gorm:"type:uuid;index"`          // Inventory location the allocation is drawn from (optional)
+	RoundPrice        *float64       // Price of the variant in this round instead of its regular price (optional)
+	DiscountPercent   *float64       // Percent off the variant's regular price in this round (optional, not with RoundPrice)
+	SalesRound        SalesRound     `gorm:"foreignKey:RoundID"`   // Many-to-One relationship with SalesRound
+	ProductVariant    ProductVariant `gorm:"foreignKey:VariantID"` // Many-to-One relationship with ProductVariant
 }
 
 // TableName sets the table name explicitly for the SalesRoundDetail model
 func (SalesRoundDetail) TableName() string {
 	return "sales-round-detail"
 }
+
+// SalePrice returns what a unit of the variant costs in the round, given its regular price
+func (d SalesRoundDetail) SalePrice(regularPrice float64) float64 {
+	if d.RoundPrice != nil {
+		return *d.RoundPrice
+	}
+	if d.DiscountPercent != nil {
+		return math.Round(regularPrice*(100-*d.DiscountPercent)) / 100
+	}
+	return regularPrice
+}
+
+// ValidateSalePrice reports whether the round price or discount percentage of a detail make sense
+func (d SalesRoundDetail) ValidateSalePrice() error {
+	if d.RoundPrice != nil && d.DiscountPercent != nil {
+		return fmt.Errorf("set either a round price or a discount percentage, not both")
+	}
+	if d.RoundPrice != nil && *d.RoundPrice < 0 {
+		return fmt.Errorf("round price cannot be negative")
+	}
+	if d.DiscountPercent != nil && (*d.DiscountPercent <= 0 || *d.DiscountPercent > 100) {
+		return fmt.Errorf("discount percentage must be above 0 and at most 100")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import "testing"
+
+func TestSalePrice(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		detail  SalesRoundDetail
+		regular float64
+		want    float64
+	}{
+		{name: "regular price", regular: 199, want: 199},
+		{name: "round price", detail: SalesRoundDetail{RoundPrice: price(149)}, regular: 199, want: 149},
+		{name: "free in the round", detail: SalesRoundDetail{RoundPrice: price(0)}, regular: 199, want: 0},
+		{name: "discount", detail: SalesRoundDetail{DiscountPercent: price(25)}, regular: 200, want: 150},
+		{name: "discount rounded to satang", detail: SalesRoundDetail{DiscountPercent: price(15)}, regular: 99.99, want: 84.99},
+		{name: "full discount", detail: SalesRoundDetail{DiscountPercent: price(100)}, regular: 199, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.detail.SalePrice(tt.regular); got != tt.want {
+				t.Errorf("SalePrice(%v) = %v, want %v", tt.regular, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSalePrice(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		detail  SalesRoundDetail
+		wantErr bool
+	}{
+		{name: "no sale price"},
+		{name: "round price", detail: SalesRoundDetail{RoundPrice: price(0)}},
+		{name: "discount", detail: SalesRoundDetail{DiscountPercent: price(100)}},
+		{name: "both", detail: SalesRoundDetail{RoundPrice: price(100), DiscountPercent: price(10)}, wantErr: true},
+		{name: "negative round price", detail: SalesRoundDetail{RoundPrice: price(-1)}, wantErr: true},
+		{name: "zero discount", detail: SalesRoundDetail{DiscountPercent: price(0)}, wantErr: true},
+		{name: "discount above 100", detail: SalesRoundDetail{DiscountPercent: price(101)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.detail.ValidateSalePrice(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSalePrice() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// SalesRoundTemplate describes a recurring sales round, e.g. every Friday at 20:00 for 2 hours,
+// that the round scheduler materializes into real sales rounds ahead of time
+type SalesRoundTemplate struct {
+	ID                 uuid.UUID                `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt          time.Time                `gorm:"type:timestamp with time zone"`
+	UpdatedAt          time.Time                `gorm:"type:timestamp with time zone"`
+	DeletedAt          gorm.DeletedAt           `gorm:"type:timestamp with time zone;index"`
+	Name               string                   `gorm:"size:100;not null"`
+	Weekday            time.Weekday             `gorm:"not null"`                      // Day of the week the round starts on
+	StartTime          string                   `gorm:"size:5;not null"`               // Start time of day, HH:MM
+	DurationMinutes    int                      `gorm:"not null"`                      // How long each round is open
+	Timezone           string                   `gorm:"size:100;not null"`             // IANA time zone the weekday and start time are in
+	LeadDays           int                      `gorm:"not null;default:7"`            // How many days ahead rounds are materialized
+	Active             bool                     `gorm:"not null;default:true"`         // Inactive templates are not materialized
+	LastMaterializedAt *time.Time               `gorm:"type:timestamp with time zone"` // Start of the latest round materialized from the template
+	Lines              []SalesRoundTemplateLine `gorm:"foreignKey:TemplateID"`         // Variant line-up of every round
+}
+
+func (SalesRoundTemplate) TableName() string {
+	return "sales-round-template"
+}
+
+// SalesRoundTemplateLine is a variant allocated to every round materialized from a template
+type SalesRoundTemplateLine struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TemplateID      uuid.UUID  `gorm:"type:uuid;not null;index"` // Foreign key for the SalesRoundTemplate
+	VariantID       uuid.UUID  `gorm:"type:uuid;not null"`       // Foreign key for the ProductVariant
+	Quantity        int        `gorm:"not null"`
+	QuantityLimit   int        `gorm:"not null"`
+	LocationID      *uuid.UUID `gorm:"type:uuid"` // Inventory location the allocation is drawn from (optional)
+	RoundPrice      *float64   // Price of the variant in every round (optional)
+	DiscountPercent *float64   // Percent off the variant's regular price in every round (optional, not with RoundPrice)
+}
+
+func (SalesRoundTemplateLine) TableName() string {
+	return "sales-round-template-line"
+}
+
+// NextOccurrences returns the start times of the template's rounds that start after from and no later than until
+func (t SalesRoundTemplate) NextOccurrences(from time.Time, until time.Time) ([]time.Time, error) {
+	location, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	startOfDay, err := time.Parse("15:04", t.StartTime)
+	if err != nil {
+		return nil, err
+	}
+
+	local := from.In(location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), startOfDay.Hour(), startOfDay.Minute(), 0, 0, location)
+	day = day.AddDate(0, 0, (int(t.Weekday)-int(day.Weekday())+7)%7)
+
+	var occurrences []time.Time
+	for ; !day.After(until); day = day.AddDate(0, 0, 7) {
+		if day.After(from) {
+			occurrences = append(occurrences, day)
+		}
+	}
+	return occurrences, nil
+}
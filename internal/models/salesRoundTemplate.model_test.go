@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // The tests load IANA zones that may not be installed
+)
+
+func TestNextOccurrences(t *testing.T) {
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	newYork, _ := time.LoadLocation("America/New_York")
+	friday := SalesRoundTemplate{Weekday: time.Friday, StartTime: "20:00", Timezone: "Asia/Bangkok"}
+	sunday := SalesRoundTemplate{Weekday: time.Sunday, StartTime: "09:00", Timezone: "America/New_York"}
+
+	tests := []struct {
+		name     string
+		template SalesRoundTemplate
+		from     time.Time
+		until    time.Time
+		want     []time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "two weeks ahead",
+			template: friday,
+			from:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			until:    time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC),
+			want:     []time.Time{time.Date(2026, 10, 23, 20, 0, 0, 0, bangkok), time.Date(2026, 10, 30, 20, 0, 0, 0, bangkok)},
+		},
+		{
+			name:     "from is in another time zone than the template",
+			template: friday,
+			from:     time.Date(2026, 10, 23, 12, 30, 0, 0, time.UTC), // 19:30 in Bangkok
+			until:    time.Date(2026, 10, 24, 0, 0, 0, 0, time.UTC),
+			want:     []time.Time{time.Date(2026, 10, 23, 20, 0, 0, 0, bangkok)},
+		},
+		{
+			name:     "a round starting at from is not included, one starting at until is",
+			template: friday,
+			from:     time.Date(2026, 10, 23, 20, 0, 0, 0, bangkok),
+			until:    time.Date(2026, 10, 30, 20, 0, 0, 0, bangkok),
+			want:     []time.Time{time.Date(2026, 10, 30, 20, 0, 0, 0, bangkok)},
+		},
+		{
+			name:     "later on the weekday moves to the next week",
+			template: friday,
+			from:     time.Date(2026, 10, 23, 21, 0, 0, 0, bangkok),
+			until:    time.Date(2026, 10, 30, 0, 0, 0, 0, bangkok),
+			want:     nil,
+		},
+		{
+			name:     "start time stays the same across a daylight saving change",
+			template: sunday,
+			from:     time.Date(2026, 3, 2, 0, 0, 0, 0, newYork),
+			until:    time.Date(2026, 3, 16, 0, 0, 0, 0, newYork),
+			want:     []time.Time{time.Date(2026, 3, 8, 9, 0, 0, 0, newYork), time.Date(2026, 3, 15, 9, 0, 0, 0, newYork)},
+		},
+		{
+			name:     "unknown time zone",
+			template: SalesRoundTemplate{Weekday: time.Friday, StartTime: "20:00", Timezone: "Mars/Olympus"},
+			from:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			until:    time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+		{
+			name:     "start time is not HH:MM",
+			template: SalesRoundTemplate{Weekday: time.Friday, StartTime: "8pm", Timezone: "Asia/Bangkok"},
+			from:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			until:    time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.template.NextOccurrences(tt.from, tt.until)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextOccurrences() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("NextOccurrences() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Shipment statuses
+const (
+	ShipmentLabelCreated = "label_created" // Booked with the carrier, waiting to be picked up
+	ShipmentShipped      = "shipped"       // Handed to the carrier
+	ShipmentInTransit    = "in_transit"
+	ShipmentDelivered    = "delivered"
+	ShipmentReturned     = "returned" // Sent back to the store, e.g. after failed delivery attempts
+)
+
+// Shipment is a parcel sent to the customer with some or all of the lines of an order
+type Shipment struct {
+	ID             uuid.UUID               `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time               `gorm:"type:timestamp with time zone"`
+	UpdatedAt      time.Time               `gorm:"type:timestamp with time zone"`
+	OrderID        uuid.UUID               `gorm:"type:uuid;not null;index"` // Foreign key for the Order
+	Carrier        string                  `gorm:"size:100;not null"`
+	TrackingNumber string                  `gorm:"size:100;index"`
+	Service        string                  `gorm:"size:100"`           // Carrier service the shipment was booked with, e.g. express
+	ShippingCost   float64                 `gorm:"not null;default:0"` // What the carrier charges
+	Status         string                  `gorm:"size:50;not null;index"`
+	Note           string                  `gorm:"type:text"`
+	LabelFormat    string                  `gorm:"size:10"` // png, pdf or zpl, when the label was created through the carrier
+	Label          []byte                  `gorm:"type:bytea"`
+	ShippedAt      time.Time               `gorm:"type:timestamp with time zone;not null"`
+	DeliveredAt    *time.Time              `gorm:"type:timestamp with time zone"`
+	LastTrackedAt  *time.Time              `gorm:"type:timestamp with time zone;index"` // When the carrier's tracking was last polled
+	Items          []ShipmentItem          `gorm:"foreignKey:ShipmentID"`
+	TrackingEvents []ShipmentTrackingEvent `gorm:"foreignKey:ShipmentID"`
+}
+
+func (Shipment) TableName() string {
+	return "shipment"
+}
+
+// ShipmentItem is the quantity of an order line packed in a shipment
+type ShipmentItem struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ShipmentID    uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the Shipment
+	OrderDetailID uuid.UUID `gorm:"type:uuid;not null;index"` // Foreign key for the OrderDetail
+	VariantID     uuid.UUID `gorm:"type:uuid;not null"`       // Copied from the order line
+	Quantity      int       `gorm:"not null"`
+}
+
+func (ShipmentItem) TableName() string {
+	return "shipment-item"
+}
+
+// ShipmentTrackingEvent is a scan or status update of a shipment reported by its carrier
+type ShipmentTrackingEvent struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt   time.Time `gorm:"type:timestamp with time zone"`
+	ShipmentID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_shipment_tracking_event"` // Foreign key for the Shipment
+	Status      string    `gorm:"size:50;not null;uniqueIndex:idx_shipment_tracking_event"`   // Carrier tracking status, see the carriers package
+	OccurredAt  time.Time `gorm:"type:timestamp with time zone;not null;uniqueIndex:idx_shipment_tracking_event"`
+	Description string    `gorm:"type:text"`
+	Location    string    `gorm:"size:255"`
+}
+
+func (ShipmentTrackingEvent) TableName() string {
+	return "shipment-tracking-event"
+}
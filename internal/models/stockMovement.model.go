@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Stock movement reasons
+const (
+	StockMovementPurchaseReceipt = "purchase_receipt"
+	StockMovementStockTake       = "stock_take"
+)
+
+// Stock adjustment reason codes
+var StockAdjustmentReasonCodes = []string{"damaged", "expired", "theft", "miscount", "found", "other"}
+
+// StockMovement is an append-only record of a change to a variant's stock
+type StockMovement struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt     time.Time  `gorm:"type:timestamp with time zone;index"`
+	VariantID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	ProductID     uuid.UUID  `gorm:"type:uuid;not null;index"`
+	LocationID    *uuid.UUID `gorm:"type:uuid;index"` // Location whose stock changed, if any
+	Quantity      int        `gorm:"not null"`        // Signed change in stock
+	Reason        string     `gorm:"size:100;not null"`
+	ReasonCode    string     `gorm:"size:50"`  // Adjustment reason code, see StockAdjustmentReasonCodes
+	ReferenceType string     `gorm:"size:100"` // What caused the movement, e.g. purchase-order-line
+	ReferenceID   *uuid.UUID `gorm:"type:uuid;index"`
+	UnitCost      float64    `gorm:"not null;default:0"`
+}
+
+func (StockMovement) TableName() string {
+	return "stock-movement"
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Stock-take session statuses
+const (
+	StockTakeOpen      = "open"
+	StockTakeApproved  = "approved"
+	StockTakeCancelled = "cancelled"
+)
+
+// StockTakeSession is a physical count of a store, or of one of its locations
+type StockTakeSession struct {
+	ID         uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt  time.Time        `gorm:"type:timestamp with time zone"`
+	UpdatedAt  time.Time        `gorm:"type:timestamp with time zone"`
+	DeletedAt  gorm.DeletedAt   `gorm:"type:timestamp with time zone;index"`
+	StoreID    uuid.UUID        `gorm:"type:uuid;not null;index"`
+	LocationID *uuid.UUID       `gorm:"type:uuid;index"` // Location being counted; nil counts the store as a whole
+	Status     string           `gorm:"size:50;not null"`
+	Note       string           `gorm:"type:text"`
+	ApprovedAt *time.Time       `gorm:"type:timestamp with time zone"`
+	Counts     []StockTakeCount `gorm:"foreignKey:SessionID"` // One-to-many relationship with counted variants
+}
+
+func (StockTakeSession) TableName() string {
+	return "stock-take-session"
+}
+
+// StockTakeCount is the counted quantity of one variant in a stock-take session
+type StockTakeCount struct {
+	ID              uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt       time.Time `gorm:"type:timestamp with time zone"`
+	UpdatedAt       time.Time `gorm:"type:timestamp with time zone"`
+	SessionID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_session_variant"` // Foreign key for the StockTakeSession
+	VariantID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_session_variant"` // Foreign key for the ProductVariant
+	CountedQuantity int       `gorm:"not null;check:counted_quantity >= 0"`
+	SystemQuantity  int       `gorm:"not null;default:0"` // System stock at approval time, kept for the audit trail
+	ReasonCode      string    `gorm:"size:50"`            // Why the count differs from the system stock
+}
+
+func (StockTakeCount) TableName() string {
+	return "stock-take-count"
+}
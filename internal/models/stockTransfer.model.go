@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Stock transfer statuses
+const (
+	StockTransferInTransit = "in_transit"
+	StockTransferReceived  = "received"
+	StockTransferCancelled = "cancelled"
+)
+
+// StockTransfer moves a quantity of a variant from one location of its store to another
+type StockTransfer struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time      `gorm:"type:timestamp with time zone"`
+	UpdatedAt      time.Time      `gorm:"type:timestamp with time zone"`
+	DeletedAt      gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	VariantID      uuid.UUID      `gorm:"type:uuid;not null;index"`
+	FromLocationID uuid.UUID      `gorm:"type:uuid;not null;index"`
+	ToLocationID   uuid.UUID      `gorm:"type:uuid;not null;index"`
+	FromStoreID    uuid.UUID      `gorm:"type:uuid;not null"`
+	ToStoreID      uuid.UUID      `gorm:"type:uuid;not null"`
+	Quantity       int            `gorm:"not null;check:quantity > 0"`
+	Status         string         `gorm:"size:50;not null"`
+	Note           string         `gorm:"type:text"`
+	ShippedAt      time.Time      `gorm:"type:timestamp with time zone;not null"`
+	ReceivedAt     *time.Time     `gorm:"type:timestamp with time zone"`
+}
+
+func (StockTransfer) TableName() string {
+	return "stock-transfer"
+}
@@ -7,13 +7,20 @@ import (
 )
 
 type Store struct {
-	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	CreatedAt time.Time      `gorm:"type:timestamp with time zone"`
-	UpdatedAt time.Time      `gorm:"type:timestamp with time zone"`
-	DeletedAt gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
-	StoreName string         `gorm:"size:255;not null"`
-	Location  string         `gorm:"size:255"`
-	Products  []Product      `gorm:"foreignKey:StoreID"` // Ensure StoreID in Product points to this ID
+	ID               uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt        time.Time           `gorm:"type:timestamp with time zone"`
+	UpdatedAt        time.Time           `gorm:"type:timestamp with time zone"`
+	DeletedAt        gorm.DeletedAt      `gorm:"type:timestamp with time zone;index"`
+	StoreName        string              `gorm:"size:255;not null"`
+	Location         string              `gorm:"size:255"`
+	PromptPayID      string              `gorm:"size:20"`                // Mobile number, national or tax ID, or e-wallet ID PromptPay payments go to
+	PricesExcludeTax bool                `gorm:"not null;default:false"` // VAT is added to prices at checkout; by default prices include VAT
+	TaxRates         []StoreTaxRate      `gorm:"foreignKey:StoreID"`     // VAT rates overriding the defaults of tax categories
+	LegalName        string              `gorm:"size:255"`               // Registered name printed on tax invoices; StoreName when empty
+	TaxID            string              `gorm:"size:13"`                // 13-digit VAT registration number; tax invoices cannot be issued without it
+	BranchCode       string              `gorm:"size:5"`                 // Branch of the registration, 00000 for the head office
+	Products         []Product           `gorm:"foreignKey:StoreID"`     // Ensure StoreID in Product points to this ID
+	Locations        []InventoryLocation `gorm:"foreignKey:StoreID"`     // Shop floors, warehouses, etc. belonging to this store
 }
 
 func (Store) TableName() string {
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// StoreTaxRate is the VAT rate a store charges for a tax category instead of the category's default
+type StoreTaxRate struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt time.Time `gorm:"type:timestamp with time zone"`
+	UpdatedAt time.Time `gorm:"type:timestamp with time zone"`
+	StoreID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_store_tax_rate"` // Foreign key for the Store
+	Category  string    `gorm:"size:50;not null;uniqueIndex:idx_store_tax_rate"`   // Tax category, see the tax package
+	Rate      float64   `gorm:"not null"`                                          // Percent
+}
+
+func (StoreTaxRate) TableName() string {
+	return "store-tax-rate"
+}
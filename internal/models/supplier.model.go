@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Supplier represents a vendor a store buys stock from
+type Supplier struct {
+	ID             uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time       `gorm:"type:timestamp with time zone"`
+	UpdatedAt      time.Time       `gorm:"type:timestamp with time zone"`
+	DeletedAt      gorm.DeletedAt  `gorm:"type:timestamp with time zone;index"`
+	StoreID        uuid.UUID       `gorm:"type:uuid;not null;index"` // Store that buys from this supplier
+	Name           string          `gorm:"size:255;not null"`
+	ContactName    string          `gorm:"size:255"`
+	Email          string          `gorm:"size:255"`
+	Phone          string          `gorm:"size:50"`
+	PurchaseOrders []PurchaseOrder `gorm:"foreignKey:SupplierID"` // One-to-many relationship with purchase orders
+}
+
+func (Supplier) TableName() string {
+	return "supplier"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// VariantStockLevel holds the on-hand quantity of a product variant at a single inventory location
+type VariantStockLevel struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt  time.Time `gorm:"type:timestamp with time zone"`
+	UpdatedAt  time.Time `gorm:"type:timestamp with time zone"`
+	VariantID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_variant_location"` // Foreign key for the ProductVariant
+	LocationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_variant_location"` // Foreign key for the InventoryLocation
+	Quantity   int       `gorm:"not null;default:0;check:quantity >= 0"`              // Quantity on hand at this location
+}
+
+func (VariantStockLevel) TableName() string {
+	return "variant-stock-level"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Waitlist entry statuses
+const (
+	WaitlistWaiting   = "waiting"   // Waiting for quantity to be released
+	WaitlistOffered   = "offered"   // Quantity is held for the customer until the offer expires
+	WaitlistFulfilled = "fulfilled" // The customer bought the offered quantity
+	WaitlistExpired   = "expired"   // The offer ran out; the held quantity went to the next customer
+	WaitlistCancelled = "cancelled" // The customer left the waitlist
+)
+
+// WaitlistEntry is a customer waiting for a sold-out variant of a sales round. Customers are offered
+// released quantity in the order they joined, and the offered quantity is held for them for a limited time.
+type WaitlistEntry struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt       time.Time  `gorm:"type:timestamp with time zone;index"` // Position in the queue
+	UpdatedAt       time.Time  `gorm:"type:timestamp with time zone"`
+	RoundID         uuid.UUID  `gorm:"type:uuid;not null;index:idx_waitlist_round_variant"` // Foreign key for the SalesRound
+	VariantID       uuid.UUID  `gorm:"type:uuid;not null;index:idx_waitlist_round_variant"` // Foreign key for the ProductVariant
+	CustomerID      uuid.UUID  `gorm:"type:uuid;not null;index"`                            // Foreign key for the Customer
+	Quantity        int        `gorm:"not null"`                                            // Quantity the customer wants
+	Status          string     `gorm:"size:50;not null;index"`
+	OfferedQuantity int        `gorm:"not null;default:0"` // Quantity held for the customer
+	OfferedAt       *time.Time `gorm:"type:timestamp with time zone"`
+	OfferExpiresAt  *time.Time `gorm:"type:timestamp with time zone;index"`
+}
+
+func (WaitlistEntry) TableName() string {
+	return "waitlist-entry"
+}
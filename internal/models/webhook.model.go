@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventWebhookTest is the type of the event sent by POST /webhooks/:id/test
+const EventWebhookTest = "webhook.test"
+
+// WebhookEventTypes are the event types a webhook subscription can ask for; * subscribes to all of them
+var WebhookEventTypes = []string{
+	EventOrderPlaced,
+	EventOrderStatusChanged,
+	EventOrderCancelled,
+	EventOrderShipped,
+	EventShipmentUpdated,
+	EventStockAdjusted,
+	EventSalesRoundOpened,
+	EventSalesRoundClosed,
+	EventPaymentCaptured,
+	EventPaymentRefunded,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"   // Waiting for its first attempt or a retry
+	WebhookDeliverySucceeded = "succeeded" // The endpoint answered 2xx
+	WebhookDeliveryFailed    = "failed"    // Every attempt failed; it can still be replayed
+)
+
+// WebhookSubscription sends the domain events of a store to a partner's URL. Every delivery is signed with the
+// subscription's secret, see the webhooks package.
+type WebhookSubscription struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt   time.Time      `gorm:"type:timestamp with time zone"`
+	UpdatedAt   time.Time      `gorm:"type:timestamp with time zone"`
+	DeletedAt   gorm.DeletedAt `gorm:"type:timestamp with time zone;index"`
+	StoreID     uuid.UUID      `gorm:"type:uuid;not null;index"` // Foreign key for the Store
+	URL         string         `gorm:"size:2048;not null"`
+	Secret      string         `gorm:"size:255;not null" json:"-"` // HMAC-SHA256 key the deliveries are signed with
+	EventTypes  string         `gorm:"type:text;not null"`         // Comma separated event types, or *
+	Description string         `gorm:"size:255"`
+	Active      bool           `gorm:"not null;default:true"` // Inactive subscriptions get no new deliveries
+	Store       Store          `gorm:"foreignKey:StoreID" json:"-"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook-subscription"
+}
+
+// EventTypeList returns the event types the subscription asked for
+func (s WebhookSubscription) EventTypeList() []string {
+	return strings.Split(s.EventTypes, ",")
+}
+
+// Subscribes reports whether the subscription wants events of the given type
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	for _, t := range s.EventTypeList() {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to one subscription, and the log of how that went. Replaying a delivery
+// creates a new one pointing at the original.
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CreatedAt      time.Time  `gorm:"type:timestamp with time zone;index"`
+	UpdatedAt      time.Time  `gorm:"type:timestamp with time zone"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event,where:replay_of_id IS NULL"` // Foreign key for the WebhookSubscription
+	EventID        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_delivery_event,where:replay_of_id IS NULL"` // Outbox event the delivery carries
+	EventType      string     `gorm:"size:100;not null"`
+	Payload        string     `gorm:"type:text;not null"` // Exact request body that is signed and sent
+	Status         string     `gorm:"size:50;not null;index"`
+	Attempts       int        `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `gorm:"type:timestamp with time zone;not null;index"`
+	LastAttemptAt  *time.Time `gorm:"type:timestamp with time zone"`
+	ResponseCode   int        `gorm:"not null;default:0"` // Status code of the last attempt; 0 if the request failed
+	ResponseBody   string     `gorm:"type:text"`          // Start of the last response body
+	LastError      string     `gorm:"type:text"`
+	DeliveredAt    *time.Time `gorm:"type:timestamp with time zone"`
+	ReplayOfID     *uuid.UUID `gorm:"type:uuid;index"` // Delivery this one replays, if any
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook-delivery"
+}
@@ -0,0 +1,50 @@
+package notifications
+
+import (
+	"log"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+)
+
+// Sink delivers a stored notification somewhere outside the database (logs, chat, e-mail, ...)
+type Sink interface {
+	Send(notification models.Notification) error
+}
+
+// Notifier stores notifications in the notification table and forwards them to the configured sinks
+type Notifier interface {
+	Notify(notification *models.Notification) error
+}
+
+type notifier struct {
+	notificationRepo repositories.NotificationRepository
+	sinks            []Sink
+}
+
+// NewNotifier creates a Notifier that fans out to the given sinks after a notification is stored
+func NewNotifier(notificationRepo repositories.NotificationRepository, sinks ...Sink) Notifier {
+	return &notifier{notificationRepo: notificationRepo, sinks: sinks}
+}
+
+func (n *notifier) Notify(notification *models.Notification) error {
+	if err := n.notificationRepo.CreateNotification(notification); err != nil {
+		return err
+	}
+
+	// A failing sink must not lose the notification, it is already stored
+	for _, sink := range n.sinks {
+		if err := sink.Send(*notification); err != nil {
+			log.Printf("Error sending notification %v to sink: %v", notification.ID, err)
+		}
+	}
+	return nil
+}
+
+// LogSink writes notifications to the application log
+type LogSink struct{}
+
+func (LogSink) Send(notification models.Notification) error {
+	log.Printf("[notification] %s: %s - %s", notification.Type, notification.Title, notification.Message)
+	return nil
+}
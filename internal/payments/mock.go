@@ -0,0 +1,162 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// Outcomes the mock provider can simulate
+const (
+	MockOutcomeSucceed = "succeed" // The customer pays; the intent is authorized
+	MockOutcomeFail    = "fail"    // The payment is declined
+)
+
+// MockProvider is an in-memory gateway for development and tests. Nothing happens until Simulate is called,
+// which plays the customer and returns the webhook the provider would send.
+type MockProvider struct {
+	secret string
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+	refunds map[string][]Refund
+}
+
+// NewMockProvider creates a mock provider that signs its webhooks with secret
+func NewMockProvider(secret string) *MockProvider {
+	return &MockProvider{
+		secret:  secret,
+		intents: make(map[string]*Intent),
+		refunds: make(map[string][]Refund),
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) CreateIntent(request IntentRequest) (*Intent, error) {
+	if request.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	intent := &Intent{
+		ID:       "mock_pi_" + uuid.NewString(),
+		Status:   IntentRequiresPayment,
+		Amount:   request.Amount,
+		Currency: request.Currency,
+	}
+	intent.NextAction = &NextAction{
+		Type: "mock_simulate",
+		Data: map[string]string{"outcomes": MockOutcomeSucceed + "," + MockOutcomeFail},
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents[intent.ID] = intent
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *MockProvider) Capture(intentID string, amount float64) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrUnknownIntent
+	}
+	switch intent.Status {
+	case IntentCaptured:
+	case IntentAuthorized:
+		if amount <= 0 || math.Round(amount*100) > math.Round(intent.Amount*100) {
+			return nil, fmt.Errorf("capture of %.2f exceeds the authorized %.2f", amount, intent.Amount)
+		}
+		intent.Status = IntentCaptured
+		intent.CapturedAmount = amount
+		intent.NextAction = nil
+	default:
+		return nil, ErrInvalidState
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *MockProvider) Refund(intentID string, amount float64, reason string) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrUnknownIntent
+	}
+	if intent.Status != IntentCaptured {
+		return nil, ErrInvalidState
+	}
+	refunded := 0.0
+	for _, refund := range p.refunds[intentID] {
+		refunded += refund.Amount
+	}
+	if amount <= 0 || math.Round((refunded+amount)*100) > math.Round(intent.CapturedAmount*100) {
+		return nil, fmt.Errorf("refund of %.2f exceeds the %.2f left to refund", amount, intent.CapturedAmount-refunded)
+	}
+
+	refund := Refund{ID: "mock_re_" + uuid.NewString(), IntentID: intentID, Amount: amount, Status: "succeeded"}
+	p.refunds[intentID] = append(p.refunds[intentID], refund)
+	return &refund, nil
+}
+
+func (p *MockProvider) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := webhooks.Verify(p.secret, header.Get(webhooks.SignatureHeader), body, 5*time.Minute, time.Now()); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Simulate plays the customer completing or failing the payment of an intent and returns the signed webhook
+// request the provider sends about it
+func (p *MockProvider) Simulate(intentID string, outcome string) (http.Header, []byte, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, nil, ErrUnknownIntent
+	}
+	if intent.Status != IntentRequiresPayment {
+		p.mu.Unlock()
+		return nil, nil, ErrInvalidState
+	}
+
+	event := WebhookEvent{ID: "mock_evt_" + uuid.NewString(), IntentID: intent.ID, Amount: intent.Amount}
+	switch outcome {
+	case MockOutcomeSucceed:
+		intent.Status = IntentAuthorized
+		event.Type = EventAuthorized
+	case MockOutcomeFail:
+		intent.Status = IntentFailed
+		intent.FailureReason = "card_declined"
+		event.Type = EventFailed
+		event.Reason = intent.FailureReason
+	default:
+		p.mu.Unlock()
+		return nil, nil, fmt.Errorf("unknown outcome %q", outcome)
+	}
+	intent.NextAction = nil
+	p.mu.Unlock()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(webhooks.SignatureHeader, webhooks.Sign(p.secret, time.Now(), body))
+	return header, body, nil
+}
@@ -0,0 +1,158 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPromptPayID is returned for a PromptPay ID that is not a Thai mobile number, a 13 digit national or
+// tax ID, or a 15 digit e-wallet ID
+var ErrInvalidPromptPayID = errors.New("PromptPay ID must be a Thai mobile number, a 13 digit national or tax ID, or a 15 digit e-wallet ID")
+
+// ErrNotSupported is returned for operations a provider cannot do, e.g. refunding a PromptPay transfer
+var ErrNotSupported = errors.New("not supported by this payment provider")
+
+// PromptPay application ID and EMVCo tag values
+const (
+	promptPayAID      = "A000000677010111"
+	promptPayCurrency = "764" // ISO 4217 numeric code of THB
+	promptPayCountry  = "TH"
+)
+
+// NormalizePromptPayID strips formatting from a PromptPay ID and checks that it is one of the supported kinds
+func NormalizePromptPayID(id string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, id)
+	switch {
+	case len(digits) == 10 && digits[0] == '0', len(digits) == 11 && strings.HasPrefix(digits, "66"),
+		len(digits) == 13, len(digits) == 15:
+		return digits, nil
+	}
+	return "", ErrInvalidPromptPayID
+}
+
+// PromptPayPayload builds the EMVCo merchant-presented QR payload that makes a banking app pay amount THB to a
+// PromptPay ID. An amount of 0 leaves it to the customer to enter.
+func PromptPayPayload(promptPayID string, amount float64) (string, error) {
+	id, err := NormalizePromptPayID(promptPayID)
+	if err != nil {
+		return "", err
+	}
+
+	// Sub-tag 01 is a mobile number as 0066 followed by the number without its leading 0, 02 a national or
+	// tax ID and 03 an e-wallet ID
+	var account string
+	switch len(id) {
+	case 10:
+		account = emvField("01", "0066"+id[1:])
+	case 11:
+		account = emvField("01", "00"+id)
+	case 13:
+		account = emvField("02", id)
+	default:
+		account = emvField("03", id)
+	}
+
+	initiation := "11" // Static: reusable, the customer enters the amount
+	if amount > 0 {
+		initiation = "12" // Dynamic: for one payment of a fixed amount
+	}
+
+	var payload strings.Builder
+	payload.WriteString(emvField("00", "01"))
+	payload.WriteString(emvField("01", initiation))
+	payload.WriteString(emvField("29", emvField("00", promptPayAID)+account))
+	payload.WriteString(emvField("53", promptPayCurrency))
+	if amount > 0 {
+		payload.WriteString(emvField("54", fmt.Sprintf("%.2f", math.Round(amount*100)/100)))
+	}
+	payload.WriteString(emvField("58", promptPayCountry))
+	payload.WriteString("6304") // The CRC covers its own tag and length
+	payload.WriteString(fmt.Sprintf("%04X", crc16CCITT(payload.String())))
+	return payload.String(), nil
+}
+
+func emvField(tag string, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+// crc16CCITT is CRC-16/CCITT-FALSE: polynomial 0x1021, initial value 0xFFFF, as EMVCo requires
+func crc16CCITT(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// PromptPayProvider takes payments as PromptPay transfers to the store's PromptPay ID. The customer scans the QR
+// payload in the intent's next action with a banking app; the bank does not call back, so a payment is captured
+// once the transfer shows up on the store's account, by reconciliation or by staff.
+type PromptPayProvider struct{}
+
+// NewPromptPayProvider creates a PromptPay provider
+func NewPromptPayProvider() *PromptPayProvider {
+	return &PromptPayProvider{}
+}
+
+func (p *PromptPayProvider) Name() string {
+	return "promptpay"
+}
+
+func (p *PromptPayProvider) CreateIntent(request IntentRequest) (*Intent, error) {
+	if request.Currency != "" && request.Currency != "THB" {
+		return nil, fmt.Errorf("PromptPay only takes THB, not %s", request.Currency)
+	}
+	if request.PayeeID == "" {
+		return nil, fmt.Errorf("no PromptPay ID to pay to; the order must be from one store that has a PromptPay ID")
+	}
+	if request.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	payload, err := PromptPayPayload(request.PayeeID, request.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return &Intent{
+		ID:       "pp_" + uuid.NewString(),
+		Status:   IntentRequiresPayment,
+		Amount:   math.Round(request.Amount*100) / 100,
+		Currency: "THB",
+		NextAction: &NextAction{
+			Type: "promptpay_qr",
+			Data: map[string]string{"payload": payload},
+		},
+	}, nil
+}
+
+// Capture is not possible through PromptPay: nothing tells the provider that a transfer arrived. PromptPay
+// payments are captured when reconciliation matches a bank statement line to their order.
+func (p *PromptPayProvider) Capture(intentID string, amount float64) (*Intent, error) {
+	return nil, ErrNotSupported
+}
+
+// Refund is not possible through PromptPay; the money has to be transferred back by hand
+func (p *PromptPayProvider) Refund(intentID string, amount float64, reason string) (*Refund, error) {
+	return nil, ErrNotSupported
+}
+
+func (p *PromptPayProvider) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,83 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCRC16CCITT(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint16
+	}{
+		{data: "", want: 0xFFFF},
+		{data: "123456789", want: 0x29B1}, // Check value of CRC-16/CCITT-FALSE
+		{data: "00020101021129370016A0000006770101110113006681234567853037645802TH6304", want: 0x823E},
+	}
+
+	for _, tt := range tests {
+		if got := crc16CCITT(tt.data); got != tt.want {
+			t.Errorf("crc16CCITT(%q) = %04X, want %04X", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestPromptPayPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		amount  float64
+		want    string
+		wantErr error
+	}{
+		{
+			name: "mobile number without amount",
+			id:   "081-234-5678",
+			want: "00020101021129370016A0000006770101110113006681234567853037645802TH6304823E",
+		},
+		{
+			name: "mobile number with country code",
+			id:   "+66 81 234 5678",
+			want: "00020101021129370016A0000006770101110113006681234567853037645802TH6304823E",
+		},
+		{
+			name:   "mobile number with amount",
+			id:     "0812345678",
+			amount: 1500.5,
+			want:   "00020101021229370016A00000067701011101130066812345678530376454071500.505802TH6304F63A",
+		},
+		{
+			name: "national ID",
+			id:   "1-2345-67890-12-3",
+			want: "00020101021129370016A0000006770101110213123456789012353037645802TH630433FC",
+		},
+		{
+			name:   "e-wallet ID with amount rounded to satang",
+			id:     "123456789012345",
+			amount: 0.0149,
+			want:   "00020101021229390016A0000006770101110315123456789012345530376454040.015802TH63045B91",
+		},
+		{
+			name:    "too short",
+			id:      "12345",
+			wantErr: ErrInvalidPromptPayID,
+		},
+		{
+			name:    "ten digits not starting with 0",
+			id:      "8123456789",
+			wantErr: ErrInvalidPromptPayID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PromptPayPayload(tt.id, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PromptPayPayload() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PromptPayPayload() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Package payments abstracts the payment gateways orders are paid through. A payment starts as an intent
+// created with a provider; the customer completes it outside the API (card form, bank app, ...), the provider
+// reports back through a signed webhook, and the intent is captured, after which the order counts as paid.
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Intent statuses
+const (
+	IntentRequiresPayment = "requires_payment" // Waiting for the customer to pay
+	IntentAuthorized      = "authorized"       // The customer paid; the money still has to be captured
+	IntentCaptured        = "captured"
+	IntentFailed          = "failed"
+	IntentCancelled       = "cancelled"
+)
+
+// Webhook event types providers report
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventRefunded   = "refund.succeeded"
+)
+
+var (
+	// ErrUnknownProvider is returned for a payment source no provider is registered for
+	ErrUnknownProvider = errors.New("unsupported payment source")
+	// ErrUnknownIntent is returned when a provider does not know an intent
+	ErrUnknownIntent = errors.New("unknown payment intent")
+	// ErrInvalidState is returned when an intent cannot be captured or refunded in its current status
+	ErrInvalidState = errors.New("payment intent is not in a state that allows this")
+	// ErrInvalidSignature is returned when a webhook is not signed by the provider
+	ErrInvalidSignature = errors.New("payment webhook signature is not valid")
+)
+
+// IntentRequest describes the payment to create
+type IntentRequest struct {
+	Reference   string // Our reference, the order code
+	PayeeID     string // Account the customer pays to, e.g. the store's PromptPay ID
+	Amount      float64
+	Currency    string
+	Description string
+	Metadata    map[string]string
+}
+
+// NextAction tells the client what the customer has to do to pay, e.g. scan a QR code
+type NextAction struct {
+	Type string            `json:"type"`
+	URL  string            `json:"url,omitempty"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Intent is a payment as the provider sees it
+type Intent struct {
+	ID             string
+	Status         string
+	Amount         float64
+	CapturedAmount float64
+	Currency       string
+	NextAction     *NextAction
+	FailureReason  string
+}
+
+// Refund is money returned for a captured intent
+type Refund struct {
+	ID       string
+	IntentID string
+	Amount   float64
+	Status   string
+}
+
+// WebhookEvent is a verified notification from a provider
+type WebhookEvent struct {
+	ID       string  `json:"id"` // Provider's event ID
+	Type     string  `json:"type"`
+	IntentID string  `json:"intent_id"`
+	Amount   float64 `json:"amount"`
+	RefundID string  `json:"refund_id,omitempty"` // refund.succeeded only
+	Reason   string  `json:"reason,omitempty"`    // payment.failed only
+}
+
+// Provider is a payment gateway
+type Provider interface {
+	// Name is the payment source orders use to pick the provider
+	Name() string
+	CreateIntent(request IntentRequest) (*Intent, error)
+	// Capture takes amount of an authorized intent
+	Capture(intentID string, amount float64) (*Intent, error)
+	// Refund returns part or all of the captured amount
+	Refund(intentID string, amount float64, reason string) (*Refund, error)
+	// VerifyWebhook checks the signature of a webhook request and parses it
+	VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// Registry holds the configured providers; orders name theirs in PaymentSource
+type Registry struct {
+	providers   map[string]Provider
+	defaultName string
+}
+
+// NewRegistry creates a registry; the first provider is used when an order names none
+func NewRegistry(defaultProvider Provider, others ...Provider) *Registry {
+	registry := &Registry{
+		providers:   map[string]Provider{defaultProvider.Name(): defaultProvider},
+		defaultName: defaultProvider.Name(),
+	}
+	for _, provider := range others {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// Get returns the provider for a payment source, or the default provider for an empty one
+func (r *Registry) Get(source string) (Provider, error) {
+	if source == "" {
+		source = r.defaultName
+	}
+	provider, ok := r.providers[strings.ToLower(source)]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// Names returns the payment sources that can be used, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
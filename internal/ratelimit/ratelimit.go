@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// CustomerIDHeader names the customer a request acts for
+const CustomerIDHeader = "X-Customer-ID"
+
+// KeyFunc identifies the client a request counts against; an empty key lets the request through unlimited
+type KeyFunc func(c *fiber.Ctx) string
+
+// Policy is a token bucket per key: a client may make Capacity requests in a burst and earns RefillPerSecond
+// requests back over time
+type Policy struct {
+	Name            string // Prefixes the bucket keys, so policies do not share buckets
+	Capacity        int
+	RefillPerSecond float64
+	Key             KeyFunc
+	Methods         []string // Methods the policy applies to; empty means every method
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // When the next token is available, for refused requests
+}
+
+// Store keeps the token buckets
+type Store interface {
+	Take(key string, policy Policy, now time.Time) (Result, error)
+	// Prune drops buckets untouched since idleSince; they have refilled completely, so nothing is lost
+	Prune(idleSince time.Time) error
+}
+
+// New returns middleware that enforces the policy, answering 429 with Retry-After when a client's bucket is empty.
+// If the store fails, requests are let through rather than taking the API down with it.
+func New(store Store, policy Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !policy.appliesTo(c.Method()) {
+			return c.Next()
+		}
+		key := policy.Key(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		result, err := store.Take(policy.Name+":"+key, policy, time.Now())
+		if err != nil {
+			log.Printf("Error applying rate limit %s: %v", policy.Name, err)
+			return c.Next()
+		}
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(policy.Capacity))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests", "retry_after": retryAfter})
+		}
+		return c.Next()
+	}
+}
+
+// StartPruning prunes the store every interval, dropping buckets idle for idleFor
+func StartPruning(store Store, interval time.Duration, idleFor time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := store.Prune(now.Add(-idleFor)); err != nil {
+				log.Printf("Error pruning rate-limit buckets: %v", err)
+			}
+		}
+	}()
+}
+
+func (p Policy) appliesTo(method string) bool {
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// take refills a bucket for the time since it was last refilled and takes a token from it if there is one.
+// The stores share it so they limit identically.
+func take(tokens float64, refilledAt time.Time, policy Policy, now time.Time) (float64, Result) {
+	capacity := float64(policy.Capacity)
+	if elapsed := now.Sub(refilledAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*policy.RefillPerSecond)
+	}
+
+	if tokens < 1 {
+		retryAfter := time.Hour
+		if policy.RefillPerSecond > 0 {
+			retryAfter = time.Duration((1 - tokens) / policy.RefillPerSecond * float64(time.Second))
+		}
+		return tokens, Result{Allowed: false, RetryAfter: retryAfter}
+	}
+	tokens--
+	return tokens, Result{Allowed: true, Remaining: int(tokens)}
+}
+
+// ByIP keys requests by the client IP
+func ByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// CustomerExists reports whether an ID belongs to a customer
+type CustomerExists func(id uuid.UUID) bool
+
+// ByCustomer keys requests by the customer they act for, from the X-Customer-ID header or the customer_id of a
+// JSON body. Nothing proves the client is that customer, so a customer key must only be applied on top of an
+// IP key, never instead of it; IDs that are not customers are not keyed, so made-up IDs get no buckets.
+func ByCustomer(exists CustomerExists) KeyFunc {
+	return func(c *fiber.Ctx) string {
+		id, err := uuid.Parse(c.Get(CustomerIDHeader))
+		if err != nil {
+			var body struct {
+				CustomerID uuid.UUID `json:"customer_id"`
+			}
+			if !c.Is("json") || c.BodyParser(&body) != nil {
+				return ""
+			}
+			id = body.CustomerID
+		}
+		if id == uuid.Nil || !exists(id) {
+			return ""
+		}
+		return "customer:" + id.String()
+	}
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	policy := Policy{Name: "test", Capacity: 5, RefillPerSecond: 2}
+	refilledAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		policy         Policy
+		tokens         float64
+		elapsed        time.Duration
+		wantTokens     float64
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:          "full bucket",
+			policy:        policy,
+			tokens:        5,
+			wantTokens:    4,
+			wantAllowed:   true,
+			wantRemaining: 4,
+		},
+		{
+			name:          "last token",
+			policy:        policy,
+			tokens:        1,
+			wantTokens:    0,
+			wantAllowed:   true,
+			wantRemaining: 0,
+		},
+		{
+			name:           "empty bucket waits for the next token",
+			policy:         policy,
+			tokens:         0,
+			wantTokens:     0,
+			wantRetryAfter: 500 * time.Millisecond,
+		},
+		{
+			name:           "part of a token",
+			policy:         policy,
+			tokens:         0.5,
+			wantTokens:     0.5,
+			wantRetryAfter: 250 * time.Millisecond,
+		},
+		{
+			name:          "refill since the last take",
+			policy:        policy,
+			tokens:        0,
+			elapsed:       time.Second,
+			wantTokens:    1,
+			wantAllowed:   true,
+			wantRemaining: 1,
+		},
+		{
+			name:          "refill stops at capacity",
+			policy:        policy,
+			tokens:        3,
+			elapsed:       time.Hour,
+			wantTokens:    4,
+			wantAllowed:   true,
+			wantRemaining: 4,
+		},
+		{
+			name:          "clock behind the last refill does not refill",
+			policy:        policy,
+			tokens:        2,
+			elapsed:       -time.Minute,
+			wantTokens:    1,
+			wantAllowed:   true,
+			wantRemaining: 1,
+		},
+		{
+			name:           "policy without refill",
+			policy:         Policy{Name: "test", Capacity: 1},
+			tokens:         0,
+			elapsed:        time.Minute,
+			wantTokens:     0,
+			wantRetryAfter: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, result := take(tt.tokens, refilledAt, tt.policy, refilledAt.Add(tt.elapsed))
+			if tokens != tt.wantTokens {
+				t.Errorf("take() tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if result.Allowed != tt.wantAllowed || result.Remaining != tt.wantRemaining || result.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("take() = %+v, want {Allowed:%v Remaining:%d RetryAfter:%s}", result, tt.wantAllowed, tt.wantRemaining, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreBurstAndRefill(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Name: "test", Capacity: 3, RefillPerSecond: 1}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		result, err := store.Take("client", policy, now)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if result.Allowed {
+			allowed++
+		}
+	}
+	if allowed != policy.Capacity {
+		t.Errorf("burst allowed %d requests, want the capacity of %d", allowed, policy.Capacity)
+	}
+	if result, _ := store.Take("other client", policy, now); !result.Allowed {
+		t.Errorf("a second client shares the first client's bucket")
+	}
+	if result, _ := store.Take("client", policy, now.Add(time.Second)); !result.Allowed {
+		t.Errorf("no token refilled after a second")
+	}
+	if result, _ := store.Take("client", policy, now.Add(time.Second)); result.Allowed {
+		t.Errorf("more than one token refilled after a second")
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type memoryBucket struct {
+	tokens     float64
+	refilledAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+// NewMemoryStore keeps the buckets in this process; each instance of the API then limits on its own
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *memoryStore) Take(key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(policy.Capacity), refilledAt: now}
+		s.buckets[key] = bucket
+	}
+	var result Result
+	bucket.tokens, result = take(bucket.tokens, bucket.refilledAt, policy, now)
+	if now.After(bucket.refilledAt) {
+		bucket.refilledAt = now
+	}
+	return result, nil
+}
+
+func (s *memoryStore) Prune(idleSince time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, bucket := range s.buckets {
+		if bucket.refilledAt.Before(idleSince) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
+
+type postgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore keeps the buckets in the rate-limit-bucket table, so every instance of the API shares them
+func NewPostgresStore(db *gorm.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Take(key string, policy Policy, now time.Time) (Result, error) {
+	var result Result
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		bucket := models.RateLimitBucket{Key: key, Tokens: float64(policy.Capacity), RefilledAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bucket, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		bucket.Tokens, result = take(bucket.Tokens, bucket.RefilledAt, policy, now)
+		if now.After(bucket.RefilledAt) { // Instances whose clocks lag must not refill the bucket twice
+			bucket.RefilledAt = now
+		}
+		return tx.Model(&bucket).Select("tokens", "refilled_at").Updates(&bucket).Error
+	})
+	return result, err
+}
+
+func (s *postgresStore) Prune(idleSince time.Time) error {
+	return s.db.Where("refilled_at < ?", idleSince).Delete(&models.RateLimitBucket{}).Error
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownAddress is returned when an order names an address that is not in its customer's address book
+var ErrUnknownAddress = errors.New("address is not in the customer's address book")
+
+// CustomerAddressRepository keeps customers' address books. A customer's first address becomes their default,
+// and at most one address of a customer is the default.
+type CustomerAddressRepository interface {
+	// CreateAddress adds an address; it becomes the default if it is marked so or is the customer's first
+	CreateAddress(address *models.CustomerAddress) error
+	GetAddressesByCustomerID(customerID uuid.UUID) ([]models.CustomerAddress, error)
+	GetAddressByID(id uuid.UUID) (*models.CustomerAddress, error)
+	// GetDefaultAddress returns the customer's default address, or gorm.ErrRecordNotFound
+	GetDefaultAddress(customerID uuid.UUID) (*models.CustomerAddress, error)
+	UpdateAddress(address *models.CustomerAddress) error
+	// SetDefaultAddress makes an address its customer's default
+	SetDefaultAddress(id uuid.UUID) (*models.CustomerAddress, error)
+	// DeleteAddress removes an address; when it was the default, the customer's most recently added address
+	// becomes the default
+	DeleteAddress(id uuid.UUID) error
+}
+
+type customerAddressRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerAddressRepository(db *gorm.DB) CustomerAddressRepository {
+	return &customerAddressRepository{db: db}
+}
+
+func (r *customerAddressRepository) CreateAddress(address *models.CustomerAddress) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockCustomer(tx, address.CustomerID); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.CustomerAddress{}).Where("customer_id = ?", address.CustomerID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			address.IsDefault = true
+		}
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, address.CustomerID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(address).Error
+	})
+}
+
+func (r *customerAddressRepository) GetAddressesByCustomerID(customerID uuid.UUID) ([]models.CustomerAddress, error) {
+	var addresses []models.CustomerAddress
+	err := r.db.Where("customer_id = ?", customerID).Order("is_default DESC, created_at DESC").Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *customerAddressRepository) GetAddressByID(id uuid.UUID) (*models.CustomerAddress, error) {
+	var address models.CustomerAddress
+	err := r.db.First(&address, "id = ?", id).Error
+	return &address, err
+}
+
+func (r *customerAddressRepository) GetDefaultAddress(customerID uuid.UUID) (*models.CustomerAddress, error) {
+	var address models.CustomerAddress
+	err := r.db.Where("customer_id = ? AND is_default", customerID).First(&address).Error
+	return &address, err
+}
+
+// UpdateAddress saves the address; the default flag is only changed through SetDefaultAddress and DeleteAddress
+func (r *customerAddressRepository) UpdateAddress(address *models.CustomerAddress) error {
+	return r.db.Model(address).
+		Select("label", "recipient", "phone", "house_number", "subdistrict", "district", "province", "postcode").
+		Updates(address).Error
+}
+
+func (r *customerAddressRepository) SetDefaultAddress(id uuid.UUID) (*models.CustomerAddress, error) {
+	var address models.CustomerAddress
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&address, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := lockCustomer(tx, address.CustomerID); err != nil {
+			return err
+		}
+		if err := clearDefaultAddress(tx, address.CustomerID); err != nil {
+			return err
+		}
+		address.IsDefault = true
+		return tx.Model(&address).Update("is_default", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (r *customerAddressRepository) DeleteAddress(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var address models.CustomerAddress
+		if err := tx.First(&address, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := lockCustomer(tx, address.CustomerID); err != nil {
+			return err
+		}
+		wasDefault := address.IsDefault
+		if err := tx.Model(&address).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+		if !wasDefault {
+			return nil
+		}
+
+		var next models.CustomerAddress
+		err := tx.Where("customer_id = ?", address.CustomerID).Order("created_at DESC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+// lockCustomer locks a customer's row, so concurrent changes to their address book do not end up with two
+// default addresses
+func lockCustomer(tx *gorm.DB, customerID uuid.UUID) error {
+	var customer models.Customer
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&customer, "id = ?", customerID).Error
+}
+
+func clearDefaultAddress(tx *gorm.DB, customerID uuid.UUID) error {
+	return tx.Model(&models.CustomerAddress{}).
+		Where("customer_id = ? AND is_default", customerID).
+		Update("is_default", false).Error
+}
@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/discounts"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownDiscountCode is returned for a discount code that does not exist
+	ErrUnknownDiscountCode = errors.New("unknown discount code")
+	// ErrDiscountCodeNotValid is returned for a discount code that is inactive or outside its validity window
+	ErrDiscountCodeNotValid = errors.New("discount code is not valid now")
+	// ErrDiscountCodeUsedUp is returned for a discount code used on as many orders as it allows
+	ErrDiscountCodeUsedUp = errors.New("discount code has been used up")
+	// ErrDiscountCodeCustomerLimit is returned when a customer used a discount code as often as it allows
+	ErrDiscountCodeCustomerLimit = errors.New("customer has used the discount code as often as allowed")
+)
+
+// DiscountRepository keeps the discount codes customers use at checkout. Codes are priced and used when an order
+// is placed; see PlaceOrder.
+type DiscountRepository interface {
+	CreateDiscountCode(code *models.DiscountCode) error
+	GetAllDiscountCodes() ([]models.DiscountCode, error)
+	GetDiscountCodeByID(id uuid.UUID) (*models.DiscountCode, error)
+	UpdateDiscountCode(code *models.DiscountCode) error
+	DeleteDiscountCode(id uuid.UUID) error
+	// QuoteOrder prices the lines of an order that is not placed yet at their prices in the order's sales round, with
+	// the discount codes and VAT PlaceOrder would apply, without using the codes
+	QuoteOrder(order *models.Order, codes []string) error
+}
+
+type discountRepository struct {
+	db *gorm.DB
+}
+
+func NewDiscountRepository(db *gorm.DB) DiscountRepository {
+	return &discountRepository{db: db}
+}
+
+func (r *discountRepository) CreateDiscountCode(code *models.DiscountCode) error {
+	code.Code = normalizeDiscountCode(code.Code)
+	return r.db.Create(code).Error
+}
+
+func (r *discountRepository) GetAllDiscountCodes() ([]models.DiscountCode, error) {
+	var codes []models.DiscountCode
+	err := r.db.Order("created_at DESC").Find(&codes).Error
+	return codes, err
+}
+
+func (r *discountRepository) GetDiscountCodeByID(id uuid.UUID) (*models.DiscountCode, error) {
+	var code models.DiscountCode
+	err := r.db.First(&code, "id = ?", id).Error
+	return &code, err
+}
+
+// UpdateDiscountCode saves a discount code's terms; how often it was used is kept
+func (r *discountRepository) UpdateDiscountCode(code *models.DiscountCode) error {
+	code.Code = normalizeDiscountCode(code.Code)
+	return r.db.Model(code).Omit("used_count", "created_at", "deleted_at").Select("*").Updates(code).Error
+}
+
+func (r *discountRepository) DeleteDiscountCode(id uuid.UUID) error {
+	result := r.db.Delete(&models.DiscountCode{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *discountRepository) QuoteOrder(order *models.Order, codes []string) error {
+	for i := range order.OrderDetail {
+		line := &order.OrderDetail[i]
+		var variant models.ProductVariant
+		if err := r.db.First(&variant, "variant_id = ?", line.VariantID).Error; err != nil {
+			return err
+		}
+		var detail models.SalesRoundDetail
+		if err := r.db.Where("round_id = ? AND variant_id = ?", order.RoundID, line.VariantID).First(&detail).Error; err != nil {
+			return err
+		}
+		line.RegularPrice = variant.Price
+		line.Price = detail.SalePrice(variant.Price)
+	}
+	if err := discountOrder(r.db, order, codes, false); err != nil {
+		return err
+	}
+	return applyOrderTax(r.db, order)
+}
+
+// discountOrder prices discount codes against the lines of an order that is not placed yet: it sets the discount
+// of each line and the order, and the redemptions to record once the order is saved. With lock, the codes stay
+// locked until the transaction ends, so two orders cannot both take a code's last use.
+func discountOrder(tx *gorm.DB, order *models.Order, codes []string, lock bool) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		name := normalizeDiscountCode(code)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	query := tx
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var found []models.DiscountCode
+	if err := query.Where("code IN ?", names).Order("code").Find(&found).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]models.DiscountCode, len(found))
+	for _, code := range found {
+		byName[code.Code] = code
+	}
+
+	now := time.Now()
+	priced := make([]discounts.Code, 0, len(names))
+	for _, name := range names {
+		code, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownDiscountCode, name)
+		}
+		var used int64
+		if code.PerCustomerLimit > 0 {
+			err := tx.Model(&models.DiscountRedemption{}).
+				Where("discount_code_id = ? AND customer_id = ?", code.ID, order.CustomerID).
+				Count(&used).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := discountCodeUsable(code, int(used), now); err != nil {
+			return err
+		}
+		priced = append(priced, toDiscountsCode(code))
+	}
+
+	cart, err := discountCart(tx, order)
+	if err != nil {
+		return err
+	}
+	result, err := discounts.Apply(cart, priced)
+	if err != nil {
+		return err
+	}
+
+	order.DiscountAmount = 0
+	for i := range order.OrderDetail {
+		line := &order.OrderDetail[i]
+		line.DiscountAmount = result.LineDiscounts[i]
+		line.Discounts = nil
+		order.DiscountAmount += line.DiscountAmount
+	}
+	for _, allocation := range result.Allocations {
+		line := &order.OrderDetail[allocation.Line]
+		line.Discounts = append(line.Discounts, models.OrderLineDiscount{
+			DiscountCodeID: allocation.CodeID,
+			Code:           allocation.Code,
+			Amount:         allocation.Amount,
+		})
+	}
+	order.FreeShipping = result.FreeShipping
+	order.DiscountRedemptions = nil
+	for _, code := range priced {
+		order.DiscountRedemptions = append(order.DiscountRedemptions, models.DiscountRedemption{
+			DiscountCodeID: code.ID,
+			CustomerID:     order.CustomerID,
+			Amount:         result.CodeDiscounts[code.ID],
+		})
+	}
+	return nil
+}
+
+// discountCodeUsable checks that a code is valid at now and has uses left, in total and for a customer who used
+// it customerUses times before
+func discountCodeUsable(code models.DiscountCode, customerUses int, now time.Time) error {
+	if !code.Active || (code.StartsAt != nil && now.Before(*code.StartsAt)) || (code.EndsAt != nil && !now.Before(*code.EndsAt)) {
+		return fmt.Errorf("%w: %s", ErrDiscountCodeNotValid, code.Code)
+	}
+	if code.UsageLimit > 0 && code.UsedCount >= code.UsageLimit {
+		return fmt.Errorf("%w: %s", ErrDiscountCodeUsedUp, code.Code)
+	}
+	if code.PerCustomerLimit > 0 && customerUses >= code.PerCustomerLimit {
+		return fmt.Errorf("%w: %s", ErrDiscountCodeCustomerLimit, code.Code)
+	}
+	return nil
+}
+
+// redeemDiscounts records the discounts of a saved order and its lines, and counts the use of its codes
+func redeemDiscounts(tx *gorm.DB, order *models.Order) error {
+	for i := range order.OrderDetail {
+		line := &order.OrderDetail[i]
+		for j := range line.Discounts {
+			line.Discounts[j].OrderID = order.ID
+			line.Discounts[j].OrderDetailID = line.ID
+		}
+		if len(line.Discounts) > 0 {
+			if err := tx.Create(&line.Discounts).Error; err != nil {
+				return err
+			}
+		}
+	}
+	for i := range order.DiscountRedemptions {
+		redemption := &order.DiscountRedemptions[i]
+		redemption.OrderID = order.ID
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.DiscountCode{}).
+			Where("id = ?", redemption.DiscountCodeID).
+			Update("used_count", gorm.Expr("used_count + 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseDiscounts gives the discount codes a cancelled order used back to the codes' limits. What the codes took
+// off the order's lines stays recorded.
+func releaseDiscounts(tx *gorm.DB, order *models.Order) error {
+	var redemptions []models.DiscountRedemption
+	if err := tx.Where("order_id = ?", order.ID).Find(&redemptions).Error; err != nil {
+		return err
+	}
+	for _, redemption := range redemptions {
+		err := tx.Model(&models.DiscountCode{}).
+			Where("id = ? AND used_count > 0", redemption.DiscountCodeID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(redemptions) == 0 {
+		return nil
+	}
+	return tx.Where("order_id = ?", order.ID).Delete(&models.DiscountRedemption{}).Error
+}
+
+// discountCart describes the lines of an order, with the store and category of their products, for pricing
+// discount codes
+func discountCart(tx *gorm.DB, order *models.Order) (discounts.Cart, error) {
+	variantIDs := make([]uuid.UUID, 0, len(order.OrderDetail))
+	for _, line := range order.OrderDetail {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+	var products []struct {
+		VariantID  uuid.UUID
+		StoreID    uuid.UUID
+		CategoryID uuid.UUID
+	}
+	err := tx.Table(`"product-variant"`).
+		Select(`"product-variant".variant_id, "product".store_id, "product".category_id`).
+		Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+		Where(`"product-variant".variant_id IN ?`, variantIDs).
+		Scan(&products).Error
+	if err != nil {
+		return discounts.Cart{}, err
+	}
+
+	cart := discounts.Cart{RoundID: order.RoundID, Lines: make([]discounts.Line, 0, len(order.OrderDetail))}
+	for _, line := range order.OrderDetail {
+		cartLine := discounts.Line{VariantID: line.VariantID, Price: line.Price, Quantity: line.Quantity}
+		for _, product := range products {
+			if product.VariantID == line.VariantID {
+				cartLine.StoreID = product.StoreID
+				cartLine.CategoryID = product.CategoryID
+				break
+			}
+		}
+		cart.Lines = append(cart.Lines, cartLine)
+	}
+	return cart, nil
+}
+
+// toDiscountsCode describes a discount code for pricing
+func toDiscountsCode(code models.DiscountCode) discounts.Code {
+	priced := discounts.Code{
+		ID:          code.ID,
+		Code:        code.Code,
+		Kind:        code.Kind,
+		Value:       code.Value,
+		BuyQuantity: code.BuyQuantity,
+		GetQuantity: code.GetQuantity,
+		Scope:       code.Scope,
+		MinSubtotal: code.MinSubtotal,
+		MaxDiscount: code.MaxDiscount,
+		Stackable:   code.Stackable,
+	}
+	if code.ScopeID != nil {
+		priced.ScopeID = *code.ScopeID
+	}
+	return priced
+}
+
+// normalizeDiscountCode returns a code the way it is stored: trimmed and upper case
+func normalizeDiscountCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
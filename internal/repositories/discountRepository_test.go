@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+)
+
+func TestDiscountCodeUsable(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		code         models.DiscountCode
+		customerUses int
+		wantErr      error
+	}{
+		{
+			name: "no limits",
+			code: models.DiscountCode{Code: "SALE", Active: true, UsedCount: 1000},
+		},
+		{
+			name:    "inactive",
+			code:    models.DiscountCode{Code: "SALE"},
+			wantErr: ErrDiscountCodeNotValid,
+		},
+		{
+			name:    "not started",
+			code:    models.DiscountCode{Code: "SALE", Active: true, StartsAt: &later},
+			wantErr: ErrDiscountCodeNotValid,
+		},
+		{
+			name:    "ended",
+			code:    models.DiscountCode{Code: "SALE", Active: true, EndsAt: &now},
+			wantErr: ErrDiscountCodeNotValid,
+		},
+		{
+			name: "within its dates",
+			code: models.DiscountCode{Code: "SALE", Active: true, StartsAt: &earlier, EndsAt: &later},
+		},
+		{
+			name: "last use left",
+			code: models.DiscountCode{Code: "SALE", Active: true, UsageLimit: 100, UsedCount: 99},
+		},
+		{
+			name:    "used up",
+			code:    models.DiscountCode{Code: "SALE", Active: true, UsageLimit: 100, UsedCount: 100},
+			wantErr: ErrDiscountCodeUsedUp,
+		},
+		{
+			name:         "customer has uses left",
+			code:         models.DiscountCode{Code: "SALE", Active: true, PerCustomerLimit: 2},
+			customerUses: 1,
+		},
+		{
+			name:         "customer used up their uses",
+			code:         models.DiscountCode{Code: "SALE", Active: true, PerCustomerLimit: 2},
+			customerUses: 2,
+			wantErr:      ErrDiscountCodeCustomerLimit,
+		},
+		{
+			name:         "customer uses do not count without a per-customer limit",
+			code:         models.DiscountCode{Code: "SALE", Active: true},
+			customerUses: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := discountCodeUsable(tt.code, tt.customerUses, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("discountCodeUsable() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
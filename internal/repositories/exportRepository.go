@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"gorm.io/gorm"
+)
+
+type ExportRepository interface {
+	StreamOrderLines(filter dtos.OrderExportFilter, fn func(row *dtos.OrderExportRowDTO) error) error
+}
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+// StreamOrderLines calls fn for every order line matching the filter, reading them one at a time from a
+// database cursor so an export never holds all of them in memory. An error from fn stops the stream.
+func (r *exportRepository) StreamOrderLines(filter dtos.OrderExportFilter, fn func(row *dtos.OrderExportRowDTO) error) error {
+	query := r.db.Table("\"order\"").
+		Select("\"order\".id AS order_id, \"order\".code AS order_code, \"order\".order_date, \"order\".status, " +
+			"\"order\".round_id, \"order\".customer_id, customer.email AS customer_email, \"order\".total_price AS order_total, " +
+			"\"order\".delivery_address, \"order\".payment_source, \"order-detail\".id AS line_id, \"order-detail\".variant_id, " +
+			"\"product-variant\".sku_code, \"order-detail\".quantity, \"order-detail\".price AS unit_price, \"order-detail\".total_price AS line_total").
+		Joins("JOIN \"order-detail\" ON \"order-detail\".order_id = \"order\".id AND \"order-detail\".deleted_at IS NULL").
+		Joins("LEFT JOIN \"product-variant\" ON \"product-variant\".variant_id = \"order-detail\".variant_id").
+		Joins("LEFT JOIN customer ON customer.id = \"order\".customer_id").
+		Where("\"order\".deleted_at IS NULL")
+	if filter.RoundID != nil {
+		query = query.Where("\"order\".round_id = ?", *filter.RoundID)
+	}
+	if filter.From != nil {
+		query = query.Where("\"order\".order_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("\"order\".order_date < ?", *filter.To)
+	}
+
+	rows, err := query.Order("\"order\".order_date, \"order\".id, \"order-detail\".created_at").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row dtos.OrderExportRowDTO
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportRowError is returned by ImportProductBatch when a row could not be written; the whole batch is rolled back
+type ImportRowError struct {
+	Row int
+	Err error
+}
+
+func (e *ImportRowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *ImportRowError) Unwrap() error {
+	return e.Err
+}
+
+type ImportRepository interface {
+	CreateImportJob(job *models.ImportJob) error
+	UpdateImportJob(job *models.ImportJob) error
+	GetImportJobByID(id uuid.UUID) (*models.ImportJob, error)
+	GetAllImportJobs(storeID *uuid.UUID) ([]models.ImportJob, error)
+	ImportProductBatch(storeID uuid.UUID, rows []dtos.ProductImportRowDTO) error
+}
+
+type importRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &importRepository{db: db}
+}
+
+func (r *importRepository) CreateImportJob(job *models.ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *importRepository) UpdateImportJob(job *models.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *importRepository) GetImportJobByID(id uuid.UUID) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.First(&job, "id = ?", id).Error
+	return &job, err
+}
+
+func (r *importRepository) GetAllImportJobs(storeID *uuid.UUID) ([]models.ImportJob, error) {
+	var jobs []models.ImportJob
+	query := r.db.Order("created_at DESC")
+	if storeID != nil {
+		query = query.Where("store_id = ?", *storeID)
+	}
+	err := query.Find(&jobs).Error
+	return jobs, err
+}
+
+// ImportProductBatch upserts the categories, products and variants of a batch of rows in one transaction.
+// Variants are matched by SKU code, products by the SKU's product or else by name and brand, and categories
+// by name. Stock is only set on products the import creates; existing stock is changed through stock movements.
+// Optional columns the file leaves out keep their values on existing products and variants.
+func (r *importRepository) ImportProductBatch(storeID uuid.UUID, rows []dtos.ProductImportRowDTO) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			if err := importProductRow(tx, storeID, row); err != nil {
+				return &ImportRowError{Row: row.Row, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func importProductRow(tx *gorm.DB, storeID uuid.UUID, row dtos.ProductImportRowDTO) error {
+	var category models.Category
+	err := tx.Where("store_id = ? AND name = ?", storeID, row.Category.Name).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		category = models.Category{Name: row.Category.Name, StoreID: storeID}
+		err = tx.Create(&category).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	var variant models.ProductVariant
+	variantErr := tx.Unscoped().Where("sku_code = ?", row.Variant.SKUCode).First(&variant).Error
+	if variantErr != nil && !errors.Is(variantErr, gorm.ErrRecordNotFound) {
+		return variantErr
+	}
+
+	var product models.Product
+	if variantErr == nil {
+		err = tx.First(&product, "id = ?", variant.ProductID).Error
+	} else {
+		err = tx.Where("store_id = ? AND product_name = ? AND brand = ?", storeID, row.Product.ProductName, row.Product.Brand).First(&product).Error
+	}
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !created {
+		return err
+	}
+	if !created && product.StoreID != storeID {
+		return fmt.Errorf("SKU %s belongs to another store", row.Variant.SKUCode)
+	}
+
+	product.StoreID = storeID
+	product.CategoryID = category.ID
+	product.ProductName = row.Product.ProductName
+	product.Brand = row.Product.Brand
+	product.Description = row.Product.Description
+	product.Currency = row.Product.Currency
+	product.Price = row.Product.Price
+	product.ImageURL = row.Product.ImageURL
+	productColumns, variantColumns := importUpdateColumns(row)
+	if created {
+		product.Stock = row.Product.Stock
+		err = tx.Create(&product).Error
+	} else {
+		err = tx.Model(&product).Select(productColumns).Updates(&product).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	variant.ProductID = product.ID
+	variant.SKUCode = row.Variant.SKUCode
+	variant.Price = row.Variant.Price
+	variant.ImageURL = row.Variant.ImageURL
+	variant.ReorderPoint = row.Variant.ReorderPoint
+	variant.ReorderQuantity = row.Variant.ReorderQuantity
+	if variantErr != nil {
+		return tx.Create(&variant).Error
+	}
+	variant.DeletedAt = gorm.DeletedAt{} // Importing a deleted SKU brings it back
+	return tx.Unscoped().Model(&variant).Select(variantColumns).Updates(&variant).Error
+}
+
+// importUpdateColumns returns the product and variant columns a row updates on existing records. Fields of
+// optional import columns are only written when the file has the column, so re-importing with fewer columns
+// keeps them; an empty image_url keeps the product's image too.
+func importUpdateColumns(row dtos.ProductImportRowDTO) (product []string, variant []string) {
+	product = []string{"category_id", "product_name", "brand", "currency", "price"}
+	if row.Columns["description"] {
+		product = append(product, "description")
+	}
+	if row.Columns["image_url"] && row.Product.ImageURL != "" {
+		product = append(product, "image_url")
+	}
+
+	variant = []string{"product_id", "price", "deleted_at"}
+	if row.Columns["variant_image_url"] {
+		variant = append(variant, "image_url")
+	}
+	if row.Columns["reorder_point"] {
+		variant = append(variant, "reorder_point")
+	}
+	if row.Columns["reorder_quantity"] {
+		variant = append(variant, "reorder_quantity")
+	}
+	return product, variant
+}
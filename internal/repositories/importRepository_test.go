@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+)
+
+func TestImportUpdateColumnsKeepsColumnsTheFileLeavesOut(t *testing.T) {
+	row := dtos.ProductImportRowDTO{Columns: map[string]bool{
+		"category": true, "product_name": true, "brand": true, "currency": true, "sku_code": true, "price": true,
+	}}
+	product, variant := importUpdateColumns(row)
+	if want := []string{"category_id", "product_name", "brand", "currency", "price"}; !reflect.DeepEqual(product, want) {
+		t.Errorf("product columns = %v, want %v", product, want)
+	}
+	if want := []string{"product_id", "price", "deleted_at"}; !reflect.DeepEqual(variant, want) {
+		t.Errorf("variant columns = %v, want %v", variant, want)
+	}
+}
+
+func TestImportUpdateColumnsWritesColumnsTheFileHas(t *testing.T) {
+	row := dtos.ProductImportRowDTO{Columns: map[string]bool{
+		"category": true, "product_name": true, "brand": true, "currency": true, "sku_code": true, "price": true,
+		"description": true, "image_url": true, "variant_image_url": true, "reorder_point": true, "reorder_quantity": true,
+	}}
+	row.Product.ImageURL = "https://example.com/a.png"
+	product, variant := importUpdateColumns(row)
+	if want := []string{"category_id", "product_name", "brand", "currency", "price", "description", "image_url"}; !reflect.DeepEqual(product, want) {
+		t.Errorf("product columns = %v, want %v", product, want)
+	}
+	if want := []string{"product_id", "price", "deleted_at", "image_url", "reorder_point", "reorder_quantity"}; !reflect.DeepEqual(variant, want) {
+		t.Errorf("variant columns = %v, want %v", variant, want)
+	}
+
+	// An empty image_url cell keeps the product's image, while an empty description clears it
+	row.Product.ImageURL = ""
+	product, _ = importUpdateColumns(row)
+	if want := []string{"category_id", "product_name", "brand", "currency", "price", "description"}; !reflect.DeepEqual(product, want) {
+		t.Errorf("product columns with an empty image_url = %v, want %v", product, want)
+	}
+}
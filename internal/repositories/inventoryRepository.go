@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInsufficientLocationStock is returned when a location does not hold enough of a variant
+	ErrInsufficientLocationStock = errors.New("not enough stock at location")
+	// ErrInsufficientProductStock is returned when a change would take a product's stock below zero
+	ErrInsufficientProductStock = errors.New("not enough product stock")
+	// ErrLocationNotInStore is returned when a location belongs to another store than the product it is used for
+	ErrLocationNotInStore = errors.New("inventory location does not belong to the product's store")
+)
+
+type InventoryRepository interface {
+	CreateLocation(location *models.InventoryLocation) error
+	GetLocationsByStoreID(storeID uuid.UUID) ([]models.InventoryLocation, error)
+	GetLocationByID(id uuid.UUID) (*models.InventoryLocation, error)
+	UpdateLocation(location *models.InventoryLocation) error
+	DeleteLocation(id uuid.UUID) error
+	GetStockLevelsByLocationID(locationID uuid.UUID) ([]models.VariantStockLevel, error)
+	GetStockLevelsByVariantID(variantID uuid.UUID) ([]models.VariantStockLevel, error)
+	SetStockLevel(locationID uuid.UUID, variantID uuid.UUID, quantity int) (*models.VariantStockLevel, error)
+	CreateTransfer(transfer *models.StockTransfer) error
+	GetAllTransfers(status string) ([]models.StockTransfer, error)
+	GetTransferByID(id uuid.UUID) (*models.StockTransfer, error)
+	ReceiveTransfer(id uuid.UUID) (*models.StockTransfer, error)
+	CancelTransfer(id uuid.UUID) (*models.StockTransfer, error)
+}
+
+type inventoryRepository struct {
+	db        *gorm.DB
+	observers stockObservers
+}
+
+func NewInventoryRepository(db *gorm.DB, observers ...StockObserver) InventoryRepository {
+	return &inventoryRepository{db: db, observers: observers}
+}
+
+func (r *inventoryRepository) CreateLocation(location *models.InventoryLocation) error {
+	return r.db.Create(location).Error
+}
+
+func (r *inventoryRepository) GetLocationsByStoreID(storeID uuid.UUID) ([]models.InventoryLocation, error) {
+	var locations []models.InventoryLocation
+	err := r.db.Where("store_id = ?", storeID).Order("created_at").Find(&locations).Error
+	return locations, err
+}
+
+func (r *inventoryRepository) GetLocationByID(id uuid.UUID) (*models.InventoryLocation, error) {
+	var location models.InventoryLocation
+	err := r.db.First(&location, "id = ?", id).Error
+	return &location, err
+}
+
+func (r *inventoryRepository) UpdateLocation(location *models.InventoryLocation) error {
+	return r.db.Save(location).Error
+}
+
+func (r *inventoryRepository) DeleteLocation(id uuid.UUID) error {
+	var held int64
+	if err := r.db.Model(&models.VariantStockLevel{}).Where("location_id = ? AND quantity > 0", id).Count(&held).Error; err != nil {
+		return err
+	}
+	if held > 0 {
+		return fmt.Errorf("location still holds stock")
+	}
+	return r.db.Delete(&models.InventoryLocation{}, "id = ?", id).Error
+}
+
+func (r *inventoryRepository) GetStockLevelsByLocationID(locationID uuid.UUID) ([]models.VariantStockLevel, error) {
+	var levels []models.VariantStockLevel
+	err := r.db.Where("location_id = ?", locationID).Find(&levels).Error
+	return levels, err
+}
+
+func (r *inventoryRepository) GetStockLevelsByVariantID(variantID uuid.UUID) ([]models.VariantStockLevel, error) {
+	var levels []models.VariantStockLevel
+	err := r.db.Where("variant_id = ?", variantID).Find(&levels).Error
+	return levels, err
+}
+
+// SetStockLevel sets the quantity of a variant held at a location. The difference is added to (or taken from) the
+// product's stock in the same transaction, so the product's stock keeps counting what its locations hold.
+func (r *inventoryRepository) SetStockLevel(locationID uuid.UUID, variantID uuid.UUID, quantity int) (*models.VariantStockLevel, error) {
+	var level models.VariantStockLevel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var variant models.ProductVariant
+		if err := tx.First(&variant, "variant_id = ?", variantID).Error; err != nil {
+			return err
+		}
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", variant.ProductID).Error; err != nil {
+			return err
+		}
+		var location models.InventoryLocation
+		if err := tx.First(&location, "id = ?", locationID).Error; err != nil {
+			return err
+		}
+		if location.StoreID != product.StoreID {
+			return ErrLocationNotInStore
+		}
+
+		var previous models.VariantStockLevel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("variant_id = ? AND location_id = ?", variantID, locationID).
+			Limit(1).
+			Find(&previous).Error
+		if err != nil {
+			return err
+		}
+
+		delta := quantity - previous.Quantity
+		if product.Stock+delta < 0 {
+			return ErrInsufficientProductStock
+		}
+		if err := adjustStockLevel(tx, variantID, locationID, delta); err != nil {
+			return err
+		}
+		if err := tx.Model(&product).Update("stock", product.Stock+delta).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("variant_id = ? AND location_id = ?", variantID, locationID).First(&level).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, models.EventStockAdjusted, "product-variant", variantID, map[string]interface{}{
+			"variant_id":  variantID,
+			"location_id": locationID,
+			"quantity":    delta,
+			"level":       quantity,
+			"reason":      "stock_level_set",
+		})
+	})
+	if err == nil {
+		r.observers.notify(variantID)
+	}
+	return &level, err
+}
+
+// CreateTransfer takes the quantity out of the source location and records the transfer as in transit. Both
+// locations must belong to the store of the variant's product: a variant's stock, and its product's, is only
+// kept in its own store, so stock moved to another store could neither be counted nor sold there.
+func (r *inventoryRepository) CreateTransfer(transfer *models.StockTransfer) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var from, to models.InventoryLocation
+		if err := tx.First(&from, "id = ?", transfer.FromLocationID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&to, "id = ?", transfer.ToLocationID).Error; err != nil {
+			return err
+		}
+		var variant models.ProductVariant
+		if err := tx.First(&variant, "variant_id = ?", transfer.VariantID).Error; err != nil {
+			return err
+		}
+		var product models.Product
+		if err := tx.First(&product, "id = ?", variant.ProductID).Error; err != nil {
+			return err
+		}
+		if err := checkTransferStores(product.StoreID, from.StoreID, to.StoreID); err != nil {
+			return err
+		}
+
+		if err := adjustStockLevel(tx, transfer.VariantID, from.ID, -transfer.Quantity); err != nil {
+			return err
+		}
+
+		transfer.FromStoreID = from.StoreID
+		transfer.ToStoreID = to.StoreID
+		transfer.Status = models.StockTransferInTransit
+		transfer.ShippedAt = time.Now()
+		log.Printf("Creating stock transfer: %v", transfer)
+		return tx.Create(transfer).Error
+	})
+	if err == nil {
+		r.observers.notify(transfer.VariantID)
+	}
+	return err
+}
+
+func (r *inventoryRepository) GetAllTransfers(status string) ([]models.StockTransfer, error) {
+	var transfers []models.StockTransfer
+	query := r.db.Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&transfers).Error
+	return transfers, err
+}
+
+func (r *inventoryRepository) GetTransferByID(id uuid.UUID) (*models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := r.db.First(&transfer, "id = ?", id).Error
+	return &transfer, err
+}
+
+// ReceiveTransfer books an in-transit transfer into its destination location. A transfer between stores, from
+// before they were refused, cannot be received and has to be cancelled.
+func (r *inventoryRepository) ReceiveTransfer(id uuid.UUID) (*models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if transfer.Status != models.StockTransferInTransit {
+			return fmt.Errorf("transfer is not in transit")
+		}
+		if transfer.ToStoreID != transfer.FromStoreID {
+			return fmt.Errorf("%w; cancel the transfer to return the stock", ErrLocationNotInStore)
+		}
+
+		if err := adjustStockLevel(tx, transfer.VariantID, transfer.ToLocationID, transfer.Quantity); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		transfer.Status = models.StockTransferReceived
+		transfer.ReceivedAt = &now
+		return tx.Save(&transfer).Error
+	})
+	if err == nil {
+		r.observers.notify(transfer.VariantID)
+	}
+	return &transfer, err
+}
+
+// CancelTransfer returns the quantity of an in-transit transfer to its source location
+func (r *inventoryRepository) CancelTransfer(id uuid.UUID) (*models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if transfer.Status != models.StockTransferInTransit {
+			return fmt.Errorf("transfer is not in transit")
+		}
+
+		if err := adjustStockLevel(tx, transfer.VariantID, transfer.FromLocationID, transfer.Quantity); err != nil {
+			return err
+		}
+
+		transfer.Status = models.StockTransferCancelled
+		return tx.Save(&transfer).Error
+	})
+	if err == nil {
+		r.observers.notify(transfer.VariantID)
+	}
+	return &transfer, err
+}
+
+// checkTransferStores returns ErrLocationNotInStore unless both locations of a transfer are in the store of the
+// variant's product
+func checkTransferStores(productStoreID, fromStoreID, toStoreID uuid.UUID) error {
+	if fromStoreID != productStoreID {
+		return fmt.Errorf("%w: source location", ErrLocationNotInStore)
+	}
+	if toStoreID != productStoreID {
+		return fmt.Errorf("%w: destination location", ErrLocationNotInStore)
+	}
+	return nil
+}
+
+// adjustStockLevel adds delta to the stock level of a variant at a location inside the given transaction.
+// A negative delta fails with ErrInsufficientLocationStock when the location does not hold enough.
+func adjustStockLevel(tx *gorm.DB, variantID uuid.UUID, locationID uuid.UUID, delta int) error {
+	var level models.VariantStockLevel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("variant_id = ? AND location_id = ?", variantID, locationID).
+		First(&level).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if delta < 0 {
+			return ErrInsufficientLocationStock
+		}
+		level = models.VariantStockLevel{VariantID: variantID, LocationID: locationID, Quantity: delta}
+		return tx.Create(&level).Error
+	} else if err != nil {
+		return err
+	}
+
+	if level.Quantity+delta < 0 {
+		return ErrInsufficientLocationStock
+	}
+	level.Quantity += delta
+	return tx.Save(&level).Error
+}
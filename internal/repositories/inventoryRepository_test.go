@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckTransferStores(t *testing.T) {
+	store, other := uuid.New(), uuid.New()
+
+	if err := checkTransferStores(store, store, store); err != nil {
+		t.Errorf("transfer within the product's store: error = %v", err)
+	}
+	if err := checkTransferStores(store, store, other); !errors.Is(err, ErrLocationNotInStore) {
+		t.Errorf("transfer to another store: error = %v, want ErrLocationNotInStore", err)
+	}
+	if err := checkTransferStores(store, other, store); !errors.Is(err, ErrLocationNotInStore) {
+		t.Errorf("transfer from another store's location: error = %v, want ErrLocationNotInStore", err)
+	}
+	if err := checkTransferStores(store, other, other); !errors.Is(err, ErrLocationNotInStore) {
+		t.Errorf("transfer between locations of another store: error = %v, want ErrLocationNotInStore", err)
+	}
+}
@@ -0,0 +1,395 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/carriers"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// invoiceCurrency is the currency invoices are issued in
+const invoiceCurrency = "THB"
+
+var (
+	// ErrOrderNotInvoiceable is returned when a tax invoice is issued for an order that is not paid
+	ErrOrderNotInvoiceable = errors.New("order is not paid")
+	// ErrOrderInvoiced is returned when a tax invoice is issued for an order that already has one
+	ErrOrderInvoiced = errors.New("order already has a tax invoice")
+	// ErrStoreNotTaxRegistered is returned when a store without a tax ID issues a tax invoice
+	ErrStoreNotTaxRegistered = errors.New("store has no tax ID")
+	// ErrNotTaxInvoice is returned when a credit note is issued against a document that is not a tax invoice
+	ErrNotTaxInvoice = errors.New("credit notes can only be issued against a tax invoice")
+	// ErrRefundCredited is returned when a credit note is issued for a refund that already has one
+	ErrRefundCredited = errors.New("refund already has a credit note")
+	// ErrRefundNotOfOrder is returned when a credit note names a refund of another order
+	ErrRefundNotOfOrder = errors.New("refund is not of the invoiced order")
+	// ErrCreditExceedsInvoice is returned when a credit note would credit more than is left of its invoice
+	ErrCreditExceedsInvoice = errors.New("credit exceeds what is left of the invoice")
+)
+
+// InvoiceRepository issues the tax invoices and credit notes of orders. Invoices are numbered without gaps per
+// store, document type and year, and are never changed once issued.
+type InvoiceRepository interface {
+	// IssueInvoice issues the tax invoice of a paid order to buyer. Buyer fields left empty are taken from the
+	// order and its customer.
+	IssueInvoice(orderID uuid.UUID, buyer models.InvoiceParty) (*models.Invoice, error)
+	// IssueCreditNote credits amount of a tax invoice, or with a refund the amount refunded. The credit is
+	// spread over the invoice's lines in proportion to their amounts.
+	IssueCreditNote(invoiceID uuid.UUID, refundID *uuid.UUID, amount float64, reason string) (*models.Invoice, error)
+	// GetInvoiceByID returns an invoice or credit note with its lines
+	GetInvoiceByID(id uuid.UUID) (*models.Invoice, error)
+	// GetInvoicesByOrderID returns the tax invoice and credit notes of an order, oldest first
+	GetInvoicesByOrderID(orderID uuid.UUID) ([]models.Invoice, error)
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{db: db}
+}
+
+func (r *invoiceRepository) IssueInvoice(orderID uuid.UUID, buyer models.InvoiceParty) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", orderID).Error; err != nil {
+			return err
+		}
+		if !isTaxableStatus(order.Status) {
+			return ErrOrderNotInvoiceable
+		}
+		var issued int64
+		err := tx.Model(&models.Invoice{}).
+			Where("order_id = ? AND type = ?", order.ID, models.InvoiceTaxInvoiceReceipt).
+			Count(&issued).Error
+		if err != nil {
+			return err
+		}
+		if issued > 0 {
+			return ErrOrderInvoiced
+		}
+
+		var lines []struct {
+			models.OrderDetail
+			StoreID     uuid.UUID
+			ProductName string
+			SKUCode     string
+		}
+		err = tx.Table(`"order-detail"`).
+			Select(`"order-detail".*, "product".store_id, "product".product_name, "product-variant".sku_code`).
+			Joins(`JOIN "product-variant" ON "product-variant".variant_id = "order-detail".variant_id`).
+			Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+			Where(`"order-detail".order_id = ? AND "order-detail".deleted_at IS NULL`, order.ID).
+			Order(`"order-detail".created_at, "order-detail".id`).
+			Scan(&lines).Error
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return fmt.Errorf("order has no lines to invoice")
+		}
+		for _, line := range lines {
+			if line.StoreID != lines[0].StoreID {
+				return ErrOrderSpansStores
+			}
+		}
+
+		var store models.Store
+		if err := tx.Preload("TaxRates").First(&store, "id = ?", lines[0].StoreID).Error; err != nil {
+			return err
+		}
+		if store.TaxID == "" {
+			return ErrStoreNotTaxRegistered
+		}
+		var customer models.Customer
+		if err := tx.First(&customer, "id = ?", order.CustomerID).Error; err != nil {
+			return err
+		}
+
+		invoice = models.Invoice{
+			StoreID:  store.ID,
+			OrderID:  order.ID,
+			Type:     models.InvoiceTaxInvoiceReceipt,
+			IssuedAt: time.Now(),
+			Seller:   sellerParty(store),
+			Buyer:    buyerParty(buyer, &order, customer),
+			Currency: invoiceCurrency,
+		}
+		legacy := legacyTaxConfig(store)
+		for i, line := range lines {
+			lineTax := tax.LineTax{Rate: line.TaxRate, Net: line.NetAmount, Tax: line.TaxAmount, Gross: line.TotalPrice}
+			if line.TaxCategory == "" {
+				lineTax = legacy.Compute(tax.Line{UnitPrice: line.Price, Quantity: line.Quantity})
+			}
+			orderDetailID := line.ID
+			invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+				LineNumber:    i + 1,
+				OrderDetailID: &orderDetailID,
+				Description:   fmt.Sprintf("%s (%s)", line.ProductName, line.SKUCode),
+				Quantity:      line.Quantity,
+				UnitPrice:     tax.Round(lineTax.Net / float64(line.Quantity)),
+				TaxRate:       lineTax.Rate,
+				NetAmount:     lineTax.Net,
+				TaxAmount:     lineTax.Tax,
+				GrossAmount:   lineTax.Gross,
+			})
+		}
+		totalInvoiceLines(&invoice)
+
+		if err := numberInvoice(tx, &invoice); err != nil {
+			return err
+		}
+		if err := tx.Create(&invoice).Error; err != nil {
+			return err
+		}
+		return recordOrderHistory(tx, &order, fmt.Sprintf("tax invoice %s issued", invoice.Number))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *invoiceRepository) IssueCreditNote(invoiceID uuid.UUID, refundID *uuid.UUID, amount float64, reason string) (*models.Invoice, error) {
+	var note models.Invoice
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the invoice keeps two credit notes from crediting the same remainder
+		var invoice models.Invoice
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Lines", func(db *gorm.DB) *gorm.DB {
+				return db.Order("line_number")
+			}).
+			First(&invoice, "id = ?", invoiceID).Error
+		if err != nil {
+			return err
+		}
+		if invoice.Type != models.InvoiceTaxInvoiceReceipt {
+			return ErrNotTaxInvoice
+		}
+
+		if refundID != nil {
+			var refund models.PaymentRefund
+			if err := tx.First(&refund, "id = ?", *refundID).Error; err != nil {
+				return err
+			}
+			var payment models.Payment
+			if err := tx.First(&payment, "id = ?", refund.PaymentID).Error; err != nil {
+				return err
+			}
+			if payment.OrderID != invoice.OrderID {
+				return ErrRefundNotOfOrder
+			}
+			var credited int64
+			if err := tx.Model(&models.Invoice{}).Where("refund_id = ?", refund.ID).Count(&credited).Error; err != nil {
+				return err
+			}
+			if credited > 0 {
+				return ErrRefundCredited
+			}
+			amount = refund.Amount
+			if reason == "" {
+				reason = refund.Reason
+			}
+		}
+		amount = tax.Round(amount)
+		if amount <= 0 {
+			return fmt.Errorf("credit amount must be positive")
+		}
+
+		var credited float64
+		err = tx.Model(&models.Invoice{}).
+			Where("invoice_id = ? AND type = ?", invoice.ID, models.InvoiceCreditNote).
+			Select("COALESCE(SUM(gross_amount), 0)").
+			Scan(&credited).Error
+		if err != nil {
+			return err
+		}
+		if amount > tax.Round(invoice.GrossAmount-credited) {
+			return ErrCreditExceedsInvoice
+		}
+
+		note = models.Invoice{
+			StoreID:   invoice.StoreID,
+			OrderID:   invoice.OrderID,
+			Type:      models.InvoiceCreditNote,
+			IssuedAt:  time.Now(),
+			Seller:    invoice.Seller,
+			Buyer:     invoice.Buyer,
+			Currency:  invoice.Currency,
+			InvoiceID: &invoice.ID,
+			RefundID:  refundID,
+			Reason:    reason,
+			Lines:     creditLines(invoice, amount),
+		}
+		totalInvoiceLines(&note)
+
+		if err := numberInvoice(tx, &note); err != nil {
+			return err
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		order := models.Order{ID: invoice.OrderID}
+		if err := tx.Select("id", "status").First(&order).Error; err != nil {
+			return err
+		}
+		return recordOrderHistory(tx, &order, fmt.Sprintf("credit note %s issued against %s for %.2f", note.Number, invoice.Number, amount))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (r *invoiceRepository) GetInvoiceByID(id uuid.UUID) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line_number")
+	}).First(&invoice, "id = ?", id).Error
+	return &invoice, err
+}
+
+func (r *invoiceRepository) GetInvoicesByOrderID(orderID uuid.UUID) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line_number")
+	}).Where("order_id = ?", orderID).Order("issued_at").Find(&invoices).Error
+	return invoices, err
+}
+
+// numberInvoice assigns an invoice the next number of its store, type and year. The sequence row stays locked
+// until the transaction ends, so numbers are handed out in order and one is only used up if the invoice is saved.
+func numberInvoice(tx *gorm.DB, invoice *models.Invoice) error {
+	sequence := models.InvoiceSequence{StoreID: invoice.StoreID, Type: invoice.Type, Year: invoice.IssuedAt.Year()}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sequence).Error; err != nil {
+		return err
+	}
+	sequence.Last++
+	if err := tx.Model(&sequence).Update("last", sequence.Last).Error; err != nil {
+		return err
+	}
+
+	invoice.Number = invoiceNumber(invoice.Type, sequence.Year, sequence.Last)
+	return nil
+}
+
+// invoiceNumber formats the number of a document type and year, e.g. INV2026-000042 or CN2026-000003
+func invoiceNumber(invoiceType string, year int, n int) string {
+	prefix := "INV"
+	if invoiceType == models.InvoiceCreditNote {
+		prefix = "CN"
+	}
+	return fmt.Sprintf("%s%d-%06d", prefix, year, n)
+}
+
+// creditLines spreads a credit over the lines of an invoice in proportion to their gross amounts, so each VAT
+// rate is credited its share. The last line takes what rounding leaves over.
+func creditLines(invoice models.Invoice, amount float64) []models.InvoiceLine {
+	lines := make([]models.InvoiceLine, 0, len(invoice.Lines))
+	left := amount
+	for i, line := range invoice.Lines {
+		gross := left
+		if i < len(invoice.Lines)-1 {
+			gross = tax.Round(amount * line.GrossAmount / invoice.GrossAmount)
+		}
+		left = tax.Round(left - gross)
+		if gross == 0 {
+			continue
+		}
+		vat := tax.Round(gross * line.TaxRate / (100 + line.TaxRate))
+		orderDetailID := line.OrderDetailID
+		lines = append(lines, models.InvoiceLine{
+			LineNumber:    len(lines) + 1,
+			OrderDetailID: orderDetailID,
+			Description:   line.Description,
+			Quantity:      1,
+			UnitPrice:     tax.Round(gross - vat),
+			TaxRate:       line.TaxRate,
+			NetAmount:     tax.Round(gross - vat),
+			TaxAmount:     vat,
+			GrossAmount:   gross,
+		})
+	}
+	return lines
+}
+
+// totalInvoiceLines sets an invoice's totals to the sums of its lines
+func totalInvoiceLines(invoice *models.Invoice) {
+	var net, vat, gross float64
+	for _, line := range invoice.Lines {
+		net += line.NetAmount
+		vat += line.TaxAmount
+		gross += line.GrossAmount
+	}
+	invoice.NetAmount = tax.Round(net)
+	invoice.TaxAmount = tax.Round(vat)
+	invoice.GrossAmount = tax.Round(gross)
+}
+
+// sellerParty is how a store is named on its invoices
+func sellerParty(store models.Store) models.InvoiceParty {
+	party := models.InvoiceParty{
+		Name:       store.LegalName,
+		TaxID:      store.TaxID,
+		BranchCode: store.BranchCode,
+		Address:    store.Location,
+		Postcode:   carriers.PostalCodeFrom(store.Location),
+	}
+	if party.Name == "" {
+		party.Name = store.StoreName
+	}
+	if party.BranchCode == "" {
+		party.BranchCode = "00000"
+	}
+	return party
+}
+
+// buyerParty fills the buyer fields left empty with the order's delivery address and its customer
+func buyerParty(buyer models.InvoiceParty, order *models.Order, customer models.Customer) models.InvoiceParty {
+	if buyer.Name == "" {
+		buyer.Name = customer.Name
+	}
+	if buyer.Email == "" {
+		buyer.Email = customer.Email
+	}
+	if buyer.Address == "" {
+		if address := order.ShippingAddress; !address.IsZero() {
+			address.Recipient = ""
+			address.Phone = ""
+			buyer.Address = address.String()
+			if buyer.Postcode == "" {
+				buyer.Postcode = address.Postcode
+			}
+		} else {
+			buyer.Address = order.DeliveryAddress
+			if buyer.Postcode == "" {
+				buyer.Postcode = carriers.PostalCodeFrom(order.DeliveryAddress)
+			}
+		}
+	}
+	if buyer.TaxID != "" && buyer.BranchCode == "" {
+		buyer.BranchCode = "00000"
+	}
+	return buyer
+}
+
+// isTaxableStatus reports whether an order in status is reported for VAT
+func isTaxableStatus(status string) bool {
+	for _, taxable := range taxableOrderStatuses {
+		if status == taxable {
+			return true
+		}
+	}
+	return false
+}
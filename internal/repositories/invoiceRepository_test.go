@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+)
+
+func TestCreditLines(t *testing.T) {
+	invoice := func(lines ...models.InvoiceLine) models.Invoice {
+		result := models.Invoice{Lines: lines}
+		for _, line := range lines {
+			result.GrossAmount += line.GrossAmount
+		}
+		return result
+	}
+	standard := func(gross float64) models.InvoiceLine {
+		return models.InvoiceLine{Description: "standard", TaxRate: 7, GrossAmount: gross}
+	}
+	zeroRated := models.InvoiceLine{Description: "zero rated", TaxRate: 0, GrossAmount: 50}
+
+	type wantLine struct {
+		gross, net, vat float64
+	}
+	tests := []struct {
+		name    string
+		invoice models.Invoice
+		amount  float64
+		want    []wantLine
+	}{
+		{
+			name:    "full credit of two VAT rates",
+			invoice: invoice(standard(107), zeroRated),
+			amount:  157,
+			want:    []wantLine{{gross: 107, net: 100, vat: 7}, {gross: 50, net: 50, vat: 0}},
+		},
+		{
+			name:    "part credit is spread by gross amount",
+			invoice: invoice(standard(107), zeroRated),
+			amount:  100,
+			want:    []wantLine{{gross: 68.15, net: 63.69, vat: 4.46}, {gross: 31.85, net: 31.85, vat: 0}},
+		},
+		{
+			name:    "last line takes the rounding",
+			invoice: invoice(standard(10), standard(10), standard(10)),
+			amount:  10,
+			want:    []wantLine{{gross: 3.33, net: 3.11, vat: 0.22}, {gross: 3.33, net: 3.11, vat: 0.22}, {gross: 3.34, net: 3.12, vat: 0.22}},
+		},
+		{
+			name:    "lines with nothing to credit are left out",
+			invoice: invoice(standard(100), models.InvoiceLine{Description: "free gift", TaxRate: 7}),
+			amount:  50,
+			want:    []wantLine{{gross: 50, net: 46.73, vat: 3.27}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := creditLines(tt.invoice, tt.amount)
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d", len(lines), len(tt.want))
+			}
+			var gross float64
+			for i, line := range lines {
+				want := tt.want[i]
+				if line.GrossAmount != want.gross || line.NetAmount != want.net || line.TaxAmount != want.vat {
+					t.Errorf("line %d = gross %v net %v vat %v, want gross %v net %v vat %v",
+						i+1, line.GrossAmount, line.NetAmount, line.TaxAmount, want.gross, want.net, want.vat)
+				}
+				if line.LineNumber != i+1 {
+					t.Errorf("line %d is numbered %d", i+1, line.LineNumber)
+				}
+				if tax.Round(line.NetAmount+line.TaxAmount) != line.GrossAmount {
+					t.Errorf("line %d: net %v and vat %v do not add up to gross %v", i+1, line.NetAmount, line.TaxAmount, line.GrossAmount)
+				}
+				gross += line.GrossAmount
+			}
+			if tax.Round(gross) != tt.amount {
+				t.Errorf("credit lines add up to %v, want %v", tax.Round(gross), tt.amount)
+			}
+		})
+	}
+}
+
+func TestInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		invoiceType string
+		year        int
+		n           int
+		want        string
+	}{
+		{invoiceType: models.InvoiceTaxInvoiceReceipt, year: 2026, n: 1, want: "INV2026-000001"},
+		{invoiceType: models.InvoiceTaxInvoiceReceipt, year: 2026, n: 42, want: "INV2026-000042"},
+		{invoiceType: models.InvoiceCreditNote, year: 2027, n: 3, want: "CN2027-000003"},
+		{invoiceType: models.InvoiceTaxInvoiceReceipt, year: 2026, n: 1234567, want: "INV2026-1234567"},
+	}
+
+	for _, tt := range tests {
+		if got := invoiceNumber(tt.invoiceType, tt.year, tt.n); got != tt.want {
+			t.Errorf("invoiceNumber(%q, %d, %d) = %q, want %q", tt.invoiceType, tt.year, tt.n, got, tt.want)
+		}
+	}
+}
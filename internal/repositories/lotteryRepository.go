@@ -0,0 +1,375 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLotteryRound is returned when a lottery round is purchased from or waitlisted directly
+	ErrLotteryRound = errors.New("sales round is a lottery; enter the draw instead")
+	// ErrNotLotteryRound is returned for lottery operations on a first-come-first-served round
+	ErrNotLotteryRound = errors.New("sales round is not a lottery")
+	// ErrLotteryClosed is returned for entries made outside the round's window
+	ErrLotteryClosed = errors.New("lottery is not open for entries")
+	// ErrLotteryStillOpen is returned when a lottery is drawn before the round has ended
+	ErrLotteryStillOpen = errors.New("lottery is still open for entries")
+	// ErrLotteryDrawn is returned when a lottery is drawn twice
+	ErrLotteryDrawn = errors.New("lottery has already been drawn")
+	// ErrAlreadyEntered is returned when a customer enters the same variant of a lottery twice
+	ErrAlreadyEntered = errors.New("customer has already entered the lottery for this variant")
+)
+
+type LotteryRepository interface {
+	EnterLottery(entry *models.LotteryEntry) error
+	GetLotteryEntries(roundID uuid.UUID, customerID *uuid.UUID) ([]models.LotteryEntry, error)
+	GetDueLotteryRounds(now time.Time) ([]uuid.UUID, error)
+	DrawLottery(roundID uuid.UUID) ([]models.LotteryEntry, error)
+	ForfeitOverdueWins(now time.Time) ([]models.LotteryEntry, error)
+	AwardReleasedQuantity() ([]models.LotteryEntry, error)
+}
+
+type lotteryRepository struct {
+	db        *gorm.DB
+	observers stockObservers
+}
+
+func NewLotteryRepository(db *gorm.DB, observers ...StockObserver) LotteryRepository {
+	return &lotteryRepository{db: db, observers: observers}
+}
+
+// EnterLottery registers a customer's entry for a variant of an open lottery round
+func (r *lotteryRepository) EnterLottery(entry *models.LotteryEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var round models.SalesRound
+		if err := tx.First(&round, "id = ?", entry.RoundID).Error; err != nil {
+			return err
+		}
+		if round.Mode != models.SalesRoundModeLottery {
+			return ErrNotLotteryRound
+		}
+		now := time.Now()
+		if round.DrawnAt != nil || now.Before(round.StartDate) || !now.Before(round.EndDate) {
+			return ErrLotteryClosed
+		}
+
+		var detail models.SalesRoundDetail
+		if err := tx.Where("round_id = ? AND variant_id = ?", entry.RoundID, entry.VariantID).First(&detail).Error; err != nil {
+			return err
+		}
+		if detail.QuantityLimit > 0 && entry.Quantity > detail.QuantityLimit {
+			return fmt.Errorf("quantity exceeds sales round limit of %d", detail.QuantityLimit)
+		}
+
+		var existing int64
+		err := tx.Model(&models.LotteryEntry{}).
+			Where("round_id = ? AND variant_id = ? AND customer_id = ?", entry.RoundID, entry.VariantID, entry.CustomerID).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyEntered
+		}
+
+		entry.Status = models.LotteryEntered
+		return tx.Create(entry).Error
+	})
+}
+
+// GetLotteryEntries returns a round's entries, in draw order once the round is drawn
+func (r *lotteryRepository) GetLotteryEntries(roundID uuid.UUID, customerID *uuid.UUID) ([]models.LotteryEntry, error) {
+	var entries []models.LotteryEntry
+	query := r.db.Where("round_id = ?", roundID).Order("rank, created_at")
+	if customerID != nil {
+		query = query.Where("customer_id = ?", *customerID)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+// GetDueLotteryRounds returns the lottery rounds that have ended but are not drawn yet
+func (r *lotteryRepository) GetDueLotteryRounds(now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.SalesRound{}).
+		Where("mode = ? AND drawn_at IS NULL AND end_date <= ?", models.SalesRoundModeLottery, now).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// DrawLottery ranks the entries of an ended lottery round by their ticket and awards each variant's quantity
+// in rank order, creating a pending order for every winner. The seed is revealed with the draw. The winners
+// are returned.
+func (r *lotteryRepository) DrawLottery(roundID uuid.UUID) ([]models.LotteryEntry, error) {
+	var winners []models.LotteryEntry
+	var variantIDs []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var round models.SalesRound
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&round, "id = ?", roundID).Error; err != nil {
+			return err
+		}
+		if round.Mode != models.SalesRoundModeLottery {
+			return ErrNotLotteryRound
+		}
+		if round.DrawnAt != nil {
+			return ErrLotteryDrawn
+		}
+		now := time.Now()
+		if now.Before(round.EndDate) {
+			return ErrLotteryStillOpen
+		}
+		if round.DrawSeed == "" {
+			return fmt.Errorf("lottery round %s has no draw seed", round.ID)
+		}
+
+		var entries []models.LotteryEntry
+		if err := tx.Where("round_id = ?", round.ID).Find(&entries).Error; err != nil {
+			return err
+		}
+		rankLotteryEntries(round.DrawSeed, entries)
+		for i := range entries {
+			if err := tx.Model(&entries[i]).Select("ticket", "rank").Updates(&entries[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		var details []models.SalesRoundDetail
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("round_id = ?", round.ID).Find(&details).Error; err != nil {
+			return err
+		}
+		for i := range details {
+			won, err := awardLotteryQuantity(tx, &round, &details[i], now)
+			if err != nil {
+				return err
+			}
+			winners = append(winners, won...)
+			variantIDs = append(variantIDs, details[i].VariantID)
+		}
+
+		round.DrawnAt = &now
+		log.Printf("Drew lottery round %s: %d entries, %d winners", round.ID, len(entries), len(winners))
+		return tx.Model(&round).Update("drawn_at", round.DrawnAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observers.notify(variantIDs...)
+	return winners, nil
+}
+
+// ForfeitOverdueWins cancels the pending orders of lottery winners who did not pay in time. The quantity goes back
+// to the round, where AwardReleasedQuantity hands it to the next entrants. The forfeited entries are returned.
+func (r *lotteryRepository) ForfeitOverdueWins(now time.Time) ([]models.LotteryEntry, error) {
+	var forfeited []models.LotteryEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var orders []models.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND payment_due_at < ?", models.OrderStatusPending, now).
+			Where("id IN (?)", tx.Model(&models.LotteryEntry{}).Select("order_id").Where("status = ?", models.LotteryWon)).
+			Find(&orders).Error
+		if err != nil {
+			return err
+		}
+
+		orderIDs := make([]uuid.UUID, 0, len(orders))
+		for i := range orders {
+			if err := cancelOrder(tx, &orders[i], "lottery payment deadline passed"); err != nil {
+				return err
+			}
+			orderIDs = append(orderIDs, orders[i].ID)
+		}
+		if len(orderIDs) == 0 {
+			return nil
+		}
+		return tx.Where("order_id IN ?", orderIDs).Find(&forfeited).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range forfeited {
+		r.observers.notify(entry.VariantID)
+	}
+	return forfeited, nil
+}
+
+// AwardReleasedQuantity hands quantity that came back to drawn lottery rounds, from forfeits or cancellations,
+// to the entrants that have not won yet, in rank order. The new winners are returned.
+func (r *lotteryRepository) AwardReleasedQuantity() ([]models.LotteryEntry, error) {
+	var winners []models.LotteryEntry
+	var variantIDs []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var details []models.SalesRoundDetail
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("quantity > 0").
+			Where("round_id IN (?)", tx.Model(&models.SalesRound{}).Select("id").Where("mode = ? AND drawn_at IS NOT NULL", models.SalesRoundModeLottery)).
+			Where("(round_id, variant_id) IN (?)", tx.Model(&models.LotteryEntry{}).Select("round_id, variant_id").Where("status = ?", models.LotteryLost)).
+			Find(&details).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range details {
+			var round models.SalesRound
+			if err := tx.First(&round, "id = ?", details[i].RoundID).Error; err != nil {
+				return err
+			}
+			won, err := awardLotteryQuantity(tx, &round, &details[i], now)
+			if err != nil {
+				return err
+			}
+			if len(won) > 0 {
+				winners = append(winners, won...)
+				variantIDs = append(variantIDs, details[i].VariantID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observers.notify(variantIDs...)
+	return winners, nil
+}
+
+// awardLotteryQuantity hands the quantity left in a lottery round's detail to the entries for its variant that have
+// not won yet, in rank order, each up to the quantity limit. Every winner gets a pending order due after the round's
+// payment window; entries left without quantity are marked lost. The new winners are returned.
+func awardLotteryQuantity(tx *gorm.DB, round *models.SalesRound, detail *models.SalesRoundDetail, now time.Time) ([]models.LotteryEntry, error) {
+	var candidates []models.LotteryEntry
+	err := tx.Where("round_id = ? AND variant_id = ? AND status IN ?", round.ID, detail.VariantID, []string{models.LotteryEntered, models.LotteryLost}).
+		Order("rank").
+		Find(&candidates).Error
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	var variant models.ProductVariant
+	if err := tx.First(&variant, "variant_id = ?", detail.VariantID).Error; err != nil {
+		return nil, err
+	}
+	// The round may hold more than the product has left, e.g. after a stock take; winners only get what is there
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", variant.ProductID).Error; err != nil {
+		return nil, err
+	}
+	dueAt := now.Add(round.PaymentWindow())
+
+	var winners []models.LotteryEntry
+	for i := range candidates {
+		entry := &candidates[i]
+		quantity := lotteryAwardQuantity(entry.Quantity, detail.QuantityLimit, detail.Quantity, product.Stock)
+		if quantity <= 0 {
+			if entry.Status == models.LotteryEntered {
+				if err := tx.Model(entry).Update("status", models.LotteryLost).Error; err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		order := models.Order{
+			CustomerID:      entry.CustomerID,
+			RoundID:         round.ID,
+			OrderDate:       now,
+			Status:          models.OrderStatusPending,
+			Code:            fmt.Sprintf("LOTTERY-%s", uuid.New().String()),
+			DeliveryAddress: entry.DeliveryAddress,
+			PaymentSource:   entry.PaymentSource,
+			PaymentDueAt:    &dueAt,
+			OrderDetail: []models.OrderDetail{{
+				VariantID:    variant.VariantID,
+				Quantity:     quantity,
+				Price:        detail.SalePrice(variant.Price),
+				RegularPrice: variant.Price,
+			}},
+		}
+		if err := applyOrderTax(tx, &order); err != nil {
+			return nil, err
+		}
+		if err := tx.Omit(clause.Associations).Create(&order).Error; err != nil {
+			return nil, err
+		}
+		line := &order.OrderDetail[0]
+		line.OrderID = order.ID
+		if err := tx.Omit(clause.Associations).Create(line).Error; err != nil {
+			return nil, err
+		}
+		if err := recordEvent(tx, models.EventOrderPlaced, "order", order.ID, orderEventPayload(&order)); err != nil {
+			return nil, err
+		}
+		result := tx.Model(&models.Product{}).
+			Where("id = ? AND stock >= ?", variant.ProductID, quantity).
+			Update("stock", gorm.Expr("stock - ?", quantity))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("not enough stock")
+		}
+
+		product.Stock -= quantity
+		detail.Quantity -= quantity
+		entry.Status = models.LotteryWon
+		entry.WonQuantity = quantity
+		entry.OrderID = &order.ID
+		if err := tx.Model(entry).Select("status", "won_quantity", "order_id").Updates(entry).Error; err != nil {
+			return nil, err
+		}
+		winners = append(winners, *entry)
+	}
+
+	return winners, tx.Model(detail).Update("quantity", detail.Quantity).Error
+}
+
+// rankLotteryEntries gives each entry its ticket for the seed and sorts the entries by ascending ticket, setting
+// their ranks from 1
+func rankLotteryEntries(seed string, entries []models.LotteryEntry) {
+	for i := range entries {
+		entries[i].Ticket = models.LotteryTicket(seed, entries[i].ID)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Ticket < entries[j].Ticket })
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+}
+
+// lotteryAwardQuantity is how much of what an entry asked for it wins: no more than the round's quantity limit,
+// the quantity left in the round or the product's stock
+func lotteryAwardQuantity(requested, limit, left, stock int) int {
+	quantity := min(requested, left, stock)
+	if limit > 0 {
+		quantity = min(quantity, limit)
+	}
+	return max(quantity, 0)
+}
+
+// commitDrawSeed gives a lottery round a random draw seed and publishes its SHA-256 as the commitment, so the
+// draw cannot be steered once entries come in
+func commitDrawSeed(round *models.SalesRound) error {
+	if round.Mode == "" {
+		round.Mode = models.SalesRoundModeFCFS
+	}
+	if round.Mode != models.SalesRoundModeLottery || round.DrawSeed != "" {
+		return nil
+	}
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return err
+	}
+	round.DrawSeed = hex.EncodeToString(seed)
+	sum := sha256.Sum256([]byte(round.DrawSeed))
+	round.DrawCommitment = hex.EncodeToString(sum[:])
+	return nil
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestLotteryAwardQuantityNeverExceedsStock(t *testing.T) {
+	if got := lotteryAwardQuantity(3, 0, 10, 2); got != 2 {
+		t.Errorf("entry for 3 with 2 in stock wins %d, want 2", got)
+	}
+	if got := lotteryAwardQuantity(3, 0, 10, 0); got != 0 {
+		t.Errorf("entry with nothing in stock wins %d, want 0", got)
+	}
+	// Stock pushed below zero elsewhere must not turn into a negative award
+	if got := lotteryAwardQuantity(3, 0, 10, -1); got != 0 {
+		t.Errorf("entry with negative stock wins %d, want 0", got)
+	}
+	if got := lotteryAwardQuantity(3, 2, 10, 10); got != 2 {
+		t.Errorf("entry for 3 with a limit of 2 wins %d, want 2", got)
+	}
+	if got := lotteryAwardQuantity(3, 0, 1, 10); got != 1 {
+		t.Errorf("entry for 3 with 1 left in the round wins %d, want 1", got)
+	}
+}
+
+func TestLotteryDrawReproducesFromTheRevealedSeed(t *testing.T) {
+	// A buyer holding the revealed seed and the entry IDs ranks the entries by SHA-256("<seed>:<entry id>")
+	const seed = "4f1c0d9e2b7a"
+	var entries []models.LotteryEntry
+	for _, id := range []string{
+		"00000000-0000-0000-0000-000000000001",
+		"00000000-0000-0000-0000-000000000002",
+		"00000000-0000-0000-0000-000000000003",
+		"00000000-0000-0000-0000-000000000004",
+		"00000000-0000-0000-0000-000000000005",
+	} {
+		entries = append(entries, models.LotteryEntry{ID: uuid.MustParse(id)})
+	}
+	rankLotteryEntries(seed, entries)
+
+	want := []struct {
+		id     string
+		ticket string
+	}{
+		{"00000000-0000-0000-0000-000000000002", "5f29e42b9058e1110faa101179b6888950228ab8de53793af0e612fdb7fa8791"},
+		{"00000000-0000-0000-0000-000000000004", "a0c05c1a084771dba8f7cbe4c200d5897996e7c943b1ae6d4c6d781618349c45"},
+		{"00000000-0000-0000-0000-000000000003", "b5a1233a65ffc092f90b51761f983fd7f34bd6e4480a5af5c26781c16fa9b8a8"},
+		{"00000000-0000-0000-0000-000000000005", "e48a2c2a5d816ba23d69f4daae080e4c9e3d6b8c0b281e8ebcd68223de855df2"},
+		{"00000000-0000-0000-0000-000000000001", "ea35cc1204555790d9a4e6d172503c64c8e16f2edcdcb1f2866b15d9b81b8e05"},
+	}
+	for i, entry := range entries {
+		if entry.ID.String() != want[i].id || entry.Ticket != want[i].ticket || entry.Rank != i+1 {
+			t.Errorf("rank %d is %s with ticket %s (rank %d), want %s with ticket %s", i+1, entry.ID, entry.Ticket, entry.Rank, want[i].id, want[i].ticket)
+		}
+	}
+
+	// The order the entries are read in does not change the draw
+	reversed := make([]models.LotteryEntry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = models.LotteryEntry{ID: entry.ID}
+	}
+	rankLotteryEntries(seed, reversed)
+	for i := range reversed {
+		if reversed[i].ID != entries[i].ID {
+			t.Fatalf("rank %d is %s when read in reverse, want %s", i+1, reversed[i].ID, entries[i].ID)
+		}
+	}
+}
+
+func TestCommitDrawSeed(t *testing.T) {
+	round := models.SalesRound{Mode: models.SalesRoundModeLottery}
+	if err := commitDrawSeed(&round); err != nil {
+		t.Fatalf("commitDrawSeed() error = %v", err)
+	}
+	sum := sha256.Sum256([]byte(round.DrawSeed))
+	if len(round.DrawSeed) != 64 || round.DrawCommitment != hex.EncodeToString(sum[:]) {
+		t.Errorf("seed %q has commitment %q, want its SHA-256", round.DrawSeed, round.DrawCommitment)
+	}
+
+	// The seed is fixed once committed, and rounds that are not lotteries get none
+	committed := round.DrawSeed
+	if err := commitDrawSeed(&round); err != nil || round.DrawSeed != committed {
+		t.Errorf("committing again changed the seed to %q (error %v)", round.DrawSeed, err)
+	}
+	fcfs := models.SalesRound{}
+	if err := commitDrawSeed(&fcfs); err != nil || fcfs.DrawSeed != "" || fcfs.Mode != models.SalesRoundModeFCFS {
+		t.Errorf("round without a mode is %s with seed %q (error %v), want fcfs without a seed", fcfs.Mode, fcfs.DrawSeed, err)
+	}
+}
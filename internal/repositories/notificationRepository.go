@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	CreateNotification(notification *models.Notification) error
+	GetNotifications(notificationType string, unreadOnly bool) ([]models.Notification, error)
+	MarkNotificationRead(id uuid.UUID) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) CreateNotification(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) GetNotifications(notificationType string, unreadOnly bool) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.Order("created_at DESC")
+	if notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *notificationRepository) MarkNotificationRead(id uuid.UUID) error {
+	result := r.db.Model(&models.Notification{}).Where("id = ? AND read_at IS NULL", id).Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
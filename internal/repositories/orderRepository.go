@@ -1,16 +1,21 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository interface {
 	CreateOrder(order *models.Order) error
+	PlaceOrder(order *models.Order, discountCodes []string) error
 	GetAllOrders() ([]models.Order, error)
 	GetOrderByID(id uuid.UUID) (*models.Order, error)
 	UpdateOrder(order *models.Order) error
@@ -20,8 +25,21 @@ type OrderRepository interface {
 	GetTotalItemsOrdered(roundID uuid.UUID) (int, error)
 	GetTotalItemsSold(roundID uuid.UUID) (int, error)
 	GetOrdersByRoundID(roundID uuid.UUID) ([]models.Order, error)
+	CancelOrder(id uuid.UUID, reason string) (*models.Order, error)
+	ConfirmOrder(id uuid.UUID) (*models.Order, error)
+	GetOrderStore(id uuid.UUID) (*models.Store, error)
+	ExpireOverdueOrders(now time.Time, limit int) ([]models.Order, error)
 }
 
+var (
+	// ErrPaymentOverdue is returned when a pending order is confirmed after its payment deadline
+	ErrPaymentOverdue = errors.New("payment deadline has passed")
+	// ErrOrderSpansStores is returned when the lines of an order come from more than one store
+	ErrOrderSpansStores = errors.New("order has products of more than one store")
+	// ErrOrderShipped is returned when an order that was already shipped, in part or in full, is cancelled
+	ErrOrderShipped = errors.New("order has already been shipped")
+)
+
 type orderRepository struct {
 	db *gorm.DB
 }
@@ -46,6 +64,61 @@ func (r *orderRepository) CreateOrder(order *models.Order) error {
 	return <-errChan
 }
 
+// PlaceOrder claims the order's lines from its sales round, applies the discount codes, creates the order with its
+// lines, takes the products' stock and records OrderPlaced, all in one transaction. A pending order is due for
+// payment within the round's payment window.
+func (r *orderRepository) PlaceOrder(order *models.Order, discountCodes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		items := make([]dtos.PurchaseItemDTO, 0, len(order.OrderDetail))
+		for _, line := range order.OrderDetail {
+			items = append(items, dtos.PurchaseItemDTO{VariantID: line.VariantID, Quantity: line.Quantity})
+		}
+		round, err := claimRoundQuantity(tx, order.RoundID, order.CustomerID, items)
+		if err != nil {
+			return err
+		}
+		if order.Status == models.OrderStatusPending && order.PaymentDueAt == nil {
+			dueAt := time.Now().Add(round.PaymentWindow())
+			order.PaymentDueAt = &dueAt
+		}
+		if err := discountOrder(tx, order, discountCodes, true); err != nil {
+			return err
+		}
+		if err := applyOrderTax(tx, order); err != nil {
+			return err
+		}
+
+		if err := tx.Omit(clause.Associations).Create(order).Error; err != nil {
+			return err
+		}
+		for i := range order.OrderDetail {
+			line := &order.OrderDetail[i]
+			line.OrderID = order.ID
+			if err := tx.Omit(clause.Associations).Create(line).Error; err != nil {
+				return err
+			}
+
+			var variant models.ProductVariant
+			if err := tx.First(&variant, "variant_id = ?", line.VariantID).Error; err != nil {
+				return err
+			}
+			result := tx.Model(&models.Product{}).
+				Where("id = ? AND stock >= ?", variant.ProductID, line.Quantity).
+				Update("stock", gorm.Expr("stock - ?", line.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("not enough stock")
+			}
+		}
+		if err := redeemDiscounts(tx, order); err != nil {
+			return err
+		}
+		return recordEvent(tx, models.EventOrderPlaced, "order", order.ID, orderEventPayload(order))
+	})
+}
+
 func (r *orderRepository) GetAllOrders() ([]models.Order, error) {
 	var orders []models.Order
 	errChan := make(chan error, 1)
@@ -92,7 +165,16 @@ func (r *orderRepository) UpdateOrder(order *models.Order) error {
 			}
 			close(errChan)
 		}()
-		errChan <- r.db.Save(order).Error
+		errChan <- r.db.Transaction(func(tx *gorm.DB) error {
+			var previous models.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, "id = ?", order.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Save(order).Error; err != nil {
+				return err
+			}
+			return recordStatusChange(tx, order, previous.Status, "")
+		})
 	}()
 
 	return <-errChan
@@ -126,7 +208,7 @@ func (r *orderRepository) GetRecognizedRevenue(roundID uuid.UUID) (float64, erro
 			}
 			close(errChan)
 		}()
-		errChan <- r.db.Model(&models.Order{}).Where("round_id = ? AND status = ?", roundID, "paid").Select("SUM(total_price)").Scan(&totalRevenue).Error
+		errChan <- r.db.Model(&models.Order{}).Where("round_id = ? AND status = ?", roundID, models.OrderStatusPaid).Select("SUM(total_price)").Scan(&totalRevenue).Error
 	}()
 	err := <-errChan
 	return totalRevenue, err
@@ -183,7 +265,7 @@ func (r *orderRepository) GetTotalItemsSold(roundID uuid.UUID) (int, error) {
 		}()
 		errChan <- r.db.Model(&models.OrderDetail{}).
 			Joins("JOIN \"order\" ON \"order\".id = \"order-detail\".order_id").
-			Where("\"order\".round_id = ? AND \"order\".status = ?", roundID, "paid").
+			Where("\"order\".round_id = ? AND \"order\".status = ?", roundID, models.OrderStatusPaid).
 			Select("SUM(\"order-detail\".quantity)").
 			Scan(&totalItems).Error
 
@@ -208,3 +290,246 @@ func (r *orderRepository) GetOrdersByRoundID(roundID uuid.UUID) ([]models.Order,
 	err := <-errChan
 	return orders, err
 }
+
+// CancelOrder cancels an order and returns its quantities to the sales round and its stock to the products.
+// The order is returned with its details.
+func (r *orderRepository) CancelOrder(id uuid.UUID, reason string) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return cancelOrder(tx, &order, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ConfirmOrder marks a pending order as paid; once its payment deadline has passed it can no longer be confirmed
+func (r *orderRepository) ConfirmOrder(id uuid.UUID) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusPending {
+			return fmt.Errorf("order is %s, not %s", order.Status, models.OrderStatusPending)
+		}
+		if order.PaymentDueAt != nil && time.Now().After(*order.PaymentDueAt) {
+			return ErrPaymentOverdue
+		}
+		return markOrderPaid(tx, &order, "confirmed by staff")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ExpireOverdueOrders cancels up to limit pending orders whose payment deadline passed before now, returning
+// their quantities to the sales rounds. Lottery wins are left to the lottery, which hands them to the next
+// entrants. The cancelled orders are returned with their details. Orders are locked with SKIP LOCKED, so an
+// order being paid at the same time is left for the next run. Each order is cancelled in a savepoint of its
+// own; one that cannot be cancelled is logged and skipped so it does not hold up the others.
+func (r *orderRepository) ExpireOverdueOrders(now time.Time, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	var expired []models.Order
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND payment_due_at < ?", models.OrderStatusPending, now).
+			Where("NOT EXISTS (?)", tx.Model(&models.LotteryEntry{}).
+				Select("1").
+				Where(`"lottery-entry".order_id = "order".id AND "lottery-entry".status = ?`, models.LotteryWon)).
+			Order("payment_due_at").
+			Limit(limit).
+			Find(&orders).Error
+		if err != nil {
+			return err
+		}
+		for i := range orders {
+			order := &orders[i]
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return cancelOrder(tx, order, "payment deadline passed")
+			})
+			if err != nil {
+				log.Printf("Error expiring order %s: %v", order.Code, err)
+				continue
+			}
+			expired = append(expired, *order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// GetOrderStore returns the store whose products the order is for
+func (r *orderRepository) GetOrderStore(id uuid.UUID) (*models.Store, error) {
+	var storeIDs []uuid.UUID
+	err := r.db.Table(`"order-detail"`).
+		Joins(`JOIN "product-variant" ON "product-variant".variant_id = "order-detail".variant_id`).
+		Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+		Where(`"order-detail".order_id = ? AND "order-detail".deleted_at IS NULL`, id).
+		Distinct(`"product".store_id`).
+		Pluck(`"product".store_id`, &storeIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	switch len(storeIDs) {
+	case 0:
+		return nil, gorm.ErrRecordNotFound
+	case 1:
+	default:
+		return nil, ErrOrderSpansStores
+	}
+
+	var store models.Store
+	err = r.db.First(&store, "id = ?", storeIDs[0]).Error
+	return &store, err
+}
+
+// markOrderPaid moves a locked pending order to paid and claims the lottery win it was created for, if any
+func markOrderPaid(tx *gorm.DB, order *models.Order, reason string) error {
+	order.Status = models.OrderStatusPaid
+	if err := tx.Model(order).Update("status", order.Status).Error; err != nil {
+		return err
+	}
+	if err := recordStatusChange(tx, order, models.OrderStatusPending, reason); err != nil {
+		return err
+	}
+	return tx.Model(&models.LotteryEntry{}).
+		Where("order_id = ? AND status = ?", order.ID, models.LotteryWon).
+		Update("status", models.LotteryClaimed).Error
+}
+
+// cancelOrder returns the quantities of a locked order to its sales round, marks it cancelled and records
+// OrderCancelled. A lottery win the order was created for is forfeited, and the discount codes it used can be
+// used again.
+func cancelOrder(tx *gorm.DB, order *models.Order, reason string) error {
+	if order.Status == models.OrderStatusCancelled {
+		return fmt.Errorf("order is already cancelled")
+	}
+	switch order.Status {
+	case models.OrderStatusPartiallyShipped, models.OrderStatusShipped, models.OrderStatusDelivered:
+		return ErrOrderShipped
+	}
+	if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderDetail).Error; err != nil {
+		return err
+	}
+
+	for _, line := range order.OrderDetail {
+		if err := restoreRoundQuantity(tx, order.RoundID, line.VariantID, line.Quantity); err != nil {
+			return err
+		}
+	}
+	err := tx.Model(&models.LotteryEntry{}).
+		Where("order_id = ? AND status = ?", order.ID, models.LotteryWon).
+		Update("status", models.LotteryForfeited).Error
+	if err != nil {
+		return err
+	}
+	if err := releaseDiscounts(tx, order); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	previous := order.Status
+	order.Status = models.OrderStatusCancelled
+	order.CancelledAt = &now
+	order.CancelReason = reason
+	log.Printf("Cancelling order %s: %s", order.Code, reason)
+	if err := tx.Model(order).Select("status", "cancelled_at", "cancel_reason").Updates(order).Error; err != nil {
+		return err
+	}
+	if err := recordStatusChange(tx, order, previous, reason); err != nil {
+		return err
+	}
+	data := orderEventPayload(order)
+	data["previous_status"] = previous
+	data["cancelled_at"] = order.CancelledAt
+	data["reason"] = reason
+	return recordEvent(tx, models.EventOrderCancelled, "order", order.ID, data)
+}
+
+// orderEventPayload describes an order and its lines in OrderPlaced and OrderCancelled events
+func orderEventPayload(order *models.Order) map[string]interface{} {
+	lines := make([]map[string]interface{}, 0, len(order.OrderDetail))
+	for _, line := range order.OrderDetail {
+		lines = append(lines, map[string]interface{}{
+			"variant_id":    line.VariantID,
+			"quantity":      line.Quantity,
+			"price":         line.Price,
+			"regular_price": line.RegularPrice,
+			"total_price":   line.TotalPrice,
+			"discount":      line.DiscountAmount,
+			"tax_rate":      line.TaxRate,
+			"tax_amount":    line.TaxAmount,
+		})
+	}
+	return map[string]interface{}{
+		"order_id":       order.ID,
+		"code":           order.Code,
+		"customer_id":    order.CustomerID,
+		"round_id":       order.RoundID,
+		"status":         order.Status,
+		"total_price":    order.TotalPrice,
+		"net_amount":     order.NetAmount,
+		"tax_amount":     order.TaxAmount,
+		"discount":       order.DiscountAmount,
+		"payment_due_at": order.PaymentDueAt,
+		"lines":          lines,
+	}
+}
+
+// recordStatusChange adds an OrderHistory entry and records OrderStatusChanged when an order's status differs
+// from the previous one
+func recordStatusChange(tx *gorm.DB, order *models.Order, previous string, reason string) error {
+	if order.Status == previous {
+		return nil
+	}
+	if err := recordOrderHistory(tx, order, reason); err != nil {
+		return err
+	}
+	return recordEvent(tx, models.EventOrderStatusChanged, "order", order.ID, map[string]interface{}{
+		"order_id":    order.ID,
+		"code":        order.Code,
+		"customer_id": order.CustomerID,
+		"round_id":    order.RoundID,
+		"from":        previous,
+		"to":          order.Status,
+		"reason":      reason,
+	})
+}
+
+// recordOrderHistory adds an OrderHistory entry with the order's current status
+func recordOrderHistory(tx *gorm.DB, order *models.Order, description string) error {
+	return tx.Create(&models.OrderHistory{
+		OrderID:     order.ID,
+		Status:      order.Status,
+		ChangedAt:   time.Now(),
+		Description: description,
+	}).Error
+}
+
+// restoreRoundQuantity puts quantity taken by an order back into the sales round and the product stock
+func restoreRoundQuantity(tx *gorm.DB, roundID uuid.UUID, variantID uuid.UUID, quantity int) error {
+	err := tx.Model(&models.SalesRoundDetail{}).
+		Where("round_id = ? AND variant_id = ?", roundID, variantID).
+		Update("quantity", gorm.Expr("quantity + ?", quantity)).Error
+	if err != nil {
+		return err
+	}
+
+	// The variant may have been deleted since the order was placed; its product still gets the stock back
+	var variant models.ProductVariant
+	if err := tx.Unscoped().First(&variant, "variant_id = ?", variantID).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.Product{}).
+		Where("id = ?", variant.ProductID).
+		Update("stock", gorm.Expr("stock + ?", quantity)).Error
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	PublishPending(limit int, publish func(event models.OutboxEvent) error) (published int, failed int, err error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// outboxLease is how long claimed events are held back from other relays while they are published; a relay
+// that dies mid-batch leaves its events to be published again once it runs out. It covers a full batch to a
+// sink that times out on every event.
+const outboxLease = 30 * time.Minute
+
+// PublishPending hands up to limit due events to publish, oldest first. Published events are marked so; failed
+// ones are retried with exponential backoff. The events are claimed for outboxLease in a transaction of their
+// own and published after it commits, so a slow sink holds no connection or row locks, several relays can run
+// side by side without publishing the same event at the same time, and the results are recorded afterwards.
+func (r *outboxRepository) PublishPending(limit int, publish func(event models.OutboxEvent) error) (int, int, error) {
+	events, err := r.claimPending(limit, time.Now())
+	if err != nil || len(events) == 0 {
+		return 0, 0, err
+	}
+
+	results := make([]error, len(events))
+	for i, event := range events {
+		results[i] = publish(event)
+	}
+
+	published, failed := 0, 0
+	now := time.Now()
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range events {
+			if results[i] == nil {
+				published++
+			} else {
+				failed++
+			}
+			if err := tx.Model(&events[i]).Updates(outboxAttempt(events[i], results[i], now)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return published, failed, nil
+}
+
+// claimPending locks up to limit due events with SKIP LOCKED and moves their next attempt past outboxLease,
+// so other relays skip them once the claim commits
+func (r *outboxRepository) claimPending(limit int, now time.Time) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND next_attempt_at <= ?", now).
+			Order("created_at").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		ids := make([]uuid.UUID, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(outboxLease)).Error
+	})
+	return events, err
+}
+
+// outboxAttempt is the update recording an attempt to publish a claimed event: published, or retried after
+// a backoff with the error kept
+func outboxAttempt(event models.OutboxEvent, publishErr error, now time.Time) map[string]interface{} {
+	attempts := event.Attempts + 1
+	if publishErr == nil {
+		return map[string]interface{}{"published_at": now, "attempts": attempts}
+	}
+	return map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": now.Add(outboxBackoff(attempts)),
+		"last_error":      publishErr.Error(),
+	}
+}
+
+// outboxBackoff doubles the wait after every failed attempt, from 5 seconds up to an hour
+func outboxBackoff(attempts int) time.Duration {
+	backoff := 5 * time.Second
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+	return backoff
+}
+
+// recordEvent writes a domain event to the outbox inside the given transaction, so it is only published if the
+// change it describes commits
+func recordEvent(tx *gorm.DB, eventType string, aggregateType string, aggregateID uuid.UUID, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       string(data),
+		NextAttemptAt: time.Now(),
+	}).Error
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+)
+
+func TestOutboxAttempt(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	published := outboxAttempt(models.OutboxEvent{Attempts: 2}, nil, now)
+	if published["published_at"] != now || published["attempts"] != 3 {
+		t.Errorf("published event update = %v, want published_at %v and 3 attempts", published, now)
+	}
+	if _, ok := published["next_attempt_at"]; ok {
+		t.Error("published event keeps its claim instead of being left alone")
+	}
+
+	failed := outboxAttempt(models.OutboxEvent{Attempts: 2}, errors.New("sink down"), now)
+	if _, ok := failed["published_at"]; ok {
+		t.Error("failed event is marked published")
+	}
+	if failed["next_attempt_at"] != now.Add(20*time.Second) || failed["last_error"] != "sink down" || failed["attempts"] != 3 {
+		t.Errorf("failed event update = %v, want a retry after 20s with the error", failed)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 4: 40 * time.Second, 20: time.Hour} {
+		if got := outboxBackoff(attempts); got != want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRefundExceedsCaptured is returned when a refund is larger than what is left of the captured amount
+var ErrRefundExceedsCaptured = errors.New("refund exceeds the captured amount left to refund")
+
+// CaptureResult is what recording a capture did
+type CaptureResult string
+
+const (
+	CaptureOrderPaid       CaptureResult = "order_paid"       // The order was pending and is now paid
+	CaptureOrderClosed     CaptureResult = "order_closed"     // The order was cancelled or paid another way; the capture must be refunded
+	CaptureAlreadyRecorded CaptureResult = "already_recorded" // The payment was captured before; nothing changed
+)
+
+type PaymentRepository interface {
+	CreatePayment(payment *models.Payment) error
+	GetPaymentByID(id uuid.UUID) (*models.Payment, error)
+	GetPaymentsByOrderID(orderID uuid.UUID) ([]models.Payment, error)
+	GetPaymentByIntent(provider string, intentID string) (*models.Payment, error)
+	GetOpenPayment(orderID uuid.UUID) (*models.Payment, error)
+	UpdatePaymentStatus(id uuid.UUID, status string, failureReason string) error
+	CapturePayment(id uuid.UUID, amount float64) (*models.Payment, CaptureResult, error)
+	RecordRefund(id uuid.UUID, refund *models.PaymentRefund) (*models.Payment, error)
+}
+
+type paymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	return &paymentRepository{db: db}
+}
+
+func (r *paymentRepository) CreatePayment(payment *models.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *paymentRepository) GetPaymentByID(id uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Preload("Refunds").First(&payment, "id = ?", id).Error
+	return &payment, err
+}
+
+func (r *paymentRepository) GetPaymentsByOrderID(orderID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Preload("Refunds").Where("order_id = ?", orderID).Order("created_at").Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) GetPaymentByIntent(provider string, intentID string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.First(&payment, "provider = ? AND provider_intent_id = ?", provider, intentID).Error
+	return &payment, err
+}
+
+// GetOpenPayment returns the latest payment of an order the customer can still complete, if any
+func (r *paymentRepository) GetOpenPayment(orderID uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("order_id = ? AND status IN ?", orderID, []string{models.PaymentPending, models.PaymentAuthorized}).
+		Order("created_at DESC").
+		First(&payment).Error
+	return &payment, err
+}
+
+func (r *paymentRepository) UpdatePaymentStatus(id uuid.UUID, status string, failureReason string) error {
+	return r.db.Model(&models.Payment{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "failure_reason": failureReason}).Error
+}
+
+// CapturePayment records that the provider captured amount for a payment and marks its order paid, recording
+// PaymentCaptured. The result is CaptureOrderClosed when the order can no longer take the money, because it was
+// cancelled or paid by another payment in the meantime; the capture is still recorded so it can be refunded.
+// Capturing an already captured payment changes nothing and gives CaptureAlreadyRecorded, whatever the order
+// has gone on to.
+func (r *paymentRepository) CapturePayment(id uuid.UUID, amount float64) (*models.Payment, CaptureResult, error) {
+	var payment models.Payment
+	var result CaptureResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", payment.OrderID).Error; err != nil {
+			return err
+		}
+		result = captureResult(&payment, &order)
+		if result == CaptureAlreadyRecorded {
+			return nil
+		}
+		_, err := capturePayment(tx, &payment, &order, amount, "payment "+payment.ID.String()+" captured")
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &payment, result, nil
+}
+
+// captureResult tells what capturing a payment does to its order
+func captureResult(payment *models.Payment, order *models.Order) CaptureResult {
+	switch {
+	case payment.CapturedAt != nil:
+		return CaptureAlreadyRecorded
+	case order.Status == models.OrderStatusPending:
+		return CaptureOrderPaid
+	default:
+		return CaptureOrderClosed
+	}
+}
+
+// RecordRefund adds a refund made by the provider to a captured payment and records PaymentRefunded. A refund
+// that was already recorded, e.g. from a repeated webhook, is ignored.
+func (r *paymentRepository) RecordRefund(id uuid.UUID, refund *models.PaymentRefund) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var existing int64
+		if err := tx.Model(&models.PaymentRefund{}).Where("provider_refund_id = ?", refund.ProviderRefundID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+		if math.Round((payment.RefundedAmount+refund.Amount)*100) > math.Round(payment.CapturedAmount*100) {
+			return ErrRefundExceedsCaptured
+		}
+
+		refund.PaymentID = payment.ID
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		payment.RefundedAmount += refund.Amount
+		payment.Status = models.PaymentPartiallyRefunded
+		if math.Round(payment.RefundedAmount*100) >= math.Round(payment.CapturedAmount*100) {
+			payment.Status = models.PaymentRefunded
+		}
+		if err := tx.Model(&payment).Select("status", "refunded_amount").Updates(&payment).Error; err != nil {
+			return err
+		}
+
+		var order models.Order
+		if err := tx.First(&order, "id = ?", payment.OrderID).Error; err != nil {
+			return err
+		}
+		data := paymentEventPayload(&payment, &order)
+		data["refund_id"] = refund.ID
+		data["refund_amount"] = refund.Amount
+		data["reason"] = refund.Reason
+		return recordEvent(tx, models.EventPaymentRefunded, "order", order.ID, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Where("payment_id = ?", payment.ID).Order("created_at").Find(&payment.Refunds).Error
+	return &payment, err
+}
+
+// capturePayment records that amount was captured for a locked payment, marks its locked order paid if it is
+// still pending and records PaymentCaptured. It reports whether the order was marked paid.
+func capturePayment(tx *gorm.DB, payment *models.Payment, order *models.Order, amount float64, reason string) (bool, error) {
+	now := time.Now()
+	payment.Status = models.PaymentCaptured
+	payment.CapturedAmount = amount
+	payment.CapturedAt = &now
+	payment.NextAction = ""
+	if err := tx.Model(payment).Select("status", "captured_amount", "captured_at", "next_action").Updates(payment).Error; err != nil {
+		return false, err
+	}
+
+	orderPaid := false
+	if order.Status == models.OrderStatusPending {
+		if err := markOrderPaid(tx, order, reason); err != nil {
+			return false, err
+		}
+		orderPaid = true
+	}
+	return orderPaid, recordEvent(tx, models.EventPaymentCaptured, "order", order.ID, paymentEventPayload(payment, order))
+}
+
+// paymentEventPayload describes a payment in PaymentCaptured and PaymentRefunded events
+func paymentEventPayload(payment *models.Payment, order *models.Order) map[string]interface{} {
+	return map[string]interface{}{
+		"payment_id":      payment.ID,
+		"order_id":        order.ID,
+		"code":            order.Code,
+		"provider":        payment.Provider,
+		"amount":          payment.Amount,
+		"currency":        payment.Currency,
+		"captured_amount": payment.CapturedAmount,
+		"refunded_amount": payment.RefundedAmount,
+		"status":          payment.Status,
+	}
+}
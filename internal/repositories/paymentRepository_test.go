@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+)
+
+func TestCaptureResult(t *testing.T) {
+	now := time.Now()
+	pending := models.Payment{Status: models.PaymentAuthorized}
+	captured := models.Payment{Status: models.PaymentCaptured, CapturedAt: &now}
+
+	if got := captureResult(&pending, &models.Order{Status: models.OrderStatusPending}); got != CaptureOrderPaid {
+		t.Errorf("capturing for a pending order = %q, want %q", got, CaptureOrderPaid)
+	}
+	for _, status := range []string{models.OrderStatusCancelled, models.OrderStatusPaid} {
+		if got := captureResult(&pending, &models.Order{Status: status}); got != CaptureOrderClosed {
+			t.Errorf("capturing for a %s order = %q, want %q", status, got, CaptureOrderClosed)
+		}
+	}
+
+	// A repeated capture or webhook must not look like money the order cannot take, whatever the order has
+	// gone on to, or a shipped order would be refunded
+	for _, status := range []string{
+		models.OrderStatusPaid,
+		models.OrderStatusPartiallyShipped,
+		models.OrderStatusShipped,
+		models.OrderStatusDelivered,
+		models.OrderStatusCancelled,
+	} {
+		if got := captureResult(&captured, &models.Order{Status: status}); got != CaptureAlreadyRecorded {
+			t.Errorf("capturing again for a %s order = %q, want %q", status, got, CaptureAlreadyRecorded)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -12,8 +13,17 @@ type ProductVariantRepository interface {
 	GetProductVariantByID(id uuid.UUID) (*models.ProductVariant, error)
 	UpdateProductVariant(productVariant *models.ProductVariant) error
 	DeleteProductVariant(id uuid.UUID) error
+	GetVariantOnHand(variantID uuid.UUID) (int, error)
+	SetLowStockAlerted(variantID uuid.UUID, alerted bool) error
+	GetLowStockVariants(storeID *uuid.UUID) ([]dtos.LowStockVariantDTO, error)
 }
 
+// variantOnHandSQL is the on-hand stock of a variant: the sum of its location stock levels, or the
+// product stock when the variant is not tracked per location. Product stock is shared by all the product's
+// variants, so an untracked variant is measured against the whole product: its reorder point only fires once
+// the product as a whole runs that low. Keep stock per location for per-variant alerts.
+const variantOnHandSQL = `COALESCE((SELECT SUM(quantity) FROM "variant-stock-level" WHERE "variant-stock-level".variant_id = "product-variant".variant_id), product.stock)`
+
 type productVariantRepository struct {
 	db *gorm.DB
 }
@@ -45,3 +55,32 @@ func (r *productVariantRepository) UpdateProductVariant(productVariant *models.P
 func (r *productVariantRepository) DeleteProductVariant(id uuid.UUID) error {
 	return r.db.Delete(&models.ProductVariant{}, "variant_id = ?", id).Error
 }
+
+func (r *productVariantRepository) GetVariantOnHand(variantID uuid.UUID) (int, error) {
+	var onHand int
+	err := r.db.Table("\"product-variant\"").
+		Select(variantOnHandSQL).
+		Joins("JOIN product ON \"product-variant\".product_id = product.id").
+		Where("\"product-variant\".variant_id = ?", variantID).
+		Row().Scan(&onHand)
+	return onHand, err
+}
+
+func (r *productVariantRepository) SetLowStockAlerted(variantID uuid.UUID, alerted bool) error {
+	return r.db.Model(&models.ProductVariant{}).Where("variant_id = ?", variantID).Update("low_stock_alerted", alerted).Error
+}
+
+func (r *productVariantRepository) GetLowStockVariants(storeID *uuid.UUID) ([]dtos.LowStockVariantDTO, error) {
+	var variants []dtos.LowStockVariantDTO
+	query := r.db.Table("\"product-variant\"").
+		Select("\"product-variant\".variant_id, \"product-variant\".product_id, product.store_id, \"product-variant\".sku_code, product.product_name, " +
+			variantOnHandSQL + " AS on_hand, \"product-variant\".reorder_point, \"product-variant\".reorder_quantity").
+		Joins("JOIN product ON \"product-variant\".product_id = product.id").
+		Where("\"product-variant\".deleted_at IS NULL AND \"product-variant\".reorder_point > 0").
+		Where(variantOnHandSQL + " <= \"product-variant\".reorder_point")
+	if storeID != nil {
+		query = query.Where("product.store_id = ?", *storeID)
+	}
+	err := query.Order("on_hand").Scan(&variants).Error
+	return variants, err
+}
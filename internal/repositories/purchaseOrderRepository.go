@@ -0,0 +1,241 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVariantNotInStore is returned for a purchase order line whose variant is not one of the order's store's products
+var ErrVariantNotInStore = errors.New("variant is not one of the store's products")
+
+type PurchaseOrderRepository interface {
+	CreatePurchaseOrder(purchaseOrder *models.PurchaseOrder) error
+	GetAllPurchaseOrders(storeID *uuid.UUID, status string) ([]models.PurchaseOrder, error)
+	GetPurchaseOrderByID(id uuid.UUID) (*models.PurchaseOrder, error)
+	UpdatePurchaseOrderStatus(id uuid.UUID, from []string, to string) (*models.PurchaseOrder, error)
+	ReceivePurchaseOrder(id uuid.UUID, receipts []dtos.PurchaseOrderReceiveLineDTO) (*models.PurchaseOrder, error)
+}
+
+type purchaseOrderRepository struct {
+	db        *gorm.DB
+	observers stockObservers
+}
+
+func NewPurchaseOrderRepository(db *gorm.DB, observers ...StockObserver) PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: db, observers: observers}
+}
+
+// CreatePurchaseOrder saves a purchase order with its lines; every line must be a variant of the order's store
+func (r *purchaseOrderRepository) CreatePurchaseOrder(purchaseOrder *models.PurchaseOrder) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkLinesInStore(tx, purchaseOrder.StoreID, purchaseOrder.Lines); err != nil {
+			return err
+		}
+		return tx.Create(purchaseOrder).Error
+	})
+}
+
+func (r *purchaseOrderRepository) GetAllPurchaseOrders(storeID *uuid.UUID, status string) ([]models.PurchaseOrder, error) {
+	var purchaseOrders []models.PurchaseOrder
+	query := r.db.Preload("Lines").Order("created_at DESC")
+	if storeID != nil {
+		query = query.Where("store_id = ?", *storeID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&purchaseOrders).Error
+	return purchaseOrders, err
+}
+
+func (r *purchaseOrderRepository) GetPurchaseOrderByID(id uuid.UUID) (*models.PurchaseOrder, error) {
+	var purchaseOrder models.PurchaseOrder
+	err := r.db.Preload("Lines").First(&purchaseOrder, "id = ?", id).Error
+	return &purchaseOrder, err
+}
+
+// UpdatePurchaseOrderStatus moves a purchase order to a new status if it is currently in one of the from statuses
+func (r *purchaseOrderRepository) UpdatePurchaseOrderStatus(id uuid.UUID, from []string, to string) (*models.PurchaseOrder, error) {
+	var purchaseOrder models.PurchaseOrder
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchaseOrder, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if !containsStatus(from, purchaseOrder.Status) {
+			return fmt.Errorf("purchase order is %s", purchaseOrder.Status)
+		}
+
+		now := time.Now()
+		purchaseOrder.Status = to
+		switch to {
+		case models.PurchaseOrderSent:
+			purchaseOrder.SentAt = &now
+		case models.PurchaseOrderClosed:
+			purchaseOrder.ClosedAt = &now
+		}
+		return tx.Save(&purchaseOrder).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPurchaseOrderByID(id)
+}
+
+// ReceivePurchaseOrder books the received quantities into stock through stock movements and
+// advances the purchase order to partially_received or closed. Either every receipt is applied or none is.
+func (r *purchaseOrderRepository) ReceivePurchaseOrder(id uuid.UUID, receipts []dtos.PurchaseOrderReceiveLineDTO) (*models.PurchaseOrder, error) {
+	var received []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var purchaseOrder models.PurchaseOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lines").First(&purchaseOrder, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if purchaseOrder.Status != models.PurchaseOrderSent && purchaseOrder.Status != models.PurchaseOrderPartiallyReceived {
+			return fmt.Errorf("purchase order is %s", purchaseOrder.Status)
+		}
+
+		if err := checkLinesInStore(tx, purchaseOrder.StoreID, purchaseOrder.Lines); err != nil {
+			return err
+		}
+		receivedLines, err := receiveLines(&purchaseOrder, receipts)
+		if err != nil {
+			return err
+		}
+
+		for _, receipt := range receivedLines {
+			line := receipt.line
+			if err := tx.Model(line).Update("quantity_received", line.QuantityReceived).Error; err != nil {
+				return err
+			}
+
+			lineID := line.ID
+			movement := models.StockMovement{
+				VariantID:     line.VariantID,
+				LocationID:    purchaseOrder.LocationID,
+				Quantity:      receipt.quantity,
+				Reason:        models.StockMovementPurchaseReceipt,
+				ReferenceType: "purchase-order-line",
+				ReferenceID:   &lineID,
+				UnitCost:      line.UnitCost,
+			}
+			if err := applyStockMovement(tx, &movement); err != nil {
+				return err
+			}
+			received = append(received, line.VariantID)
+		}
+
+		purchaseOrder.Status = receivedStatus(purchaseOrder.Lines)
+		if purchaseOrder.Status == models.PurchaseOrderClosed {
+			now := time.Now()
+			purchaseOrder.ClosedAt = &now
+		}
+
+		log.Printf("Purchase order %s is now %s", purchaseOrder.Code, purchaseOrder.Status)
+		return tx.Model(&purchaseOrder).Select("status", "closed_at").Updates(&purchaseOrder).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observers.notify(received...)
+	return r.GetPurchaseOrderByID(id)
+}
+
+// lineReceipt is a quantity received against a purchase order line
+type lineReceipt struct {
+	line     *models.PurchaseOrderLine
+	quantity int
+}
+
+// receiveLines adds receipts to the received quantities of a purchase order's lines. A receipt must name a line of
+// the order, and no line can receive more than was ordered; the order is left untouched when a receipt fails.
+func receiveLines(purchaseOrder *models.PurchaseOrder, receipts []dtos.PurchaseOrderReceiveLineDTO) ([]lineReceipt, error) {
+	lines := make(map[uuid.UUID]int, len(purchaseOrder.Lines))
+	received := make(map[uuid.UUID]int, len(purchaseOrder.Lines))
+	for i, line := range purchaseOrder.Lines {
+		lines[line.ID] = i
+		received[line.ID] = line.QuantityReceived
+	}
+
+	for _, receipt := range receipts {
+		i, ok := lines[receipt.LineID]
+		if !ok {
+			return nil, fmt.Errorf("line %s is not on this purchase order", receipt.LineID)
+		}
+		if receipt.Quantity <= 0 {
+			return nil, fmt.Errorf("received quantity must be greater than zero")
+		}
+		if received[receipt.LineID]+receipt.Quantity > purchaseOrder.Lines[i].QuantityOrdered {
+			return nil, fmt.Errorf("line %s would receive more than ordered", receipt.LineID)
+		}
+		received[receipt.LineID] += receipt.Quantity
+	}
+
+	receiptsByLine := make([]lineReceipt, 0, len(receipts))
+	for _, receipt := range receipts {
+		line := &purchaseOrder.Lines[lines[receipt.LineID]]
+		line.QuantityReceived += receipt.Quantity
+		receiptsByLine = append(receiptsByLine, lineReceipt{line: line, quantity: receipt.Quantity})
+	}
+	return receiptsByLine, nil
+}
+
+// receivedStatus is the status of a purchase order once goods were received against it: closed when every line
+// arrived in full, partially_received otherwise
+func receivedStatus(lines []models.PurchaseOrderLine) string {
+	for _, line := range lines {
+		if line.QuantityReceived < line.QuantityOrdered {
+			return models.PurchaseOrderPartiallyReceived
+		}
+	}
+	return models.PurchaseOrderClosed
+}
+
+// checkLinesInStore makes sure every line of a purchase order is a variant of one of the store's products
+func checkLinesInStore(tx *gorm.DB, storeID uuid.UUID, lines []models.PurchaseOrderLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	variantIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+	var variants []struct {
+		VariantID uuid.UUID
+		StoreID   uuid.UUID
+	}
+	err := tx.Table(`"product-variant"`).
+		Select(`"product-variant".variant_id, "product".store_id`).
+		Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+		Where(`"product-variant".variant_id IN ?`, variantIDs).
+		Scan(&variants).Error
+	if err != nil {
+		return err
+	}
+	stores := make(map[uuid.UUID]uuid.UUID, len(variants))
+	for _, variant := range variants {
+		stores[variant.VariantID] = variant.StoreID
+	}
+	for _, line := range lines {
+		if store, ok := stores[line.VariantID]; !ok || store != storeID {
+			return fmt.Errorf("%w: %s", ErrVariantNotInStore, line.VariantID)
+		}
+	}
+	return nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
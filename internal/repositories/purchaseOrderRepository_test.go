@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestReceiveLines(t *testing.T) {
+	lineA, lineB := uuid.New(), uuid.New()
+	newOrder := func(receivedA, receivedB int) *models.PurchaseOrder {
+		return &models.PurchaseOrder{Lines: []models.PurchaseOrderLine{
+			{ID: lineA, QuantityOrdered: 10, QuantityReceived: receivedA},
+			{ID: lineB, QuantityOrdered: 5, QuantityReceived: receivedB},
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		receivedA    int
+		receivedB    int
+		receipts     []dtos.PurchaseOrderReceiveLineDTO
+		wantErr      bool
+		wantReceived [2]int
+		wantStatus   string
+	}{
+		{
+			name:         "part of one line",
+			receipts:     []dtos.PurchaseOrderReceiveLineDTO{{LineID: lineA, Quantity: 4}},
+			wantReceived: [2]int{4, 0},
+			wantStatus:   models.PurchaseOrderPartiallyReceived,
+		},
+		{
+			name:         "rest of a partially received order",
+			receivedA:    4,
+			receivedB:    5,
+			receipts:     []dtos.PurchaseOrderReceiveLineDTO{{LineID: lineA, Quantity: 6}},
+			wantReceived: [2]int{10, 5},
+			wantStatus:   models.PurchaseOrderClosed,
+		},
+		{
+			name:         "same line twice in one delivery",
+			receipts:     []dtos.PurchaseOrderReceiveLineDTO{{LineID: lineB, Quantity: 2}, {LineID: lineB, Quantity: 3}},
+			wantReceived: [2]int{0, 5},
+			wantStatus:   models.PurchaseOrderPartiallyReceived,
+		},
+		{
+			name:         "more than ordered",
+			receivedA:    8,
+			receipts:     []dtos.PurchaseOrderReceiveLineDTO{{LineID: lineA, Quantity: 3}},
+			wantErr:      true,
+			wantReceived: [2]int{8, 0},
+		},
+		{
+			name:         "more than ordered across receipts leaves the order untouched",
+			receipts:     []dtos.PurchaseOrderReceiveLineDTO{{LineID: lineB, Quantity: 3}, {LineID: lineB, Quantity: 3}},
+			wantErr:      true,
+			wantReceived: [2]int{0, 0},
+		},
+		{
+			name:     "line of another order",
+			receipts: []dtos.PurchaseOrderReceiveLineDTO{{LineID: uuid.New(), Quantity: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "zero quantity",
+			receipts: []dtos.PurchaseOrderReceiveLineDTO{{LineID: lineA, Quantity: 0}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := newOrder(tt.receivedA, tt.receivedB)
+			received, err := receiveLines(order, tt.receipts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("receiveLines() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := [2]int{order.Lines[0].QuantityReceived, order.Lines[1].QuantityReceived}; got != tt.wantReceived {
+				t.Errorf("received quantities = %v, want %v", got, tt.wantReceived)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(received) != len(tt.receipts) {
+				t.Errorf("got %d receipts, want %d", len(received), len(tt.receipts))
+			}
+			if got := receivedStatus(order.Lines); got != tt.wantStatus {
+				t.Errorf("receivedStatus() = %q, want %q", got, tt.wantStatus)
+			}
+		})
+	}
+}
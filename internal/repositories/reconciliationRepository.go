@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrStatementLineClosed is returned when a statement line that already paid an order or was ignored is
+	// matched or ignored again
+	ErrStatementLineClosed = errors.New("statement line is already matched or ignored")
+	// ErrOrderNotPending is returned when a statement line is matched to an order that is not awaiting payment
+	ErrOrderNotPending = errors.New("order is not awaiting payment")
+)
+
+// OrderCandidateFilter narrows the pending orders a statement line could pay
+type OrderCandidateFilter struct {
+	StoreID *uuid.UUID // Only orders with products of this store
+	Amount  float64    // Orders totalling exactly this amount
+	From    time.Time  // Orders created at or after
+	To      time.Time  // Orders created at or before
+}
+
+type ReconciliationRepository interface {
+	// CreateStatement creates a statement together with its lines
+	CreateStatement(statement *models.BankStatement) error
+	GetStatements(storeID *uuid.UUID) ([]models.BankStatement, error)
+	// GetStatementByID returns a statement with its lines in file order
+	GetStatementByID(id uuid.UUID) (*models.BankStatement, error)
+	GetLineByID(id uuid.UUID) (*models.BankStatementLine, error)
+	// GetReviewQueue returns the lines awaiting a decision from staff, oldest transaction first
+	GetReviewQueue(storeID *uuid.UUID) ([]models.BankStatementLine, error)
+	// FingerprintSeen reports whether a line of another statement has the fingerprint
+	FingerprintSeen(fingerprint string, statementID uuid.UUID) (bool, error)
+	FindCandidateOrders(filter OrderCandidateFilter) ([]models.Order, error)
+	// FindOrdersByReference returns the pending orders whose code contains the token
+	FindOrdersByReference(token string, storeID *uuid.UUID) ([]models.Order, error)
+	// SetLineStatus records the outcome of matching a line that did not pay an order
+	SetLineStatus(id uuid.UUID, status string, candidates string, note string) error
+	// MatchLine marks the order paid by a line, capturing its open payment if it has one, and closes the line
+	// with status
+	MatchLine(id uuid.UUID, orderID uuid.UUID, status string, note string) (*models.BankStatementLine, error)
+	// IgnoreLine closes a line that did not pay an order
+	IgnoreLine(id uuid.UUID, note string) (*models.BankStatementLine, error)
+	// RefreshStatementCounts recounts the lines of a statement by status
+	RefreshStatementCounts(id uuid.UUID) error
+}
+
+type reconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+func (r *reconciliationRepository) CreateStatement(statement *models.BankStatement) error {
+	return r.db.Create(statement).Error
+}
+
+func (r *reconciliationRepository) GetStatements(storeID *uuid.UUID) ([]models.BankStatement, error) {
+	var statements []models.BankStatement
+	query := r.db.Order("created_at DESC")
+	if storeID != nil {
+		query = query.Where("store_id = ?", *storeID)
+	}
+	err := query.Find(&statements).Error
+	return statements, err
+}
+
+func (r *reconciliationRepository) GetStatementByID(id uuid.UUID) (*models.BankStatement, error) {
+	var statement models.BankStatement
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("line_number")
+	}).First(&statement, "id = ?", id).Error
+	return &statement, err
+}
+
+func (r *reconciliationRepository) GetLineByID(id uuid.UUID) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	err := r.db.First(&line, "id = ?", id).Error
+	return &line, err
+}
+
+func (r *reconciliationRepository) GetReviewQueue(storeID *uuid.UUID) ([]models.BankStatementLine, error) {
+	var lines []models.BankStatementLine
+	query := r.db.Where(`"bank-statement-line".status IN ?`, []string{models.StatementLineReview, models.StatementLineUnmatched}).
+		Order(`"bank-statement-line".transacted_at`)
+	if storeID != nil {
+		query = query.Joins(`JOIN "bank-statement" ON "bank-statement".id = "bank-statement-line".statement_id`).
+			Where(`"bank-statement".store_id = ?`, *storeID)
+	}
+	err := query.Find(&lines).Error
+	return lines, err
+}
+
+func (r *reconciliationRepository) FingerprintSeen(fingerprint string, statementID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.BankStatementLine{}).
+		Where("fingerprint = ? AND statement_id <> ?", fingerprint, statementID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *reconciliationRepository) FindCandidateOrders(filter OrderCandidateFilter) ([]models.Order, error) {
+	var orders []models.Order
+	query := r.pendingOrders(filter.StoreID).
+		Where(`ABS("order".total_price - ?) < 0.005`, filter.Amount).
+		Where(`"order".created_at BETWEEN ? AND ?`, filter.From, filter.To)
+	err := query.Order(`"order".created_at`).Find(&orders).Error
+	return orders, err
+}
+
+func (r *reconciliationRepository) FindOrdersByReference(token string, storeID *uuid.UUID) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.pendingOrders(storeID).
+		Where(`LOWER("order".code) LIKE ?`, "%"+token+"%").
+		Order(`"order".created_at`).
+		Find(&orders).Error
+	return orders, err
+}
+
+// pendingOrders selects the orders awaiting payment, optionally only those with products of a store
+func (r *reconciliationRepository) pendingOrders(storeID *uuid.UUID) *gorm.DB {
+	query := r.db.Model(&models.Order{}).Where(`"order".status = ?`, models.OrderStatusPending)
+	if storeID != nil {
+		query = query.Where(`EXISTS (SELECT 1 FROM "order-detail"
+			JOIN "product-variant" ON "product-variant".variant_id = "order-detail".variant_id
+			JOIN "product" ON "product".id = "product-variant".product_id
+			WHERE "order-detail".order_id = "order".id AND "order-detail".deleted_at IS NULL AND "product".store_id = ?)`, *storeID)
+	}
+	return query
+}
+
+func (r *reconciliationRepository) SetLineStatus(id uuid.UUID, status string, candidates string, note string) error {
+	return r.db.Model(&models.BankStatementLine{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "candidates": candidates, "note": note}).Error
+}
+
+func (r *reconciliationRepository) MatchLine(id uuid.UUID, orderID uuid.UUID, status string, note string) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenLine(tx, &line, id); err != nil {
+			return err
+		}
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", orderID).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusPending {
+			return ErrOrderNotPending
+		}
+
+		reason := fmt.Sprintf("bank transfer of %.2f on %s matched from statement line %d",
+			line.Amount, line.TransactedAt.Format("2006-01-02 15:04"), line.LineNumber)
+		if line.Reference != "" {
+			reason += " (" + line.Reference + ")"
+		}
+		// A payment the customer was asked to make by transfer, e.g. a PromptPay QR code, is captured so the
+		// order shows how it was paid; otherwise the order is just marked paid
+		var payment models.Payment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND status IN ?", order.ID, []string{models.PaymentPending, models.PaymentAuthorized}).
+			Order("created_at DESC").
+			First(&payment).Error
+		switch {
+		case err == nil:
+			if _, err := capturePayment(tx, &payment, &order, line.Amount, reason); err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := markOrderPaid(tx, &order, reason); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		now := time.Now()
+		line.Status = status
+		line.OrderID = &order.ID
+		line.Note = note
+		line.ResolvedAt = &now
+		if err := tx.Model(&line).Select("status", "order_id", "note", "resolved_at").Updates(&line).Error; err != nil {
+			return err
+		}
+		return refreshStatementCounts(tx, line.StatementID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+func (r *reconciliationRepository) IgnoreLine(id uuid.UUID, note string) (*models.BankStatementLine, error) {
+	var line models.BankStatementLine
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenLine(tx, &line, id); err != nil {
+			return err
+		}
+		now := time.Now()
+		line.Status = models.StatementLineIgnored
+		line.Note = note
+		line.ResolvedAt = &now
+		if err := tx.Model(&line).Select("status", "note", "resolved_at").Updates(&line).Error; err != nil {
+			return err
+		}
+		return refreshStatementCounts(tx, line.StatementID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+func (r *reconciliationRepository) RefreshStatementCounts(id uuid.UUID) error {
+	return refreshStatementCounts(r.db, id)
+}
+
+// lockOpenLine locks a statement line that has not paid an order or been ignored yet
+func lockOpenLine(tx *gorm.DB, line *models.BankStatementLine, id uuid.UUID) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(line, "id = ?", id).Error; err != nil {
+		return err
+	}
+	switch line.Status {
+	case models.StatementLineMatched, models.StatementLineResolved, models.StatementLineIgnored:
+		return ErrStatementLineClosed
+	}
+	return nil
+}
+
+// refreshStatementCounts recounts the lines of a statement by status
+func refreshStatementCounts(tx *gorm.DB, statementID uuid.UUID) error {
+	var counts []struct {
+		Status string
+		Lines  int
+	}
+	err := tx.Model(&models.BankStatementLine{}).
+		Select("status, COUNT(*) AS lines").
+		Where("statement_id = ?", statementID).
+		Group("status").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"total_lines": 0, "matched_lines": 0, "review_lines": 0, "unmatched_lines": 0, "ignored_lines": 0}
+	total := 0
+	for _, count := range counts {
+		total += count.Lines
+		switch count.Status {
+		case models.StatementLineMatched, models.StatementLineResolved:
+			updates["matched_lines"] = updates["matched_lines"].(int) + count.Lines
+		case models.StatementLineReview:
+			updates["review_lines"] = count.Lines
+		case models.StatementLineUnmatched:
+			updates["unmatched_lines"] = count.Lines
+		case models.StatementLineIgnored:
+			updates["ignored_lines"] = count.Lines
+		}
+	}
+	updates["total_lines"] = total
+	return tx.Model(&models.BankStatement{}).Where("id = ?", statementID).Updates(updates).Error
+}
@@ -1,15 +1,25 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errDryRun rolls back a transaction that was only run to see its result
+var errDryRun = errors.New("dry run")
+
+// ErrAllocationLocationMismatch is returned when a variant is allocated to a round from another location than the
+// one its round detail already draws from
+var ErrAllocationLocationMismatch = errors.New("already allocated to this round from a different location")
+
 type SalesRoundDetailRepository interface {
 	CreateSalesRoundDetail(salesRoundDetail *models.SalesRoundDetail) error
 	GetAllSalesRoundDetails() ([]models.SalesRoundDetail, error)
@@ -24,14 +34,17 @@ type SalesRoundDetailRepository interface {
 	GetSalesRoundDetailsByVariantID(variantID uuid.UUID) ([]models.SalesRoundDetail, error)
 	GetSalesRoundDetailByRoundIDAndVariantID(roundID uuid.UUID, variantID uuid.UUID) (*models.SalesRoundDetail, error)
 	UpdateSalesRoundDetailByRoundIDAndVariantID(roundID uuid.UUID, variantID uuid.UUID, salesRoundDetail *models.SalesRoundDetail) error
+	AllocateToSalesRound(roundID uuid.UUID, request dtos.SalesRoundAllocationRequestDTO) (*dtos.SalesRoundAllocationResultDTO, error)
+	ClaimRoundQuantity(roundID uuid.UUID, customerID uuid.UUID, items []dtos.PurchaseItemDTO) error
 }
 
 type salesRoundDetailRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	observers stockObservers
 }
 
-func NewSalesRoundDetailRepository(db *gorm.DB) SalesRoundDetailRepository {
-	return &salesRoundDetailRepository{db: db}
+func NewSalesRoundDetailRepository(db *gorm.DB, observers ...StockObserver) SalesRoundDetailRepository {
+	return &salesRoundDetailRepository{db: db, observers: observers}
 }
 
 func (r *salesRoundDetailRepository) CreateSalesRoundDetail(salesRoundDetail *models.SalesRoundDetail) error {
@@ -44,63 +57,101 @@ func (r *salesRoundDetailRepository) CreateSalesRoundDetail(salesRoundDetail *mo
 	}
 	log.Printf("Product found: %v", product)
 
-	var existingDetail models.SalesRoundDetail
-	log.Printf("Fetching existing sales round detail for round ID: %v and variant ID: %v", salesRoundDetail.RoundID, salesRoundDetail.VariantID)
-	err = r.db.Where("round_id = ? AND variant_id = ?", salesRoundDetail.RoundID, salesRoundDetail.VariantID).First(&existingDetail).Error
+	if salesRoundDetail.LocationID != nil {
+		if err := r.checkLocationBelongsToStore(*salesRoundDetail.LocationID, product.StoreID); err != nil {
+			return err
+		}
+	}
 
-	// If the sales round detail already exists
-	if err == nil {
-		log.Printf("Existing sales round detail found: %v", existingDetail)
-		// Calculate the total quantity to be updated
-		totalQuantity := existingDetail.Quantity + salesRoundDetail.Quantity
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var existingDetail models.SalesRoundDetail
+		log.Printf("Fetching existing sales round detail for round ID: %v and variant ID: %v", salesRoundDetail.RoundID, salesRoundDetail.VariantID)
+		err := tx.Where("round_id = ? AND variant_id = ?", salesRoundDetail.RoundID, salesRoundDetail.VariantID).First(&existingDetail).Error
+
+		// If the sales round detail already exists
+		if err == nil {
+			log.Printf("Existing sales round detail found: %v", existingDetail)
+			// More quantity is drawn from where the detail already draws from; another location is a separate allocation
+			if salesRoundDetail.LocationID != nil && !sameLocation(existingDetail.LocationID, salesRoundDetail.LocationID) {
+				return ErrAllocationLocationMismatch
+			}
+			// Calculate the total quantity to be updated
+			totalQuantity := existingDetail.Quantity + salesRoundDetail.Quantity
+
+			// Check if there is enough stock for the total quantity
+			if totalQuantity > product.Stock+existingDetail.Quantity {
+				log.Printf("Quantity exceeds available stock: %d > %d", totalQuantity, product.Stock+existingDetail.Quantity)
+				return fmt.Errorf("quantity exceeds available stock")
+			}
+
+			// Draw the additional quantity from the location the detail is already allocated from
+			if existingDetail.LocationID != nil {
+				if err := adjustStockLevel(tx, existingDetail.VariantID, *existingDetail.LocationID, -salesRoundDetail.Quantity); err != nil {
+					log.Printf("Error allocating from location %v: %v", *existingDetail.LocationID, err)
+					return err
+				}
+			}
+
+			// Update the existing sales round detail
+			product.Stock -= salesRoundDetail.Quantity
+			existingDetail.Quantity = totalQuantity
+			existingDetail.AllocatedQuantity += salesRoundDetail.Quantity
+			existingDetail.Remaining = product.Stock
+			existingDetail.ProductStock = product.Stock
+			if salesRoundDetail.RoundPrice != nil || salesRoundDetail.DiscountPercent != nil {
+				existingDetail.RoundPrice = salesRoundDetail.RoundPrice
+				existingDetail.DiscountPercent = salesRoundDetail.DiscountPercent
+			}
+
+			// Update the product stock
+			if err := tx.Save(product).Error; err != nil {
+				log.Printf("Error updating product stock: %v", err)
+				return err
+			}
+
+			log.Printf("Updating existing sales round detail: %v", existingDetail)
+			return tx.Save(&existingDetail).Error
+		} else if err != gorm.ErrRecordNotFound {
+			log.Printf("Error fetching existing sales round detail: %v", err)
+			return err
+		}
 
-		// Check if there is enough stock for the total quantity
-		if totalQuantity > product.Stock+existingDetail.Quantity {
-			log.Printf("Quantity exceeds available stock: %d > %d", totalQuantity, product.Stock+existingDetail.Quantity)
+		// Check if there is enough stock for the new entry
+		if salesRoundDetail.Quantity > product.Stock {
+			log.Printf("Quantity exceeds available stock: %d > %d", salesRoundDetail.Quantity, product.Stock)
 			return fmt.Errorf("quantity exceeds available stock")
 		}
 
-		// Update the existing sales round detail
+		// Take the allocation out of the source location, if one was given
+		if salesRoundDetail.LocationID != nil {
+			if err := adjustStockLevel(tx, salesRoundDetail.VariantID, *salesRoundDetail.LocationID, -salesRoundDetail.Quantity); err != nil {
+				log.Printf("Error allocating from location %v: %v", *salesRoundDetail.LocationID, err)
+				return err
+			}
+		}
+
+		// Allocate the stock for the new entry
 		product.Stock -= salesRoundDetail.Quantity
-		existingDetail.Quantity = totalQuantity
-		existingDetail.Remaining = product.Stock
-		existingDetail.ProductStock = product.Stock
 
 		// Update the product stock
-		if err := r.UpdateProductStock(product); err != nil {
+		if err := tx.Save(product).Error; err != nil {
 			log.Printf("Error updating product stock: %v", err)
 			return err
 		}
 
-		log.Printf("Updating existing sales round detail: %v", existingDetail)
-		return r.db.Save(&existingDetail).Error
-	} else if err != gorm.ErrRecordNotFound {
-		log.Printf("Error fetching existing sales round detail: %v", err)
-		return err
-	}
-
-	// Check if there is enough stock for the new entry
-	if salesRoundDetail.Quantity > product.Stock {
-		log.Printf("Quantity exceeds available stock: %d > %d", salesRoundDetail.Quantity, product.Stock)
-		return fmt.Errorf("quantity exceeds available stock")
-	}
-
-	// Allocate the stock for the new entry
-	product.Stock -= salesRoundDetail.Quantity
+		// Set the remaining stock in the sales round detail
+		salesRoundDetail.AllocatedQuantity = salesRoundDetail.Quantity
+		salesRoundDetail.ProductStock = product.Stock
+		salesRoundDetail.Remaining = product.Stock
 
-	// Update the product stock
-	if err := r.UpdateProductStock(product); err != nil {
-		log.Printf("Error updating product stock: %v", err)
-		return err
+		log.Printf("Creating new sales round detail: %v", salesRoundDetail)
+		// Create the sales round detail
+		return tx.Create(salesRoundDetail).Error
+	})
+	if err == nil {
+		r.observers.notify(salesRoundDetail.VariantID)
 	}
-
-	// Set the remaining stock in the sales round detail
-	salesRoundDetail.ProductStock = product.Stock
-	salesRoundDetail.Remaining = product.Stock
-
-	log.Printf("Creating new sales round detail: %v", salesRoundDetail)
-	// Create the sales round detail
-	return r.db.Create(salesRoundDetail).Error
+	return err
 }
 
 func (r *salesRoundDetailRepository) GetAllSalesRoundDetails() ([]models.SalesRoundDetail, error) {
@@ -137,6 +188,10 @@ func (r *salesRoundDetailRepository) GetSalesRoundDetailsByRoundID(roundID uuid.
 		Joins("JOIN product ON \"product-variant\".product_id = product.id").
 		Where("\"sales-round-detail\".round_id = ?", roundID).
 		Scan(&details).Error
+	for i := range details {
+		sale := models.SalesRoundDetail{RoundPrice: details[i].RoundPrice, DiscountPercent: details[i].DiscountPercent}
+		details[i].SalePrice = sale.SalePrice(details[i].VariantPrice)
+	}
 	log.Printf("Fetched sales round details: %v, error: %v", details, err)
 	return details, err
 }
@@ -163,17 +218,32 @@ func (r *salesRoundDetailRepository) UpdateSalesRoundDetailQuantity(id uuid.UUID
 	stockChange := detail.Quantity - quantity
 	product.Stock += stockChange
 
-	// Update the product stock
-	if err := r.UpdateProductStock(product); err != nil {
-		log.Printf("Error updating product stock: %v", err)
-		return err
-	}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// Return to (or draw from) the source location by the same amount
+		if detail.LocationID != nil && stockChange != 0 {
+			if err := adjustStockLevel(tx, detail.VariantID, *detail.LocationID, stockChange); err != nil {
+				log.Printf("Error adjusting location %v stock: %v", *detail.LocationID, err)
+				return err
+			}
+		}
 
-	// Update the sales round detail quantity
-	detail.Quantity = quantity
-	detail.Remaining = product.Stock
-	log.Printf("Updating sales round detail quantity: %v", detail)
-	return r.db.Save(&detail).Error
+		// Update the product stock
+		if err := tx.Save(product).Error; err != nil {
+			log.Printf("Error updating product stock: %v", err)
+			return err
+		}
+
+		// Update the sales round detail quantity
+		detail.Quantity = quantity
+		detail.AllocatedQuantity -= stockChange
+		detail.Remaining = product.Stock
+		log.Printf("Updating sales round detail quantity: %v", detail)
+		return tx.Save(&detail).Error
+	})
+	if err == nil {
+		r.observers.notify(detail.VariantID)
+	}
+	return err
 }
 
 func (r *salesRoundDetailRepository) GetProductVariantByID(id uuid.UUID) (*models.ProductVariant, error) {
@@ -225,3 +295,279 @@ func (r *salesRoundDetailRepository) GetSalesRoundDetailByRoundIDAndVariantID(ro
 	err := r.db.Where("round_id = ? AND variant_id = ?", roundID, variantID).First(&salesRoundDetail).Error
 	return &salesRoundDetail, err
 }
+
+// checkLocationBelongsToStore makes sure an allocation source location is one of the product's store locations
+func (r *salesRoundDetailRepository) checkLocationBelongsToStore(locationID uuid.UUID, storeID uuid.UUID) error {
+	var location models.InventoryLocation
+	if err := r.db.First(&location, "id = ?", locationID).Error; err != nil {
+		log.Printf("Error fetching inventory location %v: %v", locationID, err)
+		return fmt.Errorf("inventory location not found: %w", err)
+	}
+	if location.StoreID != storeID {
+		return ErrLocationNotInStore
+	}
+	return nil
+}
+
+// AllocateToSalesRound allocates many variants to a sales round in one transaction: either every allocation is
+// applied or none is. Category entries allocate a percentage of the stock of every variant in the category,
+// rounded down. In a dry run the allocations are applied and then rolled back, so the returned table shows
+// exactly what would happen.
+func (r *salesRoundDetailRepository) AllocateToSalesRound(roundID uuid.UUID, request dtos.SalesRoundAllocationRequestDTO) (*dtos.SalesRoundAllocationResultDTO, error) {
+	result := dtos.SalesRoundAllocationResultDTO{RoundID: roundID, DryRun: request.DryRun}
+	var allocated []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var round models.SalesRound
+		if err := tx.First(&round, "id = ?", roundID).Error; err != nil {
+			return err
+		}
+
+		allocations, err := expandCategoryAllocations(tx, request)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uuid.UUID]bool, len(allocations))
+		for _, allocation := range allocations {
+			if seen[allocation.VariantID] {
+				return fmt.Errorf("variant %s is allocated more than once", allocation.VariantID)
+			}
+			seen[allocation.VariantID] = true
+
+			line, err := allocateToRound(tx, roundID, allocation)
+			if err != nil {
+				return fmt.Errorf("variant %s: %w", allocation.VariantID, err)
+			}
+			result.Lines = append(result.Lines, *line)
+			allocated = append(allocated, allocation.VariantID)
+		}
+
+		if request.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return &result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.observers.notify(allocated...)
+	return &result, nil
+}
+
+// expandCategoryAllocations turns the category entries of a request into one allocation per variant and
+// appends them to the explicit allocations. Percentages are taken of the stock before anything is allocated.
+func expandCategoryAllocations(tx *gorm.DB, request dtos.SalesRoundAllocationRequestDTO) ([]dtos.SalesRoundAllocationDTO, error) {
+	allocations := append([]dtos.SalesRoundAllocationDTO{}, request.Allocations...)
+	for _, category := range request.Categories {
+		var variants []categoryVariantStock
+		query := tx.Table("\"product-variant\"").
+			Joins("JOIN product ON \"product-variant\".product_id = product.id").
+			Where("product.category_id = ? AND product.deleted_at IS NULL AND \"product-variant\".deleted_at IS NULL", category.CategoryID).
+			Order("\"product-variant\".product_id, \"product-variant\".sku_code")
+		if category.LocationID != nil {
+			query = query.Select("\"product-variant\".variant_id, \"product-variant\".product_id, COALESCE(\"variant-stock-level\".quantity, 0) AS stock").
+				Joins("LEFT JOIN \"variant-stock-level\" ON \"variant-stock-level\".variant_id = \"product-variant\".variant_id AND \"variant-stock-level\".location_id = ?", *category.LocationID)
+		} else {
+			query = query.Select("\"product-variant\".variant_id, \"product-variant\".product_id, product.stock")
+		}
+		if err := query.Scan(&variants).Error; err != nil {
+			return nil, err
+		}
+
+		quantities := categoryAllocationQuantities(variants, category.Percent, category.LocationID == nil)
+		for i, variant := range variants {
+			if quantities[i] <= 0 {
+				continue
+			}
+			allocations = append(allocations, dtos.SalesRoundAllocationDTO{
+				VariantID:     variant.VariantID,
+				Quantity:      quantities[i],
+				QuantityLimit: category.QuantityLimit,
+				LocationID:    category.LocationID,
+			})
+		}
+	}
+	return allocations, nil
+}
+
+// categoryVariantStock is a variant of a category allocation with the stock its percentage is taken of
+type categoryVariantStock struct {
+	VariantID uuid.UUID
+	ProductID uuid.UUID
+	Stock     int
+}
+
+// categoryAllocationQuantities returns how much of each variant a category allocation takes. Location stock is
+// per variant, so each variant gets its percentage. Product stock is shared by the product's variants (which
+// repeat it), so the percentage is taken once per product and split evenly across its variants, the first ones
+// getting the remainder. variants must list the variants of a product next to each other.
+func categoryAllocationQuantities(variants []categoryVariantStock, percent float64, perProduct bool) []int {
+	quantities := make([]int, len(variants))
+	for start := 0; start < len(variants); {
+		end := start + 1
+		if perProduct {
+			for end < len(variants) && variants[end].ProductID == variants[start].ProductID {
+				end++
+			}
+		}
+		quota := int(float64(variants[start].Stock) * percent / 100)
+		share, remainder := quota/(end-start), quota%(end-start)
+		for i := start; i < end; i++ {
+			quantities[i] = share
+			if i-start < remainder {
+				quantities[i]++
+			}
+		}
+		start = end
+	}
+	return quantities
+}
+
+// allocateToRound takes the allocation out of the product stock (and the location's stock level, if given)
+// and adds it to the variant's sales round detail, creating the detail if the variant is new to the round
+func allocateToRound(tx *gorm.DB, roundID uuid.UUID, allocation dtos.SalesRoundAllocationDTO) (*dtos.SalesRoundAllocationLineDTO, error) {
+	if allocation.Quantity <= 0 || allocation.QuantityLimit <= 0 {
+		return nil, fmt.Errorf("quantity and quantity_limit must be greater than zero")
+	}
+	sale := models.SalesRoundDetail{RoundPrice: allocation.RoundPrice, DiscountPercent: allocation.DiscountPercent}
+	if err := sale.ValidateSalePrice(); err != nil {
+		return nil, err
+	}
+
+	var variant models.ProductVariant
+	if err := tx.First(&variant, "variant_id = ?", allocation.VariantID).Error; err != nil {
+		return nil, fmt.Errorf("variant not found")
+	}
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", variant.ProductID).Error; err != nil {
+		return nil, err
+	}
+
+	var detail models.SalesRoundDetail
+	err := tx.Where("round_id = ? AND variant_id = ?", roundID, allocation.VariantID).First(&detail).Error
+	exists := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if exists && !sameLocation(detail.LocationID, allocation.LocationID) {
+		return nil, ErrAllocationLocationMismatch
+	}
+
+	if allocation.LocationID != nil {
+		var location models.InventoryLocation
+		if err := tx.First(&location, "id = ?", *allocation.LocationID).Error; err != nil {
+			return nil, fmt.Errorf("inventory location not found: %w", err)
+		}
+		if location.StoreID != product.StoreID {
+			return nil, ErrLocationNotInStore
+		}
+	}
+	if allocation.Quantity > product.Stock {
+		return nil, fmt.Errorf("quantity %d exceeds available stock %d", allocation.Quantity, product.Stock)
+	}
+	if allocation.LocationID != nil {
+		if err := adjustStockLevel(tx, allocation.VariantID, *allocation.LocationID, -allocation.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	stockBefore := product.Stock
+	product.Stock -= allocation.Quantity
+	if err := tx.Model(&product).Update("stock", product.Stock).Error; err != nil {
+		return nil, err
+	}
+
+	detail.Quantity += allocation.Quantity
+	detail.AllocatedQuantity += allocation.Quantity
+	detail.QuantityLimit = allocation.QuantityLimit
+	detail.Remaining = product.Stock
+	detail.ProductStock = product.Stock
+	if sale.RoundPrice != nil || sale.DiscountPercent != nil {
+		detail.RoundPrice = sale.RoundPrice
+		detail.DiscountPercent = sale.DiscountPercent
+	}
+	if exists {
+		err = tx.Save(&detail).Error
+	} else {
+		detail.RoundID = roundID
+		detail.VariantID = allocation.VariantID
+		detail.LocationID = allocation.LocationID
+		err = tx.Create(&detail).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.SalesRoundAllocationLineDTO{
+		VariantID:          allocation.VariantID,
+		SKUCode:            variant.SKUCode,
+		ProductName:        product.ProductName,
+		LocationID:         allocation.LocationID,
+		QuantityAllocated:  allocation.Quantity,
+		TotalQuantity:      detail.Quantity,
+		QuantityLimit:      detail.QuantityLimit,
+		ProductStockBefore: stockBefore,
+		ProductStockAfter:  product.Stock,
+	}, nil
+}
+
+// ClaimRoundQuantity takes the purchased quantities out of the sales round, all or nothing. Quantity held for
+// other waitlisted customers cannot be claimed; a customer's own waitlist offer is fulfilled by the claim.
+func (r *salesRoundDetailRepository) ClaimRoundQuantity(roundID uuid.UUID, customerID uuid.UUID, items []dtos.PurchaseItemDTO) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		_, err := claimRoundQuantity(tx, roundID, customerID, items)
+		return err
+	})
+}
+
+// claimRoundQuantity is ClaimRoundQuantity inside the given transaction; it returns the round
+func claimRoundQuantity(tx *gorm.DB, roundID uuid.UUID, customerID uuid.UUID, items []dtos.PurchaseItemDTO) (*models.SalesRound, error) {
+	var round models.SalesRound
+	if err := tx.First(&round, "id = ?", roundID).Error; err != nil {
+		return nil, err
+	}
+	if round.Mode == models.SalesRoundModeLottery {
+		return nil, ErrLotteryRound
+	}
+
+	for _, item := range items {
+		var detail models.SalesRoundDetail
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("round_id = ? AND variant_id = ?", roundID, item.VariantID).
+			First(&detail).Error
+		if err != nil {
+			return nil, err
+		}
+
+		held, err := heldRoundQuantity(tx, roundID, item.VariantID, &customerID)
+		if err != nil {
+			return nil, err
+		}
+		if item.Quantity > detail.Quantity-held {
+			log.Printf("Sales round %v has %d of variant %v left, %d held: cannot claim %d", roundID, detail.Quantity, item.VariantID, held, item.Quantity)
+			return nil, fmt.Errorf("variant %s: %w", item.VariantID, ErrRoundSoldOut)
+		}
+
+		if err := tx.Model(&detail).Update("quantity", detail.Quantity-item.Quantity).Error; err != nil {
+			return nil, err
+		}
+		err = tx.Model(&models.WaitlistEntry{}).
+			Where("round_id = ? AND variant_id = ? AND customer_id = ? AND status = ? AND offer_expires_at > ?",
+				roundID, item.VariantID, customerID, models.WaitlistOffered, time.Now()).
+			Update("status", models.WaitlistFulfilled).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &round, nil
+}
+
+func sameLocation(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
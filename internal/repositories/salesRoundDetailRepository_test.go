@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCategoryAllocationQuantities(t *testing.T) {
+	shirt, mug := uuid.New(), uuid.New()
+	variant := func(productID uuid.UUID, stock int) categoryVariantStock {
+		return categoryVariantStock{VariantID: uuid.New(), ProductID: productID, Stock: stock}
+	}
+
+	tests := []struct {
+		name       string
+		variants   []categoryVariantStock
+		percent    float64
+		perProduct bool
+		want       []int
+	}{
+		{
+			name:       "product stock is taken once and split across its variants",
+			variants:   []categoryVariantStock{variant(shirt, 100), variant(shirt, 100), variant(mug, 10)},
+			percent:    50,
+			perProduct: true,
+			want:       []int{25, 25, 5},
+		},
+		{
+			name:       "remainder goes to the first variants",
+			variants:   []categoryVariantStock{variant(shirt, 10), variant(shirt, 10), variant(shirt, 10)},
+			percent:    50,
+			perProduct: true,
+			want:       []int{2, 2, 1},
+		},
+		{
+			name:       "quota smaller than the number of variants",
+			variants:   []categoryVariantStock{variant(shirt, 3), variant(shirt, 3)},
+			percent:    40,
+			perProduct: true,
+			want:       []int{1, 0},
+		},
+		{
+			name:     "location stock is per variant",
+			variants: []categoryVariantStock{variant(shirt, 10), variant(shirt, 20)},
+			percent:  50,
+			want:     []int{5, 10},
+		},
+		{
+			name:       "no variants",
+			percent:    50,
+			perProduct: true,
+			want:       []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := categoryAllocationQuantities(tt.variants, tt.percent, tt.perProduct)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("categoryAllocationQuantities() = %v, want %v", got, tt.want)
+			}
+			if tt.perProduct {
+				total := 0
+				for _, quantity := range got {
+					total += quantity
+				}
+				if len(tt.variants) > 0 && tt.variants[0].ProductID == tt.variants[len(tt.variants)-1].ProductID {
+					if quota := int(float64(tt.variants[0].Stock) * tt.percent / 100); total != quota {
+						t.Errorf("allocated %d of one product, want its quota %d", total, quota)
+					}
+				}
+			}
+		})
+	}
+}
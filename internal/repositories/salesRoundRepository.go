@@ -1,12 +1,17 @@
 package repositories
 
 import (
+	"fmt"
 	"log"
+	"math"
+	"time"
 
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SalesRoundRepository interface {
@@ -16,17 +21,30 @@ type SalesRoundRepository interface {
 	UpdateSalesRound(salesRound *models.SalesRound) error
 	DeleteSalesRound(id uuid.UUID) error
 	GetCombinedSalesRoundProductData() ([]dtos.CombinedSalesRoundProductResponse, error) // New method
+	CloneSalesRound(sourceID uuid.UUID, salesRound *models.SalesRound, quantityScale float64) error
+	RecordRoundTransitions(now time.Time) (opened int, closed int, err error)
+	// GetSalesRoundAnalytics reports how a round sold per variant: units, regular price sales, the markdown of the
+	// round's sale prices, discount codes and revenue
+	GetSalesRoundAnalytics(roundID uuid.UUID) (*dtos.SalesRoundAnalyticsDTO, error)
 }
 
+// roundTransitionLookback limits the transitions recorded for rounds that opened or closed while nothing was
+// watching, so deploying the lifecycle does not replay the whole history
+const roundTransitionLookback = 24 * time.Hour
+
 type salesRoundRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	observers stockObservers
 }
 
-func NewSalesRoundRepository(db *gorm.DB) SalesRoundRepository {
-	return &salesRoundRepository{db: db}
+func NewSalesRoundRepository(db *gorm.DB, observers ...StockObserver) SalesRoundRepository {
+	return &salesRoundRepository{db: db, observers: observers}
 }
 
 func (r *salesRoundRepository) CreateSalesRound(salesRound *models.SalesRound) error {
+	if err := commitDrawSeed(salesRound); err != nil {
+		return err
+	}
 	return r.db.Create(salesRound).Error
 }
 
@@ -43,6 +61,9 @@ func (r *salesRoundRepository) GetSalesRoundByID(id uuid.UUID) (*models.SalesRou
 }
 
 func (r *salesRoundRepository) UpdateSalesRound(salesRound *models.SalesRound) error {
+	if err := commitDrawSeed(salesRound); err != nil {
+		return err
+	}
 	return r.db.Save(salesRound).Error
 }
 
@@ -67,3 +88,227 @@ func (r *salesRoundRepository) GetCombinedSalesRoundProductData() ([]dtos.Combin
 	}
 	return results, err
 }
+
+// CloneSalesRound creates salesRound with the variant line-up of the source round. Each variant is allocated
+// the quantity originally allocated to the source round times quantityScale, rounded; variants that scale to
+// zero are left out. The round is only created if every allocation succeeds.
+func (r *salesRoundRepository) CloneSalesRound(sourceID uuid.UUID, salesRound *models.SalesRound, quantityScale float64) error {
+	var allocated []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var source models.SalesRound
+		if err := tx.Preload("Details").First(&source, "id = ?", sourceID).Error; err != nil {
+			return err
+		}
+		if salesRound.Mode == "" {
+			salesRound.Mode = source.Mode
+			salesRound.PaymentWindowMinutes = source.PaymentWindowMinutes
+		}
+		if salesRound.AdmissionRate == 0 {
+			salesRound.AdmissionRate = source.AdmissionRate
+		}
+
+		allocations := make([]dtos.SalesRoundAllocationDTO, 0, len(source.Details))
+		for _, detail := range source.Details {
+			quantity := detail.AllocatedQuantity
+			if quantity <= 0 {
+				quantity = detail.Quantity // Allocated before the total was recorded
+			}
+			quantity = int(math.Round(float64(quantity) * quantityScale))
+			if quantity <= 0 {
+				continue
+			}
+			allocations = append(allocations, dtos.SalesRoundAllocationDTO{
+				VariantID:       detail.VariantID,
+				Quantity:        quantity,
+				QuantityLimit:   detail.QuantityLimit,
+				LocationID:      detail.LocationID,
+				RoundPrice:      detail.RoundPrice,
+				DiscountPercent: detail.DiscountPercent,
+			})
+		}
+
+		var err error
+		allocated, err = createRoundWithAllocations(tx, salesRound, allocations)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	r.observers.notify(allocated...)
+	return nil
+}
+
+// RecordRoundTransitions records SalesRoundOpened for rounds that have started and SalesRoundClosed for rounds
+// that have ended since the last run, each in the same transaction that marks the round
+func (r *salesRoundRepository) RecordRoundTransitions(now time.Time) (int, int, error) {
+	opened, closed := 0, 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var rounds []models.SalesRound
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(opened_at IS NULL AND start_date <= ? AND start_date > ?) OR (closed_at IS NULL AND end_date <= ? AND end_date > ?)",
+				now, now.Add(-roundTransitionLookback), now, now.Add(-roundTransitionLookback)).
+			Find(&rounds).Error
+		if err != nil {
+			return err
+		}
+
+		for _, round := range rounds {
+			payload := map[string]interface{}{
+				"round_id":   round.ID,
+				"name":       round.Name,
+				"mode":       round.Mode,
+				"start_date": round.StartDate,
+				"end_date":   round.EndDate,
+			}
+			if round.OpenedAt == nil && !round.StartDate.After(now) {
+				if err := tx.Model(&round).Update("opened_at", now).Error; err != nil {
+					return err
+				}
+				if err := recordEvent(tx, models.EventSalesRoundOpened, "sales-round", round.ID, payload); err != nil {
+					return err
+				}
+				opened++
+			}
+			if round.ClosedAt == nil && !round.EndDate.After(now) {
+				if err := tx.Model(&round).Update("closed_at", now).Error; err != nil {
+					return err
+				}
+				if err := recordEvent(tx, models.EventSalesRoundClosed, "sales-round", round.ID, payload); err != nil {
+					return err
+				}
+				closed++
+			}
+		}
+		return nil
+	})
+	return opened, closed, err
+}
+
+// createRoundWithAllocations creates a sales round and allocates the variants to it inside the given transaction,
+// returning the allocated variants
+func createRoundWithAllocations(tx *gorm.DB, salesRound *models.SalesRound, allocations []dtos.SalesRoundAllocationDTO) ([]uuid.UUID, error) {
+	if err := commitDrawSeed(salesRound); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(salesRound).Error; err != nil {
+		return nil, err
+	}
+
+	allocated := make([]uuid.UUID, 0, len(allocations))
+	for _, allocation := range allocations {
+		if _, err := allocateToRound(tx, salesRound.ID, allocation); err != nil {
+			return nil, fmt.Errorf("variant %s: %w", allocation.VariantID, err)
+		}
+		allocated = append(allocated, allocation.VariantID)
+	}
+	return allocated, nil
+}
+
+func (r *salesRoundRepository) GetSalesRoundAnalytics(roundID uuid.UUID) (*dtos.SalesRoundAnalyticsDTO, error) {
+	var round models.SalesRound
+	if err := r.db.First(&round, "id = ?", roundID).Error; err != nil {
+		return nil, err
+	}
+
+	var lineUp []struct {
+		models.SalesRoundDetail
+		SKUCode      string
+		ProductName  string
+		VariantPrice float64
+	}
+	err := r.db.Table(`"sales-round-detail"`).
+		Select(`"sales-round-detail".*, "product-variant".sku_code, "product-variant".price AS variant_price, "product".product_name`).
+		Joins(`JOIN "product-variant" ON "product-variant".variant_id = "sales-round-detail".variant_id`).
+		Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+		Where(`"sales-round-detail".round_id = ? AND "sales-round-detail".deleted_at IS NULL`, roundID).
+		Order(`"product".product_name, "product-variant".sku_code`).
+		Scan(&lineUp).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines ordered before the regular price was recorded count as sold at their regular price
+	var sales []struct {
+		VariantID     uuid.UUID
+		UnitsSold     int
+		RegularSales  float64
+		Sales         float64
+		CodeDiscounts float64
+		Revenue       float64
+	}
+	err = r.db.Table(`"order-detail"`).
+		Select(`"order-detail".variant_id,
+			SUM("order-detail".quantity) AS units_sold,
+			SUM(CASE WHEN "order-detail".regular_price > 0 THEN "order-detail".regular_price ELSE "order-detail".price END * "order-detail".quantity) AS regular_sales,
+			SUM("order-detail".price * "order-detail".quantity) AS sales,
+			SUM("order-detail".discount_amount) AS code_discounts,
+			SUM("order-detail".total_price) AS revenue`).
+		Joins(`JOIN "order" ON "order".id = "order-detail".order_id`).
+		Where(`"order".round_id = ? AND "order".status IN ?`, roundID, taxableOrderStatuses).
+		Where(`"order-detail".deleted_at IS NULL AND "order".deleted_at IS NULL`).
+		Group(`"order-detail".variant_id`).
+		Scan(&sales).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var orders int64
+	err = r.db.Model(&models.Order{}).
+		Where("round_id = ? AND status IN ?", roundID, taxableOrderStatuses).
+		Count(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &dtos.SalesRoundAnalyticsDTO{
+		RoundID:  round.ID,
+		Name:     round.Name,
+		Orders:   int(orders),
+		Variants: make([]dtos.SalesRoundVariantAnalyticsDTO, 0, len(lineUp)),
+	}
+	index := make(map[uuid.UUID]int, len(lineUp))
+	for _, detail := range lineUp {
+		allocated := detail.AllocatedQuantity
+		if allocated <= 0 {
+			allocated = detail.Quantity // Allocated before the total was recorded
+		}
+		index[detail.VariantID] = len(analytics.Variants)
+		analytics.Variants = append(analytics.Variants, dtos.SalesRoundVariantAnalyticsDTO{
+			VariantID:    detail.VariantID,
+			SKUCode:      detail.SKUCode,
+			ProductName:  detail.ProductName,
+			RegularPrice: detail.VariantPrice,
+			SalePrice:    detail.SalePrice(detail.VariantPrice),
+			Allocated:    allocated,
+			Left:         detail.Quantity,
+		})
+	}
+	for _, sold := range sales {
+		i, ok := index[sold.VariantID]
+		if !ok {
+			// Sold, then taken out of the round's line-up
+			i = len(analytics.Variants)
+			analytics.Variants = append(analytics.Variants, dtos.SalesRoundVariantAnalyticsDTO{VariantID: sold.VariantID})
+		}
+		variant := &analytics.Variants[i]
+		variant.UnitsSold = sold.UnitsSold
+		variant.RegularSales = tax.Round(sold.RegularSales)
+		variant.Markdown = tax.Round(sold.RegularSales - sold.Sales)
+		variant.CodeDiscounts = tax.Round(sold.CodeDiscounts)
+		variant.Revenue = tax.Round(sold.Revenue)
+
+		analytics.UnitsSold += variant.UnitsSold
+		analytics.RegularSales += variant.RegularSales
+		analytics.Markdown += variant.Markdown
+		analytics.CodeDiscounts += variant.CodeDiscounts
+		analytics.Revenue += variant.Revenue
+	}
+	analytics.RegularSales = tax.Round(analytics.RegularSales)
+	analytics.Markdown = tax.Round(analytics.Markdown)
+	analytics.CodeDiscounts = tax.Round(analytics.CodeDiscounts)
+	analytics.Revenue = tax.Round(analytics.Revenue)
+	if analytics.RegularSales > 0 {
+		analytics.MarkdownPercent = tax.Round(analytics.Markdown / analytics.RegularSales * 100)
+	}
+	return analytics, nil
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SalesRoundTemplateRepository interface {
+	CreateTemplate(template *models.SalesRoundTemplate) error
+	GetAllTemplates() ([]models.SalesRoundTemplate, error)
+	GetActiveTemplates() ([]models.SalesRoundTemplate, error)
+	GetTemplateByID(id uuid.UUID) (*models.SalesRoundTemplate, error)
+	UpdateTemplate(template *models.SalesRoundTemplate) error
+	DeleteTemplate(id uuid.UUID) error
+	GetTemplateLinesFromRound(roundID uuid.UUID) ([]models.SalesRoundTemplateLine, error)
+	MaterializeTemplate(templateID uuid.UUID, start time.Time) (*models.SalesRound, error)
+}
+
+type salesRoundTemplateRepository struct {
+	db        *gorm.DB
+	observers stockObservers
+}
+
+func NewSalesRoundTemplateRepository(db *gorm.DB, observers ...StockObserver) SalesRoundTemplateRepository {
+	return &salesRoundTemplateRepository{db: db, observers: observers}
+}
+
+func (r *salesRoundTemplateRepository) CreateTemplate(template *models.SalesRoundTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *salesRoundTemplateRepository) GetAllTemplates() ([]models.SalesRoundTemplate, error) {
+	var templates []models.SalesRoundTemplate
+	err := r.db.Preload("Lines").Order("created_at").Find(&templates).Error
+	return templates, err
+}
+
+func (r *salesRoundTemplateRepository) GetActiveTemplates() ([]models.SalesRoundTemplate, error) {
+	var templates []models.SalesRoundTemplate
+	err := r.db.Preload("Lines").Where("active = ?", true).Find(&templates).Error
+	return templates, err
+}
+
+func (r *salesRoundTemplateRepository) GetTemplateByID(id uuid.UUID) (*models.SalesRoundTemplate, error) {
+	var template models.SalesRoundTemplate
+	err := r.db.Preload("Lines").First(&template, "id = ?", id).Error
+	return &template, err
+}
+
+// UpdateTemplate saves the template and replaces its line-up with template.Lines
+func (r *salesRoundTemplateRepository) UpdateTemplate(template *models.SalesRoundTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Lines").Save(template).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&models.SalesRoundTemplateLine{}).Error; err != nil {
+			return err
+		}
+		for i := range template.Lines {
+			template.Lines[i].ID = uuid.Nil
+			template.Lines[i].TemplateID = template.ID
+		}
+		if len(template.Lines) == 0 {
+			return nil
+		}
+		return tx.Create(&template.Lines).Error
+	})
+}
+
+func (r *salesRoundTemplateRepository) DeleteTemplate(id uuid.UUID) error {
+	return r.db.Delete(&models.SalesRoundTemplate{}, "id = ?", id).Error
+}
+
+// GetTemplateLinesFromRound copies the variant line-up of an existing round into template lines
+func (r *salesRoundTemplateRepository) GetTemplateLinesFromRound(roundID uuid.UUID) ([]models.SalesRoundTemplateLine, error) {
+	var details []models.SalesRoundDetail
+	if err := r.db.Where("round_id = ?", roundID).Find(&details).Error; err != nil {
+		return nil, err
+	}
+
+	lines := make([]models.SalesRoundTemplateLine, 0, len(details))
+	for _, detail := range details {
+		quantity := detail.AllocatedQuantity
+		if quantity <= 0 {
+			quantity = detail.Quantity
+		}
+		lines = append(lines, models.SalesRoundTemplateLine{
+			VariantID:       detail.VariantID,
+			Quantity:        quantity,
+			QuantityLimit:   detail.QuantityLimit,
+			LocationID:      detail.LocationID,
+			RoundPrice:      detail.RoundPrice,
+			DiscountPercent: detail.DiscountPercent,
+		})
+	}
+	return lines, nil
+}
+
+// MaterializeTemplate creates the round of a template starting at start with the template's line-up, all or
+// nothing. It returns nil without error if the template already has a round starting at that time.
+func (r *salesRoundTemplateRepository) MaterializeTemplate(templateID uuid.UUID, start time.Time) (*models.SalesRound, error) {
+	var salesRound *models.SalesRound
+	var allocated []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var template models.SalesRoundTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, "id = ?", templateID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", templateID).Find(&template.Lines).Error; err != nil {
+			return err
+		}
+
+		var existing models.SalesRound
+		err := tx.Where("template_id = ? AND start_date = ?", templateID, start).First(&existing).Error
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		allocations := make([]dtos.SalesRoundAllocationDTO, 0, len(template.Lines))
+		for _, line := range template.Lines {
+			allocations = append(allocations, dtos.SalesRoundAllocationDTO{
+				VariantID:       line.VariantID,
+				Quantity:        line.Quantity,
+				QuantityLimit:   line.QuantityLimit,
+				LocationID:      line.LocationID,
+				RoundPrice:      line.RoundPrice,
+				DiscountPercent: line.DiscountPercent,
+			})
+		}
+
+		salesRound = &models.SalesRound{
+			Name:       fmt.Sprintf("%s %s", template.Name, start.Format("2006-01-02 15:04")),
+			StartDate:  start,
+			EndDate:    start.Add(time.Duration(template.DurationMinutes) * time.Minute),
+			TemplateID: &template.ID,
+		}
+		if allocated, err = createRoundWithAllocations(tx, salesRound, allocations); err != nil {
+			return err
+		}
+
+		log.Printf("Materialized sales round %s from template %s", salesRound.Name, template.ID)
+		return tx.Model(&template).Update("last_materialized_at", start).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observers.notify(allocated...)
+	return salesRound, nil
+}
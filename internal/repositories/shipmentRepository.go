@@ -0,0 +1,326 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOrderNotShippable is returned when a shipment is created for an order that is not paid
+	ErrOrderNotShippable = errors.New("order is not paid or has been shipped in full")
+	// ErrNothingToShip is returned when a shipment has no items left to ship
+	ErrNothingToShip = errors.New("shipment has nothing left to ship")
+)
+
+type ShipmentRepository interface {
+	// CreateShipment ships items of a paid order. Without items it ships everything not shipped yet.
+	CreateShipment(shipment *models.Shipment) error
+	// GetShipmentByID returns a shipment with its items and tracking events, without its label
+	GetShipmentByID(id uuid.UUID) (*models.Shipment, error)
+	GetShipmentsByOrderID(orderID uuid.UUID) ([]models.Shipment, error)
+	// GetShipmentLabel returns a shipment with its label
+	GetShipmentLabel(id uuid.UUID) (*models.Shipment, error)
+	// GetShipmentsToTrack returns up to limit undelivered shipments of the carriers that were not polled since
+	// before, least recently polled first
+	GetShipmentsToTrack(carriers []string, before time.Time, limit int) ([]models.Shipment, error)
+	// RecordTracking adds the tracking events a carrier reported for a shipment and moves it to status
+	RecordTracking(id uuid.UUID, events []models.ShipmentTrackingEvent, status string) (*models.Shipment, error)
+}
+
+type shipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) ShipmentRepository {
+	return &shipmentRepository{db: db}
+}
+
+// CreateShipment creates the shipment with its items, moves the order to partially_shipped or, once every line
+// is shipped in full, to shipped, and records OrderShipped, all in one transaction. An item cannot ship more of
+// a line than is left of it; a returned shipment no longer counts as shipped.
+func (r *shipmentRepository) CreateShipment(shipment *models.Shipment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", shipment.OrderID).Error; err != nil {
+			return err
+		}
+		switch order.Status {
+		case models.OrderStatusPaid, models.OrderStatusPurchased, models.OrderStatusPartiallyShipped:
+		default:
+			return ErrOrderNotShippable
+		}
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderDetail).Error; err != nil {
+			return err
+		}
+		remaining, err := unshippedQuantities(tx, &order)
+		if err != nil {
+			return err
+		}
+
+		items, err := shipmentItems(order.OrderDetail, remaining, shipment.Items)
+		if err != nil {
+			return err
+		}
+		shipment.Items = items
+
+		if shipment.Status == "" {
+			shipment.Status = models.ShipmentShipped
+		}
+		if shipment.ShippedAt.IsZero() {
+			shipment.ShippedAt = time.Now()
+		}
+		if err := tx.Create(shipment).Error; err != nil {
+			return err
+		}
+
+		reason := fmt.Sprintf("shipment %s sent with %s", shipment.ID, shipment.Carrier)
+		if err := refreshFulfilmentStatus(tx, &order, reason); err != nil {
+			return err
+		}
+		return recordEvent(tx, models.EventOrderShipped, "order", order.ID, shipmentEventPayload(shipment, &order))
+	})
+}
+
+func (r *shipmentRepository) GetShipmentByID(id uuid.UUID) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Omit("label").
+		Preload("Items").
+		Preload("TrackingEvents", func(db *gorm.DB) *gorm.DB {
+			return db.Order("occurred_at")
+		}).
+		First(&shipment, "id = ?", id).Error
+	return &shipment, err
+}
+
+func (r *shipmentRepository) GetShipmentsByOrderID(orderID uuid.UUID) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := r.db.Omit("label").Preload("Items").Where("order_id = ?", orderID).Order("shipped_at").Find(&shipments).Error
+	return shipments, err
+}
+
+func (r *shipmentRepository) GetShipmentLabel(id uuid.UUID) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.First(&shipment, "id = ?", id).Error
+	return &shipment, err
+}
+
+func (r *shipmentRepository) GetShipmentsToTrack(carriers []string, before time.Time, limit int) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := r.db.Omit("label").
+		Where("carrier IN ? AND tracking_number <> '' AND status NOT IN ?", carriers, []string{models.ShipmentDelivered, models.ShipmentReturned}).
+		Where("last_tracked_at IS NULL OR last_tracked_at < ?", before).
+		Order("last_tracked_at NULLS FIRST").
+		Limit(limit).
+		Find(&shipments).Error
+	return shipments, err
+}
+
+// RecordTracking stores the events that are new, and when the shipment's status changes records it in the
+// order history, records ShipmentUpdated and updates the order's fulfilment status, all in one transaction
+func (r *shipmentRepository) RecordTracking(id uuid.UUID, events []models.ShipmentTrackingEvent, status string) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("label").Clauses(clause.Locking{Strength: "UPDATE"}).First(&shipment, "id = ?", id).Error; err != nil {
+			return err
+		}
+		for i := range events {
+			events[i].ShipmentID = shipment.ID
+		}
+		if len(events) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		shipment.LastTrackedAt = &now
+		if status == "" || status == shipment.Status {
+			return tx.Model(&shipment).Update("last_tracked_at", now).Error
+		}
+		previous := shipment.Status
+		shipment.Status = status
+		if status == models.ShipmentDelivered && shipment.DeliveredAt == nil {
+			deliveredAt := now
+			if len(events) > 0 {
+				deliveredAt = events[len(events)-1].OccurredAt
+			}
+			shipment.DeliveredAt = &deliveredAt
+		}
+		if err := tx.Model(&shipment).Select("status", "delivered_at", "last_tracked_at").Updates(&shipment).Error; err != nil {
+			return err
+		}
+
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", shipment.OrderID).Error; err != nil {
+			return err
+		}
+		description := fmt.Sprintf("shipment %s (%s %s) is %s", shipment.ID, shipment.Carrier, shipment.TrackingNumber, status)
+		if len(events) > 0 && events[len(events)-1].Description != "" {
+			description += ": " + events[len(events)-1].Description
+		}
+		if err := recordOrderHistory(tx, &order, description); err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderDetail).Error; err != nil {
+			return err
+		}
+		if err := refreshFulfilmentStatus(tx, &order, description); err != nil {
+			return err
+		}
+
+		data := shipmentEventPayload(&shipment, &order)
+		data["previous_status"] = previous
+		data["shipment_status"] = shipment.Status
+		data["delivered_at"] = shipment.DeliveredAt
+		return recordEvent(tx, models.EventShipmentUpdated, "order", order.ID, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// refreshFulfilmentStatus moves a locked order, loaded with its details, to the status its shipments call for:
+// delivered once every line is shipped and every shipment delivered, shipped once every line is shipped,
+// partially_shipped while some are, and back to paid when every shipment came back
+func refreshFulfilmentStatus(tx *gorm.DB, order *models.Order, reason string) error {
+	remaining, err := unshippedQuantities(tx, order)
+	if err != nil {
+		return err
+	}
+	var statuses []string
+	err = tx.Model(&models.Shipment{}).
+		Where("order_id = ? AND status <> ?", order.ID, models.ShipmentReturned).
+		Pluck("status", &statuses).Error
+	if err != nil {
+		return err
+	}
+
+	previous := order.Status
+	order.Status = fulfilmentStatus(remaining, statuses)
+	if order.Status == previous {
+		return nil
+	}
+	if err := tx.Model(order).Update("status", order.Status).Error; err != nil {
+		return err
+	}
+	return recordStatusChange(tx, order, previous, reason)
+}
+
+// shipmentItems checks the items of a new shipment against what is left to ship of an order's lines, and sets
+// the variant of each item. Without items the shipment takes everything left.
+func shipmentItems(lines []models.OrderDetail, remaining map[uuid.UUID]int, items []models.ShipmentItem) ([]models.ShipmentItem, error) {
+	if len(items) == 0 {
+		for _, line := range lines {
+			if remaining[line.ID] > 0 {
+				items = append(items, models.ShipmentItem{OrderDetailID: line.ID, Quantity: remaining[line.ID]})
+			}
+		}
+		if len(items) == 0 {
+			return nil, ErrNothingToShip
+		}
+	}
+	variants := make(map[uuid.UUID]uuid.UUID, len(lines))
+	for _, line := range lines {
+		variants[line.ID] = line.VariantID
+	}
+	left := make(map[uuid.UUID]int, len(remaining))
+	for id, quantity := range remaining {
+		left[id] = quantity
+	}
+	for i := range items {
+		item := &items[i]
+		variantID, ok := variants[item.OrderDetailID]
+		if !ok {
+			return nil, fmt.Errorf("order detail %s is not a line of this order", item.OrderDetailID)
+		}
+		if item.Quantity > left[item.OrderDetailID] {
+			return nil, fmt.Errorf("order detail %s has %d left to ship, not %d", item.OrderDetailID, left[item.OrderDetailID], item.Quantity)
+		}
+		left[item.OrderDetailID] -= item.Quantity
+		item.VariantID = variantID
+	}
+	return items, nil
+}
+
+// fulfilmentStatus is the status of an order with remaining left to ship of its lines and shipments, not
+// counting returned ones, of statuses
+func fulfilmentStatus(remaining map[uuid.UUID]int, statuses []string) string {
+	shippedInFull := true
+	for _, quantity := range remaining {
+		if quantity > 0 {
+			shippedInFull = false
+		}
+	}
+	delivered := len(statuses) > 0
+	for _, status := range statuses {
+		if status != models.ShipmentDelivered {
+			delivered = false
+		}
+	}
+
+	switch {
+	case shippedInFull && delivered:
+		return models.OrderStatusDelivered
+	case shippedInFull:
+		return models.OrderStatusShipped
+	case len(statuses) > 0:
+		return models.OrderStatusPartiallyShipped
+	}
+	return models.OrderStatusPaid
+}
+
+// unshippedQuantities returns how much of each line of an order, loaded with its details, is left to ship
+func unshippedQuantities(tx *gorm.DB, order *models.Order) (map[uuid.UUID]int, error) {
+	var shipped []struct {
+		OrderDetailID uuid.UUID
+		Quantity      int
+	}
+	err := tx.Model(&models.ShipmentItem{}).
+		Select(`"shipment-item".order_detail_id, SUM("shipment-item".quantity) AS quantity`).
+		Joins(`JOIN "shipment" ON "shipment".id = "shipment-item".shipment_id`).
+		Where(`"shipment".order_id = ? AND "shipment".status <> ?`, order.ID, models.ShipmentReturned).
+		Group(`"shipment-item".order_detail_id`).
+		Scan(&shipped).Error
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[uuid.UUID]int, len(order.OrderDetail))
+	for _, line := range order.OrderDetail {
+		remaining[line.ID] = line.Quantity
+	}
+	for _, line := range shipped {
+		remaining[line.OrderDetailID] -= line.Quantity
+	}
+	return remaining, nil
+}
+
+// shipmentEventPayload describes a shipment in OrderShipped events
+func shipmentEventPayload(shipment *models.Shipment, order *models.Order) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(shipment.Items))
+	for _, item := range shipment.Items {
+		items = append(items, map[string]interface{}{
+			"order_detail_id": item.OrderDetailID,
+			"variant_id":      item.VariantID,
+			"quantity":        item.Quantity,
+		})
+	}
+	return map[string]interface{}{
+		"shipment_id":     shipment.ID,
+		"order_id":        order.ID,
+		"code":            order.Code,
+		"status":          order.Status,
+		"carrier":         shipment.Carrier,
+		"tracking_number": shipment.TrackingNumber,
+		"service":         shipment.Service,
+		"shipped_at":      shipment.ShippedAt,
+		"items":           items,
+	}
+}
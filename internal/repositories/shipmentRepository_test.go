@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestShipmentItems(t *testing.T) {
+	shirt := models.OrderDetail{ID: uuid.New(), VariantID: uuid.New(), Quantity: 3}
+	mug := models.OrderDetail{ID: uuid.New(), VariantID: uuid.New(), Quantity: 1}
+	lines := []models.OrderDetail{shirt, mug}
+	// Two shirts went out in an earlier shipment
+	remaining := map[uuid.UUID]int{shirt.ID: 1, mug.ID: 1}
+
+	items, err := shipmentItems(lines, remaining, nil)
+	if err != nil {
+		t.Fatalf("shipmentItems() without items error = %v", err)
+	}
+	if len(items) != 2 || items[0].Quantity != 1 || items[0].VariantID != shirt.VariantID || items[1].VariantID != mug.VariantID {
+		t.Errorf("shipmentItems() without items = %+v, want the last shirt and the mug", items)
+	}
+
+	if _, err := shipmentItems(lines, remaining, []models.ShipmentItem{{OrderDetailID: shirt.ID, Quantity: 2}}); err == nil {
+		t.Error("shipping 2 of a line with 1 left succeeded")
+	}
+	// Two items of the same line add up
+	split := []models.ShipmentItem{{OrderDetailID: shirt.ID, Quantity: 1}, {OrderDetailID: shirt.ID, Quantity: 1}}
+	if _, err := shipmentItems(lines, remaining, split); err == nil {
+		t.Error("shipping a line with 1 left twice succeeded")
+	}
+	if _, err := shipmentItems(lines, remaining, []models.ShipmentItem{{OrderDetailID: uuid.New(), Quantity: 1}}); err == nil {
+		t.Error("shipping a line of another order succeeded")
+	}
+	if remaining[shirt.ID] != 1 {
+		t.Errorf("remaining quantity of the shirt changed to %d", remaining[shirt.ID])
+	}
+
+	shippedInFull := map[uuid.UUID]int{shirt.ID: 0, mug.ID: 0}
+	if _, err := shipmentItems(lines, shippedInFull, nil); !errors.Is(err, ErrNothingToShip) {
+		t.Errorf("shipmentItems() of an order shipped in full error = %v, want ErrNothingToShip", err)
+	}
+}
+
+func TestFulfilmentStatus(t *testing.T) {
+	line := uuid.New()
+	left := map[uuid.UUID]int{line: 1}
+	none := map[uuid.UUID]int{line: 0}
+	tests := []struct {
+		name      string
+		remaining map[uuid.UUID]int
+		statuses  []string
+		want      string
+	}{
+		{"nothing shipped", left, nil, models.OrderStatusPaid},
+		{"partly shipped", left, []string{models.ShipmentInTransit}, models.OrderStatusPartiallyShipped},
+		{"partly shipped and delivered", left, []string{models.ShipmentDelivered}, models.OrderStatusPartiallyShipped},
+		{"shipped in full", none, []string{models.ShipmentDelivered, models.ShipmentShipped}, models.OrderStatusShipped},
+		{"label created for the rest", none, []string{models.ShipmentDelivered, models.ShipmentLabelCreated}, models.OrderStatusShipped},
+		{"delivered in full", none, []string{models.ShipmentDelivered, models.ShipmentDelivered}, models.OrderStatusDelivered},
+		// Returned shipments are left out of the statuses and their items count as unshipped again
+		{"every shipment returned", left, nil, models.OrderStatusPaid},
+	}
+	for _, tt := range tests {
+		if got := fulfilmentStatus(tt.remaining, tt.statuses); got != tt.want {
+			t.Errorf("%s: fulfilmentStatus() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockMovementRepository interface {
+	GetStockMovements(variantID *uuid.UUID, locationID *uuid.UUID) ([]models.StockMovement, error)
+}
+
+type stockMovementRepository struct {
+	db *gorm.DB
+}
+
+func NewStockMovementRepository(db *gorm.DB) StockMovementRepository {
+	return &stockMovementRepository{db: db}
+}
+
+func (r *stockMovementRepository) GetStockMovements(variantID *uuid.UUID, locationID *uuid.UUID) ([]models.StockMovement, error) {
+	var movements []models.StockMovement
+	query := r.db.Order("created_at DESC")
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	}
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	}
+	err := query.Find(&movements).Error
+	return movements, err
+}
+
+// applyStockMovement changes the product stock (and the location stock level, when the movement has a
+// location) by movement.Quantity and records the movement, all inside the given transaction.
+func applyStockMovement(tx *gorm.DB, movement *models.StockMovement) error {
+	var variant models.ProductVariant
+	if err := tx.First(&variant, "variant_id = ?", movement.VariantID).Error; err != nil {
+		return err
+	}
+
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", variant.ProductID).Error; err != nil {
+		return err
+	}
+	if product.Stock+movement.Quantity < 0 {
+		return fmt.Errorf("not enough stock")
+	}
+	product.Stock += movement.Quantity
+	if err := tx.Model(&product).Update("stock", product.Stock).Error; err != nil {
+		return err
+	}
+
+	if movement.LocationID != nil {
+		if err := adjustStockLevel(tx, movement.VariantID, *movement.LocationID, movement.Quantity); err != nil {
+			return err
+		}
+	}
+
+	movement.ProductID = product.ID
+	log.Printf("Recording stock movement: %v", movement)
+	if err := tx.Create(movement).Error; err != nil {
+		return err
+	}
+	return recordEvent(tx, models.EventStockAdjusted, "product-variant", movement.VariantID, map[string]interface{}{
+		"movement_id":    movement.ID,
+		"variant_id":     movement.VariantID,
+		"product_id":     movement.ProductID,
+		"location_id":    movement.LocationID,
+		"quantity":       movement.Quantity,
+		"product_stock":  product.Stock,
+		"reason":         movement.Reason,
+		"reason_code":    movement.ReasonCode,
+		"reference_type": movement.ReferenceType,
+		"reference_id":   movement.ReferenceID,
+	})
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import "github.com/google/uuid"
+
+// StockObserver is told about variants whose stock has just changed
+type StockObserver interface {
+	StockChanged(variantID uuid.UUID)
+}
+
+type stockObservers []StockObserver
+
+func (o stockObservers) notify(variantIDs ...uuid.UUID) {
+	for _, observer := range o {
+		for _, variantID := range variantIDs {
+			observer.StockChanged(variantID)
+		}
+	}
+}
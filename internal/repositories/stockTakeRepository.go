@@ -0,0 +1,417 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrVariantStockedPerLocation is returned when a store-wide stock take counts a variant whose stock is kept per location
+	ErrVariantStockedPerLocation = errors.New("variant is stocked per location; count it in a location stock take")
+	// ErrProductPartlyCounted is returned when a store-wide stock take counts some but not all variants of a product
+	ErrProductPartlyCounted = errors.New("a store-wide stock take must count every variant of a product")
+)
+
+type StockTakeRepository interface {
+	CreateSession(session *models.StockTakeSession) error
+	GetAllSessions(storeID *uuid.UUID, status string) ([]models.StockTakeSession, error)
+	GetSessionByID(id uuid.UUID) (*models.StockTakeSession, error)
+	SaveCounts(id uuid.UUID, counts []dtos.StockTakeCountDTO) (*models.StockTakeSession, error)
+	GetVarianceReport(id uuid.UUID) (*dtos.StockTakeVarianceReportDTO, error)
+	ApproveSession(id uuid.UUID, defaultReasonCode string) (*models.StockTakeSession, error)
+	CancelSession(id uuid.UUID) (*models.StockTakeSession, error)
+}
+
+type stockTakeRepository struct {
+	db        *gorm.DB
+	observers stockObservers
+}
+
+func NewStockTakeRepository(db *gorm.DB, observers ...StockObserver) StockTakeRepository {
+	return &stockTakeRepository{db: db, observers: observers}
+}
+
+func (r *stockTakeRepository) CreateSession(session *models.StockTakeSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *stockTakeRepository) GetAllSessions(storeID *uuid.UUID, status string) ([]models.StockTakeSession, error) {
+	var sessions []models.StockTakeSession
+	query := r.db.Preload("Counts").Order("created_at DESC")
+	if storeID != nil {
+		query = query.Where("store_id = ?", *storeID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *stockTakeRepository) GetSessionByID(id uuid.UUID) (*models.StockTakeSession, error) {
+	var session models.StockTakeSession
+	err := r.db.Preload("Counts").First(&session, "id = ?", id).Error
+	return &session, err
+}
+
+// SaveCounts records counted quantities on an open session. Counting a variant again replaces its earlier count.
+func (r *stockTakeRepository) SaveCounts(id uuid.UUID, counts []dtos.StockTakeCountDTO) (*models.StockTakeSession, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var session models.StockTakeSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if session.Status != models.StockTakeOpen {
+			return fmt.Errorf("stock take is %s", session.Status)
+		}
+
+		for i, count := range counts {
+			if count.CountedQuantity < 0 {
+				return fmt.Errorf("count %d: counted quantity cannot be negative", i+1)
+			}
+			if count.ReasonCode != "" && !containsStatus(models.StockAdjustmentReasonCodes, count.ReasonCode) {
+				return fmt.Errorf("count %d: unknown reason code %q", i+1, count.ReasonCode)
+			}
+
+			query := tx.Model(&models.ProductVariant{}).
+				Joins("JOIN product ON \"product-variant\".product_id = product.id").
+				Where("product.store_id = ?", session.StoreID)
+			switch {
+			case count.VariantID != uuid.Nil:
+				query = query.Where("\"product-variant\".variant_id = ?", count.VariantID)
+			case count.SKUCode != "":
+				query = query.Where("\"product-variant\".sku_code = ?", count.SKUCode)
+			default:
+				return fmt.Errorf("count %d: a variant_id or sku_code is required", i+1)
+			}
+			var variant models.ProductVariant
+			if err := query.First(&variant).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("count %d: variant not found in this store", i+1)
+				}
+				return err
+			}
+			if _, err := stockTakeSystemQuantity(tx, &session, variant.VariantID, false); err != nil {
+				return fmt.Errorf("count %d: %w", i+1, err)
+			}
+
+			record := models.StockTakeCount{
+				SessionID:       session.ID,
+				VariantID:       variant.VariantID,
+				CountedQuantity: count.CountedQuantity,
+				ReasonCode:      count.ReasonCode,
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "session_id"}, {Name: "variant_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"counted_quantity", "reason_code", "updated_at"}),
+			}).Create(&record).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSessionByID(id)
+}
+
+// GetVarianceReport compares every count with the system stock. Open sessions are compared with the
+// current stock, approved sessions with the stock recorded when they were approved. A store-wide session
+// reports one line per product; see stockTakeLines.
+func (r *stockTakeRepository) GetVarianceReport(id uuid.UUID) (*dtos.StockTakeVarianceReportDTO, error) {
+	session, err := r.GetSessionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := loadStockTakeLines(r.db, session)
+	if err != nil {
+		return nil, err
+	}
+
+	variantIDs := make([]uuid.UUID, 0, len(session.Counts))
+	for _, count := range session.Counts {
+		variantIDs = append(variantIDs, count.VariantID)
+	}
+	var variants []struct {
+		VariantID   uuid.UUID
+		SKUCode     string
+		ProductName string
+		Price       float64
+	}
+	err = r.db.Table("\"product-variant\"").
+		Select("\"product-variant\".variant_id, \"product-variant\".sku_code, product.product_name, \"product-variant\".price").
+		Joins("JOIN product ON \"product-variant\".product_id = product.id").
+		Where("\"product-variant\".variant_id IN ?", variantIDs).
+		Scan(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]int, len(variants))
+	for i, variant := range variants {
+		byID[variant.VariantID] = i
+	}
+
+	report := dtos.StockTakeVarianceReportDTO{
+		SessionID: session.ID,
+		Status:    session.Status,
+		Lines:     make([]dtos.StockTakeVarianceLineDTO, 0, len(lines)),
+	}
+	for _, line := range lines {
+		systemQuantity := line.Counts[0].SystemQuantity
+		if session.Status == models.StockTakeOpen {
+			if systemQuantity, err = stockTakeSystemQuantity(r.db, session, line.Counts[0].VariantID, false); err != nil {
+				return nil, err
+			}
+		}
+
+		// A product line is valued at the average price of its variants
+		var price float64
+		var skus []string
+		reportLine := dtos.StockTakeVarianceLineDTO{ProductID: line.ProductID, VariantID: line.VariantID}
+		for _, count := range line.Counts {
+			variant := variants[byID[count.VariantID]]
+			price += variant.Price / float64(len(line.Counts))
+			skus = append(skus, variant.SKUCode)
+			reportLine.ProductName = variant.ProductName
+			if reportLine.ReasonCode == "" {
+				reportLine.ReasonCode = count.ReasonCode
+			}
+		}
+		reportLine.SKUCode = strings.Join(skus, ", ")
+		reportLine.CountedQuantity = line.Counted
+		reportLine.SystemQuantity = systemQuantity
+		reportLine.Variance = line.Counted - systemQuantity
+		reportLine.VarianceValue = float64(reportLine.Variance) * price
+		report.Lines = append(report.Lines, reportLine)
+		report.TotalVarianceUnits += reportLine.Variance
+		report.TotalVarianceValue += reportLine.VarianceValue
+	}
+	return &report, nil
+}
+
+// ApproveSession posts the difference between every count and the system stock as a stock movement; a
+// store-wide session posts one per product. Every variance needs a reason code, taken from the count or from
+// defaultReasonCode. Either all adjustments are posted or none is.
+func (r *stockTakeRepository) ApproveSession(id uuid.UUID, defaultReasonCode string) (*models.StockTakeSession, error) {
+	if defaultReasonCode != "" && !containsStatus(models.StockAdjustmentReasonCodes, defaultReasonCode) {
+		return nil, fmt.Errorf("unknown reason code %q", defaultReasonCode)
+	}
+
+	var adjusted []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var session models.StockTakeSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Counts").First(&session, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if session.Status != models.StockTakeOpen {
+			return fmt.Errorf("stock take is %s", session.Status)
+		}
+		if len(session.Counts) == 0 {
+			return fmt.Errorf("nothing was counted")
+		}
+		lines, err := loadStockTakeLines(tx, &session)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			if err := line.checkCounted(); err != nil {
+				return err
+			}
+			systemQuantity, err := stockTakeSystemQuantity(tx, &session, line.Counts[0].VariantID, true)
+			if err != nil {
+				return err
+			}
+			variance := line.Counted - systemQuantity
+
+			reasonCode := ""
+			for _, count := range line.Counts {
+				if count.ReasonCode != "" {
+					reasonCode = count.ReasonCode
+					break
+				}
+			}
+			if reasonCode == "" && variance != 0 {
+				reasonCode = defaultReasonCode
+			}
+			for _, count := range line.Counts {
+				count.SystemQuantity = systemQuantity
+				if count.ReasonCode == "" {
+					count.ReasonCode = reasonCode
+				}
+				if err := tx.Model(count).Select("system_quantity", "reason_code").Updates(count).Error; err != nil {
+					return err
+				}
+			}
+
+			if variance == 0 {
+				continue
+			}
+			if reasonCode == "" {
+				return fmt.Errorf("variant %s has a variance of %d but no reason code", line.Counts[0].VariantID, variance)
+			}
+
+			countID := line.Counts[0].ID
+			movement := models.StockMovement{
+				VariantID:     line.Counts[0].VariantID,
+				LocationID:    session.LocationID,
+				Quantity:      variance,
+				Reason:        models.StockMovementStockTake,
+				ReasonCode:    reasonCode,
+				ReferenceType: "stock-take-count",
+				ReferenceID:   &countID,
+			}
+			if err := applyStockMovement(tx, &movement); err != nil {
+				return err
+			}
+			for _, count := range line.Counts {
+				adjusted = append(adjusted, count.VariantID)
+			}
+		}
+
+		now := time.Now()
+		session.Status = models.StockTakeApproved
+		session.ApprovedAt = &now
+		log.Printf("Stock take %s approved with %d adjustments", session.ID, len(adjusted))
+		return tx.Model(&session).Select("status", "approved_at").Updates(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observers.notify(adjusted...)
+	return r.GetSessionByID(id)
+}
+
+func (r *stockTakeRepository) CancelSession(id uuid.UUID) (*models.StockTakeSession, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var session models.StockTakeSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if session.Status != models.StockTakeOpen {
+			return fmt.Errorf("stock take is %s", session.Status)
+		}
+		return tx.Model(&session).Update("status", models.StockTakeCancelled).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSessionByID(id)
+}
+
+// stockTakeLine is what a stock take compares with one system stock figure: a variant at the session's location,
+// or a whole product in a store-wide session
+type stockTakeLine struct {
+	ProductID uuid.UUID
+	VariantID uuid.UUID // uuid.Nil for a whole product
+	Counts    []*models.StockTakeCount
+	Counted   int
+	Variants  int // Variants of the product; a product line is complete once they are all counted
+}
+
+// stockTakeLines groups the counts of a session into what is compared with the system stock. A location session
+// compares every variant with its stock level there. A store-wide session compares products: product stock is
+// shared by a product's variants, so their counts are added up. productOf maps each counted variant to its product,
+// and variants is how many variants each product has.
+func stockTakeLines(session *models.StockTakeSession, productOf map[uuid.UUID]uuid.UUID, variants map[uuid.UUID]int) ([]stockTakeLine, error) {
+	var lines []stockTakeLine
+	byProduct := make(map[uuid.UUID]int)
+	for i := range session.Counts {
+		count := &session.Counts[i]
+		productID, ok := productOf[count.VariantID]
+		if !ok {
+			return nil, fmt.Errorf("variant %s not found", count.VariantID)
+		}
+		if session.LocationID != nil {
+			lines = append(lines, stockTakeLine{ProductID: productID, VariantID: count.VariantID, Counts: []*models.StockTakeCount{count}, Counted: count.CountedQuantity, Variants: 1})
+			continue
+		}
+		j, ok := byProduct[productID]
+		if !ok {
+			j = len(lines)
+			byProduct[productID] = j
+			lines = append(lines, stockTakeLine{ProductID: productID, Variants: variants[productID]})
+		}
+		lines[j].Counts = append(lines[j].Counts, count)
+		lines[j].Counted += count.CountedQuantity
+	}
+	return lines, nil
+}
+
+// checkCounted returns ErrProductPartlyCounted unless every variant of the line's product was counted, since
+// the product stock can only be set from the count of all of them
+func (line stockTakeLine) checkCounted() error {
+	if len(line.Counts) < line.Variants {
+		return fmt.Errorf("%w: product %s has %d variants, %d counted", ErrProductPartlyCounted, line.ProductID, line.Variants, len(line.Counts))
+	}
+	return nil
+}
+
+// loadStockTakeLines looks up the products of a session's counted variants and groups the counts with
+// stockTakeLines
+func loadStockTakeLines(tx *gorm.DB, session *models.StockTakeSession) ([]stockTakeLine, error) {
+	variantIDs := make([]uuid.UUID, 0, len(session.Counts))
+	for _, count := range session.Counts {
+		variantIDs = append(variantIDs, count.VariantID)
+	}
+	var siblings []models.ProductVariant
+	err := tx.Where("product_id IN (?)", tx.Model(&models.ProductVariant{}).Select("product_id").Where("variant_id IN ?", variantIDs)).
+		Find(&siblings).Error
+	if err != nil {
+		return nil, err
+	}
+	productOf := make(map[uuid.UUID]uuid.UUID, len(siblings))
+	variants := make(map[uuid.UUID]int)
+	for _, variant := range siblings {
+		productOf[variant.VariantID] = variant.ProductID
+		variants[variant.ProductID]++
+	}
+	return stockTakeLines(session, productOf, variants)
+}
+
+// stockTakeSystemQuantity returns the stock the system holds for what a session counts: the stock level at the
+// session's location, or the product stock for a store-wide session. With lock the row read is locked for update.
+func stockTakeSystemQuantity(tx *gorm.DB, session *models.StockTakeSession, variantID uuid.UUID, lock bool) (int, error) {
+	query := tx
+	if lock {
+		query = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	if session.LocationID != nil {
+		var level models.VariantStockLevel
+		err := query.Where("variant_id = ? AND location_id = ?", variantID, *session.LocationID).First(&level).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return level.Quantity, err
+	}
+
+	var levels int64
+	if err := tx.Model(&models.VariantStockLevel{}).Where("variant_id = ?", variantID).Count(&levels).Error; err != nil {
+		return 0, err
+	}
+	if levels > 0 {
+		return 0, ErrVariantStockedPerLocation
+	}
+
+	var variant models.ProductVariant
+	if err := tx.First(&variant, "variant_id = ?", variantID).Error; err != nil {
+		return 0, err
+	}
+	var product models.Product
+	if err := query.First(&product, "id = ?", variant.ProductID).Error; err != nil {
+		return 0, err
+	}
+	return product.Stock, nil
+}
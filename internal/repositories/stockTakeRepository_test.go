@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestStockTakeLines(t *testing.T) {
+	shirt, mug := uuid.New(), uuid.New()
+	small, large, cup := uuid.New(), uuid.New(), uuid.New()
+	productOf := map[uuid.UUID]uuid.UUID{small: shirt, large: shirt, cup: mug}
+	variants := map[uuid.UUID]int{shirt: 2, mug: 1}
+	location := uuid.New()
+
+	type wantLine struct {
+		productID uuid.UUID
+		variantID uuid.UUID
+		counted   int
+		counts    int
+	}
+	tests := []struct {
+		name        string
+		locationID  *uuid.UUID
+		counts      []models.StockTakeCount
+		wantErr     bool
+		want        []wantLine
+		wantPartial bool
+	}{
+		{
+			name: "store-wide counts of a product with two variants add up to one line",
+			counts: []models.StockTakeCount{
+				{VariantID: small, CountedQuantity: 3},
+				{VariantID: cup, CountedQuantity: 7},
+				{VariantID: large, CountedQuantity: 4},
+			},
+			want: []wantLine{{productID: shirt, counted: 7, counts: 2}, {productID: mug, counted: 7, counts: 1}},
+		},
+		{
+			name:        "store-wide count of one of two variants",
+			counts:      []models.StockTakeCount{{VariantID: small, CountedQuantity: 3}},
+			want:        []wantLine{{productID: shirt, counted: 3, counts: 1}},
+			wantPartial: true,
+		},
+		{
+			name:       "location counts stay per variant",
+			locationID: &location,
+			counts: []models.StockTakeCount{
+				{VariantID: small, CountedQuantity: 3},
+				{VariantID: large, CountedQuantity: 4},
+			},
+			want: []wantLine{{productID: shirt, variantID: small, counted: 3, counts: 1}, {productID: shirt, variantID: large, counted: 4, counts: 1}},
+		},
+		{
+			name:    "unknown variant",
+			counts:  []models.StockTakeCount{{VariantID: uuid.New(), CountedQuantity: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &models.StockTakeSession{LocationID: tt.locationID, Counts: tt.counts}
+			lines, err := stockTakeLines(session, productOf, variants)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("stockTakeLines() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d", len(lines), len(tt.want))
+			}
+			partial := false
+			for i, line := range lines {
+				want := tt.want[i]
+				if line.ProductID != want.productID || line.VariantID != want.variantID || line.Counted != want.counted || len(line.Counts) != want.counts {
+					t.Errorf("line %d = {%s %s counted %d, %d counts}, want {%s %s counted %d, %d counts}", i,
+						line.ProductID, line.VariantID, line.Counted, len(line.Counts), want.productID, want.variantID, want.counted, want.counts)
+				}
+				if err := line.checkCounted(); err != nil {
+					if !errors.Is(err, ErrProductPartlyCounted) {
+						t.Errorf("checkCounted() error = %v, want ErrProductPartlyCounted", err)
+					}
+					partial = true
+				}
+			}
+			if partial != tt.wantPartial {
+				t.Errorf("partly counted = %v, want %v", partial, tt.wantPartial)
+			}
+		})
+	}
+}
@@ -79,7 +79,7 @@ func (r *storeRepository) GetStoreByID(id uuid.UUID) (*models.Store, error) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := r.db.Preload("Products.Category").First(&store, "id = ?", id).Error; err != nil {
+		if err := r.db.Preload("Products.Category").Preload("Locations").First(&store, "id = ?", id).Error; err != nil {
 			errChan <- err
 		}
 	}()
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SupplierRepository interface {
+	CreateSupplier(supplier *models.Supplier) error
+	GetAllSuppliers(storeID *uuid.UUID) ([]models.Supplier, error)
+	GetSupplierByID(id uuid.UUID) (*models.Supplier, error)
+	UpdateSupplier(supplier *models.Supplier) error
+	DeleteSupplier(id uuid.UUID) error
+}
+
+type supplierRepository struct {
+	db *gorm.DB
+}
+
+func NewSupplierRepository(db *gorm.DB) SupplierRepository {
+	return &supplierRepository{db: db}
+}
+
+func (r *supplierRepository) CreateSupplier(supplier *models.Supplier) error {
+	return r.db.Create(supplier).Error
+}
+
+func (r *supplierRepository) GetAllSuppliers(storeID *uuid.UUID) ([]models.Supplier, error) {
+	var suppliers []models.Supplier
+	query := r.db.Order("name")
+	if storeID != nil {
+		query = query.Where("store_id = ?", *storeID)
+	}
+	err := query.Find(&suppliers).Error
+	return suppliers, err
+}
+
+func (r *supplierRepository) GetSupplierByID(id uuid.UUID) (*models.Supplier, error) {
+	var supplier models.Supplier
+	err := r.db.First(&supplier, "id = ?", id).Error
+	return &supplier, err
+}
+
+func (r *supplierRepository) UpdateSupplier(supplier *models.Supplier) error {
+	return r.db.Save(supplier).Error
+}
+
+func (r *supplierRepository) DeleteSupplier(id uuid.UUID) error {
+	return r.db.Delete(&models.Supplier{}, "id = ?", id).Error
+}
@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// taxableOrderStatuses are the statuses of orders whose sales are reported for VAT
+var taxableOrderStatuses = []string{
+	models.OrderStatusPaid,
+	models.OrderStatusPurchased,
+	models.OrderStatusPartiallyShipped,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+}
+
+// VATSummaryRow is the sales of a store at one tax category and rate
+type VATSummaryRow struct {
+	Category    string
+	Rate        float64
+	Lines       int
+	NetAmount   float64
+	TaxAmount   float64
+	GrossAmount float64
+}
+
+// VATSummary is the sales of a store over a period, by tax category and rate, and the credit notes it issued in
+// the period, which reduce its output tax
+type VATSummary struct {
+	Orders      int
+	Rows        []VATSummaryRow
+	CreditNotes int
+	Credits     []VATSummaryRow
+}
+
+// TaxRepository keeps how stores tax their sales and reports the VAT of their orders
+type TaxRepository interface {
+	// GetStoreTaxSettings returns a store with the VAT rates it overrides
+	GetStoreTaxSettings(storeID uuid.UUID) (*models.Store, error)
+	// UpdateStoreTaxSettings sets a store's tax registration and whether its prices exclude VAT, and replaces the
+	// rates it overrides
+	UpdateStoreTaxSettings(settings *models.Store, rates map[string]float64) (*models.Store, error)
+	// GetVATSummary sums the order lines of a store's paid orders placed in [from, to), and the lines of the
+	// credit notes it issued in [from, to). Lines of orders placed before VAT was recorded per line are taxed as
+	// VAT-inclusive, as on their tax invoice.
+	GetVATSummary(storeID uuid.UUID, from, to time.Time) (*VATSummary, error)
+}
+
+type taxRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRepository(db *gorm.DB) TaxRepository {
+	return &taxRepository{db: db}
+}
+
+func (r *taxRepository) GetStoreTaxSettings(storeID uuid.UUID) (*models.Store, error) {
+	var store models.Store
+	err := r.db.Preload("TaxRates").First(&store, "id = ?", storeID).Error
+	return &store, err
+}
+
+func (r *taxRepository) UpdateStoreTaxSettings(settings *models.Store, rates map[string]float64) (*models.Store, error) {
+	storeID := settings.ID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var store models.Store
+		if err := tx.First(&store, "id = ?", storeID).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&store).
+			Select("prices_exclude_tax", "legal_name", "tax_id", "branch_code").
+			Updates(settings).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("store_id = ?", storeID).Delete(&models.StoreTaxRate{}).Error; err != nil {
+			return err
+		}
+		for category, rate := range rates {
+			if err := tx.Create(&models.StoreTaxRate{StoreID: storeID, Category: category, Rate: rate}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetStoreTaxSettings(storeID)
+}
+
+func (r *taxRepository) GetVATSummary(storeID uuid.UUID, from, to time.Time) (*VATSummary, error) {
+	query := func() *gorm.DB {
+		return r.db.Table(`"order-detail"`).
+			Joins(`JOIN "order" ON "order".id = "order-detail".order_id`).
+			Joins(`JOIN "product-variant" ON "product-variant".variant_id = "order-detail".variant_id`).
+			Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+			Where(`"product".store_id = ? AND "order".status IN ?`, storeID, taxableOrderStatuses).
+			Where(`"order".order_date >= ? AND "order".order_date < ?`, from, to).
+			Where(`"order-detail".deleted_at IS NULL AND "order".deleted_at IS NULL`)
+	}
+
+	summary := &VATSummary{}
+	var orders int64
+	if err := query().Distinct(`"order".id`).Count(&orders).Error; err != nil {
+		return nil, err
+	}
+	summary.Orders = int(orders)
+	err := query().
+		Where(`"order-detail".tax_category <> ''`).
+		Select(`"order-detail".tax_category AS category, "order-detail".tax_rate AS rate, COUNT(*) AS lines,
+			SUM("order-detail".net_amount) AS net_amount, SUM("order-detail".tax_amount) AS tax_amount,
+			SUM("order-detail".total_price) AS gross_amount`).
+		Group(`"order-detail".tax_category, "order-detail".tax_rate`).
+		Order("category, rate").
+		Scan(&summary.Rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines of orders placed before VAT was recorded per line have no tax category; they are taxed the way
+	// their tax invoice is issued
+	var legacyLines []tax.Line
+	err = query().
+		Where(`"order-detail".tax_category = ''`).
+		Select(`"order-detail".price AS unit_price, "order-detail".quantity`).
+		Scan(&legacyLines).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(legacyLines) > 0 {
+		store, err := r.GetStoreTaxSettings(storeID)
+		if err != nil {
+			return nil, err
+		}
+		config := legacyTaxConfig(*store)
+		for _, line := range legacyLines {
+			summary.Rows = addVATLine(summary.Rows, config.Compute(line))
+		}
+	}
+
+	// Credit notes count in the month they are issued, whenever the order was placed. Their lines take the
+	// category of the order line they credit; legacy lines were taxed as standard.
+	credits := func() *gorm.DB {
+		return r.db.Table(`"invoice-line"`).
+			Joins(`JOIN "invoice" ON "invoice".id = "invoice-line".invoice_id`).
+			Where(`"invoice".store_id = ? AND "invoice".type = ?`, storeID, models.InvoiceCreditNote).
+			Where(`"invoice".issued_at >= ? AND "invoice".issued_at < ?`, from, to)
+	}
+	var creditNotes int64
+	if err := credits().Distinct(`"invoice".id`).Count(&creditNotes).Error; err != nil {
+		return nil, err
+	}
+	summary.CreditNotes = int(creditNotes)
+	err = credits().
+		Joins(`LEFT JOIN "order-detail" ON "order-detail".id = "invoice-line".order_detail_id`).
+		Select(`COALESCE(NULLIF("order-detail".tax_category, ''), ?) AS category, "invoice-line".tax_rate AS rate,
+			COUNT(*) AS lines, SUM("invoice-line".net_amount) AS net_amount, SUM("invoice-line".tax_amount) AS tax_amount,
+			SUM("invoice-line".gross_amount) AS gross_amount`, tax.CategoryStandard).
+		Group(`1, "invoice-line".tax_rate`).
+		Order("category, rate").
+		Scan(&summary.Credits).Error
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// addVATLine adds a taxed line to the summary row of its category and rate, keeping rows in order of category
+// and rate
+func addVATLine(rows []VATSummaryRow, line tax.LineTax) []VATSummaryRow {
+	i := sort.Search(len(rows), func(i int) bool {
+		return rows[i].Category > line.Category || (rows[i].Category == line.Category && rows[i].Rate >= line.Rate)
+	})
+	if i == len(rows) || rows[i].Category != line.Category || rows[i].Rate != line.Rate {
+		rows = append(rows, VATSummaryRow{})
+		copy(rows[i+1:], rows[i:])
+		rows[i] = VATSummaryRow{Category: line.Category, Rate: line.Rate}
+	}
+	rows[i].Lines++
+	rows[i].NetAmount += line.Net
+	rows[i].TaxAmount += line.Tax
+	rows[i].GrossAmount += line.Gross
+	return rows
+}
+
+// applyOrderTax computes the VAT of each line of an order, after its discount, with the tax settings of the store
+// selling it, and sets the lines' and order's totals. Lines of stores whose prices exclude VAT cost more than their
+// price.
+func applyOrderTax(tx *gorm.DB, order *models.Order) error {
+	variantIDs := make([]uuid.UUID, 0, len(order.OrderDetail))
+	for _, line := range order.OrderDetail {
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+	var products []struct {
+		VariantID   uuid.UUID
+		StoreID     uuid.UUID
+		TaxCategory string
+	}
+	err := tx.Table(`"product-variant"`).
+		Select(`"product-variant".variant_id, "product".store_id, "product".tax_category`).
+		Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+		Where(`"product-variant".variant_id IN ?`, variantIDs).
+		Scan(&products).Error
+	if err != nil {
+		return err
+	}
+
+	storeIDs := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		storeIDs = append(storeIDs, product.StoreID)
+	}
+	var stores []models.Store
+	if err := tx.Preload("TaxRates").Where("id IN ?", storeIDs).Find(&stores).Error; err != nil {
+		return err
+	}
+	configs := make(map[uuid.UUID]tax.Config, len(stores))
+	for _, store := range stores {
+		configs[store.ID] = storeTaxConfig(store)
+	}
+
+	var net, vat, gross float64
+	includeTax := true
+	for i := range order.OrderDetail {
+		line := &order.OrderDetail[i]
+		config := tax.Config{PricesIncludeTax: true}
+		category := ""
+		for _, product := range products {
+			if product.VariantID == line.VariantID {
+				if storeConfig, ok := configs[product.StoreID]; ok {
+					config = storeConfig
+				}
+				category = product.TaxCategory
+				break
+			}
+		}
+
+		lineTax := config.Compute(tax.Line{Category: category, UnitPrice: line.Price, Quantity: line.Quantity, Discount: line.DiscountAmount})
+		line.TaxCategory = lineTax.Category
+		line.TaxRate = lineTax.Rate
+		line.NetAmount = lineTax.Net
+		line.TaxAmount = lineTax.Tax
+		line.TotalPrice = lineTax.Gross
+		net += lineTax.Net
+		vat += lineTax.Tax
+		gross += lineTax.Gross
+		includeTax = includeTax && config.PricesIncludeTax
+	}
+	order.NetAmount = tax.Round(net)
+	order.TaxAmount = tax.Round(vat)
+	order.TotalPrice = tax.Round(gross)
+	order.PricesIncludeTax = includeTax
+	return nil
+}
+
+// storeTaxConfig returns how a store, loaded with its tax rates, taxes its sales
+func storeTaxConfig(store models.Store) tax.Config {
+	config := tax.Config{PricesIncludeTax: !store.PricesExcludeTax, Rates: make(map[string]float64, len(store.TaxRates))}
+	for _, rate := range store.TaxRates {
+		config.Rates[rate.Category] = rate.Rate
+	}
+	return config
+}
+
+// legacyTaxConfig returns how the lines of a store's orders placed before VAT was recorded per line are taxed:
+// they paid their prices with VAT included, at the store's rates
+func legacyTaxConfig(store models.Store) tax.Config {
+	config := storeTaxConfig(store)
+	config.PricesIncludeTax = true
+	return config
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/tax"
+)
+
+func TestLegacyLinesAreSummedVATInclusive(t *testing.T) {
+	// A store whose prices now exclude VAT still collected VAT-inclusive prices on its legacy orders
+	store := models.Store{PricesExcludeTax: true}
+	rows := []VATSummaryRow{
+		{Category: tax.CategoryExempt, Rate: 0, Lines: 1, NetAmount: 50, GrossAmount: 50},
+		{Category: tax.CategoryStandard, Rate: 7, Lines: 2, NetAmount: 200, TaxAmount: 14, GrossAmount: 214},
+	}
+	config := legacyTaxConfig(store)
+	for _, line := range []tax.Line{{UnitPrice: 107, Quantity: 1}, {UnitPrice: 100, Quantity: 1}} {
+		rows = addVATLine(rows, config.Compute(line))
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want the exempt and standard rows", rows)
+	}
+	standard := rows[1]
+	standard.NetAmount, standard.TaxAmount = tax.Round(standard.NetAmount), tax.Round(standard.TaxAmount)
+	want := VATSummaryRow{Category: tax.CategoryStandard, Rate: 7, Lines: 4, NetAmount: 393.46, TaxAmount: 27.54, GrossAmount: 421}
+	if standard != want {
+		t.Errorf("standard row = %+v, want %+v", standard, want)
+	}
+}
+
+func TestAddVATLineKeepsRowsInOrder(t *testing.T) {
+	rows := []VATSummaryRow{{Category: tax.CategoryStandard, Rate: 7, Lines: 1}}
+	rows = addVATLine(rows, tax.LineTax{Category: tax.CategoryStandard, Rate: 10, Net: 100, Tax: 10, Gross: 110})
+	rows = addVATLine(rows, tax.LineTax{Category: tax.CategoryExempt, Net: 20, Gross: 20})
+	rows = addVATLine(rows, tax.LineTax{Category: tax.CategoryStandard, Rate: 5, Net: 100, Tax: 5, Gross: 105})
+
+	var got []string
+	for _, row := range rows {
+		got = append(got, fmt.Sprintf("%s %g", row.Category, row.Rate))
+	}
+	want := []string{"exempt 0", "standard 5", "standard 7", "standard 10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
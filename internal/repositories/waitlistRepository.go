@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRoundSoldOut is returned when a sales round has less quantity left than requested, counting quantity held for waitlisted customers
+	ErrRoundSoldOut = errors.New("not enough quantity left in the sales round")
+	// ErrAlreadyWaitlisted is returned when a customer joins a waitlist they are already on
+	ErrAlreadyWaitlisted = errors.New("customer is already on the waitlist")
+)
+
+type WaitlistRepository interface {
+	JoinWaitlist(entry *models.WaitlistEntry) error
+	GetWaitlist(roundID uuid.UUID, variantID *uuid.UUID, status string) ([]models.WaitlistEntry, error)
+	GetWaitlistEntryByID(id uuid.UUID) (*models.WaitlistEntry, error)
+	LeaveWaitlist(id uuid.UUID) (*models.WaitlistEntry, error)
+	OfferReleasedQuantity(variantID uuid.UUID, holdFor time.Duration) ([]models.WaitlistEntry, error)
+	ExpireOffers() ([]uuid.UUID, error)
+}
+
+type waitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) WaitlistRepository {
+	return &waitlistRepository{db: db}
+}
+
+// JoinWaitlist adds a customer to the end of the waitlist of a round's variant
+func (r *waitlistRepository) JoinWaitlist(entry *models.WaitlistEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var round models.SalesRound
+		if err := tx.First(&round, "id = ?", entry.RoundID).Error; err != nil {
+			return err
+		}
+		if round.Mode == models.SalesRoundModeLottery {
+			return ErrLotteryRound
+		}
+
+		var detail models.SalesRoundDetail
+		if err := tx.Where("round_id = ? AND variant_id = ?", entry.RoundID, entry.VariantID).First(&detail).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		err := tx.Model(&models.WaitlistEntry{}).
+			Where("round_id = ? AND variant_id = ? AND customer_id = ? AND status IN ?", entry.RoundID, entry.VariantID, entry.CustomerID,
+				[]string{models.WaitlistWaiting, models.WaitlistOffered}).
+			Count(&active).Error
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrAlreadyWaitlisted
+		}
+
+		entry.Status = models.WaitlistWaiting
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *waitlistRepository) GetWaitlist(roundID uuid.UUID, variantID *uuid.UUID, status string) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	query := r.db.Where("round_id = ?", roundID).Order("created_at")
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+func (r *waitlistRepository) GetWaitlistEntryByID(id uuid.UUID) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := r.db.First(&entry, "id = ?", id).Error
+	return &entry, err
+}
+
+// LeaveWaitlist cancels a waiting or offered entry; quantity held for the customer is released.
+// The entry is returned as it was before it was cancelled.
+func (r *waitlistRepository) LeaveWaitlist(id uuid.UUID) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistOffered {
+			return errors.New("waitlist entry is " + entry.Status)
+		}
+		return tx.Model(&models.WaitlistEntry{}).Where("id = ?", id).Update("status", models.WaitlistCancelled).Error
+	})
+	return &entry, err
+}
+
+// OfferReleasedQuantity offers the quantity of a variant that is left in open sales rounds, and not held for
+// anyone, to the waiting customers of those rounds in the order they joined. Each offer holds the quantity for
+// holdFor. The new offers are returned.
+func (r *waitlistRepository) OfferReleasedQuantity(variantID uuid.UUID, holdFor time.Duration) ([]models.WaitlistEntry, error) {
+	var offers []models.WaitlistEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var details []models.SalesRoundDetail
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("variant_id = ?", variantID).
+			Where("round_id IN (?)", tx.Model(&models.WaitlistEntry{}).Select("round_id").Where("variant_id = ? AND status = ?", variantID, models.WaitlistWaiting)).
+			Where("round_id IN (?)", tx.Model(&models.SalesRound{}).Select("id").Where("end_date > ?", now)).
+			Find(&details).Error
+		if err != nil {
+			return err
+		}
+
+		for _, detail := range details {
+			held, err := heldRoundQuantity(tx, detail.RoundID, variantID, nil)
+			if err != nil {
+				return err
+			}
+			available := detail.Quantity - held
+			if available <= 0 {
+				continue
+			}
+
+			var waiting []models.WaitlistEntry
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("round_id = ? AND variant_id = ? AND status = ?", detail.RoundID, variantID, models.WaitlistWaiting).
+				Order("created_at").
+				Find(&waiting).Error
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range offerToWaiting(waiting, available, now, holdFor) {
+				if err := tx.Model(&entry).Select("status", "offered_quantity", "offered_at", "offer_expires_at").Updates(&entry).Error; err != nil {
+					return err
+				}
+				offers = append(offers, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// offerToWaiting offers available quantity to the waiting entries in the order given, each up to the quantity
+// it asked for, until none is left. The entries that get an offer are returned with the offer filled in.
+func offerToWaiting(waiting []models.WaitlistEntry, available int, now time.Time, holdFor time.Duration) []models.WaitlistEntry {
+	var offers []models.WaitlistEntry
+	expiresAt := now.Add(holdFor)
+	for _, entry := range waiting {
+		if available <= 0 {
+			break
+		}
+		quantity := min(entry.Quantity, available)
+		entry.Status = models.WaitlistOffered
+		entry.OfferedQuantity = quantity
+		entry.OfferedAt = &now
+		entry.OfferExpiresAt = &expiresAt
+		available -= quantity
+		offers = append(offers, entry)
+	}
+	return offers
+}
+
+// ExpireOffers expires every offer that ran out and returns the variants whose held quantity was released
+func (r *waitlistRepository) ExpireOffers() ([]uuid.UUID, error) {
+	var variantIDs []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var expired []models.WaitlistEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND offer_expires_at <= ?", models.WaitlistOffered, time.Now()).
+			Find(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(expired))
+		seen := make(map[uuid.UUID]bool)
+		for _, entry := range expired {
+			ids = append(ids, entry.ID)
+			if !seen[entry.VariantID] {
+				seen[entry.VariantID] = true
+				variantIDs = append(variantIDs, entry.VariantID)
+			}
+		}
+		log.Printf("Expiring %d waitlist offers", len(ids))
+		return tx.Model(&models.WaitlistEntry{}).Where("id IN ?", ids).Update("status", models.WaitlistExpired).Error
+	})
+	return variantIDs, err
+}
+
+// heldRoundQuantity returns the quantity of a round's variant held by unexpired waitlist offers, leaving out
+// the offer of exceptCustomer if given
+func heldRoundQuantity(tx *gorm.DB, roundID uuid.UUID, variantID uuid.UUID, exceptCustomer *uuid.UUID) (int, error) {
+	var held int
+	query := tx.Model(&models.WaitlistEntry{}).
+		Select("COALESCE(SUM(offered_quantity), 0)").
+		Where("round_id = ? AND variant_id = ? AND status = ? AND offer_expires_at > ?", roundID, variantID, models.WaitlistOffered, time.Now())
+	if exceptCustomer != nil {
+		query = query.Where("customer_id <> ?", *exceptCustomer)
+	}
+	err := query.Scan(&held).Error
+	return held, err
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestOfferToWaitingGoesInJoinOrder(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	waiting := []models.WaitlistEntry{
+		{ID: uuid.New(), Quantity: 2, Status: models.WaitlistWaiting},
+		{ID: uuid.New(), Quantity: 3, Status: models.WaitlistWaiting},
+		{ID: uuid.New(), Quantity: 1, Status: models.WaitlistWaiting},
+	}
+
+	offers := offerToWaiting(waiting, 4, now, 15*time.Minute)
+	if len(offers) != 2 {
+		t.Fatalf("offerToWaiting() made %d offers, want 2", len(offers))
+	}
+	// The first customer gets all they asked for, the second what is left and the third nothing
+	for i, want := range []int{2, 2} {
+		offer := offers[i]
+		if offer.ID != waiting[i].ID || offer.OfferedQuantity != want {
+			t.Errorf("offer %d = %v of %d, want %v of %d", i, offer.ID, offer.OfferedQuantity, waiting[i].ID, want)
+		}
+		if offer.Status != models.WaitlistOffered || !offer.OfferedAt.Equal(now) || !offer.OfferExpiresAt.Equal(now.Add(15*time.Minute)) {
+			t.Errorf("offer %d = %s at %v until %v, want offered at %v for 15 minutes", i, offer.Status, offer.OfferedAt, offer.OfferExpiresAt, now)
+		}
+	}
+	if waiting[0].Status != models.WaitlistWaiting {
+		t.Errorf("offerToWaiting() changed the waiting entry to %s", waiting[0].Status)
+	}
+}
+
+func TestOfferToWaitingWithNothingAvailable(t *testing.T) {
+	waiting := []models.WaitlistEntry{{ID: uuid.New(), Quantity: 1, Status: models.WaitlistWaiting}}
+	if offers := offerToWaiting(waiting, 0, time.Now(), time.Minute); len(offers) != 0 {
+		t.Errorf("offerToWaiting() with nothing available = %v, want no offers", offers)
+	}
+}
@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/events"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// webhookMaxAttempts is how often a delivery is tried before it is marked failed
+const webhookMaxAttempts = 10
+
+// WebhookAttempt is the outcome of sending a delivery once
+type WebhookAttempt struct {
+	ResponseCode int    // 0 if no response was received
+	ResponseBody string // Start of the response body
+	Err          error  // Transport error, if any
+}
+
+// Succeeded reports whether the endpoint accepted the delivery
+func (a WebhookAttempt) Succeeded() bool {
+	return a.Err == nil && a.ResponseCode >= 200 && a.ResponseCode <= 299
+}
+
+type WebhookRepository interface {
+	CreateSubscription(subscription *models.WebhookSubscription) error
+	GetSubscriptions(storeID uuid.UUID) ([]models.WebhookSubscription, error)
+	GetSubscriptionByID(id uuid.UUID) (*models.WebhookSubscription, error)
+	UpdateSubscription(subscription *models.WebhookSubscription) error
+	DeleteSubscription(id uuid.UUID) error
+	EnqueueDeliveries(event events.Envelope, payload []byte) (int, error)
+	CreateDelivery(delivery *models.WebhookDelivery) error
+	GetDeliveries(subscriptionID uuid.UUID, status string) ([]models.WebhookDelivery, error)
+	GetDeliveryByID(id uuid.UUID) (*models.WebhookDelivery, error)
+	DeliverPending(limit int, deliver func(delivery models.WebhookDelivery, subscription models.WebhookSubscription) WebhookAttempt) (succeeded int, failed int, err error)
+	RecordAttempt(delivery *models.WebhookDelivery, attempt WebhookAttempt) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Create(subscription).Error
+}
+
+func (r *webhookRepository) GetSubscriptions(storeID uuid.UUID) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Where("store_id = ?", storeID).Order("created_at").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookRepository) GetSubscriptionByID(id uuid.UUID) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.First(&subscription, "id = ?", id).Error
+	return &subscription, err
+}
+
+func (r *webhookRepository) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Save(subscription).Error
+}
+
+// DeleteSubscription deletes a subscription; its pending deliveries are marked failed
+func (r *webhookRepository) DeleteSubscription(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WebhookSubscription{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND status = ?", id, models.WebhookDeliveryPending).
+			Updates(map[string]interface{}{"status": models.WebhookDeliveryFailed, "last_error": "subscription was deleted"}).Error
+	})
+}
+
+// EnqueueDeliveries creates a pending delivery of the event for every active subscription of the stores the
+// event concerns that asked for its type. Deliveries that already exist for the event are left alone, so
+// enqueueing the same event twice is harmless.
+func (r *webhookRepository) EnqueueDeliveries(event events.Envelope, payload []byte) (int, error) {
+	storeIDs, err := eventStoreIDs(r.db, event)
+	if err != nil || len(storeIDs) == 0 {
+		return 0, err
+	}
+
+	var subscriptions []models.WebhookSubscription
+	if err := r.db.Where("store_id IN ? AND active = ?", storeIDs, true).Find(&subscriptions).Error; err != nil {
+		return 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "replay_of_id IS NULL"}}},
+		DoNothing:   true,
+	}).Create(&deliveries)
+	return int(result.RowsAffected), result.Error
+}
+
+// eventStoreIDs finds the stores whose products an event is about
+func eventStoreIDs(db *gorm.DB, event events.Envelope) ([]uuid.UUID, error) {
+	var storeIDs []uuid.UUID
+	query := db.Table(`"product-variant"`).
+		Joins(`JOIN "product" ON "product".id = "product-variant".product_id`).
+		Distinct(`"product".store_id`)
+
+	switch event.AggregateType {
+	case "order":
+		query = query.Joins(`JOIN "order-detail" ON "order-detail".variant_id = "product-variant".variant_id`).
+			Where(`"order-detail".order_id = ?`, event.AggregateID)
+	case "product-variant":
+		query = query.Where(`"product-variant".variant_id = ?`, event.AggregateID)
+	case "sales-round":
+		query = query.Joins(`JOIN "sales-round-detail" ON "sales-round-detail".variant_id = "product-variant".variant_id`).
+			Where(`"sales-round-detail".round_id = ? AND "sales-round-detail".deleted_at IS NULL`, event.AggregateID)
+	default:
+		return nil, nil
+	}
+	err := query.Pluck(`"product".store_id`, &storeIDs).Error
+	return storeIDs, err
+}
+
+func (r *webhookRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *webhookRepository) GetDeliveries(subscriptionID uuid.UUID, status string) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := r.db.Where("subscription_id = ?", subscriptionID).Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) GetDeliveryByID(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.First(&delivery, "id = ?", id).Error
+	return &delivery, err
+}
+
+// webhookLease is how long claimed deliveries are held back from other workers while they are sent; it covers
+// a full batch to endpoints that time out
+const webhookLease = 10 * time.Minute
+
+// DeliverPending hands up to limit due deliveries to deliver, oldest first, and records each attempt. Like the
+// outbox relay, the deliveries are claimed for webhookLease with SKIP LOCKED in a short transaction and sent
+// after it commits, so several workers can run side by side and no connection or lock is held during requests.
+func (r *webhookRepository) DeliverPending(limit int, deliver func(delivery models.WebhookDelivery, subscription models.WebhookSubscription) WebhookAttempt) (int, int, error) {
+	var deliveries []models.WebhookDelivery
+	subscriptions := make(map[uuid.UUID]models.WebhookSubscription)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+			if _, ok := subscriptions[delivery.SubscriptionID]; ok {
+				continue
+			}
+			var subscription models.WebhookSubscription
+			if err := tx.First(&subscription, "id = ?", delivery.SubscriptionID).Error; err != nil {
+				return err
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(webhookLease)).Error
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	succeeded, failed := 0, 0
+	for i := range deliveries {
+		attempt := deliver(deliveries[i], subscriptions[deliveries[i].SubscriptionID])
+		if attempt.Succeeded() {
+			succeeded++
+		} else {
+			failed++
+		}
+		if err := recordWebhookAttempt(r.db, &deliveries[i], attempt); err != nil {
+			return succeeded, failed, err
+		}
+	}
+	return succeeded, failed, nil
+}
+
+func (r *webhookRepository) RecordAttempt(delivery *models.WebhookDelivery, attempt WebhookAttempt) error {
+	return recordWebhookAttempt(r.db, delivery, attempt)
+}
+
+// recordWebhookAttempt logs an attempt on the delivery and schedules the retry, or gives up after webhookMaxAttempts
+func recordWebhookAttempt(tx *gorm.DB, delivery *models.WebhookDelivery, attempt WebhookAttempt) error {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseCode = attempt.ResponseCode
+	delivery.ResponseBody = attempt.ResponseBody
+	delivery.LastError = ""
+	if attempt.Err != nil {
+		delivery.LastError = attempt.Err.Error()
+	}
+
+	switch {
+	case attempt.Succeeded():
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+	default:
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+	}
+	return tx.Save(delivery).Error
+}
+
+// webhookBackoff doubles the wait after every failed attempt, from 30 seconds up to 6 hours
+func webhookBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempts && backoff < 6*time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > 6*time.Hour {
+		backoff = 6 * time.Hour
+	}
+	return backoff
+}
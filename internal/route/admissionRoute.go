@@ -0,0 +1,11 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterAdmissionRoutes(app *fiber.App, controller controllers.AdmissionController) {
+	app.Post("/sales-rounds/:id/queue", validateUUID, controller.JoinQueue)            // Get a queue token for a round's waiting room
+	app.Get("/sales-rounds/:id/queue/:token", validateUUID, controller.GetQueueStatus) // Poll a token's position
+}
@@ -0,0 +1,16 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterCustomerAddressRoutes(app *fiber.App, controller controllers.CustomerAddressController) {
+	app.Get("/addresses/provinces", controller.GetProvinces)                           // Thai administrative areas addresses are checked against
+	app.Post("/customers/:id/addresses", validateUUID, controller.CreateAddress)       // Add to a customer's address book
+	app.Get("/customers/:id/addresses", validateUUID, controller.GetCustomerAddresses) // A customer's address book
+	app.Get("/addresses/:id", validateUUID, controller.GetAddress)                     // Get an address
+	app.Put("/addresses/:id", validateUUID, controller.UpdateAddress)                  // Edit an address
+	app.Post("/addresses/:id/default", validateUUID, controller.SetDefaultAddress)     // Make an address the customer's default
+	app.Delete("/addresses/:id", validateUUID, controller.DeleteAddress)               // Remove an address
+}
@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterDiscountCodeRoutes(app *fiber.App, controller controllers.DiscountCodeController) {
+	app.Post("/discount-codes/quote", controller.QuoteDiscounts) // Price items with discount codes without ordering
+	app.Post("/discount-codes", controller.CreateDiscountCode)
+	app.Get("/discount-codes", controller.GetDiscountCodes)
+	app.Get("/discount-codes/:id", validateUUID, controller.GetDiscountCode)
+	app.Put("/discount-codes/:id", validateUUID, controller.UpdateDiscountCode)
+	app.Delete("/discount-codes/:id", validateUUID, controller.DeleteDiscountCode)
+}
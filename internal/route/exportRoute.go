@@ -0,0 +1,10 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterExportRoutes(app *fiber.App, controller controllers.ExportController) {
+	app.Get("/exports/orders", controller.ExportOrders) // Stream order lines as CSV or NDJSON
+}
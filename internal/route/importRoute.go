@@ -0,0 +1,12 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterImportRoutes(app *fiber.App, controller controllers.ImportController) {
+	app.Post("/imports/products", controller.ImportProducts)           // Start a product CSV import
+	app.Get("/imports", controller.GetAllImportJobs)                   // List import jobs
+	app.Get("/imports/:id", validateUUID, controller.GetImportJobByID) // Import progress and error report
+}
@@ -0,0 +1,22 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterInventoryRoutes(app *fiber.App, controller controllers.InventoryController) {
+	app.Post("/stores/:id/locations", validateUUID, controller.CreateLocation)     // Create a location under a store
+	app.Get("/stores/:id/locations", validateUUID, controller.GetLocationsByStore) // Get the locations of a store
+	app.Put("/locations/:id", validateUUID, controller.UpdateLocation)             // Update a location
+	app.Delete("/locations/:id", validateUUID, controller.DeleteLocation)          // Delete an empty location
+	app.Get("/locations/:id/stock", validateUUID, controller.GetLocationStock)     // Per-variant stock at a location
+	app.Put("/locations/:id/stock", validateUUID, controller.SetLocationStock)     // Set a variant's stock at a location
+	app.Get("/product-variants/:id/stock", validateUUID, controller.GetVariantStock)
+	app.Post("/stock-transfers", controller.CreateTransfer)                           // Send stock between locations
+	app.Get("/stock-transfers", controller.GetAllTransfers)                           // List transfers
+	app.Put("/stock-transfers/:id/receive", validateUUID, controller.ReceiveTransfer) // Book a transfer into its destination
+	app.Put("/stock-transfers/:id/cancel", validateUUID, controller.CancelTransfer)   // Return an in-transit transfer to its source
+	app.Get("/stock-movements", controller.GetStockMovements)                         // Stock movement ledger
+	app.Get("/inventory/low-stock", controller.GetLowStock)                           // Variants at or below their reorder point
+}
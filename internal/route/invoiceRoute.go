@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterInvoiceRoutes(app *fiber.App, controller controllers.InvoiceController) {
+	app.Post("/orders/:id/invoices", validateUUID, controller.IssueInvoice)           // Issue the tax invoice of a paid order
+	app.Get("/orders/:id/invoices", validateUUID, controller.GetOrderInvoices)        // Tax invoice and credit notes of an order
+	app.Get("/invoices/:id", validateUUID, controller.GetInvoice)                     // Get an invoice with its lines
+	app.Get("/invoices/:id/xml", validateUUID, controller.GetInvoiceXML)              // ETDA e-Tax invoice XML
+	app.Get("/invoices/:id/html", validateUUID, controller.GetInvoiceHTML)            // Printable page, to print to PDF
+	app.Post("/invoices/:id/credit-notes", validateUUID, controller.CreateCreditNote) // Credit a refund or an amount
+}
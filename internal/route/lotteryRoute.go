@@ -0,0 +1,12 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterLotteryRoutes(app *fiber.App, controller controllers.LotteryController) {
+	app.Post("/sales-rounds/:id/lottery/entries", validateUUID, controller.EnterLottery) // Enter a lottery round while it is open
+	app.Get("/sales-rounds/:id/lottery", validateUUID, controller.GetLottery)            // Draw commitment, seed and entries in draw order
+	app.Post("/sales-rounds/:id/lottery/draw", validateUUID, controller.DrawLottery)     // Draw an ended round now
+}
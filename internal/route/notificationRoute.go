@@ -0,0 +1,11 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterNotificationRoutes(app *fiber.App, controller controllers.NotificationController) {
+	app.Get("/notifications", controller.GetNotifications)                            // List notifications
+	app.Put("/notifications/:id/read", validateUUID, controller.MarkNotificationRead) // Mark a notification as read
+}
@@ -5,9 +5,11 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterOrderRoutes(app *fiber.App, controller controllers.OrderController) {
-	app.Post("/orders", controller.CreateOrder)
+func RegisterOrderRoutes(app *fiber.App, controller controllers.OrderController, requireAdmission fiber.Handler) {
+	app.Post("/orders", requireAdmission, controller.CreateOrder) // Queued rounds need an admitted X-Queue-Token
 	app.Get("/orders", controller.GetAllOrders)
 	app.Put("/orders/:id", controller.UpdateOrder)
 	app.Delete("/orders/:id", controller.DeleteOrder)
+	app.Post("/orders/:id/cancel", validateUUID, controller.CancelOrder)   // Cancel and release the order's quantities
+	app.Post("/orders/:id/confirm", validateUUID, controller.ConfirmOrder) // Mark a pending order as paid
 }
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterPaymentRoutes(app *fiber.App, controller controllers.PaymentController) {
+	app.Post("/orders/:id/payments", validateUUID, controller.StartPayment)      // Start paying a pending order
+	app.Get("/orders/:id/payments", validateUUID, controller.GetOrderPayments)   // Payment attempts of an order
+	app.Get("/orders/:id/payment-qr", validateUUID, controller.GetPaymentQR)     // PromptPay QR code as JSON or PNG
+	app.Get("/payments/:id", validateUUID, controller.GetPayment)                // Get a payment with its refunds
+	app.Post("/payments/:id/capture", validateUUID, controller.CapturePayment)   // Capture an authorized payment
+	app.Post("/payments/:id/refunds", validateUUID, controller.RefundPayment)    // Refund part or all of a payment
+	app.Post("/payments/:id/simulate", validateUUID, controller.SimulatePayment) // Pay or decline a mock payment
+	app.Post("/payment-webhooks/:provider", controller.HandleWebhook)            // Signed notifications from providers
+}
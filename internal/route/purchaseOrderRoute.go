@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterPurchaseOrderRoutes(app *fiber.App, controller controllers.PurchaseOrderController) {
+	app.Post("/purchase-orders", controller.CreatePurchaseOrder)                            // Draft a purchase order
+	app.Get("/purchase-orders", controller.GetAllPurchaseOrders)                            // List purchase orders
+	app.Get("/purchase-orders/:id", validateUUID, controller.GetPurchaseOrderByID)          // Get a purchase order with its lines
+	app.Put("/purchase-orders/:id/send", validateUUID, controller.SendPurchaseOrder)        // draft -> sent
+	app.Post("/purchase-orders/:id/receive", validateUUID, controller.ReceivePurchaseOrder) // Receive goods (partial receipts allowed)
+	app.Put("/purchase-orders/:id/close", validateUUID, controller.ClosePurchaseOrder)      // Close without receiving the rest
+}
@@ -5,6 +5,6 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterPurchaseRoutes(app *fiber.App, controller controllers.PurchaseController) {
-	app.Post("/purchases", controller.MakePurchase)
+func RegisterPurchaseRoutes(app *fiber.App, controller controllers.PurchaseController, requireAdmission fiber.Handler) {
+	app.Post("/purchases", requireAdmission, controller.MakePurchase) // Queued rounds need an admitted X-Queue-Token
 }
@@ -0,0 +1,54 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rate-limit policies per route group. Every request counts against its client IP; purchases also count against
+// the customer they are made for. Request headers are not trusted to pick a bucket in place of the IP's.
+var (
+	// Every write: a burst of 60, then one a second
+	writePolicy = ratelimit.Policy{
+		Name:            "writes",
+		Capacity:        60,
+		RefillPerSecond: 1,
+		Key:             ratelimit.ByIP,
+		Methods:         []string{fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete},
+	}
+	// Purchases and orders from one client: a burst of 20, then one every 2 seconds
+	purchaseIPPolicy = ratelimit.Policy{
+		Name:            "purchases-ip",
+		Capacity:        20,
+		RefillPerSecond: 0.5,
+		Key:             ratelimit.ByIP,
+	}
+	// Customer sign-ups: 10 an hour per client
+	customerPolicy = ratelimit.Policy{
+		Name:            "customers",
+		Capacity:        10,
+		RefillPerSecond: 10.0 / 3600,
+		Key:             ratelimit.ByIP,
+	}
+)
+
+// purchasePolicy limits purchases and orders per customer: a burst of 5, then one every 5 seconds
+func purchasePolicy(customerExists ratelimit.CustomerExists) ratelimit.Policy {
+	return ratelimit.Policy{
+		Name:            "purchases",
+		Capacity:        5,
+		RefillPerSecond: 0.2,
+		Key:             ratelimit.ByCustomer(customerExists),
+	}
+}
+
+// RegisterRateLimits throttles writes, and purchases and sign-ups more tightly. It must be registered before
+// the routes it guards.
+func RegisterRateLimits(app *fiber.App, store ratelimit.Store, customerExists ratelimit.CustomerExists) {
+	app.Use(ratelimit.New(store, writePolicy))
+
+	purchases := []fiber.Handler{ratelimit.New(store, purchaseIPPolicy), ratelimit.New(store, purchasePolicy(customerExists))}
+	app.Post("/purchases", purchases...)
+	app.Post("/orders", purchases...)
+	app.Post("/customers", ratelimit.New(store, customerPolicy))
+}
@@ -0,0 +1,63 @@
+package route
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func newRateLimitedApp(customers ...uuid.UUID) *fiber.App {
+	known := map[uuid.UUID]bool{}
+	for _, id := range customers {
+		known[id] = true
+	}
+	app := fiber.New()
+	RegisterRateLimits(app, ratelimit.NewMemoryStore(), func(id uuid.UUID) bool { return known[id] })
+	app.Post("/purchases", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+	return app
+}
+
+func purchase(t *testing.T, app *fiber.App, customerID string) int {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, "/purchases", nil)
+	if customerID != "" {
+		req.Header.Set(ratelimit.CustomerIDHeader, customerID)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestPurchaseLimitCannotBeDodgedWithMadeUpCustomers(t *testing.T) {
+	app := newRateLimitedApp()
+	for i := 0; i < purchaseIPPolicy.Capacity; i++ {
+		if status := purchase(t, app, uuid.NewString()); status != fiber.StatusCreated {
+			t.Fatalf("purchase %d: status %d, want %d", i+1, status, fiber.StatusCreated)
+		}
+	}
+	if status := purchase(t, app, uuid.NewString()); status != fiber.StatusTooManyRequests {
+		t.Errorf("purchase past the client's limit with a fresh customer ID: status %d, want %d", status, fiber.StatusTooManyRequests)
+	}
+}
+
+func TestPurchaseLimitPerCustomer(t *testing.T) {
+	customer := uuid.New()
+	app := newRateLimitedApp(customer)
+	for i := 0; i < 5; i++ {
+		if status := purchase(t, app, customer.String()); status != fiber.StatusCreated {
+			t.Fatalf("purchase %d: status %d, want %d", i+1, status, fiber.StatusCreated)
+		}
+	}
+	if status := purchase(t, app, customer.String()); status != fiber.StatusTooManyRequests {
+		t.Errorf("sixth purchase for the customer: status %d, want %d", status, fiber.StatusTooManyRequests)
+	}
+	// Another customer from the same client still has purchases left on the client's bucket
+	if status := purchase(t, app, ""); status != fiber.StatusCreated {
+		t.Errorf("purchase without a customer: status %d, want %d", status, fiber.StatusCreated)
+	}
+}
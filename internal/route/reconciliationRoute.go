@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterReconciliationRoutes(app *fiber.App, controller controllers.ReconciliationController) {
+	app.Post("/bank-statements", controller.ImportStatement)                          // Upload and reconcile a bank statement CSV
+	app.Get("/bank-statements", controller.GetStatements)                             // List bank statements
+	app.Get("/bank-statements/:id", validateUUID, controller.GetStatement)            // Statement lines and how they were matched
+	app.Get("/bank-statement-lines/review", controller.GetReviewQueue)                // Ambiguous and unmatched lines
+	app.Post("/bank-statement-lines/:id/match", validateUUID, controller.MatchLine)   // Match a line to the order it paid
+	app.Post("/bank-statement-lines/:id/ignore", validateUUID, controller.IgnoreLine) // Dismiss a line that paid no order
+}
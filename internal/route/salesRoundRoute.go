@@ -13,4 +13,7 @@ func RegisterSalesRoundRoutes(app *fiber.App, controller controllers.SalesRoundC
 	app.Delete("/sales-rounds/:id", controller.DeleteSalesRound)          // Route for deleting a sales round by ID
 	app.Get("/sales-rounds/:id/details", controller.GetSalesRoundDetails) // Specific endpoint for sales round details
 	app.Get("/sales-rounds/combined", controller.GetCombinedSalesRoundProductData)
+	app.Post("/sales-rounds/:id/allocations", validateUUID, controller.AllocateSalesRound)  // Bulk allocation of variants, with dry run
+	app.Post("/sales-rounds/:id/clone", validateUUID, controller.CloneSalesRound)           // New round with the same line-up
+	app.Get("/sales-rounds/:id/analytics", validateUUID, controller.GetSalesRoundAnalytics) // Sales, markdown and discounts of a round
 }
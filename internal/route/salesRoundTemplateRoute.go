@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterSalesRoundTemplateRoutes(app *fiber.App, controller controllers.SalesRoundTemplateController) {
+	app.Post("/sales-round-templates", controller.CreateTemplate)                     // Create a recurring round template
+	app.Get("/sales-round-templates", controller.GetAllTemplates)                     // List templates
+	app.Post("/sales-round-templates/materialize", controller.MaterializeTemplates)   // Materialize due rounds now
+	app.Get("/sales-round-templates/:id", validateUUID, controller.GetTemplateByID)   // Get a template
+	app.Put("/sales-round-templates/:id", validateUUID, controller.UpdateTemplate)    // Replace a template's schedule and line-up
+	app.Delete("/sales-round-templates/:id", validateUUID, controller.DeleteTemplate) // Delete a template
+}
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterShipmentRoutes(app *fiber.App, controller controllers.ShipmentController) {
+	app.Post("/orders/:id/shipments", validateUUID, controller.CreateShipment)   // Ship some or all of a paid order
+	app.Get("/orders/:id/shipments", validateUUID, controller.GetOrderShipments) // Shipments of an order
+	app.Get("/shipments/:id", validateUUID, controller.GetShipment)              // Get a shipment with its items
+	app.Get("/carriers", controller.GetCarriers)                                 // Carriers shipments can be booked with
+	app.Post("/orders/:id/shipping-rates", validateUUID, controller.QuoteRates)  // Quote a carrier's rates for an order
+	app.Get("/shipments/:id/label", validateUUID, controller.GetLabel)           // Download the carrier label
+	app.Get("/shipments/:id/tracking", validateUUID, controller.GetTracking)     // Tracking events of a shipment
+	app.Post("/shipments/:id/track", validateUUID, controller.TrackShipment)     // Poll the carrier's tracking now
+}
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterStockTakeRoutes(app *fiber.App, controller controllers.StockTakeController) {
+	app.Post("/stock-takes", controller.CreateStockTake)                              // Open a stock take for a store or location
+	app.Get("/stock-takes", controller.GetAllStockTakes)                              // List stock takes
+	app.Get("/stock-takes/:id", validateUUID, controller.GetStockTakeByID)            // Get a stock take with its counts
+	app.Post("/stock-takes/:id/counts", validateUUID, controller.SubmitCounts)        // Submit counts as JSON
+	app.Post("/stock-takes/:id/counts/csv", validateUUID, controller.SubmitCountsCSV) // Submit counts as CSV
+	app.Get("/stock-takes/:id/variance", validateUUID, controller.GetVarianceReport)  // Counted vs. system stock
+	app.Put("/stock-takes/:id/approve", validateUUID, controller.ApproveStockTake)    // Post variances as stock adjustments
+	app.Put("/stock-takes/:id/cancel", validateUUID, controller.CancelStockTake)      // Cancel without adjusting stock
+}
@@ -0,0 +1,14 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterSupplierRoutes(app *fiber.App, controller controllers.SupplierController) {
+	app.Post("/suppliers", controller.CreateSupplier)
+	app.Get("/suppliers", controller.GetAllSuppliers)
+	app.Get("/suppliers/:id", validateUUID, controller.GetSupplierByID)
+	app.Put("/suppliers/:id", validateUUID, controller.UpdateSupplier)
+	app.Delete("/suppliers/:id", validateUUID, controller.DeleteSupplier)
+}
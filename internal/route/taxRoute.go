@@ -0,0 +1,12 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterTaxRoutes(app *fiber.App, controller controllers.TaxController) {
+	app.Get("/stores/:id/tax-settings", validateUUID, controller.GetTaxSettings)    // Whether prices include VAT and the rates
+	app.Put("/stores/:id/tax-settings", validateUUID, controller.UpdateTaxSettings) // Set them
+	app.Get("/stores/:id/vat-summary", validateUUID, controller.GetVATSummary)      // Monthly VAT summary (?month=YYYY-MM)
+}
@@ -0,0 +1,12 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterWaitlistRoutes(app *fiber.App, controller controllers.WaitlistController) {
+	app.Post("/sales-rounds/:id/waitlist", validateUUID, controller.JoinWaitlist) // Join the waitlist of a sold-out round variant
+	app.Get("/sales-rounds/:id/waitlist", validateUUID, controller.GetWaitlist)   // List a round's waitlist in queue order
+	app.Delete("/waitlist/:id", validateUUID, controller.LeaveWaitlist)           // Leave a waitlist
+}
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterWebhookRoutes(app *fiber.App, controller controllers.WebhookController) {
+	app.Post("/stores/:id/webhooks", validateUUID, controller.CreateSubscription)       // Subscribe a URL to the events of a store
+	app.Get("/stores/:id/webhooks", validateUUID, controller.GetSubscriptions)          // List the webhook subscriptions of a store
+	app.Get("/webhooks/:id", validateUUID, controller.GetSubscription)                  // Get a webhook subscription
+	app.Put("/webhooks/:id", validateUUID, controller.UpdateSubscription)               // Change a subscription or rotate its secret
+	app.Delete("/webhooks/:id", validateUUID, controller.DeleteSubscription)            // Delete a webhook subscription
+	app.Get("/webhooks/:id/deliveries", validateUUID, controller.GetDeliveries)         // Delivery log with response codes
+	app.Post("/webhooks/:id/test", validateUUID, controller.SendTest)                   // Send a webhook.test event now
+	app.Post("/webhook-deliveries/:id/replay", validateUUID, controller.ReplayDelivery) // Send a delivery again
+}
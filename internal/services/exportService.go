@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+)
+
+// Export formats
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+var orderExportHeader = []string{
+	"order_id", "order_code", "order_date", "status", "round_id", "customer_id", "customer_email", "order_total",
+	"delivery_address", "payment_source", "line_id", "variant_id", "sku_code", "quantity", "unit_price", "line_total",
+}
+
+// ExportService writes exports for accounting and scheduled dumps
+type ExportService interface {
+	// ExportOrders writes one record per order line in the given format to w as the rows are read
+	ExportOrders(filter dtos.OrderExportFilter, format string, w io.Writer) error
+}
+
+type exportService struct {
+	exportRepo repositories.ExportRepository
+}
+
+// NewExportService creates a new instance of ExportService
+func NewExportService(exportRepo repositories.ExportRepository) ExportService {
+	return &exportService{exportRepo: exportRepo}
+}
+
+// ValidExportFormat reports whether format is one ExportOrders can write
+func ValidExportFormat(format string) bool {
+	return format == ExportFormatCSV || format == ExportFormatNDJSON
+}
+
+func (s *exportService) ExportOrders(filter dtos.OrderExportFilter, format string, w io.Writer) error {
+	switch format {
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(orderExportHeader); err != nil {
+			return err
+		}
+		err := s.exportRepo.StreamOrderLines(filter, func(row *dtos.OrderExportRowDTO) error {
+			return writer.Write([]string{
+				row.OrderID.String(),
+				row.OrderCode,
+				row.OrderDate.Format(time.RFC3339),
+				row.Status,
+				row.RoundID.String(),
+				row.CustomerID.String(),
+				row.CustomerEmail,
+				strconv.FormatFloat(row.OrderTotal, 'f', 2, 64),
+				row.DeliveryAddress,
+				row.PaymentSource,
+				row.LineID.String(),
+				row.VariantID.String(),
+				row.SKUCode,
+				strconv.Itoa(row.Quantity),
+				strconv.FormatFloat(row.UnitPrice, 'f', 2, 64),
+				strconv.FormatFloat(row.LineTotal, 'f', 2, 64),
+			})
+		})
+		writer.Flush()
+		if err != nil {
+			return err
+		}
+		return writer.Error()
+	case ExportFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		return s.exportRepo.StreamOrderLines(filter, func(row *dtos.OrderExportRowDTO) error {
+			return encoder.Encode(row)
+		})
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// ParseExportTime parses an export bound given as a date (2006-01-02) or an RFC 3339 time. A date used as the
+// upper bound includes the whole day.
+func ParseExportTime(value string, upper bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a date (2006-01-02) or RFC 3339 time", value)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	utils "github.com/B6137151/InventoryMarketplaceSystem/internal"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// importBatchSize is the number of rows written per transaction; a batch with any bad row is not written at all
+const importBatchSize = 100
+
+// ImportService runs bulk imports of the product catalogue from CSV
+type ImportService interface {
+	// StartProductImport parses the CSV, creates the import job and imports the rows in the background
+	StartProductImport(storeID uuid.UUID, fileName string, r io.Reader) (*models.ImportJob, error)
+	// RunProductImport parses the CSV and imports the rows before returning the finished job
+	RunProductImport(storeID uuid.UUID, fileName string, r io.Reader) (*models.ImportJob, error)
+	GetImportJob(id uuid.UUID) (*models.ImportJob, error)
+	GetAllImportJobs(storeID *uuid.UUID) ([]models.ImportJob, error)
+}
+
+type importService struct {
+	importRepo repositories.ImportRepository
+	validate   *validator.Validate
+}
+
+// NewImportService creates a new instance of ImportService
+func NewImportService(importRepo repositories.ImportRepository) ImportService {
+	return &importService{
+		importRepo: importRepo,
+		validate:   validator.New(),
+	}
+}
+
+// productImportRow is a CSV row, or the reason it could not be parsed
+type productImportRow struct {
+	dto      dtos.ProductImportRowDTO
+	parseErr error
+}
+
+func (s *importService) StartProductImport(storeID uuid.UUID, fileName string, r io.Reader) (*models.ImportJob, error) {
+	job, rows, err := s.prepareProductImport(storeID, fileName, r)
+	if err != nil {
+		return nil, err
+	}
+	go s.runProductImport(job, rows)
+	return job, nil
+}
+
+func (s *importService) RunProductImport(storeID uuid.UUID, fileName string, r io.Reader) (*models.ImportJob, error) {
+	job, rows, err := s.prepareProductImport(storeID, fileName, r)
+	if err != nil {
+		return nil, err
+	}
+	s.runProductImport(job, rows)
+	return job, nil
+}
+
+func (s *importService) GetImportJob(id uuid.UUID) (*models.ImportJob, error) {
+	return s.importRepo.GetImportJobByID(id)
+}
+
+func (s *importService) GetAllImportJobs(storeID *uuid.UUID) ([]models.ImportJob, error) {
+	return s.importRepo.GetAllImportJobs(storeID)
+}
+
+func (s *importService) prepareProductImport(storeID uuid.UUID, fileName string, r io.Reader) (*models.ImportJob, []productImportRow, error) {
+	rows, err := parseProductImportCSV(storeID, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job := &models.ImportJob{
+		StoreID:   storeID,
+		Type:      models.ImportJobProducts,
+		Status:    models.ImportJobPending,
+		FileName:  fileName,
+		TotalRows: len(rows),
+	}
+	if err := s.importRepo.CreateImportJob(job); err != nil {
+		return nil, nil, err
+	}
+	return job, rows, nil
+}
+
+// runProductImport imports the rows batch by batch and records progress and row errors on the job after every batch
+func (s *importService) runProductImport(job *models.ImportJob, rows []productImportRow) {
+	started := time.Now()
+	job.Status = models.ImportJobRunning
+	job.StartedAt = &started
+	s.saveJob(job, nil)
+
+	var rowErrors []dtos.ImportRowErrorDTO
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+
+		batchErrors := make(map[int]string)
+		dtoBatch := make([]dtos.ProductImportRowDTO, 0, len(batch))
+		for _, row := range batch {
+			if err := s.validateRow(row); err != nil {
+				batchErrors[row.dto.Row] = err.Error()
+			}
+			dtoBatch = append(dtoBatch, row.dto)
+		}
+
+		if len(batchErrors) == 0 {
+			err := s.importRepo.ImportProductBatch(job.StoreID, dtoBatch)
+			var rowErr *repositories.ImportRowError
+			if errors.As(err, &rowErr) {
+				batchErrors[rowErr.Row] = rowErr.Err.Error()
+			} else if err != nil {
+				for _, row := range batch {
+					batchErrors[row.dto.Row] = err.Error()
+				}
+			}
+		}
+
+		if len(batchErrors) > 0 {
+			for _, row := range batch {
+				message, ok := batchErrors[row.dto.Row]
+				if !ok {
+					message = "not imported: its batch contains invalid rows"
+				}
+				rowErrors = append(rowErrors, dtos.ImportRowErrorDTO{Row: row.dto.Row, SKUCode: row.dto.Variant.SKUCode, Error: message})
+			}
+			job.FailedRows += len(batch)
+		} else {
+			job.ImportedRows += len(batch)
+		}
+		job.ProcessedRows += len(batch)
+		s.saveJob(job, rowErrors)
+	}
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Status = models.ImportJobCompleted
+	if job.FailedRows > 0 && job.ImportedRows == 0 {
+		job.Status = models.ImportJobFailed
+	}
+	s.saveJob(job, rowErrors)
+	log.Printf("Import job %s %s: %d imported, %d failed", job.ID, job.Status, job.ImportedRows, job.FailedRows)
+}
+
+func (s *importService) validateRow(row productImportRow) error {
+	if row.parseErr != nil {
+		return row.parseErr
+	}
+	if err := s.validate.Struct(row.dto.Category); err != nil {
+		return fmt.Errorf("category: %s", utils.ParseValidationErrors(err))
+	}
+	// The category and product are resolved while importing, so their IDs are not part of the row. Stock and
+	// image_url are optional columns of the import, so an empty stock or image does not fail the row.
+	if err := s.validate.StructExcept(row.dto.Product, "CategoryID", "Stock", "ImageURL"); err != nil {
+		return fmt.Errorf("product: %s", utils.ParseValidationErrors(err))
+	}
+	if row.dto.Product.Stock < 0 {
+		return fmt.Errorf("product: stock cannot be negative")
+	}
+	if err := s.validate.StructExcept(row.dto.Variant, "ProductID"); err != nil {
+		return fmt.Errorf("variant: %s", utils.ParseValidationErrors(err))
+	}
+	return nil
+}
+
+func (s *importService) saveJob(job *models.ImportJob, rowErrors []dtos.ImportRowErrorDTO) {
+	if rowErrors != nil {
+		report, err := json.Marshal(rowErrors)
+		if err != nil {
+			log.Printf("Failed to encode errors of import job %s: %v", job.ID, err)
+		} else {
+			job.Errors = string(report)
+		}
+	}
+	if err := s.importRepo.UpdateImportJob(job); err != nil {
+		log.Printf("Failed to update import job %s: %v", job.ID, err)
+	}
+}
+
+// parseProductImportCSV reads the rows of a product import. The header must name the category, product_name,
+// brand, currency, sku_code and price columns; description, stock, product_price, image_url, variant_image_url,
+// reorder_point and reorder_quantity are optional. A row that cannot be parsed is kept with its parse error.
+func parseProductImportCSV(storeID uuid.UUID, r io.Reader) ([]productImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row")
+	}
+	columns := make(map[string]int, len(header))
+	present := make(map[string]bool, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+		present[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, required := range []string{"category", "product_name", "brand", "currency", "sku_code", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+
+	var rows []productImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		var parseErr error
+		number := func(name string) float64 {
+			value := field(name)
+			if value == "" {
+				return 0
+			}
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil && parseErr == nil {
+				parseErr = fmt.Errorf("%s is not a number", name)
+			}
+			return n
+		}
+
+		price := number("price")
+		productPrice := price
+		if field("product_price") != "" {
+			productPrice = number("product_price")
+		}
+		row := productImportRow{dto: dtos.ProductImportRowDTO{
+			Row:      line,
+			Columns:  present,
+			Category: dtos.CategoryCreateDTO{Name: field("category"), StoreID: storeID},
+			Product: dtos.ProductCreateDTO{
+				StoreID:     storeID,
+				ProductName: field("product_name"),
+				Brand:       field("brand"),
+				Description: field("description"),
+				Currency:    field("currency"),
+				Stock:       int(number("stock")),
+				Price:       productPrice,
+				ImageURL:    field("image_url"),
+			},
+			Variant: dtos.ProductVariantCreateDTO{
+				SKUCode:         field("sku_code"),
+				Price:           price,
+				ImageURL:        field("variant_image_url"),
+				ReorderPoint:    int(number("reorder_point")),
+				ReorderQuantity: int(number("reorder_quantity")),
+			},
+		}}
+		row.parseErr = parseErr
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
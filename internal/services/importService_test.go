@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+func TestProductImportRows(t *testing.T) {
+	s := &importService{validate: validator.New()}
+	storeID := uuid.New()
+
+	tests := []struct {
+		name       string
+		csv        string
+		wantParse  bool // parseProductImportCSV fails
+		wantErrors []bool
+	}{
+		{
+			name: "only the required columns",
+			csv: "category,product_name,brand,currency,sku_code,price\n" +
+				"Shirts,Oxford shirt,Acme,THB,OX-S,590\n",
+			wantErrors: []bool{false},
+		},
+		{
+			name: "optional columns left empty",
+			csv: "category,product_name,brand,currency,sku_code,price,stock,image_url,reorder_point\n" +
+				"Shirts,Oxford shirt,Acme,THB,OX-S,590,,,\n",
+			wantErrors: []bool{false},
+		},
+		{
+			name: "all columns",
+			csv: "category,product_name,brand,currency,sku_code,price,description,stock,product_price,image_url,variant_image_url,reorder_point,reorder_quantity\n" +
+				"Shirts,Oxford shirt,Acme,THB,OX-S,590,Cotton,12,550,https://example.com/a.png,https://example.com/b.png,2,10\n",
+			wantErrors: []bool{false},
+		},
+		{
+			name: "bad rows are reported per row",
+			csv: "category,product_name,brand,currency,sku_code,price,stock\n" +
+				"Shirts,Oxford shirt,Acme,THB,OX-S,590,3\n" +
+				"Shirts,,Acme,THB,OX-M,590,3\n" +
+				"Shirts,Oxford shirt,Acme,THB,OX-L,abc,3\n" +
+				"Shirts,Oxford shirt,Acme,THB,OX-XL,590,-1\n" +
+				"Shirts,Oxford shirt,Acme,THB,,590,3\n",
+			wantErrors: []bool{false, true, true, true, true},
+		},
+		{
+			name:      "missing required column",
+			csv:       "category,product_name,brand,currency,price\nShirts,Oxford shirt,Acme,THB,590\n",
+			wantParse: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseProductImportCSV(storeID, strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantParse {
+				t.Fatalf("parseProductImportCSV() error = %v, want error %v", err, tt.wantParse)
+			}
+			if len(rows) != len(tt.wantErrors) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.wantErrors))
+			}
+			for i, row := range rows {
+				if err := s.validateRow(row); (err != nil) != tt.wantErrors[i] {
+					t.Errorf("row %d: validateRow() error = %v, want error %v", row.dto.Row, err, tt.wantErrors[i])
+				}
+			}
+		})
+	}
+}
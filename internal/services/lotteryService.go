@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/notifications"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// LotteryService runs the draws of lottery sales rounds. Rounds are drawn once they end, winners who do not pay
+// before their deadline forfeit, and forfeited quantity rolls to the next entrants in draw order.
+type LotteryService interface {
+	// Start runs RunDue now and then every interval in the background
+	Start(interval time.Duration)
+	// RunDue draws ended rounds, forfeits overdue wins and awards released quantity
+	RunDue()
+	EnterLottery(entry *models.LotteryEntry) error
+	GetLotteryEntries(roundID uuid.UUID, customerID *uuid.UUID) ([]models.LotteryEntry, error)
+	DrawLottery(roundID uuid.UUID) ([]models.LotteryEntry, error)
+}
+
+type lotteryService struct {
+	lotteryRepo repositories.LotteryRepository
+	notifier    notifications.Notifier
+
+	mu sync.Mutex
+}
+
+// NewLotteryService creates a new instance of LotteryService
+func NewLotteryService(lotteryRepo repositories.LotteryRepository, notifier notifications.Notifier) LotteryService {
+	return &lotteryService{
+		lotteryRepo: lotteryRepo,
+		notifier:    notifier,
+	}
+}
+
+func (s *lotteryService) Start(interval time.Duration) {
+	go func() {
+		s.RunDue()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.RunDue()
+		}
+	}()
+}
+
+func (s *lotteryService) RunDue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	roundIDs, err := s.lotteryRepo.GetDueLotteryRounds(now)
+	if err != nil {
+		log.Printf("Error fetching lottery rounds to draw: %v", err)
+	}
+	for _, roundID := range roundIDs {
+		winners, err := s.lotteryRepo.DrawLottery(roundID)
+		if err != nil {
+			log.Printf("Error drawing lottery round %s: %v", roundID, err)
+			continue
+		}
+		s.notifyWinners(winners)
+	}
+
+	forfeited, err := s.lotteryRepo.ForfeitOverdueWins(now)
+	if err != nil {
+		log.Printf("Error forfeiting overdue lottery wins: %v", err)
+	}
+	for _, entry := range forfeited {
+		s.notify(entry, models.NotificationLotteryForfeited, "Your lottery win was forfeited",
+			fmt.Sprintf("The %d won were not paid in time and went to the next entrant", entry.WonQuantity))
+	}
+
+	winners, err := s.lotteryRepo.AwardReleasedQuantity()
+	if err != nil {
+		log.Printf("Error awarding released lottery quantity: %v", err)
+	}
+	s.notifyWinners(winners)
+}
+
+func (s *lotteryService) EnterLottery(entry *models.LotteryEntry) error {
+	return s.lotteryRepo.EnterLottery(entry)
+}
+
+func (s *lotteryService) GetLotteryEntries(roundID uuid.UUID, customerID *uuid.UUID) ([]models.LotteryEntry, error) {
+	return s.lotteryRepo.GetLotteryEntries(roundID, customerID)
+}
+
+// DrawLottery draws an ended round right away instead of waiting for the next run
+func (s *lotteryService) DrawLottery(roundID uuid.UUID) ([]models.LotteryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	winners, err := s.lotteryRepo.DrawLottery(roundID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyWinners(winners)
+	return winners, nil
+}
+
+func (s *lotteryService) notifyWinners(winners []models.LotteryEntry) {
+	for _, entry := range winners {
+		s.notify(entry, models.NotificationLotteryWon, "You won the lottery",
+			fmt.Sprintf("%d won; pay order %s before the deadline to keep them", entry.WonQuantity, entry.OrderID))
+	}
+}
+
+func (s *lotteryService) notify(entry models.LotteryEntry, notificationType string, title string, message string) {
+	entryID := entry.ID
+	payload, _ := json.Marshal(map[string]interface{}{
+		"lottery_entry_id": entry.ID,
+		"customer_id":      entry.CustomerID,
+		"round_id":         entry.RoundID,
+		"variant_id":       entry.VariantID,
+		"won_quantity":     entry.WonQuantity,
+		"order_id":         entry.OrderID,
+	})
+	notification := models.Notification{
+		Type:          notificationType,
+		Title:         title,
+		Message:       message,
+		ReferenceType: "lottery-entry",
+		ReferenceID:   &entryID,
+		Payload:       string(payload),
+	}
+	if err := s.notifier.Notify(&notification); err != nil {
+		log.Printf("Error sending lottery notification for entry %v: %v", entry.ID, err)
+	}
+}
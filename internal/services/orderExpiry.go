@@ -0,0 +1,52 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// orderExpiryBatchSize is the number of overdue orders cancelled per transaction
+const orderExpiryBatchSize = 100
+
+// OrderExpiry cancels pending orders whose payment deadline has passed, so abandoned orders do not hold the
+// quantity of a sales round and the stock of its products forever
+type OrderExpiry interface {
+	// Start expires overdue orders now and then every interval in the background
+	Start(interval time.Duration)
+}
+
+type orderExpiry struct {
+	purchaseService PurchaseService
+}
+
+// NewOrderExpiry creates a new instance of OrderExpiry
+func NewOrderExpiry(purchaseService PurchaseService) OrderExpiry {
+	return &orderExpiry{purchaseService: purchaseService}
+}
+
+func (s *orderExpiry) Start(interval time.Duration) {
+	go func() {
+		s.expire(time.Now())
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			s.expire(now)
+		}
+	}()
+}
+
+func (s *orderExpiry) expire(now time.Time) {
+	for {
+		orders, err := s.purchaseService.ExpireOverdueOrders(now, orderExpiryBatchSize)
+		if err != nil {
+			log.Printf("Error expiring overdue orders: %v", err)
+			return
+		}
+		for _, order := range orders {
+			log.Printf("Order %s expired unpaid at %s", order.Code, order.PaymentDueAt.Format("2006-01-02 15:04:05"))
+		}
+		if len(orders) < orderExpiryBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+)
+
+// expiringPurchases hands out the overdue orders it holds a batch at a time
+type expiringPurchases struct {
+	PurchaseService
+	overdue int
+	err     error
+	calls   int
+}
+
+func (p *expiringPurchases) ExpireOverdueOrders(now time.Time, limit int) ([]models.Order, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	n := min(limit, p.overdue)
+	p.overdue -= n
+	orders := make([]models.Order, n)
+	for i := range orders {
+		orders[i].PaymentDueAt = &now
+	}
+	return orders, nil
+}
+
+func TestOrderExpiryWorksThroughFullBatches(t *testing.T) {
+	purchases := &expiringPurchases{overdue: 2*orderExpiryBatchSize + 1}
+	(&orderExpiry{purchaseService: purchases}).expire(time.Now())
+	if purchases.overdue != 0 {
+		t.Errorf("%d overdue orders left, want 0", purchases.overdue)
+	}
+	if purchases.calls != 3 {
+		t.Errorf("expired in %d batches, want 3", purchases.calls)
+	}
+}
+
+func TestOrderExpiryStopsAtAnEmptyBatch(t *testing.T) {
+	purchases := &expiringPurchases{overdue: orderExpiryBatchSize}
+	(&orderExpiry{purchaseService: purchases}).expire(time.Now())
+	if purchases.calls != 2 {
+		t.Errorf("expired in %d batches, want 2", purchases.calls)
+	}
+}
+
+func TestOrderExpiryStopsOnError(t *testing.T) {
+	purchases := &expiringPurchases{overdue: 5 * orderExpiryBatchSize, err: errors.New("connection refused")}
+	(&orderExpiry{purchaseService: purchases}).expire(time.Now())
+	if purchases.calls != 1 {
+		t.Errorf("tried %d times after an error, want 1", purchases.calls)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/events"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+)
+
+// outboxBatchSize is how many events the relay claims and publishes at a time
+const outboxBatchSize = 100
+
+// OutboxRelay publishes the events written to the outbox to every sink. An event is marked published only when
+// all sinks took it; otherwise it is retried for all of them, so sinks may see an event more than once.
+type OutboxRelay interface {
+	// Start relays pending events every interval in the background
+	Start(interval time.Duration)
+	// RelayPending publishes due events until none are left and returns how many were published
+	RelayPending() (int, error)
+}
+
+type outboxRelay struct {
+	outboxRepo repositories.OutboxRepository
+	sinks      []events.Sink
+
+	mu sync.Mutex
+}
+
+// NewOutboxRelay creates a new instance of OutboxRelay
+func NewOutboxRelay(outboxRepo repositories.OutboxRepository, sinks ...events.Sink) OutboxRelay {
+	return &outboxRelay{
+		outboxRepo: outboxRepo,
+		sinks:      sinks,
+	}
+}
+
+func (s *outboxRelay) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.RelayPending(); err != nil {
+				log.Printf("Error relaying outbox events: %v", err)
+			}
+		}
+	}()
+}
+
+func (s *outboxRelay) RelayPending() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for {
+		published, failed, err := s.outboxRepo.PublishPending(outboxBatchSize, s.publish)
+		total += published
+		if err != nil {
+			return total, err
+		}
+		// Failed events wait for their backoff; stop once a batch is not full
+		if published+failed < outboxBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *outboxRelay) publish(event models.OutboxEvent) error {
+	envelope := events.NewEnvelope(event)
+	for i, sink := range s.sinks {
+		if err := sink.Publish(envelope); err != nil {
+			log.Printf("Error publishing event %v (%s) to sink %d: %v", event.ID, event.Type, i, err)
+			return fmt.Errorf("sink %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/events"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/google/uuid"
+)
+
+// pendingOutbox hands out its events a batch at a time and keeps the ones that failed
+type pendingOutbox struct {
+	pending []models.OutboxEvent
+	failed  []models.OutboxEvent
+	batches int
+}
+
+func (o *pendingOutbox) PublishPending(limit int, publish func(event models.OutboxEvent) error) (int, int, error) {
+	o.batches++
+	batch := o.pending[:min(limit, len(o.pending))]
+	o.pending = o.pending[len(batch):]
+	published := 0
+	for _, event := range batch {
+		if publish(event) != nil {
+			o.failed = append(o.failed, event)
+			continue
+		}
+		published++
+	}
+	return published, len(batch) - published, nil
+}
+
+// recordingSink takes every event except the ones of type refuse
+type recordingSink struct {
+	refuse string
+	got    []uuid.UUID
+}
+
+func (s *recordingSink) Publish(envelope events.Envelope) error {
+	if envelope.Type == s.refuse {
+		return errors.New("refused")
+	}
+	s.got = append(s.got, envelope.ID)
+	return nil
+}
+
+func TestOutboxRelayPublishesToEverySink(t *testing.T) {
+	outbox := &pendingOutbox{}
+	for i := 0; i < outboxBatchSize+1; i++ {
+		outbox.pending = append(outbox.pending, models.OutboxEvent{ID: uuid.New(), Type: models.EventOrderPlaced})
+	}
+	outbox.pending[3].Type = models.EventStockAdjusted
+	first, second := &recordingSink{}, &recordingSink{refuse: models.EventStockAdjusted}
+
+	published, err := NewOutboxRelay(outbox, first, second).RelayPending()
+	if err != nil {
+		t.Fatalf("RelayPending() error = %v", err)
+	}
+	if published != outboxBatchSize {
+		t.Errorf("published %d events, want %d", published, outboxBatchSize)
+	}
+	if outbox.batches != 2 {
+		t.Errorf("relayed in %d batches, want 2", outbox.batches)
+	}
+	// The event the second sink refused is retried, although the first sink took it
+	if len(outbox.failed) != 1 || outbox.failed[0].Type != models.EventStockAdjusted {
+		t.Errorf("failed events = %v, want the one the second sink refused", outbox.failed)
+	}
+	if len(first.got) != outboxBatchSize+1 || len(second.got) != outboxBatchSize {
+		t.Errorf("sinks got %d and %d events, want %d and %d", len(first.got), len(second.got), outboxBatchSize+1, outboxBatchSize)
+	}
+}
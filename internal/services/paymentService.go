@@ -0,0 +1,307 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/payments"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrOrderNotPayable is returned when a payment is started for an order that is not awaiting payment
+var ErrOrderNotPayable = errors.New("order is not awaiting payment")
+
+// PaymentService takes payments for orders through the provider named in their PaymentSource. Orders stay
+// pending until a payment is captured; providers report payments through webhooks, which authorize and then
+// capture them.
+type PaymentService interface {
+	// Provider returns the provider for a payment source, or payments.ErrUnknownProvider
+	Provider(source string) (payments.Provider, error)
+	// StartPayment creates a payment intent for a pending order, or returns the one the customer can still complete
+	StartPayment(orderID uuid.UUID) (*models.Payment, error)
+	GetPayment(id uuid.UUID) (*models.Payment, error)
+	GetOrderPayments(orderID uuid.UUID) ([]models.Payment, error)
+	// CapturePayment captures an authorized payment and marks its order paid; a captured payment is returned as is
+	CapturePayment(id uuid.UUID) (*models.Payment, error)
+	// RefundPayment refunds amount of a captured payment; 0 refunds everything that is left
+	RefundPayment(id uuid.UUID, amount float64, reason string) (*models.Payment, error)
+	// RefundOrder refunds what is left of every captured payment of an order
+	RefundOrder(orderID uuid.UUID, reason string) error
+	// HandleWebhook verifies and applies a webhook sent by a provider
+	HandleWebhook(providerName string, header http.Header, body []byte) error
+}
+
+type paymentService struct {
+	paymentRepo repositories.PaymentRepository
+	orderRepo   repositories.OrderRepository
+	providers   *payments.Registry
+	currency    string
+}
+
+// NewPaymentService creates a new instance of PaymentService; payments are taken in currency
+func NewPaymentService(paymentRepo repositories.PaymentRepository, orderRepo repositories.OrderRepository, providers *payments.Registry, currency string) PaymentService {
+	return &paymentService{
+		paymentRepo: paymentRepo,
+		orderRepo:   orderRepo,
+		providers:   providers,
+		currency:    currency,
+	}
+}
+
+func (s *paymentService) Provider(source string) (payments.Provider, error) {
+	return s.providers.Get(source)
+}
+
+func (s *paymentService) StartPayment(orderID uuid.UUID) (*models.Payment, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, ErrOrderNotPayable
+	}
+	open, err := s.paymentRepo.GetOpenPayment(orderID)
+	if err == nil {
+		return open, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	provider, err := s.providers.Get(order.PaymentSource)
+	if err != nil {
+		return nil, err
+	}
+	// The customer pays into the account of the store the order is from, e.g. its PromptPay ID
+	var payeeID string
+	store, err := s.orderRepo.GetOrderStore(order.ID)
+	switch {
+	case err == nil:
+		payeeID = store.PromptPayID
+	case !errors.Is(err, repositories.ErrOrderSpansStores) && !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	intent, err := provider.CreateIntent(payments.IntentRequest{
+		Reference:   order.Code,
+		PayeeID:     payeeID,
+		Amount:      order.TotalPrice,
+		Currency:    s.currency,
+		Description: "Order " + order.Code,
+		Metadata:    map[string]string{"order_id": order.ID.String()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create payment intent: %w", err)
+	}
+
+	payment := &models.Payment{
+		OrderID:          order.ID,
+		Provider:         provider.Name(),
+		ProviderIntentID: intent.ID,
+		Amount:           intent.Amount,
+		Currency:         intent.Currency,
+		PayeeID:          payeeID,
+		Status:           models.PaymentPending,
+	}
+	if intent.NextAction != nil {
+		nextAction, err := json.Marshal(intent.NextAction)
+		if err != nil {
+			return nil, err
+		}
+		payment.NextAction = string(nextAction)
+	}
+	if err := s.paymentRepo.CreatePayment(payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (s *paymentService) GetPayment(id uuid.UUID) (*models.Payment, error) {
+	return s.paymentRepo.GetPaymentByID(id)
+}
+
+func (s *paymentService) GetOrderPayments(orderID uuid.UUID) ([]models.Payment, error) {
+	return s.paymentRepo.GetPaymentsByOrderID(orderID)
+}
+
+func (s *paymentService) CapturePayment(id uuid.UUID) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.CapturedAt != nil {
+		return payment, nil
+	}
+	if err := checkCapturable(payment); err != nil {
+		return nil, err
+	}
+	provider, err := s.providers.Get(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := provider.Capture(payment.ProviderIntentID, payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+	captured, result, err := s.paymentRepo.CapturePayment(payment.ID, intent.CapturedAmount)
+	if err != nil {
+		return nil, err
+	}
+	if result == repositories.CaptureOrderClosed {
+		// The order was cancelled or paid another way while the customer was paying; give the money back
+		log.Printf("Payment %s was captured for order %s, which no longer takes it; refunding", captured.ID, captured.OrderID)
+		if refunded, err := s.RefundPayment(captured.ID, 0, "order no longer awaiting payment"); err != nil {
+			log.Printf("Error refunding payment %s: %v", captured.ID, err)
+		} else {
+			captured = refunded
+		}
+	}
+	return captured, nil
+}
+
+// checkCapturable returns ErrInvalidState unless the provider authorized the payment. Nothing else shows that
+// the money is there: a pending PromptPay payment, say, is only captured once its transfer is on a statement.
+func checkCapturable(payment *models.Payment) error {
+	if payment.Status != models.PaymentAuthorized {
+		return fmt.Errorf("%w: payment is %s, not authorized", payments.ErrInvalidState, payment.Status)
+	}
+	return nil
+}
+
+func (s *paymentService) RefundPayment(id uuid.UUID, amount float64, reason string) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.CapturedAt == nil {
+		return nil, payments.ErrInvalidState
+	}
+	left := math.Round((payment.CapturedAmount-payment.RefundedAmount)*100) / 100
+	if amount == 0 {
+		amount = left
+	}
+	if amount <= 0 || amount > left {
+		return nil, repositories.ErrRefundExceedsCaptured
+	}
+
+	provider, err := s.providers.Get(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+	refund, err := provider.Refund(payment.ProviderIntentID, amount, reason)
+	if err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.RecordRefund(payment.ID, &models.PaymentRefund{
+		ProviderRefundID: refund.ID,
+		Amount:           refund.Amount,
+		Reason:           reason,
+	})
+}
+
+func (s *paymentService) RefundOrder(orderID uuid.UUID, reason string) error {
+	orderPayments, err := s.paymentRepo.GetPaymentsByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+	for _, payment := range orderPayments {
+		if payment.CapturedAt == nil || payment.Status == models.PaymentRefunded {
+			continue
+		}
+		if _, err := s.RefundPayment(payment.ID, 0, reason); err != nil {
+			return fmt.Errorf("refunding payment %s: %w", payment.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *paymentService) HandleWebhook(providerName string, header http.Header, body []byte) error {
+	provider, err := s.providers.Get(providerName)
+	if err != nil {
+		return err
+	}
+	event, err := provider.VerifyWebhook(header, body)
+	if err != nil {
+		return err
+	}
+	payment, err := s.paymentRepo.GetPaymentByIntent(provider.Name(), event.IntentID)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case payments.EventAuthorized:
+		if payment.Status == models.PaymentPending {
+			if err := s.paymentRepo.UpdatePaymentStatus(payment.ID, models.PaymentAuthorized, ""); err != nil {
+				return err
+			}
+		}
+		_, err = s.CapturePayment(payment.ID)
+		return err
+	case payments.EventCaptured:
+		_, result, err := s.paymentRepo.CapturePayment(payment.ID, event.Amount)
+		if err == nil && result == repositories.CaptureOrderClosed {
+			_, err = s.RefundPayment(payment.ID, 0, "order no longer awaiting payment")
+		}
+		return err
+	case payments.EventFailed:
+		if payment.Status != models.PaymentPending && payment.Status != models.PaymentAuthorized {
+			return nil
+		}
+		return s.paymentRepo.UpdatePaymentStatus(payment.ID, models.PaymentFailed, event.Reason)
+	case payments.EventRefunded:
+		_, err = s.paymentRepo.RecordRefund(payment.ID, &models.PaymentRefund{
+			ProviderRefundID: event.RefundID,
+			Amount:           event.Amount,
+			Reason:           event.Reason,
+		})
+		return err
+	default:
+		log.Printf("Ignoring %s webhook of type %s", provider.Name(), event.Type)
+		return nil
+	}
+}
+
+// PaymentResponse converts a payment for API responses
+func PaymentResponse(payment models.Payment) dtos.PaymentResponseDTO {
+	response := dtos.PaymentResponseDTO{
+		ID:               payment.ID,
+		OrderID:          payment.OrderID,
+		Provider:         payment.Provider,
+		ProviderIntentID: payment.ProviderIntentID,
+		Amount:           payment.Amount,
+		Currency:         payment.Currency,
+		Status:           payment.Status,
+		CapturedAmount:   payment.CapturedAmount,
+		RefundedAmount:   payment.RefundedAmount,
+		FailureReason:    payment.FailureReason,
+		Refunds:          make([]dtos.PaymentRefundResponseDTO, 0, len(payment.Refunds)),
+		CreatedAt:        payment.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:        payment.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if payment.NextAction != "" {
+		response.NextAction = json.RawMessage(payment.NextAction)
+	}
+	if payment.CapturedAt != nil {
+		response.CapturedAt = payment.CapturedAt.Format("2006-01-02 15:04:05")
+	}
+	for _, refund := range payment.Refunds {
+		response.Refunds = append(response.Refunds, dtos.PaymentRefundResponseDTO{
+			ID:               refund.ID,
+			ProviderRefundID: refund.ProviderRefundID,
+			Amount:           refund.Amount,
+			Reason:           refund.Reason,
+			CreatedAt:        refund.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return response
+}
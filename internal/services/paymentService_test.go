@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/payments"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryPayments keeps payments and the status of their orders the way the payment repository records them
+type memoryPayments struct {
+	repositories.PaymentRepository
+	payments map[uuid.UUID]*models.Payment
+	orders   map[uuid.UUID]string
+}
+
+func (r *memoryPayments) add(provider, intentID, status, orderStatus string) *models.Payment {
+	payment := &models.Payment{ID: uuid.New(), OrderID: uuid.New(), Provider: provider, ProviderIntentID: intentID, Status: status, Amount: 100}
+	r.payments[payment.ID] = payment
+	r.orders[payment.OrderID] = orderStatus
+	return payment
+}
+
+func (r *memoryPayments) GetPaymentByID(id uuid.UUID) (*models.Payment, error) {
+	payment, ok := r.payments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *payment
+	return &copied, nil
+}
+
+func (r *memoryPayments) GetPaymentByIntent(provider string, intentID string) (*models.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.Provider == provider && payment.ProviderIntentID == intentID {
+			copied := *payment
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryPayments) UpdatePaymentStatus(id uuid.UUID, status string, failureReason string) error {
+	r.payments[id].Status = status
+	return nil
+}
+
+func (r *memoryPayments) CapturePayment(id uuid.UUID, amount float64) (*models.Payment, repositories.CaptureResult, error) {
+	payment := r.payments[id]
+	if payment.CapturedAt != nil {
+		return payment, repositories.CaptureAlreadyRecorded, nil
+	}
+	now := time.Now()
+	payment.Status, payment.CapturedAmount, payment.CapturedAt = models.PaymentCaptured, amount, &now
+	if r.orders[payment.OrderID] != models.OrderStatusPending {
+		return payment, repositories.CaptureOrderClosed, nil
+	}
+	r.orders[payment.OrderID] = models.OrderStatusPaid
+	return payment, repositories.CaptureOrderPaid, nil
+}
+
+func (r *memoryPayments) RecordRefund(id uuid.UUID, refund *models.PaymentRefund) (*models.Payment, error) {
+	payment := r.payments[id]
+	payment.RefundedAmount += refund.Amount
+	payment.Status = models.PaymentRefunded
+	return payment, nil
+}
+
+func newTestPaymentService(t *testing.T) (*paymentService, *memoryPayments, *payments.MockProvider) {
+	t.Helper()
+	repo := &memoryPayments{payments: map[uuid.UUID]*models.Payment{}, orders: map[uuid.UUID]string{}}
+	mock := payments.NewMockProvider("whsec_test")
+	service := NewPaymentService(repo, nil, payments.NewRegistry(mock, payments.NewPromptPayProvider()), "THB").(*paymentService)
+	return service, repo, mock
+}
+
+// payWithMock starts a mock intent for a new payment and has the customer pay it, returning the webhook
+func payWithMock(t *testing.T, repo *memoryPayments, mock *payments.MockProvider, orderStatus string) (*models.Payment, http.Header, []byte) {
+	t.Helper()
+	intent, err := mock.CreateIntent(payments.IntentRequest{Amount: 100, Currency: "THB"})
+	if err != nil {
+		t.Fatalf("CreateIntent() error = %v", err)
+	}
+	payment := repo.add(mock.Name(), intent.ID, models.PaymentPending, orderStatus)
+	header, body, err := mock.Simulate(intent.ID, payments.MockOutcomeSucceed)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	return payment, header, body
+}
+
+func TestAuthorizedWebhookCapturesAndPaysTheOrder(t *testing.T) {
+	service, repo, mock := newTestPaymentService(t)
+	payment, header, body := payWithMock(t, repo, mock, models.OrderStatusPending)
+
+	if err := service.HandleWebhook(mock.Name(), header, body); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+	if got := repo.payments[payment.ID]; got.Status != models.PaymentCaptured || got.CapturedAmount != 100 {
+		t.Errorf("payment is %s with %.2f captured, want captured with 100", got.Status, got.CapturedAmount)
+	}
+	if repo.orders[payment.OrderID] != models.OrderStatusPaid {
+		t.Errorf("order is %s, want paid", repo.orders[payment.OrderID])
+	}
+}
+
+func TestRepeatedCaptureNeverRefundsAShippedOrder(t *testing.T) {
+	service, repo, mock := newTestPaymentService(t)
+	payment, header, body := payWithMock(t, repo, mock, models.OrderStatusPending)
+	if err := service.HandleWebhook(mock.Name(), header, body); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+	repo.orders[payment.OrderID] = models.OrderStatusShipped
+
+	// The provider delivers the webhook again, and staff press capture as well
+	if err := service.HandleWebhook(mock.Name(), header, body); err != nil {
+		t.Errorf("redelivered webhook error = %v", err)
+	}
+	if _, err := service.CapturePayment(payment.ID); err != nil {
+		t.Errorf("CapturePayment() of a captured payment error = %v", err)
+	}
+	if got := repo.payments[payment.ID]; got.RefundedAmount != 0 || got.Status != models.PaymentCaptured {
+		t.Errorf("payment is %s with %.2f refunded, want captured with nothing refunded", got.Status, got.RefundedAmount)
+	}
+}
+
+func TestCaptureForACancelledOrderIsRefunded(t *testing.T) {
+	service, repo, mock := newTestPaymentService(t)
+	payment, header, body := payWithMock(t, repo, mock, models.OrderStatusCancelled)
+
+	if err := service.HandleWebhook(mock.Name(), header, body); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+	if got := repo.payments[payment.ID]; got.Status != models.PaymentRefunded || got.RefundedAmount != 100 {
+		t.Errorf("payment is %s with %.2f refunded, want refunded in full", got.Status, got.RefundedAmount)
+	}
+}
+
+func TestPendingPaymentsCannotBeCaptured(t *testing.T) {
+	service, repo, _ := newTestPaymentService(t)
+	for _, provider := range []string{"promptpay", "mock"} {
+		payment := repo.add(provider, "intent_"+provider, models.PaymentPending, models.OrderStatusPending)
+		if _, err := service.CapturePayment(payment.ID); !errors.Is(err, payments.ErrInvalidState) {
+			t.Errorf("capturing a pending %s payment: error = %v, want ErrInvalidState", provider, err)
+		}
+		if repo.orders[payment.OrderID] != models.OrderStatusPending {
+			t.Errorf("order of a pending %s payment is %s, want pending", provider, repo.orders[payment.OrderID])
+		}
+	}
+
+	// Even when authorized, PromptPay has nothing to capture from; the transfer is matched on a statement
+	payment := repo.add("promptpay", "intent_authorized", models.PaymentAuthorized, models.OrderStatusPending)
+	if _, err := service.CapturePayment(payment.ID); !errors.Is(err, payments.ErrNotSupported) {
+		t.Errorf("capturing a PromptPay payment: error = %v, want ErrNotSupported", err)
+	}
+	if repo.orders[payment.OrderID] != models.OrderStatusPending {
+		t.Errorf("order of the PromptPay payment is %s, want pending", repo.orders[payment.OrderID])
+	}
+}
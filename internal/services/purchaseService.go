@@ -1,7 +1,10 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/dtos"
@@ -17,6 +20,10 @@ type PurchaseService interface {
 	GetOrderByID(id uuid.UUID) (*models.Order, error)
 	UpdateOrder(order *models.Order) error
 	DeleteOrder(id uuid.UUID) error
+	CancelOrder(id uuid.UUID, reason string) (*models.Order, error)
+	ConfirmOrder(id uuid.UUID) (*models.Order, error)
+	// ExpireOverdueOrders cancels up to limit pending orders that were not paid by their deadline
+	ExpireOverdueOrders(now time.Time, limit int) ([]models.Order, error)
 }
 
 type purchaseService struct {
@@ -25,6 +32,9 @@ type purchaseService struct {
 	productVariantRepo   repositories.ProductVariantRepository
 	productRepo          repositories.ProductRepository
 	salesRoundDetailRepo repositories.SalesRoundDetailRepository
+	addressRepo          repositories.CustomerAddressRepository
+	paymentService       PaymentService
+	stockObservers       []repositories.StockObserver
 }
 
 // NewPurchaseService creates a new instance of PurchaseService
@@ -34,6 +44,9 @@ func NewPurchaseService(
 	productVariantRepo repositories.ProductVariantRepository,
 	productRepo repositories.ProductRepository,
 	salesRoundDetailRepo repositories.SalesRoundDetailRepository,
+	addressRepo repositories.CustomerAddressRepository,
+	paymentService PaymentService,
+	stockObservers ...repositories.StockObserver,
 ) PurchaseService {
 	return &purchaseService{
 		orderRepo:            orderRepo,
@@ -41,15 +54,31 @@ func NewPurchaseService(
 		productVariantRepo:   productVariantRepo,
 		productRepo:          productRepo,
 		salesRoundDetailRepo: salesRoundDetailRepo,
+		addressRepo:          addressRepo,
+		paymentService:       paymentService,
+		stockObservers:       stockObservers,
 	}
 }
 
+// MakePurchase places a pending order and starts its payment with the provider named in PaymentSource; the order
+// is paid once the payment is captured. An order placed with an address book entry keeps a copy of it, and the
+// discount codes of the request are applied to its lines before VAT.
 func (s *purchaseService) MakePurchase(request dtos.PurchaseCreateDTO) (dtos.OrderResponseDTO, error) {
+	provider, err := s.paymentService.Provider(request.PaymentSource)
+	if err != nil {
+		return dtos.OrderResponseDTO{}, err
+	}
+	address, err := s.deliveryAddress(request)
+	if err != nil {
+		return dtos.OrderResponseDTO{}, err
+	}
+
 	// Auto-generate order code
 	orderCode := fmt.Sprintf("ORDER-%s", uuid.New().String())
 
 	// Initialize total price
 	totalPrice := 0.0
+	lines := make([]models.OrderDetail, 0, len(request.Items))
 
 	// Check and adjust stock and calculate total price
 	for _, item := range request.Items {
@@ -80,60 +109,55 @@ func (s *purchaseService) MakePurchase(request dtos.PurchaseCreateDTO) (dtos.Ord
 			return dtos.OrderResponseDTO{}, fmt.Errorf("quantity exceeds sales round limit")
 		}
 
-		totalPrice += productVariant.Price * float64(item.Quantity)
-
-		// Update sales round detail
-		salesRoundDetail.Quantity -= item.Quantity
-		if err := s.salesRoundDetailRepo.UpdateSalesRoundDetail(salesRoundDetail); err != nil {
-			return dtos.OrderResponseDTO{}, err
-		}
+		// Charge the round's sale price, if it has one
+		price := salesRoundDetail.SalePrice(productVariant.Price)
+		totalPrice += price * float64(item.Quantity)
+		lines = append(lines, models.OrderDetail{
+			VariantID:    item.VariantID,
+			Quantity:     item.Quantity,
+			Price:        price,
+			RegularPrice: productVariant.Price,
+			TotalPrice:   price * float64(item.Quantity),
+		})
 	}
 
-	// Create order
+	// Create the order; claiming the round quantity (quantity held for waitlisted customers is not available),
+	// taking the stock and recording OrderPlaced happen in one transaction
 	order := models.Order{
 		CustomerID:      request.CustomerID,
 		RoundID:         request.RoundID,
 		OrderDate:       time.Now(),
-		Status:          "ซื้อ สำเร็จ", // Automatically set status
-		Code:            orderCode,     // Auto-generated order code
-		TotalPrice:      totalPrice,    // Calculated total price
+		Status:          models.OrderStatusPending, // Paid once the payment is captured
+		Code:            orderCode,                 // Auto-generated order code
+		TotalPrice:      totalPrice,                // Calculated total price; discounts and VAT are applied when the order is placed
 		DeliveryAddress: request.DeliveryAddress,
-		PaymentSource:   request.PaymentSource,
+		PaymentSource:   provider.Name(),
+		OrderDetail:     lines,
+	}
+	if address != nil {
+		order.AddressID = &address.ID
+		order.ShippingAddress = address.PostalAddress
+		order.DeliveryAddress = truncate(address.PostalAddress.String(), 255)
 	}
 
-	if err := s.orderRepo.CreateOrder(&order); err != nil {
+	if err := s.orderRepo.PlaceOrder(&order, request.DiscountCodes); err != nil {
 		return dtos.OrderResponseDTO{}, err
 	}
 
-	// Create order details and update stock
+	// Let the observers (e.g. the low-stock evaluator) look at the variants that were sold
 	for _, item := range request.Items {
-		productVariant, err := s.productVariantRepo.GetProductVariantByID(item.VariantID)
-		if err != nil {
-			return dtos.OrderResponseDTO{}, err
-		}
-
-		product, err := s.productRepo.GetProductByID(productVariant.ProductID)
-		if err != nil {
-			return dtos.OrderResponseDTO{}, err
-		}
-
-		product.Stock -= item.Quantity
-		if err := s.productRepo.UpdateProduct(product); err != nil {
-			return dtos.OrderResponseDTO{}, err
-		}
-
-		orderDetail := models.OrderDetail{
-			OrderID:    order.ID,
-			VariantID:  item.VariantID,
-			Quantity:   item.Quantity,
-			Price:      productVariant.Price,
-			TotalPrice: productVariant.Price * float64(item.Quantity),
-		}
+		s.stockChanged(item.VariantID)
+	}
 
-		if err := s.orderDetailRepo.CreateOrderDetail(&orderDetail); err != nil {
-			return dtos.OrderResponseDTO{}, err
+	payment, err := s.paymentService.StartPayment(order.ID)
+	if err != nil {
+		// Without a payment the customer could never pay; release the quantity again
+		if _, cancelErr := s.CancelOrder(order.ID, "payment could not be started"); cancelErr != nil {
+			log.Printf("Error cancelling order %s without payment: %v", order.Code, cancelErr)
 		}
+		return dtos.OrderResponseDTO{}, err
 	}
+	paymentResponse := PaymentResponse(*payment)
 
 	response := dtos.OrderResponseDTO{
 		ID:              order.ID,
@@ -143,15 +167,46 @@ func (s *purchaseService) MakePurchase(request dtos.PurchaseCreateDTO) (dtos.Ord
 		Status:          order.Status,
 		Code:            order.Code,
 		TotalPrice:      order.TotalPrice,
+		NetAmount:       order.NetAmount,
+		TaxAmount:       order.TaxAmount,
+		DiscountAmount:  order.DiscountAmount,
+		FreeShipping:    order.FreeShipping,
+		Discounts:       OrderDiscountsResponse(order),
 		DeliveryAddress: order.DeliveryAddress,
+		AddressID:       order.AddressID,
+		ShippingAddress: PostalAddressResponse(order.ShippingAddress),
 		PaymentSource:   order.PaymentSource,
+		PaymentDueAt:    order.PaymentDueAt,
 		CreatedAt:       order.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:       order.UpdatedAt.Format("2006-01-02 15:04:05"),
+		Payment:         &paymentResponse,
 	}
 
 	return response, nil
 }
 
+// deliveryAddress returns the address book entry a purchase ships to: the one it names, or the customer's default
+// when it gives no delivery address either. It returns nil for a purchase with only a free-form address, or
+// whose customer has no default address.
+func (s *purchaseService) deliveryAddress(request dtos.PurchaseCreateDTO) (*models.CustomerAddress, error) {
+	if request.AddressID == nil {
+		if strings.TrimSpace(request.DeliveryAddress) != "" {
+			return nil, nil
+		}
+		address, err := s.addressRepo.GetDefaultAddress(request.CustomerID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return address, err
+	}
+
+	address, err := s.addressRepo.GetAddressByID(*request.AddressID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && address.CustomerID != request.CustomerID) {
+		return nil, repositories.ErrUnknownAddress
+	}
+	return address, err
+}
+
 func (s *purchaseService) GetAllOrders() ([]models.Order, error) {
 	return s.orderRepo.GetAllOrders()
 }
@@ -167,3 +222,86 @@ func (s *purchaseService) UpdateOrder(order *models.Order) error {
 func (s *purchaseService) DeleteOrder(id uuid.UUID) error {
 	return s.orderRepo.DeleteOrder(id)
 }
+
+// CancelOrder cancels an order, releases its quantities and refunds its captured payments; observers such as the
+// waitlist see the released variants
+func (s *purchaseService) CancelOrder(id uuid.UUID, reason string) (*models.Order, error) {
+	order, err := s.orderRepo.CancelOrder(id, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.paymentService.RefundOrder(order.ID, "order cancelled: "+reason); err != nil {
+		log.Printf("Error refunding cancelled order %s: %v", order.Code, err)
+	}
+	for _, line := range order.OrderDetail {
+		s.stockChanged(line.VariantID)
+	}
+	return order, nil
+}
+
+// ConfirmOrder marks a pending order, such as a lottery win, as paid
+func (s *purchaseService) ConfirmOrder(id uuid.UUID) (*models.Order, error) {
+	return s.orderRepo.ConfirmOrder(id)
+}
+
+// ExpireOverdueOrders releases the quantities of unpaid orders back to their rounds; observers such as the
+// waitlist see the released variants
+func (s *purchaseService) ExpireOverdueOrders(now time.Time, limit int) ([]models.Order, error) {
+	orders, err := s.orderRepo.ExpireOverdueOrders(now, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		for _, line := range order.OrderDetail {
+			s.stockChanged(line.VariantID)
+		}
+	}
+	return orders, nil
+}
+
+func (s *purchaseService) stockChanged(variantID uuid.UUID) {
+	for _, observer := range s.stockObservers {
+		observer.StockChanged(variantID)
+	}
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// OrderDiscountsResponse lists what the discount codes of an order took off its lines, for API responses
+func OrderDiscountsResponse(order models.Order) []dtos.OrderDiscountDTO {
+	var responses []dtos.OrderDiscountDTO
+	for _, line := range order.OrderDetail {
+		for _, discount := range line.Discounts {
+			responses = append(responses, dtos.OrderDiscountDTO{
+				OrderDetailID: line.ID,
+				VariantID:     line.VariantID,
+				Code:          discount.Code,
+				Amount:        discount.Amount,
+			})
+		}
+	}
+	return responses
+}
+
+// PostalAddressResponse converts a structured address for API responses; it returns nil for an empty address
+func PostalAddressResponse(address models.PostalAddress) *dtos.PostalAddressDTO {
+	if address.IsZero() {
+		return nil
+	}
+	return &dtos.PostalAddressDTO{
+		Recipient:   address.Recipient,
+		Phone:       address.Phone,
+		HouseNumber: address.HouseNumber,
+		Subdistrict: address.Subdistrict,
+		District:    address.District,
+		Province:    address.Province,
+		Postcode:    address.Postcode,
+	}
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// statementClockSkew allows for a bank clock running slightly ahead of ours when a transfer is made right after ordering
+const statementClockSkew = 10 * time.Minute
+
+// referenceToken finds what could be part of an order code in a transfer reference, e.g. the first block of its UUID
+var referenceToken = regexp.MustCompile(`[0-9A-Fa-f][0-9A-Fa-f-]{6,}[0-9A-Fa-f]`)
+
+// statementDateLayouts are the date formats accepted in bank statements; times are in the server's time zone
+// unless the format has an offset
+var statementDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	"02-01-2006 15:04",
+	"02-01-2006",
+}
+
+// ReconciliationService matches the credits of uploaded bank statements to pending orders. A credit is matched
+// automatically when its reference names exactly one pending order of the same amount, or when it is the only
+// pending order of that amount placed within the match window before the transfer; matched orders are marked
+// paid. Everything else is left for staff to review.
+type ReconciliationService interface {
+	// ImportStatement parses a bank statement CSV, optionally for the orders of one store, and reconciles its lines
+	ImportStatement(storeID *uuid.UUID, fileName string, r io.Reader) (*models.BankStatement, error)
+	GetStatements(storeID *uuid.UUID) ([]models.BankStatement, error)
+	GetStatement(id uuid.UUID) (*models.BankStatement, error)
+	// GetReviewQueue returns the ambiguous and unmatched lines awaiting staff
+	GetReviewQueue(storeID *uuid.UUID) ([]models.BankStatementLine, error)
+	// MatchLine records that a line paid an order, chosen by staff, and marks the order paid
+	MatchLine(id uuid.UUID, orderID uuid.UUID, note string) (*models.BankStatementLine, error)
+	// IgnoreLine dismisses a line that did not pay an order
+	IgnoreLine(id uuid.UUID, note string) (*models.BankStatementLine, error)
+}
+
+type reconciliationService struct {
+	reconciliationRepo repositories.ReconciliationRepository
+	window             time.Duration
+}
+
+// NewReconciliationService creates a new instance of ReconciliationService; a transfer is matched to orders
+// placed at most window before it
+func NewReconciliationService(reconciliationRepo repositories.ReconciliationRepository, window time.Duration) ReconciliationService {
+	return &reconciliationService{
+		reconciliationRepo: reconciliationRepo,
+		window:             window,
+	}
+}
+
+// statementLine is a parsed line of a bank statement
+type statementLine struct {
+	line     models.BankStatementLine
+	dateOnly bool // The statement gives the day of the transfer but not the time
+}
+
+func (s *reconciliationService) ImportStatement(storeID *uuid.UUID, fileName string, r io.Reader) (*models.BankStatement, error) {
+	parsed, err := parseStatementCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.BankStatement{
+		StoreID:    storeID,
+		FileName:   fileName,
+		TotalLines: len(parsed),
+		Lines:      make([]models.BankStatementLine, 0, len(parsed)),
+	}
+	for _, p := range parsed {
+		statement.Lines = append(statement.Lines, p.line)
+	}
+	if err := s.reconciliationRepo.CreateStatement(statement); err != nil {
+		return nil, err
+	}
+
+	// An order is paid by at most one line of the statement
+	claimed := make(map[uuid.UUID]bool)
+	for i := range parsed {
+		line := statement.Lines[i]
+		if err := s.reconcile(statement, line, parsed[i].dateOnly, claimed); err != nil {
+			log.Printf("Error reconciling line %d of bank statement %s: %v", line.LineNumber, statement.ID, err)
+			if err := s.reconciliationRepo.SetLineStatus(line.ID, models.StatementLineReview, "", "could not be matched: "+err.Error()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.reconciliationRepo.RefreshStatementCounts(statement.ID); err != nil {
+		return nil, err
+	}
+
+	statement, err = s.reconciliationRepo.GetStatementByID(statement.ID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Bank statement %s reconciled: %d matched, %d to review, %d unmatched, %d ignored",
+		statement.ID, statement.MatchedLines, statement.ReviewLines, statement.UnmatchedLines, statement.IgnoredLines)
+	return statement, nil
+}
+
+// reconcile decides what a new line of a statement paid and records it on the line
+func (s *reconciliationService) reconcile(statement *models.BankStatement, line models.BankStatementLine, dateOnly bool, claimed map[uuid.UUID]bool) error {
+	if line.Amount <= 0 {
+		return s.reconciliationRepo.SetLineStatus(line.ID, models.StatementLineIgnored, "", "not a credit")
+	}
+	seen, err := s.reconciliationRepo.FingerprintSeen(line.Fingerprint, statement.ID)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return s.reconciliationRepo.SetLineStatus(line.ID, models.StatementLineIgnored, "", "already imported with an earlier statement")
+	}
+
+	referenced, err := s.referencedOrders(line, statement.StoreID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(referenced) == 1 && amountsEqual(referenced[0].TotalPrice, line.Amount) && !claimed[referenced[0].ID]:
+		return s.autoMatch(line, referenced[0], "reference and amount match order "+referenced[0].Code, claimed)
+	case len(referenced) == 1:
+		note := fmt.Sprintf("reference names order %s, which totals %.2f", referenced[0].Code, referenced[0].TotalPrice)
+		if claimed[referenced[0].ID] {
+			note = fmt.Sprintf("reference names order %s, which another line of this statement paid", referenced[0].Code)
+		}
+		return s.review(line, referenced, note)
+	case len(referenced) > 1:
+		return s.review(line, referenced, fmt.Sprintf("reference matches %d pending orders", len(referenced)))
+	}
+
+	to := line.TransactedAt.Add(statementClockSkew)
+	if dateOnly {
+		to = line.TransactedAt.Add(24 * time.Hour)
+	}
+	candidates, err := s.reconciliationRepo.FindCandidateOrders(repositories.OrderCandidateFilter{
+		StoreID: statement.StoreID,
+		Amount:  line.Amount,
+		From:    line.TransactedAt.Add(-s.window),
+		To:      to,
+	})
+	if err != nil {
+		return err
+	}
+	unclaimed := candidates[:0]
+	for _, order := range candidates {
+		if !claimed[order.ID] {
+			unclaimed = append(unclaimed, order)
+		}
+	}
+
+	switch len(unclaimed) {
+	case 0:
+		return s.reconciliationRepo.SetLineStatus(line.ID, models.StatementLineUnmatched, "", "no pending order of this amount was placed before the transfer")
+	case 1:
+		return s.autoMatch(line, unclaimed[0], "only pending order of this amount placed before the transfer", claimed)
+	default:
+		return s.review(line, unclaimed, fmt.Sprintf("%d pending orders of this amount were placed before the transfer", len(unclaimed)))
+	}
+}
+
+// referencedOrders returns the pending orders whose code appears in the reference or description of a line
+func (s *reconciliationService) referencedOrders(line models.BankStatementLine, storeID *uuid.UUID) ([]models.Order, error) {
+	var orders []models.Order
+	found := make(map[uuid.UUID]bool)
+	for _, token := range referenceToken.FindAllString(line.Reference+" "+line.Description, -1) {
+		if len(strings.ReplaceAll(token, "-", "")) < 8 {
+			continue
+		}
+		matches, err := s.reconciliationRepo.FindOrdersByReference(strings.ToLower(token), storeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, order := range matches {
+			if !found[order.ID] {
+				found[order.ID] = true
+				orders = append(orders, order)
+			}
+		}
+	}
+	return orders, nil
+}
+
+// autoMatch marks the order paid by a line; if the order stopped awaiting payment in the meantime the line
+// goes to review instead
+func (s *reconciliationService) autoMatch(line models.BankStatementLine, order models.Order, note string, claimed map[uuid.UUID]bool) error {
+	_, err := s.reconciliationRepo.MatchLine(line.ID, order.ID, models.StatementLineMatched, note)
+	if errors.Is(err, repositories.ErrOrderNotPending) {
+		return s.review(line, []models.Order{order}, "order "+order.Code+" stopped awaiting payment while matching")
+	}
+	if err != nil {
+		return err
+	}
+	claimed[order.ID] = true
+	return nil
+}
+
+// review leaves a line for staff to match to one of the candidate orders
+func (s *reconciliationService) review(line models.BankStatementLine, candidates []models.Order, note string) error {
+	ids := make([]uuid.UUID, 0, len(candidates))
+	for _, order := range candidates {
+		ids = append(ids, order.ID)
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return s.reconciliationRepo.SetLineStatus(line.ID, models.StatementLineReview, string(encoded), note)
+}
+
+func (s *reconciliationService) GetStatements(storeID *uuid.UUID) ([]models.BankStatement, error) {
+	return s.reconciliationRepo.GetStatements(storeID)
+}
+
+func (s *reconciliationService) GetStatement(id uuid.UUID) (*models.BankStatement, error) {
+	return s.reconciliationRepo.GetStatementByID(id)
+}
+
+func (s *reconciliationService) GetReviewQueue(storeID *uuid.UUID) ([]models.BankStatementLine, error) {
+	return s.reconciliationRepo.GetReviewQueue(storeID)
+}
+
+func (s *reconciliationService) MatchLine(id uuid.UUID, orderID uuid.UUID, note string) (*models.BankStatementLine, error) {
+	if note == "" {
+		note = "matched by staff"
+	}
+	return s.reconciliationRepo.MatchLine(id, orderID, models.StatementLineResolved, note)
+}
+
+func (s *reconciliationService) IgnoreLine(id uuid.UUID, note string) (*models.BankStatementLine, error) {
+	return s.reconciliationRepo.IgnoreLine(id, note)
+}
+
+// amountsEqual compares amounts of money to the satang
+func amountsEqual(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
+
+// parseStatementCSV reads the lines of a bank statement. The header must name a date column (date,
+// transaction_date or value_date) and either an amount column, negative for debits, or credit and debit
+// columns; time, reference and description are optional.
+func parseStatementCSV(r io.Reader) ([]statementLine, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(names ...string) string {
+		for _, name := range names {
+			if _, ok := columns[name]; ok {
+				return name
+			}
+		}
+		return ""
+	}
+	dateColumn := column("date", "transaction_date", "value_date")
+	amountColumn := column("amount")
+	creditColumn := column("credit", "deposit")
+	debitColumn := column("debit", "withdrawal")
+	referenceColumn := column("reference", "ref", "memo")
+	descriptionColumn := column("description", "details", "narrative")
+	if dateColumn == "" {
+		return nil, fmt.Errorf("missing date column")
+	}
+	if amountColumn == "" && creditColumn == "" {
+		return nil, fmt.Errorf("missing amount or credit column")
+	}
+
+	var lines []statementLine
+	occurrences := make(map[string]int)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && name != "" && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		value := field(dateColumn)
+		if t := field("time"); t != "" {
+			value += " " + t
+		}
+		transactedAt, dateOnly, err := parseStatementDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		var amount float64
+		if amountColumn != "" {
+			if amount, err = parseStatementAmount(field(amountColumn)); err != nil {
+				return nil, fmt.Errorf("row %d: amount: %w", row, err)
+			}
+		} else {
+			credit, err := parseStatementAmount(field(creditColumn))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: credit: %w", row, err)
+			}
+			debit, err := parseStatementAmount(field(debitColumn))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: debit: %w", row, err)
+			}
+			amount = credit - math.Abs(debit)
+		}
+
+		line := models.BankStatementLine{
+			LineNumber:   row,
+			TransactedAt: transactedAt,
+			Amount:       amount,
+			Reference:    field(referenceColumn),
+			Description:  field(descriptionColumn),
+			Status:       models.StatementLineUnmatched,
+		}
+		// Identical lines in one statement are separate transfers; the occurrence tells them apart while still
+		// recognising them in an overlapping statement
+		key := fmt.Sprintf("%s|%.2f|%s|%s", transactedAt.UTC().Format(time.RFC3339), amount, line.Reference, line.Description)
+		occurrences[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+		line.Fingerprint = hex.EncodeToString(sum[:])
+		lines = append(lines, statementLine{line: line, dateOnly: dateOnly})
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("statement has no lines")
+	}
+	return lines, nil
+}
+
+// parseStatementDate parses the date of a statement line and reports whether it has no time of day
+func parseStatementDate(value string) (time.Time, bool, error) {
+	for _, layout := range statementDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, !strings.Contains(layout, "15"), nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("unrecognised date %q", value)
+}
+
+// parseStatementAmount parses an amount such as "1,250.00" or "THB 1250"; an empty amount is 0
+func parseStatementAmount(value string) (float64, error) {
+	value = strings.NewReplacer(",", "", " ", "", "THB", "", "฿", "").Replace(value)
+	if value == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return amount, nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// memoryReconciliation keeps statements and pending orders, and marks orders paid the way the repository does
+type memoryReconciliation struct {
+	repositories.ReconciliationRepository
+	orders     []*models.Order
+	statements map[uuid.UUID]*models.BankStatement
+	lines      map[uuid.UUID]*models.BankStatementLine
+}
+
+func newMemoryReconciliation(orders ...*models.Order) *memoryReconciliation {
+	return &memoryReconciliation{
+		orders:     orders,
+		statements: map[uuid.UUID]*models.BankStatement{},
+		lines:      map[uuid.UUID]*models.BankStatementLine{},
+	}
+}
+
+func (r *memoryReconciliation) CreateStatement(statement *models.BankStatement) error {
+	statement.ID = uuid.New()
+	for i := range statement.Lines {
+		statement.Lines[i].ID = uuid.New()
+		statement.Lines[i].StatementID = statement.ID
+		r.lines[statement.Lines[i].ID] = &statement.Lines[i]
+	}
+	r.statements[statement.ID] = statement
+	return nil
+}
+
+func (r *memoryReconciliation) GetStatementByID(id uuid.UUID) (*models.BankStatement, error) {
+	return r.statements[id], nil
+}
+
+func (r *memoryReconciliation) RefreshStatementCounts(id uuid.UUID) error {
+	return nil
+}
+
+func (r *memoryReconciliation) FingerprintSeen(fingerprint string, statementID uuid.UUID) (bool, error) {
+	for _, line := range r.lines {
+		if line.Fingerprint == fingerprint && line.StatementID != statementID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryReconciliation) FindOrdersByReference(token string, storeID *uuid.UUID) ([]models.Order, error) {
+	var orders []models.Order
+	for _, order := range r.orders {
+		if order.Status == models.OrderStatusPending && strings.Contains(strings.ToLower(order.Code), token) {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *memoryReconciliation) FindCandidateOrders(filter repositories.OrderCandidateFilter) ([]models.Order, error) {
+	var orders []models.Order
+	for _, order := range r.orders {
+		if order.Status == models.OrderStatusPending && amountsEqual(order.TotalPrice, filter.Amount) &&
+			!order.CreatedAt.Before(filter.From) && !order.CreatedAt.After(filter.To) {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *memoryReconciliation) SetLineStatus(id uuid.UUID, status string, candidates string, note string) error {
+	line := r.lines[id]
+	line.Status, line.Candidates, line.Note = status, candidates, note
+	return nil
+}
+
+func (r *memoryReconciliation) MatchLine(id uuid.UUID, orderID uuid.UUID, status string, note string) (*models.BankStatementLine, error) {
+	for _, order := range r.orders {
+		if order.ID != orderID {
+			continue
+		}
+		if order.Status != models.OrderStatusPending {
+			return nil, repositories.ErrOrderNotPending
+		}
+		order.Status = models.OrderStatusPaid
+		line := r.lines[id]
+		line.Status, line.OrderID, line.Note = status, &order.ID, note
+		return line, nil
+	}
+	return nil, errors.New("order not found")
+}
+
+// importStatement reconciles a statement CSV against the orders of repo
+func importStatement(t *testing.T, repo *memoryReconciliation, csv string) *models.BankStatement {
+	t.Helper()
+	statement, err := NewReconciliationService(repo, 24*time.Hour).ImportStatement(nil, "statement.csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("ImportStatement() error = %v", err)
+	}
+	return statement
+}
+
+func pendingOrder(code string, total float64, hour, minute int) *models.Order {
+	return &models.Order{
+		ID:         uuid.New(),
+		Code:       code,
+		TotalPrice: total,
+		Status:     models.OrderStatusPending,
+		CreatedAt:  time.Date(2026, 10, 19, hour, minute, 0, 0, time.Local),
+	}
+}
+
+func TestImportStatementMatchesTransfersToOrders(t *testing.T) {
+	referenced := pendingOrder("3f2a9c1e-7b44-4d2e-9a10-55e2c1d0b7aa", 1250, 9, 50)
+	onlyOne := pendingOrder("8d0b5e21-0c3f-4a7e-b1d2-7f3e9a6c4b10", 1499, 10, 0)
+	twinA := pendingOrder("c4e1a7b3-91f0-4c2d-8e6a-2b5d7f9e1a03", 800, 9, 0)
+	twinB := pendingOrder("e7f3c9a1-5b2d-4e8f-a0c6-1d4b8e2f7a95", 800, 10, 0)
+	later := pendingOrder("f1a2b3c4-d5e6-4f70-8a9b-0c1d2e3f4a5b", 800, 12, 0) // Placed after the transfer
+	repo := newMemoryReconciliation(referenced, onlyOne, twinA, twinB, later)
+
+	statement := importStatement(t, repo, `date,time,amount,reference,description
+2026-10-19,10:05,"1,250.00",PAY 3F2A9C1E,PromptPay transfer
+2026-10-19,10:30,"1,499.00",,Transfer from K. Somsak
+2026-10-19,10:30,"1,499.00",,Transfer from K. Somsak
+2026-10-19,11:00,-35.00,,Transfer fee
+2026-10-19,11:30,800.00,,Transfer
+`)
+
+	want := []struct {
+		status string
+		order  *models.Order
+	}{
+		{models.StatementLineMatched, referenced},
+		{models.StatementLineMatched, onlyOne},
+		{models.StatementLineUnmatched, nil}, // The only order of the amount was paid by the line above
+		{models.StatementLineIgnored, nil},
+		{models.StatementLineReview, nil}, // Two orders of the amount were placed before the transfer
+	}
+	for i, line := range statement.Lines {
+		if line.Status != want[i].status {
+			t.Errorf("line %d is %s (%s), want %s", line.LineNumber, line.Status, line.Note, want[i].status)
+		}
+		if want[i].order != nil && (line.OrderID == nil || *line.OrderID != want[i].order.ID) {
+			t.Errorf("line %d paid %v, want order %s", line.LineNumber, line.OrderID, want[i].order.Code)
+		}
+	}
+	review := statement.Lines[4].Candidates
+	if !strings.Contains(review, twinA.ID.String()) || !strings.Contains(review, twinB.ID.String()) || strings.Contains(review, later.ID.String()) {
+		t.Errorf("line 6 candidates = %s, want the two orders placed before the transfer", review)
+	}
+	if referenced.Status != models.OrderStatusPaid || onlyOne.Status != models.OrderStatusPaid || twinA.Status != models.OrderStatusPending {
+		t.Errorf("orders are %s, %s, %s; want paid, paid, pending", referenced.Status, onlyOne.Status, twinA.Status)
+	}
+}
+
+func TestImportStatementIgnoresLinesOfAnOverlappingStatement(t *testing.T) {
+	repo := newMemoryReconciliation(pendingOrder("8d0b5e21-0c3f-4a7e-b1d2-7f3e9a6c4b10", 1499, 10, 0))
+	importStatement(t, repo, `date,time,amount,description
+2026-10-19,10:30,1499.00,Transfer from K. Somsak
+`)
+	// The next day's export starts with the same transfer
+	statement := importStatement(t, repo, `date,time,amount,description
+2026-10-19,10:30,1499.00,Transfer from K. Somsak
+2026-10-20,09:00,1499.00,Transfer from K. Somsak
+`)
+	if statement.Lines[0].Status != models.StatementLineIgnored {
+		t.Errorf("line imported before is %s, want ignored", statement.Lines[0].Status)
+	}
+	if statement.Lines[1].Status != models.StatementLineUnmatched {
+		t.Errorf("new line is %s (%s), want unmatched", statement.Lines[1].Status, statement.Lines[1].Note)
+	}
+}
+
+func TestParseStatementCSV(t *testing.T) {
+	lines, err := parseStatementCSV(strings.NewReader(`Transaction_Date, Credit, Debit, Ref, Details
+19/10/2026 10:30,"1,499.00",,AB12,Transfer
+,,,,
+19/10/2026,,35.00,,Fee
+19/10/2026,THB 800,,,Transfer
+19/10/2026,THB 800,,,Transfer
+`))
+	if err != nil {
+		t.Fatalf("parseStatementCSV() error = %v", err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("parseStatementCSV() returned %d lines, want 4 without the blank row", len(lines))
+	}
+	first := lines[0]
+	wantAt := time.Date(2026, 10, 19, 10, 30, 0, 0, time.Local)
+	if !first.line.TransactedAt.Equal(wantAt) || first.dateOnly || first.line.Amount != 1499 || first.line.Reference != "AB12" || first.line.LineNumber != 2 {
+		t.Errorf("first line = %+v (date only %v), want row 2 of 1499.00 at %s", first.line, first.dateOnly, wantAt)
+	}
+	if lines[1].line.Amount != -35 || !lines[1].dateOnly || lines[1].line.LineNumber != 4 {
+		t.Errorf("fee line = %+v (date only %v), want a date-only debit of 35.00 on row 4", lines[1].line, lines[1].dateOnly)
+	}
+	// Identical transfers on one statement are told apart, yet fingerprint the same way in another statement
+	if lines[2].line.Fingerprint == lines[3].line.Fingerprint {
+		t.Error("identical lines of one statement have the same fingerprint")
+	}
+	again, err := parseStatementCSV(strings.NewReader("date,amount,description\n19/10/2026,800,Transfer\n"))
+	if err != nil {
+		t.Fatalf("parseStatementCSV() error = %v", err)
+	}
+	if again[0].line.Fingerprint != lines[2].line.Fingerprint {
+		t.Error("the same transfer fingerprints differently in another statement")
+	}
+
+	for csv, want := range map[string]string{
+		"amount,description\n800,Transfer\n":          "missing date column",
+		"date,description\n19/10/2026,Transfer\n":     "missing amount or credit column",
+		"date,amount\n19/10/2026,800\n2026-13-45,1\n": "row 3",
+		"date,amount\n19/10/2026,eight hundred\n":     "row 2: amount",
+		"date,amount\n": "no lines",
+	} {
+		if _, err := parseStatementCSV(strings.NewReader(csv)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseStatementCSV(%q) error = %v, want %q", csv, err, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+)
+
+// RoundLifecycle records SalesRoundOpened and SalesRoundClosed as rounds reach their start and end dates
+type RoundLifecycle interface {
+	// Start records due transitions now and then every interval in the background
+	Start(interval time.Duration)
+}
+
+type roundLifecycle struct {
+	salesRoundRepo repositories.SalesRoundRepository
+}
+
+// NewRoundLifecycle creates a new instance of RoundLifecycle
+func NewRoundLifecycle(salesRoundRepo repositories.SalesRoundRepository) RoundLifecycle {
+	return &roundLifecycle{salesRoundRepo: salesRoundRepo}
+}
+
+func (s *roundLifecycle) Start(interval time.Duration) {
+	go func() {
+		s.record(time.Now())
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			s.record(now)
+		}
+	}()
+}
+
+func (s *roundLifecycle) record(now time.Time) {
+	opened, closed, err := s.salesRoundRepo.RecordRoundTransitions(now)
+	if err != nil {
+		log.Printf("Error recording sales round transitions: %v", err)
+		return
+	}
+	if opened > 0 || closed > 0 {
+		log.Printf("Sales rounds opened: %d, closed: %d", opened, closed)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/notifications"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+)
+
+// RoundScheduler materializes sales round templates into real sales rounds ahead of time
+type RoundScheduler interface {
+	// Start materializes due rounds now and then every interval in the background
+	Start(interval time.Duration)
+	// MaterializeDue creates every round that starts within its template's lead time and does not exist yet
+	MaterializeDue()
+}
+
+type roundScheduler struct {
+	templateRepo repositories.SalesRoundTemplateRepository
+	notifier     notifications.Notifier
+
+	mu       sync.Mutex
+	reported map[string]bool // Failed occurrences already notified about, so a retry does not notify again
+}
+
+// NewRoundScheduler creates a new instance of RoundScheduler
+func NewRoundScheduler(templateRepo repositories.SalesRoundTemplateRepository, notifier notifications.Notifier) RoundScheduler {
+	return &roundScheduler{
+		templateRepo: templateRepo,
+		notifier:     notifier,
+		reported:     make(map[string]bool),
+	}
+}
+
+func (s *roundScheduler) Start(interval time.Duration) {
+	go func() {
+		s.MaterializeDue()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.MaterializeDue()
+		}
+	}()
+}
+
+func (s *roundScheduler) MaterializeDue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.templateRepo.GetActiveTemplates()
+	if err != nil {
+		log.Printf("Error fetching sales round templates: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, template := range templates {
+		from := now
+		if template.LastMaterializedAt != nil && template.LastMaterializedAt.After(from) {
+			from = *template.LastMaterializedAt
+		}
+		occurrences, err := template.NextOccurrences(from, now.AddDate(0, 0, template.LeadDays))
+		if err != nil {
+			log.Printf("Sales round template %s has an invalid schedule: %v", template.ID, err)
+			continue
+		}
+
+		for _, start := range occurrences {
+			if _, err := s.templateRepo.MaterializeTemplate(template.ID, start); err != nil {
+				// Later rounds wait until this one can be created, so rounds are never skipped silently
+				s.reportFailure(template, start, err)
+				break
+			}
+		}
+	}
+}
+
+func (s *roundScheduler) reportFailure(template models.SalesRoundTemplate, start time.Time, err error) {
+	log.Printf("Error materializing sales round template %s at %v: %v", template.ID, start, err)
+
+	key := fmt.Sprintf("%s/%d", template.ID, start.Unix())
+	if s.reported[key] {
+		return
+	}
+	s.reported[key] = true
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"template_id": template.ID,
+		"start_date":  start,
+		"error":       err.Error(),
+	})
+	notification := models.Notification{
+		Type:          models.NotificationRoundMaterializeFailed,
+		Title:         fmt.Sprintf("Could not create sales round %s", template.Name),
+		Message:       fmt.Sprintf("The round starting %s could not be created: %v", start.Format("2006-01-02 15:04"), err),
+		ReferenceType: "sales-round-template",
+		ReferenceID:   &template.ID,
+		Payload:       string(payload),
+	}
+	if err := s.notifier.Notify(&notification); err != nil {
+		log.Printf("Error sending notification: %v", err)
+	}
+}
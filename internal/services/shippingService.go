@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/carriers"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// shippingTrackBatchSize is the number of shipments polled per round
+const shippingTrackBatchSize = 50
+
+// ShippingService ships orders with the registered carriers: it quotes rates, books shipments and their labels,
+// and polls the carriers' tracking to keep shipments and their orders up to date
+type ShippingService interface {
+	// Carriers returns the names of the registered carriers
+	Carriers() []string
+	// QuoteRates returns what each service of a carrier charges to ship the parcels of an order
+	QuoteRates(orderID uuid.UUID, carrierName string, parcels []carriers.Parcel) ([]carriers.Rate, error)
+	// ShipWithCarrier books the shipment with its carrier, stores the tracking number and label it returns, and
+	// records the shipment. The booking is cancelled if the shipment cannot be recorded.
+	ShipWithCarrier(shipment *models.Shipment, service string, parcels []carriers.Parcel) error
+	// TrackShipment polls the carrier of a shipment for tracking events now
+	TrackShipment(id uuid.UUID) (*models.Shipment, error)
+	// Start polls the tracking of undelivered shipments now and then every interval in the background
+	Start(interval time.Duration)
+}
+
+type shippingService struct {
+	shipmentRepo repositories.ShipmentRepository
+	orderRepo    repositories.OrderRepository
+	customerRepo repositories.CustomerRepository
+	carriers     *carriers.Registry
+}
+
+// NewShippingService creates a new instance of ShippingService
+func NewShippingService(shipmentRepo repositories.ShipmentRepository, orderRepo repositories.OrderRepository, customerRepo repositories.CustomerRepository, registry *carriers.Registry) ShippingService {
+	return &shippingService{
+		shipmentRepo: shipmentRepo,
+		orderRepo:    orderRepo,
+		customerRepo: customerRepo,
+		carriers:     registry,
+	}
+}
+
+func (s *shippingService) Carriers() []string {
+	return s.carriers.Names()
+}
+
+func (s *shippingService) QuoteRates(orderID uuid.UUID, carrierName string, parcels []carriers.Parcel) ([]carriers.Rate, error) {
+	carrier, err := s.carriers.Get(carrierName)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	request, err := s.rateRequest(order, parcels)
+	if err != nil {
+		return nil, err
+	}
+	return carrier.QuoteRates(*request)
+}
+
+func (s *shippingService) ShipWithCarrier(shipment *models.Shipment, service string, parcels []carriers.Parcel) error {
+	carrier, err := s.carriers.Get(shipment.Carrier)
+	if err != nil {
+		return err
+	}
+	order, err := s.orderRepo.GetOrderByID(shipment.OrderID)
+	if err != nil {
+		return err
+	}
+	request, err := s.rateRequest(order, parcels)
+	if err != nil {
+		return err
+	}
+
+	label, err := carrier.CreateShipment(carriers.LabelRequest{
+		RateRequest: *request,
+		Reference:   order.Code,
+		Service:     service,
+	})
+	if err != nil {
+		return err
+	}
+	shipment.Carrier = carrier.Name()
+	shipment.TrackingNumber = label.TrackingNumber
+	shipment.Service = label.Service
+	shipment.ShippingCost = label.Amount
+	shipment.LabelFormat = label.Format
+	shipment.Label = label.Data
+	shipment.Status = models.ShipmentLabelCreated
+
+	if err := s.shipmentRepo.CreateShipment(shipment); err != nil {
+		if cancelErr := carrier.CancelShipment(label.TrackingNumber); cancelErr != nil {
+			log.Printf("Error cancelling %s shipment %s: %v", carrier.Name(), label.TrackingNumber, cancelErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *shippingService) TrackShipment(id uuid.UUID) (*models.Shipment, error) {
+	shipment, err := s.shipmentRepo.GetShipmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	carrier, err := s.carriers.Get(shipment.Carrier)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.TrackingNumber == "" {
+		return nil, carriers.ErrUnknownTracking
+	}
+	return s.track(carrier, shipment)
+}
+
+func (s *shippingService) Start(interval time.Duration) {
+	go func() {
+		s.poll(time.Now(), interval)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			s.poll(now, interval)
+		}
+	}()
+}
+
+// poll tracks the shipments that were not tracked in the last interval
+func (s *shippingService) poll(now time.Time, interval time.Duration) {
+	before := now.Add(-interval / 2)
+	for {
+		shipments, err := s.shipmentRepo.GetShipmentsToTrack(s.carriers.Names(), before, shippingTrackBatchSize)
+		if err != nil {
+			log.Printf("Error loading shipments to track: %v", err)
+			return
+		}
+		for i := range shipments {
+			carrier, err := s.carriers.Get(shipments[i].Carrier)
+			if err != nil {
+				continue
+			}
+			if _, err := s.track(carrier, &shipments[i]); err != nil {
+				log.Printf("Error tracking %s shipment %s: %v", shipments[i].Carrier, shipments[i].TrackingNumber, err)
+			}
+		}
+		if len(shipments) < shippingTrackBatchSize {
+			return
+		}
+	}
+}
+
+// track fetches the tracking events of a shipment and records them. A tracking number the carrier no longer
+// knows is still recorded as tracked, so it does not hold up the other shipments.
+func (s *shippingService) track(carrier carriers.Carrier, shipment *models.Shipment) (*models.Shipment, error) {
+	events, err := carrier.TrackingEvents(shipment.TrackingNumber)
+	if err != nil && !errors.Is(err, carriers.ErrUnknownTracking) {
+		return nil, err
+	}
+
+	status := ""
+	records := make([]models.ShipmentTrackingEvent, 0, len(events))
+	for _, event := range events {
+		records = append(records, models.ShipmentTrackingEvent{
+			Status:      event.Status,
+			OccurredAt:  event.OccurredAt,
+			Description: event.Description,
+			Location:    event.Location,
+		})
+		if mapped := shipmentStatusFor(event.Status); mapped != "" {
+			status = mapped
+		}
+	}
+	updated, recordErr := s.shipmentRepo.RecordTracking(shipment.ID, records, status)
+	if recordErr != nil {
+		return nil, recordErr
+	}
+	return updated, err
+}
+
+// shipmentStatusFor returns the shipment status a carrier tracking status moves a shipment to, or an empty string
+// when it does not move it
+func shipmentStatusFor(trackingStatus string) string {
+	switch trackingStatus {
+	case carriers.TrackingPickedUp:
+		return models.ShipmentShipped
+	case carriers.TrackingInTransit, carriers.TrackingOutForDelivery, carriers.TrackingFailedAttempt:
+		return models.ShipmentInTransit
+	case carriers.TrackingDelivered:
+		return models.ShipmentDelivered
+	case carriers.TrackingReturned:
+		return models.ShipmentReturned
+	}
+	return ""
+}
+
+// rateRequest describes shipping the parcels of an order from its store to its delivery address
+func (s *shippingService) rateRequest(order *models.Order, parcels []carriers.Parcel) (*carriers.RateRequest, error) {
+	store, err := s.orderRepo.GetOrderStore(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &carriers.RateRequest{
+		Origin: carriers.Address{
+			Name:       store.StoreName,
+			Line1:      store.Location,
+			PostalCode: carriers.PostalCodeFrom(store.Location),
+			Country:    "TH",
+		},
+		Destination:   s.destination(order),
+		Parcels:       parcels,
+		DeclaredValue: order.TotalPrice,
+	}, nil
+}
+
+// destination is the address an order ships to: the copy of the address book entry it was placed with, or else
+// its free-form delivery address addressed to the customer
+func (s *shippingService) destination(order *models.Order) carriers.Address {
+	if address := order.ShippingAddress; !address.IsZero() {
+		return carriers.Address{
+			Name:        address.Recipient,
+			Phone:       address.Phone,
+			Line1:       address.HouseNumber,
+			SubDistrict: address.Subdistrict,
+			District:    address.District,
+			Province:    address.Province,
+			PostalCode:  address.Postcode,
+			Country:     "TH",
+		}
+	}
+	recipient := ""
+	if customer, err := s.customerRepo.GetCustomerByID(order.CustomerID); err == nil {
+		recipient = customer.Name
+	}
+	return carriers.Address{
+		Name:       recipient,
+		Line1:      order.DeliveryAddress,
+		PostalCode: carriers.PostalCodeFrom(order.DeliveryAddress),
+		Country:    "TH",
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/carriers"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// memoryShipments records shipments and the tracking reported for them
+type memoryShipments struct {
+	repositories.ShipmentRepository
+	shipments map[uuid.UUID]*models.Shipment
+	err       error
+}
+
+func (r *memoryShipments) CreateShipment(shipment *models.Shipment) error {
+	if r.err != nil {
+		return r.err
+	}
+	shipment.ID = uuid.New()
+	r.shipments[shipment.ID] = shipment
+	return nil
+}
+
+func (r *memoryShipments) GetShipmentByID(id uuid.UUID) (*models.Shipment, error) {
+	return r.shipments[id], nil
+}
+
+func (r *memoryShipments) RecordTracking(id uuid.UUID, events []models.ShipmentTrackingEvent, status string) (*models.Shipment, error) {
+	shipment := r.shipments[id]
+	shipment.TrackingEvents = events
+	if status != "" {
+		shipment.Status = status
+	}
+	return shipment, nil
+}
+
+// shippedOrder is an order placed at a Bangkok store for delivery to Chiang Mai
+type shippedOrder struct {
+	repositories.OrderRepository
+	order models.Order
+}
+
+func (r *shippedOrder) GetOrderByID(id uuid.UUID) (*models.Order, error) {
+	return &r.order, nil
+}
+
+func (r *shippedOrder) GetOrderStore(id uuid.UUID) (*models.Store, error) {
+	return &models.Store{StoreName: "Siam Store", Location: "99 Rama I Rd, Pathum Wan, Bangkok 10330"}, nil
+}
+
+func newTestShippingService(shipments *memoryShipments, carrier carriers.Carrier) ShippingService {
+	orders := &shippedOrder{order: models.Order{
+		ID:              uuid.New(),
+		Code:            "ORD-1",
+		DeliveryAddress: "12 Nimmanhaemin Rd, Mueang Chiang Mai, Chiang Mai 50200",
+		TotalPrice:      1200,
+		ShippingAddress: models.PostalAddress{Recipient: "Somchai", Postcode: "50200", Province: "Chiang Mai"},
+	}}
+	return NewShippingService(shipments, orders, nil, carriers.NewRegistry(carrier))
+}
+
+func TestShipWithTheFakeCarrierAndTrackIt(t *testing.T) {
+	carrier := carriers.NewFakeCarrier(time.Hour)
+	shipments := &memoryShipments{shipments: map[uuid.UUID]*models.Shipment{}}
+	service := newTestShippingService(shipments, carrier)
+
+	shipment := &models.Shipment{Carrier: carrier.Name()}
+	if err := service.ShipWithCarrier(shipment, carriers.FakeServiceStandard, []carriers.Parcel{{WeightGrams: 1500}}); err != nil {
+		t.Fatalf("ShipWithCarrier() error = %v", err)
+	}
+	// 2 kg across provinces
+	if shipment.Status != models.ShipmentLabelCreated || shipment.TrackingNumber == "" || shipment.ShippingCost != 60 || len(shipment.Label) == 0 {
+		t.Errorf("shipment is %s with tracking %q for %.2f, want a 60.00 label", shipment.Status, shipment.TrackingNumber, shipment.ShippingCost)
+	}
+
+	// Announced to the carrier only; the shipment waits to be picked up
+	tracked, err := service.TrackShipment(shipment.ID)
+	if err != nil {
+		t.Fatalf("TrackShipment() error = %v", err)
+	}
+	if tracked.Status != models.ShipmentLabelCreated || len(tracked.TrackingEvents) != 1 {
+		t.Errorf("after booking the shipment is %s with %d events, want label_created with 1", tracked.Status, len(tracked.TrackingEvents))
+	}
+
+	// Three hours later the parcel is out for delivery
+	shipment.TrackingNumber = fmt.Sprintf("FK%010d%02dTH", time.Now().Add(-3*time.Hour).Unix(), 1)
+	tracked, err = service.TrackShipment(shipment.ID)
+	if err != nil {
+		t.Fatalf("TrackShipment() error = %v", err)
+	}
+	if tracked.Status != models.ShipmentInTransit || len(tracked.TrackingEvents) != 4 {
+		t.Errorf("three hours on the shipment is %s with %d events, want in_transit with 4", tracked.Status, len(tracked.TrackingEvents))
+	}
+}
+
+func TestShipWithCarrierCancelsALabelItCannotRecord(t *testing.T) {
+	carrier := carriers.NewFakeCarrier(time.Hour)
+	shipments := &memoryShipments{shipments: map[uuid.UUID]*models.Shipment{}, err: errors.New("order is not paid")}
+	service := newTestShippingService(shipments, carrier)
+
+	shipment := &models.Shipment{Carrier: carrier.Name()}
+	if err := service.ShipWithCarrier(shipment, carriers.FakeServiceStandard, []carriers.Parcel{{WeightGrams: 500}}); err == nil {
+		t.Fatal("ShipWithCarrier() succeeded for a shipment that was not recorded")
+	}
+	if _, err := carrier.TrackingEvents(shipment.TrackingNumber); !errors.Is(err, carriers.ErrUnknownTracking) {
+		t.Errorf("the label of the unrecorded shipment is still booked: TrackingEvents() error = %v", err)
+	}
+}
+
+func TestShipmentStatusFor(t *testing.T) {
+	tests := map[string]string{
+		carriers.TrackingInfoReceived:   "",
+		carriers.TrackingPickedUp:       models.ShipmentShipped,
+		carriers.TrackingInTransit:      models.ShipmentInTransit,
+		carriers.TrackingOutForDelivery: models.ShipmentInTransit,
+		carriers.TrackingFailedAttempt:  models.ShipmentInTransit,
+		carriers.TrackingDelivered:      models.ShipmentDelivered,
+		carriers.TrackingReturned:       models.ShipmentReturned,
+		"customs_hold":                  "",
+	}
+	for trackingStatus, want := range tests {
+		if got := shipmentStatusFor(trackingStatus); got != want {
+			t.Errorf("shipmentStatusFor(%q) = %q, want %q", trackingStatus, got, want)
+		}
+	}
+}
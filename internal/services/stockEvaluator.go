@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/notifications"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// StockEvaluator checks variants against their reorder point in the background after their stock changed
+// and raises a low-stock notification the first time a variant drops to or below it.
+type StockEvaluator interface {
+	repositories.StockObserver
+	Start()
+}
+
+type stockEvaluator struct {
+	productVariantRepo repositories.ProductVariantRepository
+	productRepo        repositories.ProductRepository
+	notifier           notifications.Notifier
+
+	queue   chan uuid.UUID
+	mu      sync.Mutex
+	pending map[uuid.UUID]bool
+}
+
+// NewStockEvaluator creates a new instance of StockEvaluator
+func NewStockEvaluator(
+	productVariantRepo repositories.ProductVariantRepository,
+	productRepo repositories.ProductRepository,
+	notifier notifications.Notifier,
+) StockEvaluator {
+	return &stockEvaluator{
+		productVariantRepo: productVariantRepo,
+		productRepo:        productRepo,
+		notifier:           notifier,
+		queue:              make(chan uuid.UUID, 1024),
+		pending:            make(map[uuid.UUID]bool),
+	}
+}
+
+// StockChanged queues a variant for evaluation; a variant already waiting in the queue is not queued twice
+func (e *stockEvaluator) StockChanged(variantID uuid.UUID) {
+	e.mu.Lock()
+	if e.pending[variantID] {
+		e.mu.Unlock()
+		return
+	}
+	e.pending[variantID] = true
+	e.mu.Unlock()
+
+	select {
+	case e.queue <- variantID:
+	default:
+		// Never block the caller's request; the next stock change will queue the variant again
+		log.Printf("Stock evaluator queue is full, skipping variant %v", variantID)
+		e.mu.Lock()
+		delete(e.pending, variantID)
+		e.mu.Unlock()
+	}
+}
+
+func (e *stockEvaluator) Start() {
+	go func() {
+		for variantID := range e.queue {
+			e.mu.Lock()
+			delete(e.pending, variantID)
+			e.mu.Unlock()
+
+			if err := e.evaluate(variantID); err != nil {
+				log.Printf("Error evaluating stock of variant %v: %v", variantID, err)
+			}
+		}
+	}()
+}
+
+func (e *stockEvaluator) evaluate(variantID uuid.UUID) error {
+	variant, err := e.productVariantRepo.GetProductVariantByID(variantID)
+	if err != nil {
+		return err
+	}
+	if variant.ReorderPoint <= 0 {
+		return nil
+	}
+
+	onHand, err := e.productVariantRepo.GetVariantOnHand(variantID)
+	if err != nil {
+		return err
+	}
+
+	// Stock recovered (e.g. goods received): arm the alert again
+	if onHand > variant.ReorderPoint {
+		if variant.LowStockAlerted {
+			return e.productVariantRepo.SetLowStockAlerted(variantID, false)
+		}
+		return nil
+	}
+	if variant.LowStockAlerted {
+		return nil
+	}
+
+	product, err := e.productRepo.GetProductByID(variant.ProductID)
+	if err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"variant_id":       variant.VariantID,
+		"product_id":       variant.ProductID,
+		"sku_code":         variant.SKUCode,
+		"on_hand":          onHand,
+		"reorder_point":    variant.ReorderPoint,
+		"reorder_quantity": variant.ReorderQuantity,
+	})
+	storeID := product.StoreID
+	notification := models.Notification{
+		Type:          models.NotificationLowStock,
+		StoreID:       &storeID,
+		Title:         fmt.Sprintf("Low stock: %s (%s)", product.ProductName, variant.SKUCode),
+		Message:       fmt.Sprintf("%d on hand, reorder point is %d; suggested reorder quantity %d", onHand, variant.ReorderPoint, variant.ReorderQuantity),
+		ReferenceType: "product-variant",
+		ReferenceID:   &variant.VariantID,
+		Payload:       string(payload),
+	}
+	if err := e.notifier.Notify(&notification); err != nil {
+		return err
+	}
+	return e.productVariantRepo.SetLowStockAlerted(variantID, true)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/notifications"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// WaitlistService keeps the waitlists of sold-out round variants. Whenever quantity of a variant is released,
+// by a cancelled order, a larger allocation or an expired offer, it is offered to the waiting customers in
+// the order they joined and held for them for a limited time.
+type WaitlistService interface {
+	repositories.StockObserver
+	// Start processes released quantity and expires offers in the background
+	Start()
+	JoinWaitlist(entry *models.WaitlistEntry) error
+	GetWaitlist(roundID uuid.UUID, variantID *uuid.UUID, status string) ([]models.WaitlistEntry, error)
+	LeaveWaitlist(id uuid.UUID) (*models.WaitlistEntry, error)
+}
+
+type waitlistService struct {
+	waitlistRepo repositories.WaitlistRepository
+	notifier     notifications.Notifier
+	holdFor      time.Duration
+
+	queue   chan uuid.UUID
+	mu      sync.Mutex
+	pending map[uuid.UUID]bool
+}
+
+// NewWaitlistService creates a new instance of WaitlistService; offers hold the quantity for holdFor
+func NewWaitlistService(waitlistRepo repositories.WaitlistRepository, notifier notifications.Notifier, holdFor time.Duration) WaitlistService {
+	return &waitlistService{
+		waitlistRepo: waitlistRepo,
+		notifier:     notifier,
+		holdFor:      holdFor,
+		queue:        make(chan uuid.UUID, 1024),
+		pending:      make(map[uuid.UUID]bool),
+	}
+}
+
+// StockChanged queues a variant to have its released quantity offered to waiting customers
+func (s *waitlistService) StockChanged(variantID uuid.UUID) {
+	s.mu.Lock()
+	if s.pending[variantID] {
+		s.mu.Unlock()
+		return
+	}
+	s.pending[variantID] = true
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- variantID:
+	default:
+		// Never block the caller; the expiry sweep or the next change will pick the variant up again
+		log.Printf("Waitlist queue is full, skipping variant %v", variantID)
+		s.mu.Lock()
+		delete(s.pending, variantID)
+		s.mu.Unlock()
+	}
+}
+
+func (s *waitlistService) Start() {
+	go func() {
+		for variantID := range s.queue {
+			s.mu.Lock()
+			delete(s.pending, variantID)
+			s.mu.Unlock()
+
+			if err := s.offer(variantID); err != nil {
+				log.Printf("Error offering released quantity of variant %v: %v", variantID, err)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			variantIDs, err := s.waitlistRepo.ExpireOffers()
+			if err != nil {
+				log.Printf("Error expiring waitlist offers: %v", err)
+				continue
+			}
+			for _, variantID := range variantIDs {
+				s.StockChanged(variantID)
+			}
+		}
+	}()
+}
+
+func (s *waitlistService) JoinWaitlist(entry *models.WaitlistEntry) error {
+	if err := s.waitlistRepo.JoinWaitlist(entry); err != nil {
+		return err
+	}
+	// Quantity may already be free, e.g. released while nobody was waiting
+	s.StockChanged(entry.VariantID)
+	return nil
+}
+
+func (s *waitlistService) GetWaitlist(roundID uuid.UUID, variantID *uuid.UUID, status string) ([]models.WaitlistEntry, error) {
+	return s.waitlistRepo.GetWaitlist(roundID, variantID, status)
+}
+
+func (s *waitlistService) LeaveWaitlist(id uuid.UUID) (*models.WaitlistEntry, error) {
+	entry, err := s.waitlistRepo.LeaveWaitlist(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status == models.WaitlistOffered {
+		s.StockChanged(entry.VariantID)
+	}
+	entry.Status = models.WaitlistCancelled
+	return entry, nil
+}
+
+func (s *waitlistService) offer(variantID uuid.UUID) error {
+	offers, err := s.waitlistRepo.OfferReleasedQuantity(variantID, s.holdFor)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range offers {
+		entryID := entry.ID
+		payload, _ := json.Marshal(map[string]interface{}{
+			"waitlist_entry_id": entry.ID,
+			"customer_id":       entry.CustomerID,
+			"round_id":          entry.RoundID,
+			"variant_id":        entry.VariantID,
+			"quantity":          entry.OfferedQuantity,
+			"expires_at":        entry.OfferExpiresAt,
+		})
+		notification := models.Notification{
+			Type:          models.NotificationWaitlistOffer,
+			Title:         "Your waitlisted item is available",
+			Message:       fmt.Sprintf("%d held for you until %s", entry.OfferedQuantity, entry.OfferExpiresAt.Format("2006-01-02 15:04:05")),
+			ReferenceType: "waitlist-entry",
+			ReferenceID:   &entryID,
+			Payload:       string(payload),
+		}
+		if err := s.notifier.Notify(&notification); err != nil {
+			log.Printf("Error sending waitlist offer %v: %v", entry.ID, err)
+		}
+	}
+	return nil
+}
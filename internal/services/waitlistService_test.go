@@ -0,0 +1,97 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// releasedWaitlist offers its entries once for any variant; it stands in for the round quantity checks
+type releasedWaitlist struct {
+	repositories.WaitlistRepository
+	offers  []models.WaitlistEntry
+	left    *models.WaitlistEntry
+	offered []uuid.UUID
+}
+
+func (r *releasedWaitlist) OfferReleasedQuantity(variantID uuid.UUID, holdFor time.Duration) ([]models.WaitlistEntry, error) {
+	r.offered = append(r.offered, variantID)
+	offers := r.offers
+	r.offers = nil
+	return offers, nil
+}
+
+func (r *releasedWaitlist) LeaveWaitlist(id uuid.UUID) (*models.WaitlistEntry, error) {
+	entry := *r.left
+	return &entry, nil
+}
+
+type sentNotifications []models.Notification
+
+func (n *sentNotifications) Notify(notification *models.Notification) error {
+	*n = append(*n, *notification)
+	return nil
+}
+
+func TestStockChangedQueuesAVariantOnce(t *testing.T) {
+	service := NewWaitlistService(&releasedWaitlist{}, &sentNotifications{}, time.Minute).(*waitlistService)
+	variantID := uuid.New()
+	service.StockChanged(variantID)
+	service.StockChanged(variantID)
+	service.StockChanged(uuid.New())
+	if len(service.queue) != 2 {
+		t.Errorf("queue holds %d variants, want 2", len(service.queue))
+	}
+}
+
+func TestOfferNotifiesEveryCustomer(t *testing.T) {
+	expiresAt := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	offers := []models.WaitlistEntry{
+		{ID: uuid.New(), CustomerID: uuid.New(), Status: models.WaitlistOffered, OfferedQuantity: 2, OfferExpiresAt: &expiresAt},
+		{ID: uuid.New(), CustomerID: uuid.New(), Status: models.WaitlistOffered, OfferedQuantity: 1, OfferExpiresAt: &expiresAt},
+	}
+	var sent sentNotifications
+	service := NewWaitlistService(&releasedWaitlist{offers: offers}, &sent, 15*time.Minute).(*waitlistService)
+
+	if err := service.offer(uuid.New()); err != nil {
+		t.Fatalf("offer() error = %v", err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("offer() sent %d notifications, want 2", len(sent))
+	}
+	for i, notification := range sent {
+		if notification.Type != models.NotificationWaitlistOffer || *notification.ReferenceID != offers[i].ID {
+			t.Errorf("notification %d = %s for %v, want a waitlist offer for %v", i, notification.Type, notification.ReferenceID, offers[i].ID)
+		}
+		if !strings.Contains(notification.Payload, offers[i].CustomerID.String()) {
+			t.Errorf("notification %d payload %s does not name customer %v", i, notification.Payload, offers[i].CustomerID)
+		}
+	}
+	if !strings.HasPrefix(sent[0].Message, "2 held for you until 2026-03-01 10:15:00") {
+		t.Errorf("notification message = %q", sent[0].Message)
+	}
+}
+
+func TestLeavingWithAnOfferReleasesItsQuantity(t *testing.T) {
+	for _, status := range []string{models.WaitlistWaiting, models.WaitlistOffered} {
+		entry := &models.WaitlistEntry{ID: uuid.New(), VariantID: uuid.New(), Status: status}
+		service := NewWaitlistService(&releasedWaitlist{left: entry}, &sentNotifications{}, time.Minute).(*waitlistService)
+
+		left, err := service.LeaveWaitlist(entry.ID)
+		if err != nil {
+			t.Fatalf("LeaveWaitlist() error = %v", err)
+		}
+		if left.Status != models.WaitlistCancelled {
+			t.Errorf("LeaveWaitlist() of a %s entry = %s, want %s", status, left.Status, models.WaitlistCancelled)
+		}
+		// Only held quantity is released; a waiting entry held nothing
+		queued := len(service.queue) == 1
+		if queued != (status == models.WaitlistOffered) {
+			t.Errorf("LeaveWaitlist() of a %s entry queued the variant: %v", status, queued)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/events"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookBatchSize       = 20
+	webhookTimeout         = 10 * time.Second
+	webhookResponseBodyMax = 1024 // Bytes of the response body kept in the delivery log
+)
+
+// WebhookService sends domain events to the webhook subscriptions of the stores they concern. It is an outbox
+// sink: relayed events become pending deliveries, which are then sent, signed, and retried with exponential
+// backoff until the endpoint answers 2xx or the attempts run out.
+type WebhookService interface {
+	events.Sink
+	// Start sends due deliveries every interval in the background
+	Start(interval time.Duration)
+	// DeliverPending sends due deliveries until none are left and returns how many succeeded
+	DeliverPending() (int, error)
+	CreateSubscription(subscription *models.WebhookSubscription) error
+	GetSubscriptions(storeID uuid.UUID) ([]models.WebhookSubscription, error)
+	GetSubscriptionByID(id uuid.UUID) (*models.WebhookSubscription, error)
+	UpdateSubscription(subscription *models.WebhookSubscription) error
+	DeleteSubscription(id uuid.UUID) error
+	GetDeliveries(subscriptionID uuid.UUID, status string) ([]models.WebhookDelivery, error)
+	// SendTest sends a webhook.test event to a subscription right away and returns the delivery
+	SendTest(subscriptionID uuid.UUID) (*models.WebhookDelivery, error)
+	// Replay sends the payload of a delivery again as a new delivery, right away
+	Replay(deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	webhookRepo repositories.WebhookRepository
+	client      *http.Client
+
+	mu sync.Mutex
+}
+
+// NewWebhookService creates a new instance of WebhookService
+func NewWebhookService(webhookRepo repositories.WebhookRepository) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		client:      webhooks.NewClient(webhookTimeout),
+	}
+}
+
+// Publish enqueues deliveries of a relayed event; the relay retries it if that fails
+func (s *webhookService) Publish(envelope events.Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	_, err = s.webhookRepo.EnqueueDeliveries(envelope, payload)
+	return err
+}
+
+func (s *webhookService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := s.DeliverPending(); err != nil {
+				log.Printf("Error delivering webhooks: %v", err)
+			}
+		}
+	}()
+}
+
+func (s *webhookService) DeliverPending() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for {
+		succeeded, failed, err := s.webhookRepo.DeliverPending(webhookBatchSize, s.send)
+		total += succeeded
+		if err != nil {
+			return total, err
+		}
+		if succeeded+failed < webhookBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *webhookService) CreateSubscription(subscription *models.WebhookSubscription) error {
+	if subscription.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			return err
+		}
+		subscription.Secret = secret
+	}
+	return s.webhookRepo.CreateSubscription(subscription)
+}
+
+func (s *webhookService) GetSubscriptions(storeID uuid.UUID) ([]models.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscriptions(storeID)
+}
+
+func (s *webhookService) GetSubscriptionByID(id uuid.UUID) (*models.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscriptionByID(id)
+}
+
+func (s *webhookService) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	return s.webhookRepo.UpdateSubscription(subscription)
+}
+
+func (s *webhookService) DeleteSubscription(id uuid.UUID) error {
+	return s.webhookRepo.DeleteSubscription(id)
+}
+
+func (s *webhookService) GetDeliveries(subscriptionID uuid.UUID, status string) ([]models.WebhookDelivery, error) {
+	return s.webhookRepo.GetDeliveries(subscriptionID, status)
+}
+
+func (s *webhookService) SendTest(subscriptionID uuid.UUID) (*models.WebhookDelivery, error) {
+	subscription, err := s.webhookRepo.GetSubscriptionByID(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"store_id":        subscription.StoreID,
+		"message":         "This is a test event",
+	})
+	if err != nil {
+		return nil, err
+	}
+	envelope := events.Envelope{
+		ID:            uuid.New(),
+		Type:          models.EventWebhookTest,
+		AggregateType: "webhook-subscription",
+		AggregateID:   subscription.ID,
+		OccurredAt:    time.Now(),
+		Payload:       data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        envelope.ID,
+		EventType:      envelope.Type,
+		Payload:        string(payload),
+	}
+	return delivery, s.sendNow(delivery, *subscription)
+}
+
+func (s *webhookService) Replay(deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	original, err := s.webhookRepo.GetDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := s.webhookRepo.GetSubscriptionByID(original.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		ReplayOfID:     &original.ID,
+	}
+	return delivery, s.sendNow(delivery, *subscription)
+}
+
+// sendNow creates the delivery and sends it at once. The background worker leaves it alone for a minute, so it
+// only picks the delivery up if this attempt was never recorded.
+func (s *webhookService) sendNow(delivery *models.WebhookDelivery, subscription models.WebhookSubscription) error {
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.NextAttemptAt = time.Now().Add(time.Minute)
+	if err := s.webhookRepo.CreateDelivery(delivery); err != nil {
+		return err
+	}
+	return s.webhookRepo.RecordAttempt(delivery, s.send(*delivery, subscription))
+}
+
+// send posts the delivery's payload to the subscription URL, signed with its secret
+func (s *webhookService) send(delivery models.WebhookDelivery, subscription models.WebhookSubscription) repositories.WebhookAttempt {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return repositories.WebhookAttempt{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "InventoryMarketplaceSystem-Webhooks/1.0")
+	req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(subscription.Secret, time.Now(), body))
+	req.Header.Set(webhooks.EventTypeHeader, delivery.EventType)
+	req.Header.Set(webhooks.EventIDHeader, delivery.EventID.String())
+	req.Header.Set(webhooks.DeliveryIDHeader, delivery.ID.String())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return repositories.WebhookAttempt{Err: err}
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyMax))
+	attempt := repositories.WebhookAttempt{
+		ResponseCode: resp.StatusCode,
+		ResponseBody: strings.ReplaceAll(strings.ToValidUTF8(string(responseBody), ""), "\x00", ""),
+		Err:          err,
+	}
+	if attempt.Err == nil && !attempt.Succeeded() {
+		attempt.Err = fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return attempt
+}
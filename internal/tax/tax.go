@@ -0,0 +1,137 @@
+// Package tax computes the value added tax of order lines. Each line is taxed at the rate of its tax category,
+// which a store can override, and prices either include VAT (the tax is extracted from them) or exclude it (the
+// tax is added on top). Amounts are rounded to the satang per line, as on a Thai tax invoice.
+package tax
+
+import (
+	"errors"
+	"math"
+)
+
+// Tax categories of products
+const (
+	CategoryStandard  = "standard"   // Taxed at the standard VAT rate
+	CategoryZeroRated = "zero_rated" // Taxable at 0%, e.g. exported goods; reported separately from exempt sales
+	CategoryExempt    = "exempt"     // Outside VAT, e.g. unprocessed agricultural products
+)
+
+// StandardRate is the Thai standard VAT rate, in percent
+const StandardRate = 7.0
+
+// ErrUnknownCategory is returned for a tax category that does not exist
+var ErrUnknownCategory = errors.New("unknown tax category")
+
+// Categories returns every tax category
+func Categories() []string {
+	return []string{CategoryStandard, CategoryZeroRated, CategoryExempt}
+}
+
+// ValidCategory reports whether category is a tax category
+func ValidCategory(category string) bool {
+	for _, c := range Categories() {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRate returns the rate, in percent, of a category when a store does not configure one
+func DefaultRate(category string) float64 {
+	if category == CategoryStandard || category == "" {
+		return StandardRate
+	}
+	return 0
+}
+
+// Config is how a store taxes its sales
+type Config struct {
+	PricesIncludeTax bool               // Prices are VAT-inclusive; the tax is part of them
+	Rates            map[string]float64 // Rate in percent by category, overriding DefaultRate
+}
+
+// Rate returns the rate, in percent, the config taxes a category at. Exempt sales are never taxed.
+func (c Config) Rate(category string) float64 {
+	if category == CategoryExempt {
+		return 0
+	}
+	if rate, ok := c.Rates[category]; ok {
+		return rate
+	}
+	return DefaultRate(category)
+}
+
+// Line is an order line to tax
+type Line struct {
+	Category  string
+	UnitPrice float64
+	Quantity  int
+	Discount  float64 // Taken off UnitPrice x Quantity before the line is taxed
+}
+
+// LineTax is the tax of a line
+type LineTax struct {
+	Category string
+	Rate     float64 // Percent
+	Net      float64 // Amount before VAT
+	Tax      float64
+	Gross    float64 // Amount the customer pays, Net + Tax
+}
+
+// Compute taxes a line
+func (c Config) Compute(line Line) LineTax {
+	category := line.Category
+	if category == "" {
+		category = CategoryStandard
+	}
+	rate := c.Rate(category)
+	amount := Round(line.UnitPrice*float64(line.Quantity) - line.Discount)
+
+	result := LineTax{Category: category, Rate: rate}
+	if c.PricesIncludeTax {
+		result.Gross = amount
+		result.Tax = Round(amount * rate / (100 + rate))
+		result.Net = Round(amount - result.Tax)
+	} else {
+		result.Net = amount
+		result.Tax = Round(amount * rate / 100)
+		result.Gross = Round(amount + result.Tax)
+	}
+	return result
+}
+
+// Round rounds an amount half away from zero to the satang
+func Round(amount float64) float64 {
+	// The epsilon keeps amounts such as 1.005, stored as 1.00499..., from rounding down
+	return math.Round(amount*100+math.Copysign(1e-9, amount)) / 100
+}
+
+// ValidTaxID reports whether id is a 13-digit Thai tax ID with a valid check digit
+func ValidTaxID(id string) bool {
+	if len(id) != 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if id[i] < '0' || id[i] > '9' {
+			return false
+		}
+		if i < 12 {
+			sum += int(id[i]-'0') * (13 - i)
+		}
+	}
+	return int(id[12]-'0') == (11-sum%11)%10
+}
+
+// ValidBranchCode reports whether code is a 5-digit branch code, 00000 being the head office
+func ValidBranchCode(code string) bool {
+	if len(code) != 5 {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
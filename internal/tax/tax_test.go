@@ -0,0 +1,95 @@
+package tax
+
+import "testing"
+
+func TestCompute(t *testing.T) {
+	inclusive := Config{PricesIncludeTax: true}
+	exclusive := Config{}
+	tests := []struct {
+		name   string
+		config Config
+		line   Line
+		want   LineTax
+	}{
+		{
+			name:   "inclusive, tax extracted from the price",
+			config: inclusive,
+			line:   Line{UnitPrice: 107, Quantity: 1},
+			want:   LineTax{Category: CategoryStandard, Rate: 7, Net: 100, Tax: 7, Gross: 107},
+		},
+		{
+			name:   "inclusive, tax rounded to the satang",
+			config: inclusive,
+			line:   Line{UnitPrice: 100, Quantity: 1},
+			want:   LineTax{Category: CategoryStandard, Rate: 7, Net: 93.46, Tax: 6.54, Gross: 100},
+		},
+		{
+			name:   "inclusive, discount taken off before tax",
+			config: inclusive,
+			line:   Line{Category: CategoryStandard, UnitPrice: 50, Quantity: 2, Discount: 10},
+			want:   LineTax{Category: CategoryStandard, Rate: 7, Net: 84.11, Tax: 5.89, Gross: 90},
+		},
+		{
+			name:   "exclusive, tax added on top",
+			config: exclusive,
+			line:   Line{UnitPrice: 19.99, Quantity: 3},
+			want:   LineTax{Category: CategoryStandard, Rate: 7, Net: 59.97, Tax: 4.2, Gross: 64.17},
+		},
+		{
+			name:   "store rate override",
+			config: Config{Rates: map[string]float64{CategoryStandard: 10}},
+			line:   Line{UnitPrice: 100, Quantity: 1},
+			want:   LineTax{Category: CategoryStandard, Rate: 10, Net: 100, Tax: 10, Gross: 110},
+		},
+		{
+			name:   "zero rated",
+			config: exclusive,
+			line:   Line{Category: CategoryZeroRated, UnitPrice: 100, Quantity: 1},
+			want:   LineTax{Category: CategoryZeroRated, Rate: 0, Net: 100, Tax: 0, Gross: 100},
+		},
+		{
+			name:   "exempt even when a store sets a rate for it",
+			config: Config{PricesIncludeTax: true, Rates: map[string]float64{CategoryExempt: 7}},
+			line:   Line{Category: CategoryExempt, UnitPrice: 100, Quantity: 1},
+			want:   LineTax{Category: CategoryExempt, Rate: 0, Net: 100, Tax: 0, Gross: 100},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.config.Compute(tt.line); got != tt.want {
+			t.Errorf("%s: Compute() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := map[float64]float64{
+		1.005:  1.01,
+		2.675:  2.68,
+		-1.005: -1.01,
+		0.004:  0,
+		6.545:  6.55,
+		64.17:  64.17,
+	}
+	for amount, want := range tests {
+		if got := Round(amount); got != want {
+			t.Errorf("Round(%v) = %v, want %v", amount, got, want)
+		}
+	}
+}
+
+func TestValidTaxID(t *testing.T) {
+	tests := map[string]bool{
+		"0105553000016":  true,
+		"3100100123451":  true,
+		"0105553000017":  false, // Wrong check digit
+		"010555300001":   false,
+		"01055530000160": false,
+		"010555300001a":  false,
+		"":               false,
+	}
+	for id, want := range tests {
+		if got := ValidTaxID(id); got != want {
+			t.Errorf("ValidTaxID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
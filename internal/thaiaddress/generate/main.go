@@ -0,0 +1,143 @@
+// Command generate builds areas.json from the DOPA / Thailand Post list of subdistricts, as published in JSON by
+// github.com/kongvut/thai-province-data (api_province_with_amphure_tambon.json): an array of provinces, each
+// with its amphure (districts) and their tambon (subdistricts) with zip_code. The dataset it writes is marked
+// complete.
+//
+//	go run ./generate -source api_province_with_amphure_tambon.json -out areas.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/thaiaddress"
+)
+
+type sourceArea struct {
+	NameTH  string       `json:"name_th"`
+	NameEN  string       `json:"name_en"`
+	ZipCode json.Number  `json:"zip_code"`
+	Amphure []sourceArea `json:"amphure"`
+	Tambon  []sourceArea `json:"tambon"`
+}
+
+// designations are written before district names in the source; the dataset leaves them out
+var designations = []string{"เขต", "อำเภอ", "Khet ", "Amphoe "}
+
+func main() {
+	source := flag.String("source", "", "file or URL of the province, amphure and tambon JSON")
+	out := flag.String("out", "areas.json", "file to write the dataset to")
+	flag.Parse()
+	if *source == "" {
+		log.Fatal("-source is required")
+	}
+
+	r, err := open(*source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+	var sourceProvinces []sourceArea
+	if err := json.NewDecoder(r).Decode(&sourceProvinces); err != nil {
+		log.Fatalf("reading %s: %v", *source, err)
+	}
+
+	dataset, err := convert(sourceProvinces)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(dataset, "", " ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	subdistricts := 0
+	for _, province := range dataset.Provinces {
+		for _, district := range province.Districts {
+			subdistricts += len(district.Subdistricts)
+		}
+	}
+	log.Printf("wrote %d provinces and %d subdistricts to %s", len(dataset.Provinces), subdistricts, *out)
+}
+
+func open(source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", source, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// convert turns the source list into a complete dataset. Areas the source marks as abolished, with a leading
+// "*", are left out; each province's postcode prefixes are taken from its subdistricts.
+func convert(sourceProvinces []sourceArea) (thaiaddress.Dataset, error) {
+	dataset := thaiaddress.Dataset{Complete: true}
+	for _, sp := range sourceProvinces {
+		province := thaiaddress.Area{NameTH: strings.TrimSpace(sp.NameTH), NameEN: strings.TrimSpace(sp.NameEN)}
+		prefixes := map[string]bool{}
+		for _, sd := range sp.Amphure {
+			if abolished(sd) {
+				continue
+			}
+			district := thaiaddress.Area{NameTH: undesignated(sd.NameTH), NameEN: undesignated(sd.NameEN)}
+			for _, ss := range sd.Tambon {
+				if abolished(ss) {
+					continue
+				}
+				postcode := ss.ZipCode.String()
+				if len(postcode) != 5 || postcode[0] == '0' {
+					return dataset, fmt.Errorf("%s, %s, %s has no postcode", ss.NameEN, district.NameEN, province.NameEN)
+				}
+				district.Subdistricts = append(district.Subdistricts, thaiaddress.Area{
+					NameTH:   strings.TrimSpace(ss.NameTH),
+					NameEN:   strings.TrimSpace(ss.NameEN),
+					Postcode: postcode,
+				})
+				prefixes[postcode[:2]] = true
+			}
+			if len(district.Subdistricts) > 0 {
+				province.Districts = append(province.Districts, district)
+			}
+		}
+		for prefix := range prefixes {
+			province.PostcodePrefixes = append(province.PostcodePrefixes, prefix)
+		}
+		sort.Strings(province.PostcodePrefixes)
+		if len(province.Districts) == 0 {
+			return dataset, fmt.Errorf("province %s has no districts", province.NameEN)
+		}
+		dataset.Provinces = append(dataset.Provinces, province)
+	}
+	if len(dataset.Provinces) != 77 {
+		return dataset, fmt.Errorf("source lists %d provinces, want 77", len(dataset.Provinces))
+	}
+	return dataset, nil
+}
+
+func abolished(area sourceArea) bool {
+	return strings.HasPrefix(strings.TrimSpace(area.NameTH), "*") || strings.TrimSpace(area.NameTH) == ""
+}
+
+func undesignated(name string) string {
+	name = strings.TrimSpace(name)
+	for _, designation := range designations {
+		name = strings.TrimPrefix(name, designation)
+	}
+	return strings.TrimSpace(name)
+}
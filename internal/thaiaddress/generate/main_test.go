@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	// Bangkok as the source lists it, with an abolished district, and 76 provinces of one subdistrict each
+	source := `[{"name_th": "กรุงเทพมหานคร", "name_en": "Bangkok", "amphure": [
+		{"name_th": "เขตพระนคร", "name_en": "Khet Phra Nakhon", "tambon": [
+			{"name_th": "พระบรมมหาราชวัง", "name_en": "Phra Borom Maha Ratchawang", "zip_code": 10200},
+			{"name_th": "วังบูรพาภิรมย์", "name_en": "Wang Burapha Phirom", "zip_code": "10200"}]},
+		{"name_th": "*เขตเก่า", "name_en": "*Old", "tambon": [{"name_th": "เก่า", "name_en": "Old", "zip_code": 10999}]}]}`
+	for i := 0; i < 76; i++ {
+		source += fmt.Sprintf(`, {"name_th": "จังหวัด%d", "name_en": "Province %d", "amphure": [{"name_th": "อำเภอเมือง", "name_en": "Amphoe Mueang",
+			"tambon": [{"name_th": "ในเมือง", "name_en": "Nai Mueang", "zip_code": %d}]}]}`, i, i, 11000+i*1000)
+	}
+	source += "]"
+
+	var provinces []sourceArea
+	if err := json.NewDecoder(strings.NewReader(source)).Decode(&provinces); err != nil {
+		t.Fatalf("decoding source: %v", err)
+	}
+	dataset, err := convert(provinces)
+	if err != nil {
+		t.Fatalf("convert() error = %v", err)
+	}
+	if !dataset.Complete {
+		t.Error("dataset is not marked complete")
+	}
+	bangkok := dataset.Provinces[0]
+	if len(bangkok.Districts) != 1 {
+		t.Fatalf("Bangkok has %d districts, want the one that was not abolished", len(bangkok.Districts))
+	}
+	if district := bangkok.Districts[0]; district.NameTH != "พระนคร" || district.NameEN != "Phra Nakhon" {
+		t.Errorf("district = %s / %s, want the names without designations", district.NameTH, district.NameEN)
+	}
+	if got := bangkok.Districts[0].Subdistricts[1].Postcode; got != "10200" {
+		t.Errorf("postcode given as a string = %q, want 10200", got)
+	}
+	if got := strings.Join(bangkok.PostcodePrefixes, ","); got != "10" {
+		t.Errorf("Bangkok postcode prefixes = %s, want 10", got)
+	}
+
+	if _, err := convert(provinces[:76]); err == nil {
+		t.Error("convert() of a source missing a province succeeded")
+	}
+}
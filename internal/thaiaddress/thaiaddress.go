@@ -0,0 +1,204 @@
+// Package thaiaddress validates Thai postal addresses against a dataset of administrative areas: provinces
+// (changwat), their districts (amphoe, or khet in Bangkok) and subdistricts (tambon, or khwaeng).
+//
+// areas.json is generated from the DOPA / Thailand Post list of subdistricts with `go generate`; see
+// generate/main.go. A dataset marked complete lists every area, and addresses in areas it does not list are
+// refused. The areas.json in the tree is the hand-written one the package started with: every province with the
+// first two digits of its postcodes, but only some districts and subdistricts, so below the province it only
+// checks the areas it lists. Another dataset, e.g. a newer list, can be loaded at startup with Load.
+package thaiaddress
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+//go:generate go run ./generate -source api_province_with_amphure_tambon.json -out areas.json
+
+//go:embed areas.json
+var areasJSON []byte
+
+var (
+	// ErrInvalidPostcode is returned for a postcode that is not five digits
+	ErrInvalidPostcode = errors.New("postcode must be 5 digits")
+	// ErrUnknownProvince is returned for a province that is not in the dataset
+	ErrUnknownProvince = errors.New("unknown province")
+	// ErrUnknownDistrict is returned for a district that is not in its province
+	ErrUnknownDistrict = errors.New("unknown district")
+	// ErrUnknownSubdistrict is returned for a subdistrict that is not in its district
+	ErrUnknownSubdistrict = errors.New("unknown subdistrict")
+	// ErrPostcodeMismatch is returned when the postcode is not one of the area's
+	ErrPostcodeMismatch = errors.New("postcode does not belong to the area")
+	// ErrInvalidPhone is returned for a phone number that is not a Thai landline or mobile number
+	ErrInvalidPhone = errors.New("phone must be a Thai number, e.g. 0812345678 or +66812345678")
+)
+
+var (
+	postcodePattern = regexp.MustCompile(`^\d{5}$`)
+	// phonePattern matches landline (9 digits) and mobile (10 digits) numbers in the national format
+	phonePattern = regexp.MustCompile(`^0[1-9]\d{7,8}$`)
+	// prefixes are the designations written before area names, which the dataset leaves out
+	prefixes = []string{
+		"จังหวัด", "จ.", "อำเภอ", "อ.", "เขต", "ตำบล", "ต.", "แขวง",
+		"changwat ", "amphoe ", "amphur ", "khet ", "tambon ", "khwaeng ",
+	}
+	// suffixes are the designations written after area names
+	suffixes = []string{" province", " district", " subdistrict"}
+)
+
+// Area is a province, district or subdistrict
+type Area struct {
+	NameTH           string   `json:"name_th"`
+	NameEN           string   `json:"name_en"`
+	PostcodePrefixes []string `json:"postcode_prefixes,omitempty"` // Provinces only
+	Postcode         string   `json:"postcode,omitempty"`          // Subdistricts only
+	Districts        []Area   `json:"districts,omitempty"`
+	Subdistricts     []Area   `json:"subdistricts,omitempty"`
+}
+
+// Dataset is the format of areas.json
+type Dataset struct {
+	// Complete is set when every district and subdistrict is listed; otherwise areas are only checked as far
+	// down as they are listed
+	Complete  bool   `json:"complete"`
+	Provinces []Area `json:"provinces"`
+}
+
+// Address is the administrative part of an address, as entered
+type Address struct {
+	Subdistrict string
+	District    string
+	Province    string
+	Postcode    string
+}
+
+var (
+	provinces []Area
+	complete  bool
+)
+
+func init() {
+	if err := Load(bytes.NewReader(areasJSON)); err != nil {
+		panic(fmt.Sprintf("thaiaddress: invalid dataset: %v", err))
+	}
+}
+
+// Load replaces the dataset with one read from r, in the format of the embedded areas.json. It is meant to be
+// called at startup, before addresses are validated.
+func Load(r io.Reader) error {
+	var dataset Dataset
+	if err := json.NewDecoder(r).Decode(&dataset); err != nil {
+		return err
+	}
+	if len(dataset.Provinces) == 0 {
+		return fmt.Errorf("dataset has no provinces")
+	}
+	provinces, complete = dataset.Provinces, dataset.Complete
+	return nil
+}
+
+// Provinces returns every province in the dataset
+func Provinces() []Area {
+	return provinces
+}
+
+// Normalize validates an address and returns it with the areas named as in the dataset, in Thai. Names can be
+// given in Thai or English, with or without designations such as "จังหวัด" or "Khet". When the dataset is not
+// complete, a district or subdistrict below an area whose children it does not list is kept as entered.
+func Normalize(address Address) (Address, error) {
+	postcode := strings.TrimSpace(address.Postcode)
+	if !postcodePattern.MatchString(postcode) {
+		return address, ErrInvalidPostcode
+	}
+
+	province := find(provinces, address.Province)
+	if province == nil {
+		return address, fmt.Errorf("%w: %s", ErrUnknownProvince, address.Province)
+	}
+	if !hasPrefix(postcode, province.PostcodePrefixes) {
+		return address, fmt.Errorf("%w: %s is not a postcode of %s", ErrPostcodeMismatch, postcode, province.NameEN)
+	}
+	normalized := Address{
+		Subdistrict: strings.TrimSpace(address.Subdistrict),
+		District:    strings.TrimSpace(address.District),
+		Province:    province.NameTH,
+		Postcode:    postcode,
+	}
+	if len(province.Districts) == 0 && !complete {
+		return normalized, nil
+	}
+
+	district := find(province.Districts, address.District)
+	if district == nil {
+		return address, fmt.Errorf("%w: %s is not in %s", ErrUnknownDistrict, address.District, province.NameEN)
+	}
+	normalized.District = district.NameTH
+	if len(district.Subdistricts) == 0 && !complete {
+		return normalized, nil
+	}
+
+	subdistrict := find(district.Subdistricts, address.Subdistrict)
+	if subdistrict == nil {
+		return address, fmt.Errorf("%w: %s is not in %s", ErrUnknownSubdistrict, address.Subdistrict, district.NameEN)
+	}
+	if subdistrict.Postcode != "" && subdistrict.Postcode != postcode {
+		return address, fmt.Errorf("%w: %s is %s", ErrPostcodeMismatch, subdistrict.NameEN, subdistrict.Postcode)
+	}
+	normalized.Subdistrict = subdistrict.NameTH
+	return normalized, nil
+}
+
+// NormalizePhone validates a Thai phone number and returns it in the national format, without spaces or
+// dashes; +66 numbers are converted
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+	if strings.HasPrefix(phone, "+66") {
+		phone = "0" + strings.TrimPrefix(phone, "+66")
+	}
+	if !phonePattern.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
+
+// find returns the area named name, in Thai or English
+func find(areas []Area, name string) *Area {
+	key := normalizeName(name)
+	if key == "" {
+		return nil
+	}
+	for i := range areas {
+		if normalizeName(areas[i].NameTH) == key || normalizeName(areas[i].NameEN) == key {
+			return &areas[i]
+		}
+	}
+	return nil
+}
+
+// normalizeName lower-cases a name and strips designations, spaces and hyphens, so "Khet Pathum Wan",
+// "pathumwan" and "เขตปทุมวัน" compare equal to the dataset's names
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, prefix := range prefixes {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	for _, suffix := range suffixes {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(name)
+}
+
+func hasPrefix(postcode string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(postcode, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package thaiaddress
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	lumphini := Address{Subdistrict: "ลุมพินี", District: "ปทุมวัน", Province: "กรุงเทพมหานคร", Postcode: "10330"}
+
+	tests := []struct {
+		name    string
+		address Address
+		want    Address
+		wantErr error
+	}{
+		{
+			name:    "Thai names",
+			address: lumphini,
+			want:    lumphini,
+		},
+		{
+			name:    "Thai names with designations",
+			address: Address{Subdistrict: "แขวงลุมพินี", District: "เขตปทุมวัน", Province: "จังหวัดกรุงเทพมหานคร", Postcode: " 10330 "},
+			want:    lumphini,
+		},
+		{
+			name:    "English names in any case and spacing",
+			address: Address{Subdistrict: "Khwaeng Lumphini", District: "pathumwan district", Province: "BANGKOK", Postcode: "10330"},
+			want:    lumphini,
+		},
+		{
+			name:    "postcode of another subdistrict",
+			address: Address{Subdistrict: "Lumphini", District: "Pathum Wan", Province: "Bangkok", Postcode: "10500"},
+			wantErr: ErrPostcodeMismatch,
+		},
+		{
+			name:    "postcode of another province",
+			address: Address{Subdistrict: "Lumphini", District: "Pathum Wan", Province: "Bangkok", Postcode: "50200"},
+			wantErr: ErrPostcodeMismatch,
+		},
+		{
+			name:    "postcode is not five digits",
+			address: Address{Subdistrict: "Lumphini", District: "Pathum Wan", Province: "Bangkok", Postcode: "1033"},
+			wantErr: ErrInvalidPostcode,
+		},
+		{
+			name:    "unknown province",
+			address: Address{Subdistrict: "Lumphini", District: "Pathum Wan", Province: "Atlantis", Postcode: "10330"},
+			wantErr: ErrUnknownProvince,
+		},
+		{
+			name:    "district of another province",
+			address: Address{Subdistrict: "Si Phum", District: "Mueang Chiang Mai", Province: "Bangkok", Postcode: "10200"},
+			wantErr: ErrUnknownDistrict,
+		},
+		{
+			name:    "subdistrict of another district",
+			address: Address{Subdistrict: "Si Lom", District: "Pathum Wan", Province: "Bangkok", Postcode: "10330"},
+			wantErr: ErrUnknownSubdistrict,
+		},
+		{
+			name:    "district whose subdistricts are not listed keeps the subdistrict as entered",
+			address: Address{Subdistrict: " Phra Borom Maha Ratchawang ", District: "Phra Nakhon", Province: "Bangkok", Postcode: "10200"},
+			want:    Address{Subdistrict: "Phra Borom Maha Ratchawang", District: "พระนคร", Province: "กรุงเทพมหานคร", Postcode: "10200"},
+		},
+		{
+			name:    "province whose districts are not listed keeps the district as entered",
+			address: Address{Subdistrict: "Pak Nam", District: "Mueang Samut Prakan", Province: "Samut Prakan", Postcode: "10270"},
+			want:    Address{Subdistrict: "Pak Nam", District: "Mueang Samut Prakan", Province: "สมุทรปราการ", Postcode: "10270"},
+		},
+		{
+			name:    "postcode is still checked where districts are not listed",
+			address: Address{Subdistrict: "Pak Nam", District: "Mueang Samut Prakan", Province: "Samut Prakan", Postcode: "50200"},
+			wantErr: ErrPostcodeMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.address)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Normalize() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != tt.want {
+				t.Errorf("Normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	embedded, embeddedComplete := provinces, complete
+	t.Cleanup(func() { provinces, complete = embedded, embeddedComplete })
+
+	dataset := `{"complete": true, "provinces": [{"name_th": "สมุทรปราการ", "name_en": "Samut Prakan", "postcode_prefixes": ["10"],
+		"districts": [{"name_th": "เมืองสมุทรปราการ", "name_en": "Mueang Samut Prakan",
+			"subdistricts": [{"name_th": "ปากน้ำ", "name_en": "Pak Nam", "postcode": "10270"}]}]}]}`
+	if err := Load(strings.NewReader(dataset)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got, err := Normalize(Address{Subdistrict: "Pak Nam", District: "Mueang Samut Prakan", Province: "Samut Prakan", Postcode: "10270"})
+	if err != nil {
+		t.Fatalf("Normalize() with the loaded dataset error = %v", err)
+	}
+	if want := (Address{Subdistrict: "ปากน้ำ", District: "เมืองสมุทรปราการ", Province: "สมุทรปราการ", Postcode: "10270"}); got != want {
+		t.Errorf("Normalize() = %+v, want %+v", got, want)
+	}
+	if _, err := Normalize(Address{Subdistrict: "Lumphini", District: "Pathum Wan", Province: "Bangkok", Postcode: "10330"}); !errors.Is(err, ErrUnknownProvince) {
+		t.Errorf("Normalize() of a province the loaded dataset leaves out error = %v, want ErrUnknownProvince", err)
+	}
+	// A complete dataset lists every area, so one it does not list is refused
+	if _, err := Normalize(Address{Subdistrict: "Thai Ban", District: "Mueang Samut Prakan", Province: "Samut Prakan", Postcode: "10270"}); !errors.Is(err, ErrUnknownSubdistrict) {
+		t.Errorf("Normalize() of a subdistrict the complete dataset leaves out error = %v, want ErrUnknownSubdistrict", err)
+	}
+
+	for _, bad := range []string{`{"provinces": []}`, `not json`} {
+		if err := Load(strings.NewReader(bad)); err == nil {
+			t.Errorf("Load(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone   string
+		want    string
+		wantErr bool
+	}{
+		{phone: "0812345678", want: "0812345678"},
+		{phone: "081-234-5678", want: "0812345678"},
+		{phone: "+66 81 234 5678", want: "0812345678"},
+		{phone: "(02) 123 4567", want: "021234567"},
+		{phone: "+6621234567", want: "021234567"},
+		{phone: "812345678", wantErr: true},
+		{phone: "0012345678", wantErr: true},
+		{phone: "08123456789", wantErr: true},
+		{phone: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizePhone(%q) error = %v, want error %v", tt.phone, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination is returned for a webhook URL that is not https, or that points at the API's own
+// network: loopback, private, link-local and other non-public addresses
+var ErrForbiddenDestination = errors.New("webhook URL must be https on a public address")
+
+// ValidateURL checks that a subscription URL is https and, when its host is an IP address, that the address
+// is public. Host names are checked when they are dialled, as they can resolve differently later.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: %s is not a URL", ErrForbiddenDestination, raw)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: %s is not https", ErrForbiddenDestination, raw)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !PublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, ip)
+	}
+	return nil
+}
+
+// PublicIP reports whether deliveries may be sent to ip
+func PublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		// 100.64.0.0/10 is carrier-grade NAT, shared address space that is not reachable from the internet
+		if ip[0] == 100 && ip[1]&0xc0 == 64 {
+			return false
+		}
+		if ip[0] == 0 {
+			return false
+		}
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// NewClient returns an HTTP client for deliveries. It only sends https requests and refuses to connect to
+// addresses that are not public, after name resolution and on every redirect, so a subscription cannot reach
+// services inside the API's network. Proxies from the environment are not used, as they would hide the address.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s", ErrForbiddenDestination, req.URL)
+			}
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateURL(t *testing.T) {
+	for _, allowed := range []string{
+		"https://hooks.example.com/inventory",
+		"https://203.0.113.10:8443/hook",
+	} {
+		if err := ValidateURL(allowed); err != nil {
+			t.Errorf("ValidateURL(%q) error = %v", allowed, err)
+		}
+	}
+	for _, refused := range []string{
+		"http://hooks.example.com/inventory",
+		"ftp://hooks.example.com/",
+		"https://127.0.0.1/hook",
+		"https://[::1]/hook",
+		"https://10.0.0.5/hook",
+		"https://192.168.1.1/hook",
+		"https://169.254.169.254/latest/meta-data/",
+		"https://[fe80::1]/hook",
+		"https://0.0.0.0/hook",
+		"not a url",
+	} {
+		if err := ValidateURL(refused); !errors.Is(err, ErrForbiddenDestination) {
+			t.Errorf("ValidateURL(%q) error = %v, want ErrForbiddenDestination", refused, err)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"203.0.113.10":    true,
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.0.1":     false,
+		"100.64.0.1":      false,
+		"169.254.169.254": false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+		"::1":             false,
+		"fc00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		if got := PublicIP(net.ParseIP(address)); got != want {
+			t.Errorf("PublicIP(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	reached := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	// The server listens on loopback, as an internal service would; a host name resolving there is refused too
+	for _, url := range []string{server.URL, "https://localhost:" + server.URL[len("https://127.0.0.1:"):]} {
+		_, err := NewClient(time.Second).Post(url, "application/json", nil)
+		if !errors.Is(err, ErrForbiddenDestination) {
+			t.Errorf("posting to %s: error = %v, want ErrForbiddenDestination", url, err)
+		}
+	}
+	if reached {
+		t.Error("the internal server was reached")
+	}
+}
@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256("whsec_test", `1700000000.{"id":1}`), computed independently
+	want := "t=1700000000,v1=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"
+	if got := Sign("whsec_test", time.Unix(1700000000, 0), []byte(`{"id":1}`)); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	sent := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+	header := Sign("whsec_test", sent, body)
+
+	if err := Verify("whsec_test", header, body, 5*time.Minute, sent.Add(time.Minute)); err != nil {
+		t.Errorf("Verify() of a fresh signature error = %v", err)
+	}
+	if err := Verify("whsec_test", " v1="+header[strings.Index(header, "v1=")+3:]+", t=1700000000", body, 0, sent); err != nil {
+		t.Errorf("Verify() with the parts reordered error = %v", err)
+	}
+	if err := Verify("whsec_test", header, body, 5*time.Minute, sent.Add(6*time.Minute)); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Verify() of an old signature error = %v, want ErrSignatureExpired", err)
+	}
+	if err := Verify("whsec_test", header, body, 0, sent.Add(24*time.Hour)); err != nil {
+		t.Errorf("Verify() without a tolerance error = %v", err)
+	}
+	if err := Verify("whsec_other", header, body, 0, sent); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with another secret error = %v, want ErrInvalidSignature", err)
+	}
+	if err := Verify("whsec_test", header, []byte(`{"id":2}`), 0, sent); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() of a changed body error = %v, want ErrInvalidSignature", err)
+	}
+	// Moving the timestamp to dodge the tolerance breaks the signature
+	forged := strings.Replace(header, "t=1700000000", "t=1700000300", 1)
+	if err := Verify("whsec_test", forged, body, 5*time.Minute, sent.Add(6*time.Minute)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with a moved timestamp error = %v, want ErrInvalidSignature", err)
+	}
+	for _, malformed := range []string{"", "v1=abc", "t=abc,v1=abc", "t=1700000000"} {
+		if err := Verify("whsec_test", malformed, body, 0, sent); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Verify(%q) error = %v, want ErrInvalidSignature", malformed, err)
+		}
+	}
+}
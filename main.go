@@ -2,19 +2,31 @@ package main
 
 import (
 	"log"
+	"os"
 	"runtime"
 	"sync"
+	"time"
+	_ "time/tzdata" // Time zones for sales round templates, also where the image has no zoneinfo
 
 	_ "github.com/B6137151/InventoryMarketplaceSystem/docs" // Swagger docs
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/admission"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/carriers"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/controllers"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/events"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/models"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/notifications"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/payments"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/ratelimit"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/repositories"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/route"
 	"github.com/B6137151/InventoryMarketplaceSystem/internal/services"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/thaiaddress"
+	"github.com/B6137151/InventoryMarketplaceSystem/internal/webhooks"
 	"github.com/B6137151/InventoryMarketplaceSystem/pkg/database"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/swagger" // swagger middleware for Fiber
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -52,6 +64,42 @@ func main() {
 			&models.SalesRound{},
 			&models.ProductVariant{},
 			&models.OrderHistory{},
+			&models.InventoryLocation{},
+			&models.VariantStockLevel{},
+			&models.StockTransfer{},
+			&models.Supplier{},
+			&models.PurchaseOrder{},
+			&models.PurchaseOrderLine{},
+			&models.StockMovement{},
+			&models.Notification{},
+			&models.StockTakeSession{},
+			&models.StockTakeCount{},
+			&models.ImportJob{},
+			&models.SalesRoundTemplate{},
+			&models.SalesRoundTemplateLine{},
+			&models.WaitlistEntry{},
+			&models.LotteryEntry{},
+			&models.RateLimitBucket{},
+			&models.AdmissionRound{},
+			&models.AdmissionTicket{},
+			&models.OutboxEvent{},
+			&models.WebhookSubscription{},
+			&models.WebhookDelivery{},
+			&models.Payment{},
+			&models.PaymentRefund{},
+			&models.BankStatement{},
+			&models.BankStatementLine{},
+			&models.Shipment{},
+			&models.ShipmentItem{},
+			&models.ShipmentTrackingEvent{},
+			&models.CustomerAddress{},
+			&models.StoreTaxRate{},
+			&models.Invoice{},
+			&models.InvoiceLine{},
+			&models.InvoiceSequence{},
+			&models.DiscountCode{},
+			&models.DiscountRedemption{},
+			&models.OrderLineDiscount{},
 			//&models.Purchase{}, // Added Purchase model
 		); err != nil {
 			log.Fatalf("Failed to migrate the tables: %v", err)
@@ -68,20 +116,143 @@ func main() {
 		return
 	}
 
+	// Run a CLI subcommand instead of the server when one is given
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(db, os.Args[1:]))
+	}
+
 	// Initialize repositories
 	storeRepository := repositories.NewStoreRepository(db)
 	categoryRepository := repositories.NewCategoryRepository(db)
 	customerRepository := repositories.NewCustomerRepository(db)
+	customerAddressRepository := repositories.NewCustomerAddressRepository(db)
+	taxRepository := repositories.NewTaxRepository(db)
+	invoiceRepository := repositories.NewInvoiceRepository(db)
+	discountRepository := repositories.NewDiscountRepository(db)
 	productRepository := repositories.NewProductRepository(db)
 	productVariantRepository := repositories.NewProductVariantRepository(db)
-	salesRoundRepository := repositories.NewSalesRoundRepository(db)
+	notificationRepository := repositories.NewNotificationRepository(db)
+
+	// Low-stock alerts are evaluated in the background whenever stock changes
+	notifier := notifications.NewNotifier(notificationRepository, notifications.LogSink{})
+	stockEvaluator := services.NewStockEvaluator(productVariantRepository, productRepository, notifier)
+	stockEvaluator.Start()
+
+	// Quantity released in a sold-out round is offered to its waitlist, first come first served
+	waitlistRepository := repositories.NewWaitlistRepository(db)
+	waitlistService := services.NewWaitlistService(waitlistRepository, notifier, 15*time.Minute)
+	waitlistService.Start()
+
+	salesRoundRepository := repositories.NewSalesRoundRepository(db, stockEvaluator, waitlistService)
 	orderRepository := repositories.NewOrderRepository(db)
-	salesRoundDetailRepository := repositories.NewSalesRoundDetailRepository(db)
+	salesRoundDetailRepository := repositories.NewSalesRoundDetailRepository(db, stockEvaluator, waitlistService)
 	orderDetailRepository := repositories.NewOrderDetailRepository(db)
 	orderHistoryRepository := repositories.NewOrderHistoryRepository(db)
+	inventoryRepository := repositories.NewInventoryRepository(db, stockEvaluator, waitlistService)
+	stockMovementRepository := repositories.NewStockMovementRepository(db)
+	supplierRepository := repositories.NewSupplierRepository(db)
+	purchaseOrderRepository := repositories.NewPurchaseOrderRepository(db, stockEvaluator, waitlistService)
+	stockTakeRepository := repositories.NewStockTakeRepository(db, stockEvaluator, waitlistService)
+	importRepository := repositories.NewImportRepository(db)
+	exportRepository := repositories.NewExportRepository(db)
+	salesRoundTemplateRepository := repositories.NewSalesRoundTemplateRepository(db, stockEvaluator, waitlistService)
+	lotteryRepository := repositories.NewLotteryRepository(db, stockEvaluator)
+	outboxRepository := repositories.NewOutboxRepository(db)
+	webhookRepository := repositories.NewWebhookRepository(db)
+	paymentRepository := repositories.NewPaymentRepository(db)
+	reconciliationRepository := repositories.NewReconciliationRepository(db)
+	shipmentRepository := repositories.NewShipmentRepository(db)
+
+	// Addresses are checked against the area dataset embedded in thaiaddress, or the one in THAI_ADDRESS_DATASET
+	// when given, e.g. a newer list
+	if path := os.Getenv("THAI_ADDRESS_DATASET"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("failed to open address dataset: %v", err)
+		}
+		err = thaiaddress.Load(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed to load address dataset: %v", err)
+		}
+	}
 
 	// Initialize services
-	purchaseService := services.NewPurchaseService(orderRepository, orderDetailRepository, productVariantRepository, productRepository, salesRoundDetailRepository)
+	// Orders are paid through the provider named in their payment source. The mock provider lets anyone mark a
+	// payment as succeeded, so it is only offered when PAYMENT_MOCK_ENABLED=true; it signs its webhooks with
+	// PAYMENT_MOCK_SECRET, or a secret generated at startup.
+	paymentProviders := []payments.Provider{payments.NewPromptPayProvider()}
+	var mockProvider *payments.MockProvider
+	if os.Getenv("PAYMENT_MOCK_ENABLED") == "true" {
+		mockSecret := os.Getenv("PAYMENT_MOCK_SECRET")
+		if mockSecret == "" {
+			secret, err := webhooks.NewSecret()
+			if err != nil {
+				log.Fatalf("failed to generate mock payment secret: %v", err)
+			}
+			mockSecret = secret
+		}
+		mockProvider = payments.NewMockProvider(mockSecret)
+		paymentProviders = append([]payments.Provider{mockProvider}, paymentProviders...)
+		log.Println("mock payment provider enabled; payments can be simulated")
+	}
+	paymentCurrency := os.Getenv("PAYMENT_CURRENCY")
+	if paymentCurrency == "" {
+		paymentCurrency = "THB"
+	}
+	paymentService := services.NewPaymentService(paymentRepository, orderRepository, payments.NewRegistry(paymentProviders[0], paymentProviders[1:]...), paymentCurrency)
+
+	purchaseService := services.NewPurchaseService(orderRepository, orderDetailRepository, productVariantRepository, productRepository, salesRoundDetailRepository, customerAddressRepository, paymentService, stockEvaluator, waitlistService)
+	// Pending orders not paid within their round's payment window are cancelled and their quantity released
+	orderExpiry := services.NewOrderExpiry(purchaseService)
+	orderExpiry.Start(time.Minute)
+	importService := services.NewImportService(importRepository)
+	// Bank transfers are matched to orders placed up to three days before them
+	reconciliationService := services.NewReconciliationService(reconciliationRepository, 72*time.Hour)
+	exportService := services.NewExportService(exportRepository)
+	// Shipments booked with a carrier are tracked every five minutes. The fake carrier moves its parcels one
+	// tracking step every ten minutes.
+	shippingService := services.NewShippingService(shipmentRepository, orderRepository, customerRepository, carriers.NewRegistry(carriers.NewFakeCarrier(10*time.Minute)))
+	shippingService.Start(5 * time.Minute)
+
+	// Recurring rounds are materialized from their templates ahead of time
+	roundScheduler := services.NewRoundScheduler(salesRoundTemplateRepository, notifier)
+	roundScheduler.Start(time.Hour)
+
+	// Lottery rounds are drawn when they end; unpaid wins roll to the next entrants
+	lotteryService := services.NewLotteryService(lotteryRepository, notifier)
+	lotteryService.Start(time.Minute)
+
+	// Flash-sale rounds admit buyers through a waiting room at their admission rate. Like rate limits, the queue
+	// is kept in Postgres when several instances serve the API (RATE_LIMIT_STORE=postgres), in memory otherwise.
+	admissionQueue := admission.NewQueue(admission.SalesRoundLookup(salesRoundRepository), admission.Options{})
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		admissionQueue = admission.NewPostgresQueue(db, admission.SalesRoundLookup(salesRoundRepository), admission.Options{})
+	}
+	admissionQueue.Start(100 * time.Millisecond)
+
+	// Domain events are written to the outbox with the change that caused them and relayed from there
+	eventSinks := []events.Sink{events.LogSink{}}
+	if path := os.Getenv("OUTBOX_FILE"); path != "" {
+		fileSink, err := events.NewFileSink(path)
+		if err != nil {
+			log.Fatalf("failed to open outbox file: %v", err)
+		}
+		eventSinks = append(eventSinks, fileSink)
+	}
+	if url := os.Getenv("OUTBOX_HTTP_URL"); url != "" {
+		eventSinks = append(eventSinks, events.NewHTTPSink(url))
+	}
+	// Webhook subscriptions get the relayed events of their store, signed and retried until delivered
+	webhookService := services.NewWebhookService(webhookRepository)
+	webhookService.Start(10 * time.Second)
+	eventSinks = append(eventSinks, webhookService)
+
+	outboxRelay := services.NewOutboxRelay(outboxRepository, eventSinks...)
+	outboxRelay.Start(5 * time.Second)
+
+	roundLifecycle := services.NewRoundLifecycle(salesRoundRepository)
+	roundLifecycle.Start(time.Minute)
 
 	// Initialize controllers
 	storeController := controllers.NewStoreController(storeRepository)
@@ -95,8 +266,38 @@ func main() {
 	orderDetailController := controllers.NewOrderDetailController(orderDetailRepository)
 	orderHistoryController := controllers.NewOrderHistoryController(orderHistoryRepository)
 	purchaseController := controllers.NewPurchaseController(purchaseService)
+	inventoryController := controllers.NewInventoryController(inventoryRepository, stockMovementRepository, productVariantRepository)
+	supplierController := controllers.NewSupplierController(supplierRepository)
+	purchaseOrderController := controllers.NewPurchaseOrderController(purchaseOrderRepository, supplierRepository, inventoryRepository)
+	notificationController := controllers.NewNotificationController(notificationRepository)
+	stockTakeController := controllers.NewStockTakeController(stockTakeRepository, inventoryRepository)
+	importController := controllers.NewImportController(importService)
+	exportController := controllers.NewExportController(exportService)
+	salesRoundTemplateController := controllers.NewSalesRoundTemplateController(salesRoundTemplateRepository, roundScheduler)
+	waitlistController := controllers.NewWaitlistController(waitlistService)
+	lotteryController := controllers.NewLotteryController(lotteryService, salesRoundRepository)
+	admissionController := controllers.NewAdmissionController(admissionQueue)
+	webhookController := controllers.NewWebhookController(webhookService, storeRepository)
+	paymentController := controllers.NewPaymentController(paymentService, mockProvider)
+	reconciliationController := controllers.NewReconciliationController(reconciliationService)
+	shipmentController := controllers.NewShipmentController(shipmentRepository, shippingService)
+	customerAddressController := controllers.NewCustomerAddressController(customerAddressRepository)
+	taxController := controllers.NewTaxController(taxRepository)
+	invoiceController := controllers.NewInvoiceController(invoiceRepository)
+	discountCodeController := controllers.NewDiscountCodeController(discountRepository)
+
+	// Rate limits are kept in Postgres when several instances serve the API, in memory otherwise
+	rateLimitStore := ratelimit.NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		rateLimitStore = ratelimit.NewPostgresStore(db)
+	}
+	ratelimit.StartPruning(rateLimitStore, 10*time.Minute, 24*time.Hour)
 
 	// Register routes
+	route.RegisterRateLimits(app, rateLimitStore, func(id uuid.UUID) bool {
+		_, err := customerRepository.GetCustomerByID(id)
+		return err == nil
+	})
 	route.RegisterStoreRoutes(app, storeController)
 	route.RegisterCategoryRoutes(app, categoryController)
 	route.RegisterCustomerRoutes(app, customerController)
@@ -104,10 +305,29 @@ func main() {
 	route.RegisterProductVariantRoutes(app, productVariantController)
 	route.RegisterSalesRoundRoutes(app, salesRoundController)
 	route.RegisterSalesRoundDetailRoutes(app, salesRoundDetailController)
-	route.RegisterOrderRoutes(app, orderController)
+	route.RegisterOrderRoutes(app, orderController, admissionController.RequireAdmission)
 	route.RegisterOrderDetailRoutes(app, orderDetailController)
 	route.RegisterOrderHistoryRoutes(app, orderHistoryController)
-	route.RegisterPurchaseRoutes(app, purchaseController)
+	route.RegisterPurchaseRoutes(app, purchaseController, admissionController.RequireAdmission)
+	route.RegisterInventoryRoutes(app, inventoryController)
+	route.RegisterSupplierRoutes(app, supplierController)
+	route.RegisterPurchaseOrderRoutes(app, purchaseOrderController)
+	route.RegisterNotificationRoutes(app, notificationController)
+	route.RegisterStockTakeRoutes(app, stockTakeController)
+	route.RegisterImportRoutes(app, importController)
+	route.RegisterExportRoutes(app, exportController)
+	route.RegisterSalesRoundTemplateRoutes(app, salesRoundTemplateController)
+	route.RegisterWaitlistRoutes(app, waitlistController)
+	route.RegisterLotteryRoutes(app, lotteryController)
+	route.RegisterAdmissionRoutes(app, admissionController)
+	route.RegisterWebhookRoutes(app, webhookController)
+	route.RegisterPaymentRoutes(app, paymentController)
+	route.RegisterReconciliationRoutes(app, reconciliationController)
+	route.RegisterShipmentRoutes(app, shipmentController)
+	route.RegisterCustomerAddressRoutes(app, customerAddressController)
+	route.RegisterTaxRoutes(app, taxController)
+	route.RegisterInvoiceRoutes(app, invoiceController)
+	route.RegisterDiscountCodeRoutes(app, discountCodeController)
 
 	// Serve a simple message at the root URL
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm"
 	"log"
 	"os"
+	"strings"
 )
 
 func SetupDatabase() *gorm.DB {
@@ -37,6 +38,10 @@ func SetupDatabase() *gorm.DB {
 	}
 	log.Println("Database connection established.")
 
+	if err := migrateOrderHistory(db); err != nil {
+		log.Fatalf("Failed to migrate order history: %v", err)
+	}
+
 	// Auto-migrate all tables
 	if err := db.AutoMigrate(
 		&models.Store{},
@@ -56,3 +61,31 @@ func SetupDatabase() *gorm.DB {
 
 	return db
 }
+
+// migrateOrderHistory moves aside the integer order_id of order-history, which could not hold the UUID of an
+// order, so AutoMigrate can add it back as a UUID. The old values are kept in legacy_order_id.
+func migrateOrderHistory(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.OrderHistory{}) {
+		return nil
+	}
+	columns, err := db.Migrator().ColumnTypes(&models.OrderHistory{})
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if column.Name() != "order_id" || strings.EqualFold(column.DatabaseTypeName(), "uuid") {
+			continue
+		}
+		log.Println("Moving the integer order_id of order-history to legacy_order_id")
+		if db.Migrator().HasIndex(&models.OrderHistory{}, "OrderID") {
+			if err := db.Migrator().DropIndex(&models.OrderHistory{}, "OrderID"); err != nil {
+				return err
+			}
+		}
+		if err := db.Migrator().RenameColumn(&models.OrderHistory{}, "order_id", "legacy_order_id"); err != nil {
+			return err
+		}
+		return db.Exec(`ALTER TABLE "order-history" ALTER COLUMN legacy_order_id DROP NOT NULL`).Error
+	}
+	return nil
+}
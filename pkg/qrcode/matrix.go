@@ -0,0 +1,270 @@
+package qrcode
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{Version: version, Level: level, Size: size, modules: make([][]bool, size)}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+	}
+	c.drawFunctionPatterns()
+	return c
+}
+
+// isFunction reports whether a module belongs to a function pattern rather than data
+func (c *Code) isFunction(x, y int) bool {
+	size := c.Size
+	switch {
+	case x <= 8 && y <= 8, x >= size-8 && y <= 8, x <= 8 && y >= size-8: // Finders, separators, format
+		return true
+	case x == 6 || y == 6: // Timing
+		return true
+	case c.Version >= 7 && ((x >= size-11 && x < size-8 && y < 6) || (y >= size-11 && y < size-8 && x < 6)): // Version
+		return true
+	}
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, py := range positions {
+		for j, px := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			if abs(x-px) <= 2 && abs(y-py) <= 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.modules[6][i] = i%2 == 0
+		c.modules[i][6] = i%2 == 0
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, py := range positions {
+		for j, px := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.modules[py+dy][px+dx] = max(abs(dx), abs(dy)) != 1
+				}
+			}
+		}
+	}
+
+	c.drawFormat(0)
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its separator around the centre x, y
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.modules[yy][xx] = dist != 2 && dist != 4
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information for a mask, and the dark module
+func (c *Code) drawFormat(mask int) {
+	data := formatLevelBits[c.Level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.modules[i][8] = bit(i)
+	}
+	c.modules[7][8] = bit(6)
+	c.modules[8][8] = bit(7)
+	c.modules[8][7] = bit(8)
+	for i := 9; i < 15; i++ {
+		c.modules[8][14-i] = bit(i)
+	}
+
+	for i := 0; i < 8; i++ {
+		c.modules[8][c.Size-1-i] = bit(i)
+	}
+	for i := 8; i < 15; i++ {
+		c.modules[c.Size-15+i][8] = bit(i)
+	}
+	c.modules[c.Size-8][8] = true
+}
+
+// drawVersion draws both copies of the version information of versions 7 and up
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.modules[b][a] = dark
+		c.modules[a][b] = dark
+	}
+}
+
+// drawData places the codewords in the zigzag order, upwards and downwards in column pairs from the right
+func (c *Code) drawData(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.isFunction(x, y) && i < len(codewords)*8 {
+					c.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by a mask pattern; applying it twice undoes it
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.isFunction(x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty score
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty scores how hard the symbol is to read: long runs, 2x2 blocks, finder-like patterns and imbalance
+func (c *Code) penalty() int {
+	size := c.Size
+	penalty := 0
+	get := func(x, y int, vertical bool) bool {
+		if vertical {
+			return c.modules[x][y]
+		}
+		return c.modules[y][x]
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			run := 1
+			for x := 1; x < size; x++ {
+				if get(x, y, vertical) == get(x-1, y, vertical) {
+					run++
+					if run == 5 {
+						penalty += 3
+					} else if run > 5 {
+						penalty++
+					}
+				} else {
+					run = 1
+				}
+			}
+			for x := 0; x+11 <= size; x++ {
+				if finderLike(func(i int) bool { return get(x+i, y, vertical) }) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				v := c.modules[y][x]
+				if v == c.modules[y][x+1] && v == c.modules[y+1][x] && v == c.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := size * size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return penalty + max(k, 0)*10
+}
+
+// finderLike matches an 11 module window of dark-light-dark-dark-dark-light-dark with four light modules before
+// or after it
+func finderLike(at func(i int) bool) bool {
+	for _, pattern := range [2]uint16{0b00001011101, 0b10111010000} {
+		match := true
+		for i := 0; i < 11 && match; i++ {
+			match = at(i) == ((pattern>>(10-i))&1 == 1)
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+// Package qrcode encodes text as a QR code (ISO/IEC 18004, byte mode, versions 1 to 40) and renders it as a
+// PNG image. It only covers what the API needs, e.g. PromptPay payment codes, and has no dependencies outside
+// the standard library.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Level is the error correction level; higher levels survive more damage but need a larger code
+type Level int
+
+const (
+	Low      Level = iota // Recovers about 7% of the code
+	Medium                // About 15%
+	Quartile              // About 25%
+	High                  // About 30%
+)
+
+// ErrTooLong is returned when the text does not fit in a version 40 code at the requested level
+var ErrTooLong = errors.New("qrcode: text is too long")
+
+// Code is an encoded QR code
+type Code struct {
+	Version int
+	Level   Level
+	Size    int      // Modules per side
+	modules [][]bool // [y][x], true is dark
+}
+
+// Dark reports whether the module at column x, row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encodes text in the smallest version that fits at the given level
+func Encode(text string, level Level) (*Code, error) {
+	data := []byte(text)
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if 4+charCountBits(v)+8*len(data) <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	// Byte mode segment, terminator and padding
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := dataCodewords(version, level) * 8
+	bits.append(0, min(4, capacity-bits.len()))
+	bits.append(0, (8-bits.len()%8)%8)
+	for pad := 0xEC; bits.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	code := newCode(version, level)
+	code.drawData(addErrorCorrection(bits.bytes(), version, level))
+	code.applyBestMask()
+	return code, nil
+}
+
+// Image renders the code with scale pixels per module and a quiet zone of border modules
+func (c *Code) Image(scale, border int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	side := (c.Size + 2*border) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+border)*scale+dx, (y+border)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG renders the code as a PNG image with the recommended quiet zone of 4 modules
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(scale, 4)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (value>>i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, len(b.bits)/8)
+	for i, bit := range b.bits {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
@@ -0,0 +1,200 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncodeMatchesReferenceSymbols(t *testing.T) {
+	// Symbols made by an independent encoder (github.com/skip2/go-qrcode), "#" dark. Encoders may pick
+	// different masks, so these are payloads where both pick the same one.
+	tests := []struct {
+		text    string
+		level   Level
+		version int
+		symbol  []string
+	}{
+		{
+			text:    "hello world",
+			level:   Medium,
+			version: 1,
+			symbol: []string{
+				"#######..#.##.#######",
+				"#.....#...#...#.....#",
+				"#.###.#.####..#.###.#",
+				"#.###.#.###.#.#.###.#",
+				"#.###.#.#.#.#.#.###.#",
+				"#.....#.#..#..#.....#",
+				"#######.#.#.#.#######",
+				"........#.#..........",
+				"#.#####..#.#..#####..",
+				".##.##.#.#.########.#",
+				"#.#.####.##.###..###.",
+				"#.#..#...#.###..###..",
+				"...#.#####..###.....#",
+				"........#.#.#...##..#",
+				"#######....#..#...##.",
+				"#.....#.#....#.#.####",
+				"#.###.#.#..#..##....#",
+				"#.###.#.##..######...",
+				"#.###.#.##..#..#..#..",
+				"#.....#..##.##..###..",
+				"#######.##.##.#.#..#.",
+			},
+		},
+		{
+			text:    "hello world",
+			level:   High,
+			version: 2,
+			symbol: []string{
+				"#######.#.#.#...#.#######",
+				"#.....#.##...###..#.....#",
+				"#.###.#.#.#.##.##.#.###.#",
+				"#.###.#....##.##..#.###.#",
+				"#.###.#..#...##.#.#.###.#",
+				"#.....#.#..#..###.#.....#",
+				"#######.#.#.#.#.#.#######",
+				"........##..#..#.........",
+				"..###.#.###.#.######..###",
+				"###..#..#...##.#...#..#..",
+				"#...#.####....##.#..##.##",
+				"##.#.#.##...#...#.#....##",
+				"..#..###.#.#####.########",
+				"#.###...#..##..##..#..#..",
+				"#.....#..#.#.....#####.##",
+				"#.###.....#...#.#.###...#",
+				"#.#..###...##.#.#######..",
+				"........#....##.#...#.#..",
+				"#######......####.#.#.###",
+				"#.....#....#...##...##.#.",
+				"#.###.#.#.#...#########..",
+				"#.###.#.##...##...#.##..#",
+				"#.###.#.##.#####..##.#..#",
+				"#.....#..###..##.#.##...#",
+				"#######....#..#.#..#..###",
+			},
+		},
+		{
+			text:    "https://example.com/pay?ref=a1b2c3d4e5",
+			level:   Quartile,
+			version: 4,
+			symbol: []string{
+				"#######..#....###.#..##...#######",
+				"#.....#.#.#.######.##.#.#.#.....#",
+				"#.###.#..#.##..#####.#..#.#.###.#",
+				"#.###.#.#.#..####....##.#.#.###.#",
+				"#.###.#.######.#.##.#.#...#.###.#",
+				"#.....#..#.##...###.#..##.#.....#",
+				"#######.#.#.#.#.#.#.#.#.#.#######",
+				"........####.##.#..##............",
+				".#.####.#....###.#.##....##.##.#.",
+				".##.#....#.####....#..##....###..",
+				"......#...#.###..##..###..#.#####",
+				"##..#..###.#######.##..#..##..#.#",
+				"###...#.#..#....###.###..###...##",
+				"#..#....#..###.###.#...##..#.##..",
+				"..##..##.#.##...#....#..#.#.###..",
+				"##.....###.#...##..#.....#..###.#",
+				"##...##..###......###..#######...",
+				"....#......#.##...##.##...#.#.###",
+				".##.#.#####..#####.#...#.###.##.#",
+				"..#.....##..##.#...#.#..##..###..",
+				".#.#.###.##..#..#.#.##..#....#..#",
+				"##..##.##...##...#..####....###..",
+				"#.#####.##.###.#..#...##.##..#.##",
+				"#....#..#.#...#.#.....#.##..###.#",
+				"####.##..##..##.##.##..######...#",
+				"........#...#..#.##.....#...#.#..",
+				"#######..#.#####.########.#.#.##.",
+				"#.....#.####.##....#...##...#####",
+				"#.###.#.######..######.######....",
+				"#.###.#.###...###...#..###.#....#",
+				"#.###.#..#..##...##.#.##...##.###",
+				"#.....#.##.#..####....##.########",
+				"#######..####.##.##.....#.###....",
+			},
+		},
+	}
+	for _, tt := range tests {
+		code, err := Encode(tt.text, tt.level)
+		if err != nil {
+			t.Fatalf("Encode(%q) error = %v", tt.text, err)
+		}
+		if code.Version != tt.version || code.Size != len(tt.symbol) {
+			t.Fatalf("Encode(%q) is version %d of %d modules, want version %d of %d", tt.text, code.Version, code.Size, tt.version, len(tt.symbol))
+		}
+		if got := symbol(code); strings.Join(got, "\n") != strings.Join(tt.symbol, "\n") {
+			t.Errorf("Encode(%q, %d) =\n%s\nwant\n%s", tt.text, tt.level, strings.Join(got, "\n"), strings.Join(tt.symbol, "\n"))
+		}
+	}
+}
+
+func TestEncodeChoosesTheSmallestVersion(t *testing.T) {
+	// Version 1-L holds 17 bytes and version 40-L 2953
+	tests := []struct {
+		length  int
+		level   Level
+		version int
+	}{
+		{17, Low, 1},
+		{18, Low, 2},
+		{14, Medium, 1},
+		{15, Medium, 2},
+		{2953, Low, 40},
+	}
+	for _, tt := range tests {
+		code, err := Encode(strings.Repeat("a", tt.length), tt.level)
+		if err != nil {
+			t.Fatalf("Encode() of %d bytes error = %v", tt.length, err)
+		}
+		if code.Version != tt.version {
+			t.Errorf("Encode() of %d bytes at level %d is version %d, want %d", tt.length, tt.level, code.Version, tt.version)
+		}
+	}
+	if _, err := Encode(strings.Repeat("a", 2954), Low); err != ErrTooLong {
+		t.Errorf("Encode() of 2954 bytes error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode("hello world", Medium)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	data, err := code.PNG(2)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding the PNG: %v", err)
+	}
+	// 21 modules and a quiet zone of 4 on each side, 2 pixels each
+	if side := img.Bounds().Dx(); side != 58 || img.Bounds().Dy() != 58 {
+		t.Errorf("image is %v, want 58x58", img.Bounds())
+	}
+	if r, _, _, _ := img.At(7, 7).RGBA(); r != 0xffff {
+		t.Errorf("quiet zone is not light")
+	}
+	if r, _, _, _ := img.At(8, 8).RGBA(); r != 0 {
+		t.Errorf("top left finder corner is not dark")
+	}
+}
+
+func symbol(code *Code) []string {
+	rows := make([]string, code.Size)
+	for y := range rows {
+		var row strings.Builder
+		for x := 0; x < code.Size; x++ {
+			if code.Dark(x, y) {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+		rows[y] = row.String()
+	}
+	return rows
+}
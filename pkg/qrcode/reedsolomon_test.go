@@ -0,0 +1,68 @@
+package qrcode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRSDivisor(t *testing.T) {
+	// Generator polynomials of ISO/IEC 18004 Annex A, as integer coefficients below the leading x^n
+	tests := map[int][]byte{
+		7:  {127, 122, 154, 164, 11, 68, 117},
+		10: {216, 194, 159, 111, 199, 94, 95, 113, 157, 193},
+	}
+	for degree, want := range tests {
+		if got := rsDivisor(degree); !bytes.Equal(got, want) {
+			t.Errorf("rsDivisor(%d) = %v, want %v", degree, got, want)
+		}
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{
+			// ISO/IEC 18004 Annex I: "01234567" in numeric mode, version 1-M
+			name: "01234567 1-M",
+			data: []byte{16, 32, 12, 86, 97, 128, 236, 17, 236, 17, 236, 17, 236, 17, 236, 17},
+			want: []byte{165, 36, 212, 193, 237, 54, 199, 135, 44, 85},
+		},
+		{
+			name: "HELLO WORLD 1-M",
+			data: []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+			want: []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23},
+		},
+	}
+	for _, tt := range tests {
+		if got := rsRemainder(tt.data, rsDivisor(len(tt.want))); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: rsRemainder() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddErrorCorrectionInterleavesBlocks(t *testing.T) {
+	// Version 5-Q has two blocks of 15 data codewords followed by two of 16
+	data := make([]byte, dataCodewords(5, Quartile))
+	for i := range data {
+		data[i] = byte(i)
+	}
+	got := addErrorCorrection(data, 5, Quartile)
+	if len(got) != rawDataModules(5)/8 {
+		t.Fatalf("addErrorCorrection() returned %d codewords, want %d", len(got), rawDataModules(5)/8)
+	}
+	wantStart := []byte{0, 15, 30, 46, 1, 16, 31, 47}
+	if !bytes.Equal(got[:8], wantStart) {
+		t.Errorf("first codewords = %v, want %v", got[:8], wantStart)
+	}
+	// The last data codewords come from the two long blocks only
+	if got[60] != 45 || got[61] != 61 {
+		t.Errorf("codewords 60 and 61 = %d, %d, want 45, 61", got[60], got[61])
+	}
+	divisor := rsDivisor(eccCodewordsPerBlock[Quartile][5])
+	if ecc := rsRemainder(data[:15], divisor); got[62] != ecc[0] || got[len(got)-4] != ecc[len(ecc)-1] {
+		t.Errorf("error correction codewords of the first block are not interleaved first")
+	}
+}